@@ -15,6 +15,10 @@ import (
 
 	"moul.io/depviz/v4/internal/backend"
 	"moul.io/depviz/v4/internal/core"
+	"moul.io/depviz/v4/internal/core/gitea"
+	"moul.io/depviz/v4/internal/core/gitlab"
+	"moul.io/depviz/v4/internal/core/jira"
+	"moul.io/depviz/v4/internal/core/linear"
 	"moul.io/depviz/v4/live"
 )
 
@@ -58,10 +62,18 @@ func run(ctx context.Context, args []string) error {
 		return runQuery(ctx, dbPath, args)
 	case "brief":
 		return runBrief(ctx, dbPath, args)
+	case "forecast":
+		return runForecast(ctx, dbPath, args)
+	case "estimate":
+		return runEstimate(ctx, dbPath, args)
+	case "field":
+		return runField(ctx, dbPath, args)
 	case "gen":
 		return runGen(ctx, dbPath, args)
 	case "sync":
 		return runSync(ctx, dbPath, args)
+	case "push":
+		return runPush(ctx, dbPath, args)
 	case "live":
 		return runLive(ctx, args)
 	case "backup":
@@ -76,20 +88,41 @@ func run(ctx context.Context, args []string) error {
 }
 
 func runIngest(ctx context.Context, dbPath string, args []string) error {
-	if len(args) < 2 || args[0] != "events" {
-		return errors.New("usage: depviz ingest events <path> [--board default]")
+	if len(args) < 2 {
+		return errors.New("usage: depviz ingest events|flow|csv <path> [--board default]")
 	}
+	switch args[0] {
+	case "events":
+		return runIngestEvents(ctx, dbPath, args[1:])
+	case "flow":
+		return runIngestFlow(ctx, dbPath, args[1:])
+	case "csv":
+		return runIngestCSV(ctx, dbPath, args[1:])
+	default:
+		return fmt.Errorf("unknown ingest format %q", args[0])
+	}
+}
+
+func runIngestEvents(ctx context.Context, dbPath string, args []string) error {
 	fs := flag.NewFlagSet("ingest events", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	board := fs.String("board", core.DefaultBoardID, "board id")
-	if err := fs.Parse(args[2:]); err != nil {
+	check := fs.Bool("check", false, "report every problem with line and column, without writing")
+	if err := fs.Parse(args[1:]); err != nil {
 		return err
 	}
-	f, err := os.Open(args[1])
+	f, err := os.Open(args[0])
 	if err != nil {
 		return err
 	}
 	defer f.Close()
+	if *check {
+		diags, err := core.CheckEvents(f)
+		if err != nil {
+			return err
+		}
+		return reportDiagnostics(args[0], diags)
+	}
 	s, err := core.OpenStore(ctx, dbPath)
 	if err != nil {
 		return err
@@ -103,9 +136,84 @@ func runIngest(ctx context.Context, dbPath string, args []string) error {
 	return nil
 }
 
+func runIngestFlow(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("ingest flow", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	check := fs.Bool("check", false, "report every problem with line and column, without writing")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if *check {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		return reportDiagnostics(args[0], core.CheckFlow(string(data)))
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	doc, err := s.IngestFlow(ctx, f, *board)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	fmt.Printf("ingested %d nodes and %d edges into board %s\n", len(doc.Nodes), len(doc.Edges), *board)
+	return nil
+}
+
+func runIngestCSV(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("ingest csv", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	mapping := fs.String("map", "", "field=Column pairs, as in id=Key,title=Summary,state=Status,depends_on=Deps")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	m, err := core.ParseCSVMap(*mapping)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := s.IngestCSV(ctx, f, *board, core.CSVImportOptions{Map: m, Name: filepath.Base(args[0])})
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	fmt.Printf("ingested %d rows and %d links into board %s\n", res.Rows, res.Links, *board)
+	return nil
+}
+
+// reportDiagnostics prints diagnostics in the file:line:col format editors and
+// pre-commit hooks understand, and fails when any of them is an error.
+func reportDiagnostics(path string, diags core.Diagnostics) error {
+	for _, d := range diags {
+		fmt.Printf("%s:%s\n", path, d)
+	}
+	if errs := diags.Errors(); len(errs) > 0 {
+		return fmt.Errorf("%s: %d error(s), %d warning(s)", path, len(errs), len(diags)-len(errs))
+	}
+	return nil
+}
+
 func runBoard(ctx context.Context, dbPath string, args []string) error {
 	if len(args) == 0 {
-		return errors.New("usage: depviz board list | depviz board note <board> <text>")
+		return errors.New("usage: depviz board list|create|scope|note ...")
 	}
 	s, err := core.OpenStore(ctx, dbPath)
 	if err != nil {
@@ -119,9 +227,47 @@ func runBoard(ctx context.Context, dbPath string, args []string) error {
 			return err
 		}
 		for _, b := range boards {
-			fmt.Printf("%s\t%s\n", b.ID, b.Name)
+			fmt.Printf("%s\t%s\t%s\n", b.ID, b.Name, b.ScopeQuery)
 		}
 		return nil
+	case "create":
+		fs := flag.NewFlagSet("board create", flag.ContinueOnError)
+		fs.SetOutput(os.Stderr)
+		scope := fs.String("scope", "", "scope query, e.g. \"repo:a/b repo:a/c label:bug\"")
+		description := fs.String("description", "", "board description")
+		if len(args) < 2 {
+			return errors.New("usage: depviz board create <name> [--scope query] [--description text]")
+		}
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		if _, err := core.ParseGitHubScope(*scope); err != nil {
+			return err
+		}
+		b, err := s.CreateBoardWithConfig(ctx, args[1], *description, *scope, `{}`)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created board %s\n", b.ID)
+		return nil
+	case "scope":
+		if len(args) < 2 {
+			return errors.New("usage: depviz board scope <board> [query]")
+		}
+		if len(args) == 2 {
+			snap, err := s.Snapshot(ctx, args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Println(snap.Board.ScopeQuery)
+			return nil
+		}
+		b, err := s.SetBoardScope(ctx, args[1], strings.Join(args[2:], " "))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("board %s scope: %s\n", b.ID, b.ScopeQuery)
+		return nil
 	case "note":
 		if len(args) < 3 {
 			return errors.New("usage: depviz board note <board> <text>")
@@ -148,6 +294,7 @@ func runEdge(ctx context.Context, dbPath string, args []string) error {
 	fs.SetOutput(os.Stderr)
 	kind := fs.String("kind", "blocked_by", "edge kind")
 	board := fs.String("board", core.DefaultBoardID, "board id")
+	noCycles := fs.Bool("no-cycles", false, "refuse the edge if it would create a dependency cycle")
 	if err := fs.Parse(args[3:]); err != nil {
 		return err
 	}
@@ -156,7 +303,11 @@ func runEdge(ctx context.Context, dbPath string, args []string) error {
 		return err
 	}
 	defer s.Close()
-	e, err := s.AddEdge(ctx, *board, args[1], args[2], *kind, "local", map[string]string{"created_by": "depviz edge add"})
+	addEdge := s.AddEdge
+	if *noCycles {
+		addEdge = s.AddEdgeAcyclic
+	}
+	e, err := addEdge(ctx, *board, args[1], args[2], *kind, "local", map[string]string{"created_by": "depviz edge add"})
 	if err != nil {
 		return err
 	}
@@ -166,11 +317,13 @@ func runEdge(ctx context.Context, dbPath string, args []string) error {
 
 func runQuery(ctx context.Context, dbPath string, args []string) error {
 	if len(args) == 0 {
-		return errors.New("usage: depviz query ready|blockers [--board default]")
+		return errors.New("usage: depviz query ready|blockers|cycles|critical-path|nodes [--board default] [--where key=value]")
 	}
 	fs := flag.NewFlagSet("query", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	board := fs.String("board", core.DefaultBoardID, "board id")
+	var where fieldFilterFlags
+	fs.Var(&where, "where", "field filter such as priority=high or points>=3 (repeatable)")
 	if err := fs.Parse(args[1:]); err != nil {
 		return err
 	}
@@ -179,6 +332,34 @@ func runQuery(ctx context.Context, dbPath string, args []string) error {
 		return err
 	}
 	defer s.Close()
+	snap, err := s.Snapshot(ctx, *board)
+	if err != nil {
+		return err
+	}
+	match, err := core.MatchFieldFilters(snap, where)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "nodes":
+		for _, n := range snap.Nodes {
+			if match[n.ID] {
+				fmt.Printf("%s\t%s\t%s\n", n.ID, n.State, n.Title)
+			}
+		}
+		return nil
+	case "critical-path":
+		for _, p := range core.BuildCriticalPaths(snap) {
+			if !match[p.Goal] {
+				continue
+			}
+			fmt.Printf("%s\tlength %d\n", p.Goal, p.Length)
+			for _, item := range p.Chain {
+				fmt.Printf("\t%s\t%d\t%s\n", item.ID, item.TransitiveImpact, item.Title)
+			}
+		}
+		return nil
+	}
 	brief, err := s.BuildBrief(ctx, *board)
 	if err != nil {
 		return err
@@ -186,11 +367,22 @@ func runQuery(ctx context.Context, dbPath string, args []string) error {
 	switch args[0] {
 	case "ready":
 		for _, item := range brief.Ready {
-			fmt.Printf("%s\t%s\t%s\n", item.ID, item.State, item.Title)
+			if match[item.ID] {
+				fmt.Printf("%s\t%s\t%s\n", item.ID, item.State, item.Title)
+			}
 		}
 	case "blockers":
 		for _, item := range brief.Blockers {
-			fmt.Printf("%s\t%d\t%s\n", item.ID, item.Impact, item.Title)
+			if match[item.ID] {
+				fmt.Printf("%s\t%d\t%s\n", item.ID, item.Impact, item.Title)
+			}
+		}
+	case "cycles":
+		for i, c := range brief.Cycles {
+			fmt.Printf("cycle %d\t%s\n", i+1, strings.Join(c.Nodes, " "))
+			for _, e := range c.Edges {
+				fmt.Printf("\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Blocked, e.Blocker, e.Kind, e.Authority)
+			}
 		}
 	default:
 		return fmt.Errorf("unknown query %q", args[0])
@@ -198,6 +390,96 @@ func runQuery(ctx context.Context, dbPath string, args []string) error {
 	return nil
 }
 
+// fieldFilterFlags collects repeated --where flags.
+type fieldFilterFlags []core.FieldFilter
+
+func (f *fieldFilterFlags) String() string {
+	parts := make([]string, len(*f))
+	for i, filter := range *f {
+		parts[i] = filter.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *fieldFilterFlags) Set(value string) error {
+	filter, err := core.ParseFieldFilter(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, filter)
+	return nil
+}
+
+func runField(ctx context.Context, dbPath string, args []string) error {
+	const fieldUsage = "usage: depviz field define|list|set|get|unset ... [--board default]"
+	if len(args) == 0 {
+		return errors.New(fieldUsage)
+	}
+	fs := flag.NewFlagSet("field "+args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	fieldType := fs.String("type", core.FieldTypeText, "field type (enum, number, date, person, text)")
+	options := fs.String("options", "", "comma-separated enum options")
+	label := fs.String("label", "", "display label")
+	authority := fs.String("authority", "local", "authority recorded with the value")
+	var positional []string
+	rest := args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	switch {
+	case args[0] == "define" && len(positional) == 1:
+		var opts []string
+		if *options != "" {
+			opts = strings.Split(*options, ",")
+		}
+		def, err := s.DefineField(ctx, core.FieldDefinition{BoardID: *board, Key: positional[0], Type: *fieldType, Label: *label, Options: opts})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("defined %s (%s) on board %s\n", def.Key, def.Type, def.BoardID)
+	case args[0] == "list" && len(positional) == 0:
+		defs, err := s.FieldDefinitions(ctx, *board)
+		if err != nil {
+			return err
+		}
+		for _, def := range defs {
+			fmt.Printf("%s\t%s\t%s\t%s\n", def.Key, def.Type, def.Label, strings.Join(def.Options, ","))
+		}
+	case args[0] == "set" && len(positional) == 3:
+		fv, err := s.SetNodeField(ctx, *board, positional[0], positional[1], positional[2], *authority)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s = %s\n", fv.OwnerID, fv.Key, fv.ValueJSON)
+	case args[0] == "get" && len(positional) == 1:
+		values, err := s.NodeFields(ctx, *board, positional[0])
+		if err != nil {
+			return err
+		}
+		for _, fv := range values {
+			fmt.Printf("%s\t%s\t%s\n", fv.Key, fv.ValueJSON, fv.Authority)
+		}
+	case args[0] == "unset" && len(positional) == 2:
+		return s.ClearNodeField(ctx, *board, positional[0], positional[1])
+	default:
+		return errors.New(fieldUsage)
+	}
+	return nil
+}
+
 func runBrief(ctx context.Context, dbPath string, args []string) error {
 	fs := flag.NewFlagSet("brief", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
@@ -244,15 +526,110 @@ func runBrief(ctx context.Context, dbPath string, args []string) error {
 	return core.RenderBrief(os.Stdout, brief)
 }
 
+func runForecast(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	target := fs.String("target", "", "node id to forecast")
+	trials := fs.Int("trials", 2000, "number of simulated schedules")
+	perOwner := fs.Int("per-owner", 1, "cards one owner works on at a time")
+	start := fs.String("start", "", "start date, YYYY-MM-DD (default today)")
+	seed := fs.Uint64("seed", 0, "random seed for reproducible runs")
+	format := fs.String("format", "text", "output format (text, json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *target == "" {
+		return errors.New("usage: depviz forecast --board default --target <node>")
+	}
+	opts := core.ForecastOptions{Target: *target, Trials: *trials, PerOwner: *perOwner, Seed: *seed}
+	if *start != "" {
+		t, err := time.Parse("2006-01-02", *start)
+		if err != nil {
+			return fmt.Errorf("invalid --start: %w", err)
+		}
+		opts.Start = t
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	forecast, err := s.Forecast(ctx, *board, opts)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+		return core.RenderForecast(os.Stdout, forecast)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(forecast)
+	default:
+		return fmt.Errorf("unknown forecast format %q", *format)
+	}
+}
+
+func runEstimate(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("estimate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	var positional []string
+	rest := args
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+	if len(positional) != 2 {
+		return errors.New("usage: depviz estimate <node> <3d|4h|2w|XS..XXL> [--board default]")
+	}
+	if _, ok := core.ParseEstimate(positional[1]); !ok {
+		return fmt.Errorf("invalid estimate %q: use 3d, 4h, 2w, a number of days, or XS..XXL", positional[1])
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ok, err := s.BoardHasNode(ctx, *board, positional[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no such node %s on board %s", positional[0], *board)
+	}
+	value, _ := json.Marshal(positional[1])
+	if err := s.SetFieldValue(ctx, core.FieldValue{BoardID: *board, OwnerType: "node", OwnerID: positional[0], Key: "estimate", ValueJSON: string(value)}); err != nil {
+		return err
+	}
+	fmt.Printf("estimate %s = %s\n", positional[0], positional[1])
+	return nil
+}
+
 func runGen(ctx context.Context, dbPath string, args []string) error {
 	if len(args) == 0 {
-		return errors.New("usage: depviz gen html|json --board default --out dist/depviz.html")
+		return errors.New("usage: depviz gen html|json|flow|csv|dot|mermaid|plantuml|svg|graphml|cytoscape --board default --out dist/depviz.html")
 	}
 	switch args[0] {
 	case "html":
 		return runGenHTML(ctx, dbPath, args[1:])
 	case "json":
 		return runGenJSON(ctx, dbPath, args[1:])
+	case "flow":
+		return runGenFlow(ctx, dbPath, args[1:])
+	case "csv":
+		return runGenCSV(ctx, dbPath, args[1:])
+	case "dot", "mermaid", "plantuml", "svg":
+		return runGenGraph(ctx, dbPath, args[0], args[1:])
+	case "graphml", "cytoscape":
+		return runGenGraphExport(ctx, dbPath, args[0], args[1:])
 	default:
 		return fmt.Errorf("unknown gen target %q", args[0])
 	}
@@ -318,26 +695,548 @@ func runGenJSON(ctx context.Context, dbPath string, args []string) error {
 	return nil
 }
 
+func runGenFlow(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("gen flow", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	out := fs.String("out", "-", "output file, - for stdout")
+	repo := fs.String("repo", "", "default repo for #N refs (default: the most common repo)")
+	inferred := fs.Bool("inferred", false, "include soft and inferred relations as comments")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	snap, err := s.Snapshot(ctx, *board)
+	if err != nil {
+		return err
+	}
+	text := core.RenderFlow(snap, core.FlowRenderOptions{Repo: *repo, Inferred: *inferred})
+	if *out == "-" {
+		_, err := fmt.Print(text)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, []byte(text), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *out)
+	return nil
+}
+
+func runGenCSV(ctx context.Context, dbPath string, args []string) error {
+	fs := flag.NewFlagSet("gen csv", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	snap, err := s.Snapshot(ctx, *board)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		return core.RenderCSV(os.Stdout, snap)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := core.RenderCSV(f, snap); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *out)
+	return nil
+}
+
+func runGenGraph(ctx context.Context, dbPath, format string, args []string) error {
+	fs := flag.NewFlagSet("gen "+format, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board id")
+	out := fs.String("out", "-", "output file, - for stdout")
+	cluster := fs.String("cluster", "", "group nodes by repo or owner")
+	hideClosed := fs.Bool("hide-closed", false, "leave closed nodes out")
+	focus := fs.String("focus", "", "only show the neighborhood of this node")
+	depth := fs.Int("depth", 1, "edges to follow from --focus")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	snap, err := s.Snapshot(ctx, *board)
+	if err != nil {
+		return err
+	}
+	opts := core.GraphRenderOptions{Format: format, Cluster: *cluster, HideClosed: *hideClosed, Focus: *focus, Depth: *depth}
+	render := core.RenderGraph
+	if format == "svg" {
+		render = core.RenderSVG
+	}
+	text, err := render(snap, opts)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err := fmt.Print(text)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, []byte(text), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *out)
+	return nil
+}
+
+func runGenGraphExport(ctx context.Context, dbPath, format string, args []string) error {
+	fs := flag.NewFlagSet("gen "+format, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board ids, separated by commas")
+	all := fs.Bool("all", false, "export every board")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var boards []string
+	if !*all {
+		for _, id := range strings.Split(*board, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				boards = append(boards, id)
+			}
+		}
+		if len(boards) == 0 {
+			return errors.New("--board is empty: name a board or use --all")
+		}
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	g, err := s.BuildGraphExport(ctx, boards)
+	if err != nil {
+		return err
+	}
+	render := core.RenderGraphML
+	if format == "cytoscape" {
+		render = core.RenderCytoscape
+	}
+	if *out == "-" {
+		return render(os.Stdout, g)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := render(f, g); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *out)
+	return nil
+}
+
 func runSync(ctx context.Context, dbPath string, args []string) error {
-	if len(args) < 2 || args[0] != "github" {
-		return errors.New("usage: depviz sync github owner/repo [--limit 200]")
+	if len(args) > 0 {
+		switch args[0] {
+		case "github-project":
+			return runSyncGitHubProject(ctx, dbPath, args[1:])
+		case "gitlab":
+			return runSyncGitLab(ctx, dbPath, args[1:])
+		case "jira":
+			return runSyncJira(ctx, dbPath, args[1:])
+		case "linear":
+			return runSyncLinear(ctx, dbPath, args[1:])
+		case "gitea":
+			return runSyncGitea(ctx, dbPath, args[1:])
+		case "md":
+			return runSyncMarkdown(ctx, dbPath, args[1:])
+		case "code":
+			return runSyncCode(ctx, dbPath, args[1:])
+		case "git":
+			return runSyncGit(ctx, dbPath, args[1:])
+		}
+	}
+	if len(args) == 0 || args[0] != "github" {
+		return errors.New("usage: depviz sync github [owner/repo] [--board default] [--scope query] [--limit 200] [--full] [--bodies-only]\n       depviz sync github-project <owner/number|url|id> [--board default] [--limit 500]\n       depviz sync gitlab <group/project> [--board default] [--limit 200] [--url https://gitlab.com]\n       depviz sync jira <KEY> [--jql query] [--board default] [--limit 200] [--url https://acme.atlassian.net]\n       depviz sync linear --team <KEY> [--board default] [--limit 200]\n       depviz sync gitea --url https://git.example --repo <owner/name> [--board default] [--limit 200]\n       depviz sync md <dir> [--board default] [--repo owner/name]\n       depviz sync code [./...] [--board default] [--repo owner/name]\n       depviz sync git [repo-path] [--board default] [--repo owner/name] [--limit 500] [--hard]")
+	}
+	args = args[1:]
+	var repo string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		repo, args = args[0], args[1:]
 	}
 	fs := flag.NewFlagSet("sync github", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
-	limit := fs.Int("limit", 200, "max issues and PRs to import")
-	if err := fs.Parse(args[2:]); err != nil {
+	board := fs.String("board", core.DefaultBoardID, "board to sync into, using its stored scope")
+	scope := fs.String("scope", "", "scope query overriding the board's, e.g. \"repo:a/b org:c label:bug\"")
+	login := fs.String("login", "", "GitHub login for my-work scopes (default: the token's user)")
+	limit := fs.Int("limit", 200, "max issues and PRs to import per repo or search")
+	full := fs.Bool("full", false, "ignore sync cursors and refetch everything in scope")
+	bodiesOnly := fs.Bool("bodies-only", false, "read relations from bodies only, skipping comments, reviews and timelines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncGitHub(ctx, s, core.GitHubSyncOptions{
+		Board:      *board,
+		Repo:       repo,
+		Scope:      *scope,
+		Login:      *login,
+		Limit:      *limit,
+		Full:       *full,
+		BodiesOnly: *bodiesOnly,
+	})
+	if err != nil {
+		return err
+	}
+	kind := "full"
+	if res.Incremental {
+		kind = "incremental"
+	}
+	fmt.Printf("synced %d GitHub cards and %d links from %s into board %s (%s, %d unchanged)\n", res.Items, res.Links, res.Scope, *board, kind, res.Unchanged)
+	if res.RateLimit.Limit > 0 {
+		fmt.Printf("GitHub rate limit: %d/%d remaining, resets %s\n", res.RateLimit.Remaining, res.RateLimit.Limit, res.RateLimit.Reset.Local().Format(time.Kitchen))
+	}
+	return nil
+}
+
+func runSyncGitHubProject(ctx context.Context, dbPath string, args []string) error {
+	var project string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		project, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync github-project", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	limit := fs.Int("limit", 500, "max project items to import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if project == "" && fs.NArg() > 0 {
+		project = fs.Arg(0)
+	}
+	if project == "" {
+		return errors.New("usage: depviz sync github-project <owner/number|url|id> [--board default] [--limit 500]")
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncGitHubProject(ctx, s, core.GitHubProjectSyncOptions{Board: *board, Project: project, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced project %q into board %s: %d issues and PRs, %d drafts, %d fields, %d links\n", res.Title, *board, res.Items, res.Drafts, res.Fields, res.Links)
+	return nil
+}
+
+func runSyncGitLab(ctx context.Context, dbPath string, args []string) error {
+	var project string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		project, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync gitlab", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	limit := fs.Int("limit", 200, "max issues, and max merge requests, to import")
+	instance := fs.String("url", "", "GitLab instance URL for self-hosted GitLab (default: $GITLAB_URL or https://gitlab.com)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if project == "" && fs.NArg() > 0 {
+		project = fs.Arg(0)
+	}
+	if project == "" {
+		return errors.New("usage: depviz sync gitlab <group/project> [--board default] [--limit 200] [--url https://gitlab.com]")
+	}
+	client := gitlab.FromEnv()
+	if *instance != "" {
+		client.BaseURL = gitlab.APIURL(*instance)
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncGitLab(ctx, s, core.GitLabSyncOptions{Board: *board, Project: project, Limit: *limit, Client: client})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d GitLab issues and merge requests and %d links from %s into board %s\n", res.Items, res.Links, res.Project, *board)
+	return nil
+}
+
+func runSyncJira(ctx context.Context, dbPath string, args []string) error {
+	var project string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		project, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync jira", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&project, "project", project, "Jira project key, such as OPS")
+	jql := fs.String("jql", "", "JQL narrowing the issues to import")
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	limit := fs.Int("limit", 200, "max issues to import")
+	site := fs.String("url", "", "Jira site URL (default: $JIRA_URL)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if project == "" && fs.NArg() > 0 {
+		project = fs.Arg(0)
+	}
+	if project == "" && *jql == "" {
+		return errors.New("usage: depviz sync jira <KEY> [--jql query] [--board default] [--limit 200] [--url https://acme.atlassian.net]")
+	}
+	client := jira.FromEnv()
+	if *site != "" {
+		client.BaseURL = *site
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncJira(ctx, s, core.JiraSyncOptions{Board: *board, Project: project, JQL: *jql, Limit: *limit, Client: client})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d Jira issues and %d links into board %s\n", res.Items, res.Links, *board)
+	return nil
+}
+
+func runSyncLinear(ctx context.Context, dbPath string, args []string) error {
+	var team string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		team, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync linear", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&team, "team", team, "Linear team key, such as ENG")
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	limit := fs.Int("limit", 200, "max issues to import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if team == "" && fs.NArg() > 0 {
+		team = fs.Arg(0)
+	}
+	if team == "" {
+		return errors.New("usage: depviz sync linear --team <KEY> [--board default] [--limit 200]")
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncLinear(ctx, s, core.LinearSyncOptions{Board: *board, Team: team, Limit: *limit, Client: linear.FromEnv()})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d Linear issues, %d projects, %d cycles and %d links from team %s into board %s\n", res.Items, res.Projects, res.Cycles, res.Links, res.Team, *board)
+	return nil
+}
+
+func runSyncGitea(ctx context.Context, dbPath string, args []string) error {
+	var repo string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		repo, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync gitea", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&repo, "repo", repo, "repo to import, as owner/name")
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	limit := fs.Int("limit", 200, "max issues, and max pull requests, to import")
+	instance := fs.String("url", "", "Gitea or Forgejo instance URL (default: $GITEA_URL)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if repo == "" && fs.NArg() > 0 {
+		repo = fs.Arg(0)
+	}
+	if repo == "" {
+		return errors.New("usage: depviz sync gitea --url https://git.example --repo <owner/name> [--board default] [--limit 200]")
+	}
+	client := gitea.FromEnv()
+	if *instance != "" {
+		client.BaseURL = *instance
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncGitea(ctx, s, core.GiteaSyncOptions{Board: *board, Repo: repo, Limit: *limit, Client: client})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d Gitea issues and pull requests and %d links from %s into board %s\n", res.Items, res.Links, res.Repo, *board)
+	return nil
+}
+
+func runSyncMarkdown(ctx context.Context, dbPath string, args []string) error {
+	var dir string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync md", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	repo := fs.String("repo", "", "owner/name that short refs like #412 point at")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if dir == "" && fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if dir == "" {
+		return errors.New("usage: depviz sync md <dir> [--board default] [--repo owner/name]")
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncMarkdown(ctx, s, core.MarkdownSyncOptions{Board: *board, Dir: dir, Repo: *repo})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d tasks and %d links from %d Markdown files into board %s, removed %d\n", res.Items, res.Links, res.Files, *board, res.Removed)
+	return nil
+}
+
+func runSyncCode(ctx context.Context, dbPath string, args []string) error {
+	dir := "./..."
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync code", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	repo := fs.String("repo", "", "owner/name that short refs like #123 point at")
+	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
 	s, err := core.OpenStore(ctx, dbPath)
 	if err != nil {
 		return err
 	}
 	defer s.Close()
-	count, err := core.SyncGitHub(ctx, s, core.GitHubSyncOptions{Repo: args[1], Limit: *limit})
+	res, err := core.SyncCode(ctx, s, core.CodeSyncOptions{Board: *board, Dir: dir, Repo: *repo})
 	if err != nil {
 		return err
 	}
-	fmt.Printf("synced %d GitHub cards from %s\n", count, args[1])
+	fmt.Printf("synced %d code comments and %d links from %d files into board %s, removed %d\n", res.Items, res.Links, res.Files, *board, res.Removed)
+	return nil
+}
+
+func runSyncGit(ctx context.Context, dbPath string, args []string) error {
+	path := "."
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("sync git", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board to sync into")
+	repo := fs.String("repo", "", "owner/name that refs and branch numbers point at (default: the origin remote)")
+	limit := fs.Int("limit", 500, "max commits to read")
+	hard := fs.Bool("hard", false, "store edges as hard instead of soft")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.SyncGit(ctx, s, core.GitSyncOptions{Board: *board, Path: path, Repo: *repo, Limit: *limit, Hard: *hard})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d commits, %d branches and %d links from %s into board %s, removed %d\n", res.Commits, res.Branches, res.Links, path, *board, res.Removed)
+	return nil
+}
+
+func runPush(ctx context.Context, dbPath string, args []string) error {
+	if len(args) == 0 || args[0] != "github" {
+		return errors.New("usage: depviz push github [--board default] [--dry-run] [--native]")
+	}
+	fs := flag.NewFlagSet("push github", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	board := fs.String("board", core.DefaultBoardID, "board whose edges to publish")
+	dryRun := fs.Bool("dry-run", false, "print the changes without writing to GitHub")
+	native := fs.Bool("native", false, "use GitHub's native blocked-by relations where available")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	s, err := core.OpenStore(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	res, err := core.PushGitHub(ctx, s, core.GitHubPushOptions{Board: *board, DryRun: *dryRun, Native: *native})
+	if err != nil {
+		return err
+	}
+	for _, change := range res.Changes {
+		fmt.Printf("%s\n", change.NodeID)
+		for _, line := range change.Removed {
+			fmt.Printf("  -%s\n", line)
+		}
+		for _, line := range change.Added {
+			fmt.Printf("  +%s\n", line)
+		}
+		for _, id := range change.Native {
+			fmt.Printf("  +native blocked by %s\n", id)
+		}
+	}
+	verb := "updated"
+	if res.DryRun {
+		verb = "would update"
+	}
+	for _, id := range res.Skipped {
+		fmt.Printf("%s\n  skipped: outside the board's repos and not writable\n", id)
+	}
+	for _, f := range res.Failed {
+		fmt.Printf("%s\n  failed: %s\n", f.NodeID, f.Error)
+	}
+	fmt.Printf("%s %d GitHub issues from board %s (%d unchanged)\n", verb, len(res.Changes), res.Board, res.Unchanged)
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d GitHub issues could not be updated", len(res.Failed))
+	}
 	return nil
 }
 
@@ -472,6 +1371,7 @@ func runServer(ctx context.Context, dbPath string, args []string) error {
 		GitHubAppID:             os.Getenv("DEPVIZ_GITHUB_APP_ID"),
 		GitHubAppPrivateKeyFile: os.Getenv("DEPVIZ_GITHUB_PRIVATE_KEY_FILE"),
 		GitHubWebhookSecret:     os.Getenv("DEPVIZ_GITHUB_WEBHOOK_SECRET"),
+		GitHubAPIURL:            os.Getenv("DEPVIZ_GITHUB_API_URL"),
 		SessionTTL:              30 * 24 * time.Hour,
 	}
 	srv := backend.NewServer(s, cfg)
@@ -517,15 +1417,37 @@ func usage() {
 
 Usage:
   depviz init
-  depviz ingest events <path>
-  depviz sync github owner/repo [--limit 200]
+  depviz ingest events <path> [--check]
+  depviz ingest flow <file.md|file.depviz> [--check]
+  depviz ingest csv <file.csv> [--board default] [--map id=Key,title=Summary,depends_on=Deps]
+  depviz sync github [owner/repo] [--board default] [--scope query] [--limit 200] [--full] [--bodies-only]
+  depviz sync github-project <owner/number|url|id> [--board default] [--limit 500]
+  depviz sync gitlab <group/project> [--board default] [--limit 200] [--url https://gitlab.com]
+  depviz sync jira <KEY> [--jql query] [--board default] [--limit 200] [--url https://acme.atlassian.net]
+  depviz sync linear --team <KEY> [--board default] [--limit 200]
+  depviz sync gitea --url https://git.example --repo <owner/name> [--board default] [--limit 200]
+  depviz sync md <dir> [--board default] [--repo owner/name]
+  depviz sync code [./...] [--board default] [--repo owner/name]
+  depviz sync git [repo-path] [--board default] [--repo owner/name] [--limit 500] [--hard]
+  depviz push github [--board default] [--dry-run] [--native]
   depviz board list
+  depviz board create <name> [--scope "repo:a/b org:c label:bug"]
+  depviz board scope <board> [query]
   depviz board note <board> <text>
-  depviz edge add <from> <to> --kind blocked_by
-  depviz query ready|blockers
+  depviz edge add <from> <to> --kind blocked_by [--no-cycles]
+  depviz query ready|blockers|cycles|critical-path|nodes [--board default] [--where key=value]
   depviz brief [--workflow=board-status]
+  depviz estimate <node> <3d|4h|2w|XS..XXL> [--board default]
+  depviz field define <key> --type enum|number|date|person|text [--options a,b]
+  depviz field list|get <node>|set <node> <key> <value>|unset <node> <key>
+  depviz forecast --board default --target <node> [--per-owner 1] [--seed N]
   depviz gen html --board default --view graph --out dist/depviz.html
   depviz gen json --board default --out dist/depviz.json
+  depviz gen flow --board default [--out plan.depviz] [--inferred]
+  depviz gen csv --board default [--out items.csv]
+  depviz gen dot|mermaid|plantuml --board default [--out graph.dot] [--cluster repo|owner] [--hide-closed] [--focus <id>] [--depth 1]
+  depviz gen svg --board default [--out graph.svg] [--hide-closed] [--focus <id>] [--depth 1]
+  depviz gen graphml|cytoscape [--board default[,other]] [--all] [--out graph.graphml]
   depviz live --addr 127.0.0.1:8686
   depviz backup [--out backups]
   depviz restore --from <backup.db> [--force]
@@ -544,5 +1466,8 @@ Environment:
   DEPVIZ_GITHUB_APP_ID         GitHub App id
   DEPVIZ_GITHUB_PRIVATE_KEY_FILE
                                GitHub App private key PEM path
-  DEPVIZ_GITHUB_WEBHOOK_SECRET GitHub App webhook secret`)
+  DEPVIZ_GITHUB_WEBHOOK_SECRET GitHub App webhook secret
+  DEPVIZ_GITHUB_API_URL        server GitHub API URL, for GitHub Enterprise
+  GITHUB_TOKEN, GH_TOKEN       CLI GitHub token, else "gh auth token"
+  GITHUB_API_URL               CLI GitHub API URL, for GitHub Enterprise`)
 }
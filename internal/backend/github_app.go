@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"crypto"
 	"crypto/hmac"
@@ -23,6 +22,7 @@ import (
 	"time"
 
 	"moul.io/depviz/v4/internal/core"
+	"moul.io/depviz/v4/internal/core/github"
 )
 
 func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
@@ -55,16 +55,14 @@ func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
-	case "issues":
-		if err := s.ingestGitHubIssueWebhook(r.Context(), body); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-			return
-		}
-	case "pull_request":
-		if err := s.ingestGitHubPullRequestWebhook(r.Context(), body); err != nil {
+	default:
+		res, err := core.IngestGitHubWebhook(r.Context(), s.store, event, body)
+		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "event": event, "result": res})
+		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "event": event})
 }
@@ -74,42 +72,20 @@ func (s *Server) syncGitHubInstallation(ctx context.Context, installationID int6
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://api.github.com/app/installations/%d", installationID), nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	req.Header.Set("Authorization", "Bearer "+jwt)
 	var installation githubInstallationPayload
-	if err := s.doJSON(req, &installation); err != nil {
+	if err := s.githubClient(jwt).Get(ctx, fmt.Sprintf("/app/installations/%d", installationID), &installation); err != nil {
 		return err
 	}
 	raw, _ := json.Marshal(installation)
 	return s.upsertGitHubInstallation(ctx, installation, raw)
 }
 
-func (s *Server) githubInstallationAccessToken(ctx context.Context, installationID int64) (githubInstallationToken, error) {
+func (s *Server) githubInstallationAccessToken(ctx context.Context, installationID int64) (github.InstallationToken, error) {
 	jwt, err := s.githubAppJWT()
 	if err != nil {
-		return githubInstallationToken{}, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://api.github.com/app/installations/%d/access_tokens", installationID), bytes.NewReader([]byte(`{}`)))
-	if err != nil {
-		return githubInstallationToken{}, err
+		return github.InstallationToken{}, err
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	var token githubInstallationToken
-	if err := s.doJSON(req, &token); err != nil {
-		return githubInstallationToken{}, err
-	}
-	if token.Token == "" {
-		return githubInstallationToken{}, errors.New("github did not return an installation token")
-	}
-	return token, nil
+	return s.githubClient(jwt).CreateInstallationToken(ctx, installationID)
 }
 
 func (s *Server) githubAppJWT() (string, error) {
@@ -231,11 +207,6 @@ func parseInstallationID(value string) int64 {
 	return id
 }
 
-type githubInstallationToken struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-}
-
 type githubInstallationPayload struct {
 	ID                  int64  `json:"id"`
 	TargetType          string `json:"target_type"`
@@ -249,82 +220,3 @@ type githubInstallationPayload struct {
 		AvatarURL string `json:"avatar_url"`
 	} `json:"account"`
 }
-
-func (s *Server) ingestGitHubIssueWebhook(ctx context.Context, body []byte) error {
-	var payload struct {
-		Action string `json:"action"`
-		Issue  struct {
-			ID      int64  `json:"id"`
-			Number  int    `json:"number"`
-			Title   string `json:"title"`
-			State   string `json:"state"`
-			HTMLURL string `json:"html_url"`
-			User    struct {
-				Login string `json:"login"`
-			} `json:"user"`
-		} `json:"issue"`
-		Repository struct {
-			FullName string `json:"full_name"`
-		} `json:"repository"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return err
-	}
-	if payload.Issue.Number == 0 || payload.Repository.FullName == "" {
-		return nil
-	}
-	state := payload.Issue.State
-	if state == "" {
-		state = "open"
-	}
-	nodeID := fmt.Sprintf("gh:%s#%d", payload.Repository.FullName, payload.Issue.Number)
-	raw, _ := json.Marshal(payload.Issue)
-	return s.store.UpsertNode(ctx, core.Node{
-		ID:       nodeID,
-		Kind:     "issue",
-		Title:    payload.Issue.Title,
-		State:    state,
-		Owner:    payload.Issue.User.Login,
-		DataJSON: string(raw),
-	})
-}
-
-func (s *Server) ingestGitHubPullRequestWebhook(ctx context.Context, body []byte) error {
-	var payload struct {
-		Action      string `json:"action"`
-		PullRequest struct {
-			ID      int64  `json:"id"`
-			Number  int    `json:"number"`
-			Title   string `json:"title"`
-			State   string `json:"state"`
-			Draft   bool   `json:"draft"`
-			HTMLURL string `json:"html_url"`
-			User    struct {
-				Login string `json:"login"`
-			} `json:"user"`
-		} `json:"pull_request"`
-		Repository struct {
-			FullName string `json:"full_name"`
-		} `json:"repository"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return err
-	}
-	if payload.PullRequest.Number == 0 || payload.Repository.FullName == "" {
-		return nil
-	}
-	state := payload.PullRequest.State
-	if state == "" {
-		state = "open"
-	}
-	nodeID := fmt.Sprintf("gh:%s!%d", payload.Repository.FullName, payload.PullRequest.Number)
-	raw, _ := json.Marshal(payload.PullRequest)
-	return s.store.UpsertNode(ctx, core.Node{
-		ID:       nodeID,
-		Kind:     "pull_request",
-		Title:    payload.PullRequest.Title,
-		State:    state,
-		Owner:    payload.PullRequest.User.Login,
-		DataJSON: string(raw),
-	})
-}
@@ -11,11 +11,13 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"moul.io/depviz/v4/internal/core"
+	"moul.io/depviz/v4/internal/core/github"
 	"moul.io/depviz/v4/live"
 )
 
@@ -135,7 +137,10 @@ type Config struct {
 	GitHubAppID             string
 	GitHubAppPrivateKeyFile string
 	GitHubWebhookSecret     string
-	SessionTTL              time.Duration
+	// GitHubAPIURL overrides github.DefaultBaseURL, for GitHub Enterprise
+	// Server or a test stand-in.
+	GitHubAPIURL string
+	SessionTTL   time.Duration
 	// BasicAuthUser/BasicAuthPass gate every route except /api/health when set.
 	// Without them a deployed instance is world-readable: sessions only exist via
 	// GitHub OAuth, so an instance with no OAuth app configured has no other gate.
@@ -179,15 +184,19 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/api/board-sync", s.handleBoardSync)
 	mux.HandleFunc("/api/github/orgs", s.handleGitHubOrgs)
 	mux.HandleFunc("/api/github/projects", s.handleGitHubProjects)
+	mux.HandleFunc("/api/github/projects/sync", s.handleGitHubProjectSync)
 	mux.HandleFunc("/api/github/repos", s.handleGitHubRepos)
 	mux.HandleFunc("/api/github/webhook", s.handleGitHubWebhook)
 	mux.HandleFunc("/api/github/create-issue", s.handleCreateGitHubIssue)
 	mux.HandleFunc("/api/github/update-issue", s.handleUpdateGitHubIssue)
 	mux.HandleFunc("/api/github/comment", s.handleCreateGitHubComment)
+	mux.HandleFunc("/api/github/push", s.handleGitHubPush)
 	mux.HandleFunc("/api/board-source/apply", s.handleBoardSourceApply)
 	mux.HandleFunc("/api/suggestions/dismiss", s.handleDismissSuggestion)
 	mux.HandleFunc("/api/board-sync-logs", s.handleBoardSyncLogs)
 	mux.HandleFunc("/api/board-views", s.handleBoardViews)
+	mux.HandleFunc("/api/fields", s.handleFields)
+	mux.HandleFunc("/api/node-fields", s.handleNodeFields)
 	mux.HandleFunc("/api/overrides", s.handleOverrides)
 	mux.HandleFunc("/api/auth/github/start", s.handleGitHubStart)
 	mux.HandleFunc("/api/auth/github/callback", s.handleGitHubCallback)
@@ -268,13 +277,17 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
-	if _, ok := s.requireAccount(w, r); !ok {
+	account, ok := s.requireAccount(w, r)
+	if !ok {
 		return
 	}
 	board := r.URL.Query().Get("board")
 	if board == "" {
 		board = core.DefaultBoardID
 	}
+	if !s.requireBoardAccess(w, r, board, account) {
+		return
+	}
 	payload, err := s.store.BuildExport(r.Context(), board)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
@@ -284,66 +297,22 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	if format == "flow" {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
-		_, _ = fmt.Fprint(w, snapshotToFlowText(payload))
+		_, _ = fmt.Fprint(w, core.RenderFlow(payload.Snapshot, core.FlowRenderOptions{Repo: r.URL.Query().Get("repo"), Inferred: r.URL.Query().Get("inferred") == "1"}))
 		return
 	}
-	writeJSON(w, http.StatusOK, payload)
-}
-
-func snapshotToFlowText(payload any) string {
-	data, _ := json.Marshal(payload)
-	var export struct {
-		Snapshot struct {
-			Board struct {
-				Name string `json:"name"`
-				ID   string `json:"id"`
-			} `json:"board"`
-			Nodes []struct {
-				ID    string `json:"id"`
-				Kind  string `json:"kind"`
-				Title string `json:"title"`
-				State string `json:"state"`
-				Owner string `json:"owner"`
-			} `json:"nodes"`
-			Edges []struct {
-				FromID    string `json:"from_id"`
-				ToID      string `json:"to_id"`
-				Kind      string `json:"kind"`
-				Authority string `json:"authority"`
-			} `json:"edges"`
-		} `json:"snapshot"`
-	}
-	if err := json.Unmarshal(data, &export); err != nil {
-		return ""
-	}
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("board %q\n", export.Snapshot.Board.Name))
-	for _, n := range export.Snapshot.Nodes {
-		if strings.HasPrefix(n.ID, "gh:") {
-			continue
-		}
-		sb.WriteString(fmt.Sprintf("%s %s", n.Kind, strings.ReplaceAll(n.ID, n.Kind+":", "")))
-		if n.Title != "" && n.Title != n.ID {
-			sb.WriteString(fmt.Sprintf(" %q", n.Title))
-		}
-		if n.State != "" && n.State != "open" {
-			sb.WriteString(fmt.Sprintf(" [%s]", n.State))
-		}
-		sb.WriteString("\n")
-	}
-	for _, e := range export.Snapshot.Edges {
-		if e.Authority == "local" || e.Authority == "user" {
-			verb := e.Kind
-			if e.Kind == "blocked_by" {
-				verb = "depends on"
-			}
-			if e.Kind == "relates_to" {
-				verb = "relates to"
-			}
-			sb.WriteString(fmt.Sprintf("%s %s %s\n", e.FromID, verb, e.ToID))
+	if format == "svg" {
+		depth, _ := strconv.Atoi(r.URL.Query().Get("depth"))
+		image, err := core.RenderSVG(payload.Snapshot, core.GraphRenderOptions{HideClosed: r.URL.Query().Get("hide_closed") == "1", Focus: r.URL.Query().Get("focus"), Depth: depth})
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
 		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, image)
+		return
 	}
-	return sb.String()
+	writeJSON(w, http.StatusOK, payload)
 }
 
 func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
@@ -706,6 +675,7 @@ func (s *Server) handleBoardSync(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		BoardID string `json:"board_id"`
 		Limit   int    `json:"limit"`
+		Full    bool   `json:"full"`
 	}
 	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -734,11 +704,12 @@ func (s *Server) handleBoardSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	syncCtx := withActivity(r.Context(), act)
-	count, edges, err := s.syncGitHubBoardScope(syncCtx, token, account.Login, boardID, snap.Board, limit)
+	res, err := s.syncGitHubBoardScope(syncCtx, token, account, boardID, tokenMode, limit, in.Full)
 	if err != nil && canRetryGitHubPublicSync(err, tokenMode, snap.Board) {
-		count, edges, err = s.syncGitHubBoardScope(syncCtx, "", account.Login, boardID, snap.Board, limit)
 		tokenMode = "github-public-rest"
+		res, err = s.syncGitHubBoardScope(syncCtx, "", account, boardID, tokenMode, limit, in.Full)
 	}
+	count, edges := res.Items, res.Links
 	if err != nil {
 		err = friendlyGitHubSyncError(err, tokenMode, snap.Board.ScopeQuery)
 		s.activities.Fail(act, err.Error())
@@ -747,8 +718,8 @@ func (s *Server) handleBoardSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.activities.Finish(act, count, 0, fmt.Sprintf("%d items, %d links", count, edges))
-	_ = s.store.RecordBoardSync(r.Context(), boardID, "ok", map[string]any{"scope": snap.Board.ScopeQuery, "limit": limit, "mode": tokenMode, "items": count, "links": edges})
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "scope": snap.Board.ScopeQuery, "mode": tokenMode, "items": count, "links": edges})
+	_ = s.store.RecordBoardSync(r.Context(), boardID, "ok", map[string]any{"scope": snap.Board.ScopeQuery, "limit": limit, "mode": tokenMode, "items": count, "links": edges, "unchanged": res.Unchanged, "incremental": res.Incremental})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "scope": snap.Board.ScopeQuery, "mode": tokenMode, "items": count, "links": edges, "unchanged": res.Unchanged, "incremental": res.Incremental, "rate_limit": res.RateLimit})
 }
 
 func (s *Server) handleGitHubStart(w http.ResponseWriter, r *http.Request) {
@@ -904,7 +875,7 @@ func (s *Server) handleGitHubRepos(w http.ResponseWriter, r *http.Request) {
 	}
 	var repos []githubRepo
 	path := "/user/repos?affiliation=owner,collaborator,organization_member&sort=updated&per_page=100"
-	if err := s.doGitHubREST(r.Context(), token, path, &repos); err != nil {
+	if err := s.githubClient(token).Get(r.Context(), path, &repos); err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
@@ -940,7 +911,7 @@ func (s *Server) handleGitHubOrgs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var orgs []githubOrg
-	if err := s.doGitHubREST(r.Context(), token, "/user/orgs?per_page=100", &orgs); err != nil {
+	if err := s.githubClient(token).Get(r.Context(), "/user/orgs?per_page=100", &orgs); err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
@@ -991,7 +962,7 @@ func (s *Server) handleGitHubProjects(w http.ResponseWriter, r *http.Request) {
 		}
 	}`
 	var gql githubProjectsGraphQL
-	if err := s.doGitHubGraphQL(r.Context(), token, query, &gql); err != nil {
+	if err := s.githubClient(token).GraphQL(r.Context(), query, nil, &gql); err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
@@ -1023,6 +994,63 @@ func (s *Server) handleGitHubProjects(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"projects": projects})
 }
 
+func (s *Server) handleGitHubProjectSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	account, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		BoardID string `json:"board_id"`
+		Project string `json:"project"`
+		Limit   int    `json:"limit"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	boardID := strings.TrimSpace(in.BoardID)
+	if boardID == "" {
+		boardID = core.DefaultBoardID
+	}
+	if strings.TrimSpace(in.Project) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "project is required"})
+		return
+	}
+	if !s.requireBoardAccess(w, r, boardID, account) {
+		return
+	}
+	snap, err := s.store.Snapshot(r.Context(), boardID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	token, tokenMode, err := s.githubTokenForBoardSync(r.Context(), account.ID, snap.Board)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	act := s.activities.Start("sync", "Syncing project "+in.Project)
+	res, err := core.SyncGitHubProject(r.Context(), s.store, core.GitHubProjectSyncOptions{
+		Board:   boardID,
+		Project: in.Project,
+		Limit:   in.Limit,
+		Fetcher: core.GitHubRESTFetcher{Client: s.githubClient(token)},
+	})
+	if err != nil {
+		err = friendlyGitHubSyncError(err, tokenMode, in.Project)
+		s.activities.Fail(act, err.Error())
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	s.activities.Finish(act, res.Items+res.Drafts, res.Items+res.Drafts, fmt.Sprintf("%d items, %d drafts, %d fields", res.Items, res.Drafts, res.Fields))
+	writeJSON(w, http.StatusOK, res)
+}
+
 func (s *Server) handleOverrides(w http.ResponseWriter, r *http.Request) {
 	account, ok := s.requireAccount(w, r)
 	if !ok {
@@ -1118,6 +1146,21 @@ func (s *Server) requireBoardAccess(w http.ResponseWriter, r *http.Request, boar
 	return true
 }
 
+// requireBoardNode answers 404 unless nodeID is an item of boardID, so board
+// access cannot reach nodes that only live on other boards.
+func (s *Server) requireBoardNode(w http.ResponseWriter, r *http.Request, boardID, nodeID string) bool {
+	ok, err := s.store.BoardHasNode(r.Context(), boardID, nodeID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return false
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "node not found on board"})
+		return false
+	}
+	return true
+}
+
 func (s *Server) githubAccessTokenForAccount(w http.ResponseWriter, r *http.Request, accountID string) (string, bool) {
 	conn, ok, err := s.store.OAuthConnectionForAccount(r.Context(), accountID, "github")
 	if err != nil {
@@ -1185,13 +1228,6 @@ func (s *Server) githubInstallationTokenForOwner(ctx context.Context, owner stri
 	return "", false, nil
 }
 
-func githubOwnerForBoard(board core.Board) string {
-	if repo := repoForBoard(board); repo != "" {
-		return strings.Split(repo, "/")[0]
-	}
-	return orgForBoard(board)
-}
-
 func friendlyGitHubSyncError(err error, tokenMode, scope string) error {
 	msg := strings.TrimSpace(err.Error())
 	switch {
@@ -1243,15 +1279,8 @@ func (s *Server) exchangeGitHubCode(ctx context.Context, code string) (githubTok
 }
 
 func (s *Server) fetchGitHubUser(ctx context.Context, accessToken string) (githubUser, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
-	if err != nil {
-		return githubUser{}, err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	req.Header.Set("Authorization", "Bearer "+accessToken)
 	var user githubUser
-	if err := s.doJSON(req, &user); err != nil {
+	if err := s.githubClient(accessToken).Get(ctx, "/user", &user); err != nil {
 		return githubUser{}, err
 	}
 	if user.ID == 0 || user.Login == "" {
@@ -1260,235 +1289,37 @@ func (s *Server) fetchGitHubUser(ctx context.Context, accessToken string) (githu
 	return user, nil
 }
 
-func (s *Server) doGitHubREST(ctx context.Context, accessToken, path string, out any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com"+path, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
-	if accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-	}
-	return s.doJSON(req, out)
-}
-
-func (s *Server) doGitHubGraphQL(ctx context.Context, accessToken, query string, out any) error {
-	body, _ := json.Marshal(map[string]string{"query": query})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.github.com/graphql", bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	var envelope struct {
-		Data   json.RawMessage `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors"`
-	}
-	if err := s.doJSON(req, &envelope); err != nil {
-		return err
-	}
-	if len(envelope.Errors) > 0 {
-		return errors.New(envelope.Errors[0].Message)
-	}
-	return json.Unmarshal(envelope.Data, out)
-}
-
-func (s *Server) syncGitHubRepoBoard(ctx context.Context, accessToken, boardID, repo string, limit int) (int, int, error) {
-	sourceID := "github:" + repo
-	if err := s.store.UpsertSource(ctx, core.Source{
-		ID:           sourceID,
-		Kind:         "github",
-		Name:         repo,
-		URL:          "https://github.com/" + repo,
+// githubClient returns an API client for token, or an anonymous one when
+// token is empty.
+func (s *Server) githubClient(token string) *github.Client {
+	c := github.NewClient(token)
+	c.HTTPClient = s.client
+	if s.cfg.GitHubAPIURL != "" {
+		c.BaseURL = s.cfg.GitHubAPIURL
+	}
+	return c
+}
+
+func (s *Server) syncGitHubBoardScope(ctx context.Context, accessToken string, account core.Account, boardID, tokenMode string, limit int, full bool) (core.GitHubSyncResult, error) {
+	client := s.githubClient(accessToken)
+	client.Cache = core.GitHubResponseCache{Store: s.store, AccountID: account.ID}
+	// Anonymous public syncs get 60 requests an hour, so they read bodies only.
+	return core.SyncGitHub(ctx, s.store, core.GitHubSyncOptions{
+		Board:        boardID,
+		Login:        account.Login,
+		Limit:        limit,
+		Full:         full,
+		BodiesOnly:   accessToken == "",
+		Fetcher:      core.GitHubRESTFetcher{Client: client},
 		Capabilities: `{"read":true,"write":"github-app"}`,
 		Sync:         `{"mode":"oauth-rest"}`,
-		UpdatedAt:    time.Now().UTC(),
-	}); err != nil {
-		return 0, 0, err
-	}
-	if a := activityFromCtx(ctx); a != nil {
-		s.activities.Update(a, 0, 0, "fetching "+repo)
-	}
-	var issues []githubIssueREST
-	path := fmt.Sprintf("/repos/%s/issues?state=all&sort=updated&direction=desc&per_page=%d", repo, limit)
-	if err := s.doGitHubREST(ctx, accessToken, path, &issues); err != nil {
-		return 0, 0, err
-	}
-	count := 0
-	edgeCount := 0
-	for _, issue := range issues {
-		node, err := s.upsertGitHubIssueREST(ctx, boardID, repo, issue)
-		if err != nil {
-			return count, edgeCount, err
-		}
-		count++
-		if a := activityFromCtx(ctx); a != nil {
-			s.activities.Update(a, count, len(issues), "")
-		}
-		for _, edge := range core.ExtractDependencyEdges(repo, node.ID, issue.Body) {
-			if _, err := s.store.AddEdgeWithConfidence(ctx, boardID, edge.From, edge.To, edge.Kind, "github-inferred", edge.Confidence, edge); err != nil {
-				return count, edgeCount, err
+		Mode:         tokenMode,
+		Progress: func(done, total int, message string) {
+			if a := activityFromCtx(ctx); a != nil {
+				s.activities.Update(a, done, total, message)
 			}
-			edgeCount++
-		}
-	}
-	return count, edgeCount, nil
-}
-
-func (s *Server) upsertGitHubIssueREST(ctx context.Context, boardID, repo string, issue githubIssueREST) (core.Node, error) {
-	sourceID := "github:" + repo
-	if err := s.store.UpsertSource(ctx, core.Source{
-		ID:           sourceID,
-		Kind:         "github",
-		Name:         repo,
-		URL:          "https://github.com/" + repo,
-		Capabilities: `{"read":true,"write":"github-app"}`,
-		Sync:         `{"mode":"oauth-rest"}`,
-		UpdatedAt:    time.Now().UTC(),
-	}); err != nil {
-		return core.Node{}, err
-	}
-	marker := "#"
-	kind := "issue"
-	if issue.PullRequest.URL != "" {
-		marker = "!"
-		kind = "pr"
-	}
-	id := fmt.Sprintf("gh:%s%s%d", repo, marker, issue.Number)
-	payload, _ := json.Marshal(map[string]any{
-		"source":     "github",
-		"kind":       kind,
-		"repo":       repo,
-		"number":     issue.Number,
-		"labels":     issue.LabelNames(),
-		"assignees":  issue.AssigneePeople(),
-		"author":     issue.AuthorPerson(),
-		"milestone":  issue.Milestone.Title,
-		"body":       issue.Body,
-		"synced_at":  time.Now().UTC().Format(time.RFC3339),
-		"html_url":   issue.HTMLURL,
-		"api_url":    issue.URL,
-		"repository": repo,
-		"draft":      issue.Draft,
+		},
 	})
-	node := core.Node{
-		ID:        id,
-		Kind:      kind,
-		Title:     issue.Title,
-		State:     strings.ToLower(issue.State),
-		Owner:     firstString(issue.AssigneeNames()),
-		DataJSON:  string(payload),
-		UpdatedAt: parseGitHubRESTTime(issue.UpdatedAt),
-	}
-	if err := s.store.UpsertNode(ctx, node); err != nil {
-		return core.Node{}, err
-	}
-	if err := s.store.UpsertSourceRef(ctx, id, sourceID, fmt.Sprintf("%s%d", marker, issue.Number), issue.HTMLURL); err != nil {
-		return core.Node{}, err
-	}
-	if err := s.store.AddNodeToBoard(ctx, boardID, id, kind, ""); err != nil {
-		return core.Node{}, err
-	}
-	return node, nil
-}
-
-func (s *Server) syncGitHubBoardScope(ctx context.Context, accessToken, login, boardID string, board core.Board, limit int) (int, int, error) {
-	if repo := repoForBoard(board); repo != "" {
-		return s.syncGitHubRepoBoard(ctx, accessToken, boardID, repo, limit)
-	}
-	if owner := orgForBoard(board); owner != "" {
-		return s.syncGitHubOrgBoard(ctx, accessToken, boardID, owner, limit)
-	}
-	if board.ScopeQuery == "my-work" || boardPreset(board) == "my-work" {
-		return s.syncGitHubMyWorkBoard(ctx, accessToken, login, boardID, limit)
-	}
-	return 0, 0, errors.New("sync currently supports repo, org, and my-work views")
-}
-
-func (s *Server) syncGitHubOrgBoard(ctx context.Context, accessToken, boardID, owner string, limit int) (int, int, error) {
-	if a := activityFromCtx(ctx); a != nil {
-		s.activities.Update(a, 0, 0, "fetching repos for "+owner)
-	}
-	var repos []githubRepo
-	if err := s.doGitHubREST(ctx, accessToken, fmt.Sprintf("/orgs/%s/repos?sort=updated&direction=desc&per_page=20", owner), &repos); err != nil {
-		return 0, 0, err
-	}
-	totalItems := 0
-	totalLinks := 0
-	perRepo := limit / maxInt(1, len(repos))
-	if perRepo < 5 {
-		perRepo = 5
-	}
-	if perRepo > 30 {
-		perRepo = 30
-	}
-	for _, repo := range repos {
-		if repo.FullName == "" {
-			continue
-		}
-		items, links, err := s.syncGitHubRepoBoard(ctx, accessToken, boardID, repo.FullName, perRepo)
-		if err != nil {
-			return totalItems, totalLinks, err
-		}
-		totalItems += items
-		totalLinks += links
-		if a := activityFromCtx(ctx); a != nil {
-			s.activities.Update(a, totalItems, 0, "")
-		}
-	}
-	return totalItems, totalLinks, nil
-}
-
-func (s *Server) syncGitHubMyWorkBoard(ctx context.Context, accessToken, login, boardID string, limit int) (int, int, error) {
-	if login == "" {
-		return 0, 0, errors.New("github login is required for my-work sync")
-	}
-	if a := activityFromCtx(ctx); a != nil {
-		s.activities.Update(a, 0, 0, "fetching issues for "+login)
-	}
-	query := url.QueryEscape(fmt.Sprintf("involves:%s archived:false sort:updated-desc", login))
-	var payload struct {
-		Items []githubSearchIssue `json:"items"`
-	}
-	if err := s.doGitHubREST(ctx, accessToken, fmt.Sprintf("/search/issues?q=%s&per_page=%d", query, limit), &payload); err != nil {
-		return 0, 0, err
-	}
-	count := 0
-	edges := 0
-	for _, item := range payload.Items {
-		repo := item.RepoFullName()
-		if repo == "" {
-			continue
-		}
-		node, err := s.upsertGitHubIssueREST(ctx, boardID, repo, githubIssueREST{
-			Number:      item.Number,
-			Title:       item.Title,
-			State:       item.State,
-			URL:         item.URL,
-			HTMLURL:     item.HTMLURL,
-			Body:        item.Body,
-			UpdatedAt:   item.UpdatedAt,
-			PullRequest: item.PullRequest,
-		})
-		if err != nil {
-			return count, edges, err
-		}
-		count++
-		if a := activityFromCtx(ctx); a != nil {
-			s.activities.Update(a, count, len(payload.Items), "")
-		}
-		for _, edge := range core.ExtractDependencyEdges(repo, node.ID, item.Body) {
-			if _, err := s.store.AddEdgeWithConfidence(ctx, boardID, edge.From, edge.To, edge.Kind, "github-inferred", edge.Confidence, edge); err != nil {
-				return count, edges, err
-			}
-			edges++
-		}
-	}
-	return count, edges, nil
 }
 
 func (s *Server) doJSON(req *http.Request, out any) error {
@@ -1606,101 +1437,6 @@ type githubRepo struct {
 	} `json:"owner"`
 }
 
-type githubIssueREST struct {
-	ID        int64  `json:"id"`
-	Number    int    `json:"number"`
-	Title     string `json:"title"`
-	State     string `json:"state"`
-	URL       string `json:"url"`
-	HTMLURL   string `json:"html_url"`
-	Body      string `json:"body"`
-	UpdatedAt string `json:"updated_at"`
-	Labels    []struct {
-		Name string `json:"name"`
-	} `json:"labels"`
-	Assignees []struct {
-		Login     string `json:"login"`
-		AvatarURL string `json:"avatar_url"`
-		HTMLURL   string `json:"html_url"`
-	} `json:"assignees"`
-	User struct {
-		Login     string `json:"login"`
-		AvatarURL string `json:"avatar_url"`
-		HTMLURL   string `json:"html_url"`
-	} `json:"user"`
-	Milestone struct {
-		Title string `json:"title"`
-	} `json:"milestone"`
-	PullRequest githubPullRequestRef `json:"pull_request"`
-	Draft       bool                 `json:"draft"`
-}
-
-type githubPullRequestRef struct {
-	URL     string `json:"url"`
-	HTMLURL string `json:"html_url"`
-}
-
-type githubSearchIssue struct {
-	Number        int                  `json:"number"`
-	Title         string               `json:"title"`
-	State         string               `json:"state"`
-	URL           string               `json:"url"`
-	HTMLURL       string               `json:"html_url"`
-	Body          string               `json:"body"`
-	UpdatedAt     string               `json:"updated_at"`
-	RepositoryURL string               `json:"repository_url"`
-	PullRequest   githubPullRequestRef `json:"pull_request"`
-}
-
-func (g githubSearchIssue) RepoFullName() string {
-	return strings.TrimPrefix(g.RepositoryURL, "https://api.github.com/repos/")
-}
-
-func (g githubIssueREST) LabelNames() []string {
-	out := make([]string, 0, len(g.Labels))
-	for _, label := range g.Labels {
-		if label.Name != "" {
-			out = append(out, label.Name)
-		}
-	}
-	return out
-}
-
-func (g githubIssueREST) AssigneeNames() []string {
-	out := make([]string, 0, len(g.Assignees))
-	for _, assignee := range g.Assignees {
-		if assignee.Login != "" {
-			out = append(out, assignee.Login)
-		}
-	}
-	return out
-}
-
-func (g githubIssueREST) AssigneePeople() []map[string]string {
-	out := make([]map[string]string, 0, len(g.Assignees))
-	for _, assignee := range g.Assignees {
-		if assignee.Login != "" {
-			out = append(out, map[string]string{
-				"login":      assignee.Login,
-				"avatar_url": assignee.AvatarURL,
-				"html_url":   assignee.HTMLURL,
-			})
-		}
-	}
-	return out
-}
-
-func (g githubIssueREST) AuthorPerson() map[string]string {
-	if g.User.Login == "" {
-		return nil
-	}
-	return map[string]string{
-		"login":      g.User.Login,
-		"avatar_url": g.User.AvatarURL,
-		"html_url":   g.User.HTMLURL,
-	}
-}
-
 type githubOrg struct {
 	ID        int64  `json:"id"`
 	Login     string `json:"login"`
@@ -1781,47 +1517,13 @@ func (s *Server) handleCreateGitHubIssue(w http.ResponseWriter, r *http.Request)
 	if in.Milestone > 0 {
 		issueBody["milestone"] = in.Milestone
 	}
-	payload, _ := json.Marshal(issueBody)
 	act := s.activities.Start("github-write", "Creating GitHub issue")
-	apiURL := "https://api.github.com/repos/" + parts[0] + "/" + parts[1] + "/issues"
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, apiURL, bytes.NewReader(payload))
-	if err != nil {
-		s.activities.Fail(act, err.Error())
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/vnd.github+json")
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
+	var ghResult map[string]any
+	if err := s.githubWrite(r.Context(), token, http.MethodPost, "/repos/"+parts[0]+"/"+parts[1]+"/issues", issueBody, &ghResult); err != nil {
 		s.activities.Fail(act, err.Error())
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
-	defer resp.Body.Close()
-	var ghResult map[string]any
-	_ = json.NewDecoder(resp.Body).Decode(&ghResult)
-	if resp.StatusCode >= 300 {
-		errMsg, _ := ghResult["message"].(string)
-		var friendlyErr string
-		switch resp.StatusCode {
-		case 401:
-			friendlyErr = "github: unauthorized - token may be expired or invalid"
-		case 403:
-			friendlyErr = "github: forbidden - token lacks write permission for this repository"
-		case 404:
-			friendlyErr = "github: repository not found or no access"
-		case 429:
-			friendlyErr = "github: rate limit exceeded"
-		default:
-			friendlyErr = "github: " + errMsg
-		}
-		s.activities.Fail(act, friendlyErr)
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": friendlyErr})
-		return
-	}
 	issueURL, _ := ghResult["html_url"].(string)
 	issueNumber := fmt.Sprint(ghResult["number"])
 	issueNumber = strings.TrimSuffix(issueNumber, ".0")
@@ -1894,39 +1596,9 @@ func (s *Server) handleUpdateGitHubIssue(w http.ResponseWriter, r *http.Request)
 	if len(in.Assignees) > 0 {
 		patchBody["assignees"] = in.Assignees
 	}
-	payload, _ := json.Marshal(patchBody)
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/issues/%d", in.Repo, in.IssueNumber)
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodPatch, apiURL, bytes.NewReader(payload))
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/vnd.github+json")
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
-		return
-	}
-	defer resp.Body.Close()
 	var ghResult map[string]any
-	_ = json.NewDecoder(resp.Body).Decode(&ghResult)
-	if resp.StatusCode >= 300 {
-		errMsg, _ := ghResult["message"].(string)
-		switch resp.StatusCode {
-		case 401:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: unauthorized - token may be expired or invalid"})
-		case 403:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: forbidden - token lacks write permission for this repository"})
-		case 404:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: repository not found or no access"})
-		case 429:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: rate limit exceeded"})
-		default:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: " + errMsg})
-		}
+	if err := s.githubWrite(r.Context(), token, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", in.Repo, in.IssueNumber), patchBody, &ghResult); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "issue": ghResult})
@@ -1960,44 +1632,97 @@ func (s *Server) handleCreateGitHubComment(w http.ResponseWriter, r *http.Reques
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no github token available; sign in first"})
 		return
 	}
-	payload, _ := json.Marshal(map[string]string{"body": in.Body})
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/issues/%d/comments", in.Repo, in.IssueNumber)
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, apiURL, bytes.NewReader(payload))
+	var ghResult map[string]any
+	if err := s.githubWrite(r.Context(), token, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", in.Repo, in.IssueNumber), map[string]string{"body": in.Body}, &ghResult); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	commentURL, _ := ghResult["html_url"].(string)
+	commentID := fmt.Sprint(ghResult["id"])
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "url": commentURL, "id": commentID})
+}
+
+// handleGitHubPush publishes a board's local blocking edges to its GitHub
+// issues. Send dry_run first to review the diff.
+func (s *Server) handleGitHubPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	account, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		BoardID string `json:"board_id"`
+		DryRun  bool   `json:"dry_run"`
+		Native  bool   `json:"native"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	boardID := strings.TrimSpace(in.BoardID)
+	if boardID == "" {
+		boardID = core.DefaultBoardID
+	}
+	if !s.requireBoardAccess(w, r, boardID, account) {
+		return
+	}
+	snap, err := s.store.Snapshot(r.Context(), boardID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/vnd.github+json")
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	token, tokenMode, err := s.githubTokenForBoardSync(r.Context(), account.ID, snap.Board)
 	if err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
-	defer resp.Body.Close()
-	var ghResult map[string]any
-	_ = json.NewDecoder(resp.Body).Decode(&ghResult)
-	if resp.StatusCode >= 300 {
-		errMsg, _ := ghResult["message"].(string)
-		switch resp.StatusCode {
-		case 401:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: unauthorized - token may be expired or invalid"})
-		case 403:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: forbidden - token lacks write permission for this repository"})
-		case 404:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: repository not found or no access"})
-		case 429:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: rate limit exceeded"})
-		default:
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "github: " + errMsg})
-		}
+	act := s.activities.Start("github-write", "Pushing dependencies to GitHub")
+	res, err := core.PushGitHub(r.Context(), s.store, core.GitHubPushOptions{
+		Board:  boardID,
+		DryRun: in.DryRun,
+		Native: in.Native,
+		Writer: core.GitHubRESTFetcher{Client: s.githubClient(token)},
+	})
+	if err != nil {
+		err = friendlyGitHubSyncError(err, tokenMode, boardScopeLabel(snap.Board))
+		s.activities.Fail(act, err.Error())
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
 	}
-	commentURL, _ := ghResult["html_url"].(string)
-	commentID := fmt.Sprint(ghResult["id"])
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "url": commentURL, "id": commentID})
+	msg := fmt.Sprintf("%d issues changed", len(res.Changes))
+	if len(res.Failed) > 0 {
+		msg += fmt.Sprintf(", %d failed", len(res.Failed))
+	}
+	s.activities.Finish(act, len(res.Changes), len(res.Changes), msg)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// githubWrite sends a write request with a 15s budget and turns GitHub
+// errors into messages a user can act on.
+func (s *Server) githubWrite(ctx context.Context, token, method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	_, err := s.githubClient(token).Do(ctx, github.Request{Method: method, Path: path, Body: body}, out)
+	var apiErr *github.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		return errors.New("github: unauthorized - token may be expired or invalid")
+	case http.StatusForbidden:
+		return errors.New("github: forbidden - token lacks write permission for this repository")
+	case http.StatusNotFound:
+		return errors.New("github: repository not found or no access")
+	case http.StatusTooManyRequests:
+		return errors.New("github: rate limit exceeded")
+	default:
+		return errors.New("github: " + apiErr.Message)
+	}
 }
 
 func (s *Server) handleBoardSourceApply(w http.ResponseWriter, r *http.Request) {
@@ -2043,6 +1768,7 @@ func (s *Server) handleBoardSourceApply(w http.ResponseWriter, r *http.Request)
 			EdgeID string `json:"edge_id"`
 		} `json:"link_deletes"`
 		BaseUpdatedAt string `json:"base_updated_at"`
+		RefuseCycles  bool   `json:"refuse_cycles"`
 	}
 	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -2145,6 +1871,30 @@ func (s *Server) handleBoardSourceApply(w http.ResponseWriter, r *http.Request)
 			errs = append(errs, "link create: to node not found: "+to)
 		}
 	}
+	if in.RefuseCycles && len(errs) == 0 {
+		removed := map[string]bool{}
+		for _, ld := range in.LinkDeletes {
+			removed[strings.TrimSpace(ld.EdgeID)] = true
+		}
+		kept := snap
+		kept.Edges = nil
+		for _, edge := range snap.Edges {
+			if !removed[edgeSelectionID(edge)] {
+				kept.Edges = append(kept.Edges, edge)
+			}
+		}
+		var added []core.Edge
+		for _, lc := range in.LinkCreates {
+			from, to, kind := strings.TrimSpace(lc.FromID), strings.TrimSpace(lc.ToID), strings.TrimSpace(lc.Kind)
+			if kind == "" {
+				kind = "blocked_by"
+			}
+			added = append(added, core.Edge{ID: "link-create:" + from + ":" + to + ":" + kind, FromID: from, ToID: to, Kind: kind, Confidence: 1, Authority: "user"})
+		}
+		for _, cycle := range core.CyclesCreatedBy(kept, added) {
+			errs = append(errs, "link create: would create a dependency cycle: "+strings.Join(cycle.Nodes, " -> "))
+		}
+	}
 	if len(errs) > 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"errors": errs})
 		return
@@ -2160,7 +1910,7 @@ func (s *Server) handleBoardSourceApply(w http.ResponseWriter, r *http.Request)
 		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "summary": summary, "errors": []string{}})
 		return
 	}
-	patch := core.BoardSourcePatch{}
+	patch := core.BoardSourcePatch{RefuseCycles: in.RefuseCycles}
 	for _, c := range in.Creates {
 		patch.Creates = append(patch.Creates, core.BoardSourceCreate{
 			Kind:        c.Kind,
@@ -2199,6 +1949,12 @@ func (s *Server) handleBoardSourceApply(w http.ResponseWriter, r *http.Request)
 	act := s.activities.Start("patch-apply", "Applying source patch")
 	if err := s.store.ApplyBoardSourcePatch(r.Context(), boardID, patch); err != nil {
 		s.activities.Fail(act, err.Error())
+		// A concurrent apply can close a cycle the check above did not see.
+		var cycleErr *core.CycleError
+		if errors.As(err, &cycleErr) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": []string{"link create: would create a dependency cycle: " + strings.Join(cycleErr.Cycle.Nodes, " -> ")}})
+			return
+		}
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
@@ -2267,6 +2023,134 @@ func (s *Server) handleBoardViews(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleFields(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		boardID := r.URL.Query().Get("board_id")
+		if !s.requireBoardAccess(w, r, boardID, account) {
+			return
+		}
+		fields, err := s.store.FieldDefinitions(r.Context(), boardID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if fields == nil {
+			fields = []core.FieldDefinition{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"fields": fields})
+	case http.MethodPost:
+		var in core.FieldDefinition
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if !s.requireBoardAccess(w, r, in.BoardID, account) {
+			return
+		}
+		field, err := s.store.DefineField(r.Context(), in)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"field": field})
+	case http.MethodDelete:
+		boardID := r.URL.Query().Get("board_id")
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "key is required"})
+			return
+		}
+		if !s.requireBoardAccess(w, r, boardID, account) {
+			return
+		}
+		if err := s.store.DeleteFieldDefinition(r.Context(), boardID, key); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *Server) handleNodeFields(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.requireAccount(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		boardID, nodeID := r.URL.Query().Get("board_id"), r.URL.Query().Get("node_id")
+		if boardID == "" || nodeID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "board_id and node_id are required"})
+			return
+		}
+		if !s.requireBoardAccess(w, r, boardID, account) || !s.requireBoardNode(w, r, boardID, nodeID) {
+			return
+		}
+		values, err := s.store.NodeFields(r.Context(), boardID, nodeID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if values == nil {
+			values = []core.FieldValue{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"values": values})
+	case http.MethodPost, http.MethodDelete:
+		var in struct {
+			BoardID string          `json:"board_id"`
+			NodeID  string          `json:"node_id"`
+			Key     string          `json:"key"`
+			Value   json.RawMessage `json:"value"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&in); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if strings.TrimSpace(in.NodeID) == "" || strings.TrimSpace(in.Key) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node_id and key are required"})
+			return
+		}
+		if strings.TrimSpace(in.BoardID) == "" {
+			in.BoardID = core.DefaultBoardID
+		}
+		if !s.requireBoardAccess(w, r, in.BoardID, account) || !s.requireBoardNode(w, r, in.BoardID, in.NodeID) {
+			return
+		}
+		if r.Method == http.MethodDelete {
+			if err := s.store.ClearNodeField(r.Context(), in.BoardID, in.NodeID, in.Key); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+			return
+		}
+		// Values may arrive as JSON strings or bare numbers; both are validated
+		// from their text form.
+		raw := strings.TrimSpace(string(in.Value))
+		var text string
+		if err := json.Unmarshal(in.Value, &text); err == nil {
+			raw = text
+		}
+		value, err := s.store.SetNodeField(r.Context(), in.BoardID, in.NodeID, in.Key, raw, "user")
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"value": value})
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
 func (s *Server) handleBoardSyncLogs(w http.ResponseWriter, r *http.Request) {
 	if _, ok := s.requireAccount(w, r); !ok {
 		return
@@ -2437,78 +2321,40 @@ func resolveBoardNodeRef(snap core.Snapshot, value string) (string, error) {
 	return value, nil
 }
 
+// repoForBoard returns the repo of a board scoped to exactly one repo, which
+// short #N refs resolve against.
 func repoForBoard(board core.Board) string {
-	if strings.HasPrefix(board.ScopeQuery, "repo:") {
-		return strings.TrimSpace(strings.TrimPrefix(board.ScopeQuery, "repo:"))
-	}
-	var cfg struct {
-		Repo string `json:"repo"`
-	}
-	if board.ConfigJSON != "" && json.Unmarshal([]byte(board.ConfigJSON), &cfg) == nil {
-		return strings.TrimSpace(cfg.Repo)
-	}
-	return ""
-}
-
-func orgForBoard(board core.Board) string {
-	if strings.HasPrefix(board.ScopeQuery, "org:") {
-		return strings.TrimSpace(strings.TrimPrefix(board.ScopeQuery, "org:"))
-	}
-	var cfg struct {
-		Owner string `json:"owner"`
-	}
-	if board.ConfigJSON != "" && json.Unmarshal([]byte(board.ConfigJSON), &cfg) == nil {
-		if repoForBoard(board) == "" {
-			return strings.TrimSpace(cfg.Owner)
-		}
+	scope, err := core.BoardGitHubScope(board)
+	if err != nil || len(scope.Repos) != 1 {
+		return ""
 	}
-	return ""
+	return scope.Repos[0]
 }
 
-func boardPreset(board core.Board) string {
-	var cfg struct {
-		Preset string `json:"preset"`
+func githubOwnerForBoard(board core.Board) string {
+	scope, err := core.BoardGitHubScope(board)
+	if err != nil {
+		return ""
 	}
-	if board.ConfigJSON != "" && json.Unmarshal([]byte(board.ConfigJSON), &cfg) == nil {
-		return strings.TrimSpace(cfg.Preset)
+	if owners := scope.Owners(); len(owners) > 0 {
+		return owners[0]
 	}
 	return ""
 }
 
 func boardScopeLabel(board core.Board) string {
-	if repo := repoForBoard(board); repo != "" {
-		return repo
-	}
-	if org := orgForBoard(board); org != "" {
-		return org
-	}
-	if board.ScopeQuery == "my-work" || boardPreset(board) == "my-work" {
+	scope, err := core.BoardGitHubScope(board)
+	switch {
+	case err != nil:
+	case scope.MyWork && len(scope.Owners()) == 0:
 		return "my work"
+	case len(scope.Repos)+len(scope.Orgs) == 1 && !scope.NeedsSearch():
+		return strings.TrimPrefix(strings.TrimPrefix(scope.String(), "repo:"), "org:")
+	default:
+		return scope.String()
 	}
 	if board.Name != "" {
 		return board.Name
 	}
 	return board.ScopeQuery
 }
-
-func parseGitHubRESTTime(value string) time.Time {
-	t, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
-	if err != nil {
-		return time.Now().UTC().Truncate(time.Second)
-	}
-	return t.UTC().Truncate(time.Second)
-}
-
-func firstString(values []string) string {
-	if len(values) == 0 {
-		return ""
-	}
-	return values[0]
-}
-
-func maxInt(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
@@ -206,3 +206,65 @@ func TestHandleBoardSourceApplyMissingBoard(t *testing.T) {
 		t.Fatalf("missing board apply status = %d, want %d", res.StatusCode, http.StatusNotFound)
 	}
 }
+
+func TestHandleBoardSourceApplyRefusesCycle(t *testing.T) {
+	ctx := context.Background()
+	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.AddEdge(ctx, core.DefaultBoardID, "task:a", "task:b", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := store.UpsertOAuthAccount(ctx, core.OAuthAccountInput{
+		Provider:   "github",
+		ExternalID: "42",
+		Login:      "moul",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateWebSession(ctx, account.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(store, Config{Addr: "127.0.0.1:0", BaseURL: "https://depviz.example"})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	payload := map[string]any{
+		"board_id":      "default",
+		"refuse_cycles": true,
+		"link_creates": []map[string]any{
+			{"from_id": "task:b", "to_id": "task:a", "kind": "blocked_by"},
+		},
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/board-source/apply", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("cyclic apply status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+	var out struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Errors) != 1 || out.Errors[0] != "link create: would create a dependency cycle: task:a -> task:b" {
+		t.Fatalf("errors = %q", out.Errors)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -15,6 +16,7 @@ import (
 	"testing"
 
 	"moul.io/depviz/v4/internal/core"
+	"moul.io/depviz/v4/internal/core/github"
 )
 
 func TestHealthAndAnonymousSession(t *testing.T) {
@@ -123,6 +125,35 @@ func TestAuthenticatedExport(t *testing.T) {
 	if payload.Snapshot.Board.ID != core.DefaultBoardID {
 		t.Fatalf("board id = %q, want %q", payload.Snapshot.Board.ID, core.DefaultBoardID)
 	}
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/api/export?format=svg", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+	svg, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svg.Body.Close()
+	body, _ := io.ReadAll(svg.Body)
+	if svg.StatusCode != http.StatusOK || svg.Header.Get("Content-Type") != "image/svg+xml" || !strings.HasPrefix(string(body), "<svg ") {
+		t.Fatalf("svg export = %d %q %s", svg.StatusCode, svg.Header.Get("Content-Type"), body)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/api/export?format=svg&board=missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+	missing, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("svg export of a missing board = %d, want %d", missing.StatusCode, http.StatusNotFound)
+	}
 }
 
 func TestAuthenticatedBoards(t *testing.T) {
@@ -252,6 +283,93 @@ func TestAuthenticatedBoards(t *testing.T) {
 	}
 }
 
+func TestFieldEndpoints(t *testing.T) {
+	ctx := context.Background()
+	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.AddEdge(ctx, core.DefaultBoardID, "task:a", "task:b", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	account, err := store.UpsertOAuthAccount(ctx, core.OAuthAccountInput{
+		Provider:   "github",
+		ExternalID: "42",
+		Login:      "moul",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateWebSession(ctx, account.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(store, Config{})
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/fields", `{"board_id":"default","key":"points","type":"number"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("define status = %d, want %d body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	rec = do(http.MethodPost, "/api/node-fields", `{"board_id":"default","node_id":"task:a","key":"points","value":"many"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid value status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = do(http.MethodPost, "/api/node-fields", `{"board_id":"default","node_id":"task:a","key":"points","value":3}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set status = %d, want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/api/node-fields?node_id=task:a", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("read without board status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	other, err := store.CreateBoard(ctx, "Other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = do(http.MethodGet, "/api/node-fields?board_id="+other.ID+"&node_id=task:a", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("read from other board status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		rec = do(method, "/api/node-fields", `{"board_id":"`+other.ID+`","node_id":"task:a","key":"points","value":4}`)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s on other board status = %d, want %d", method, rec.Code, http.StatusNotFound)
+		}
+	}
+	rec = do(http.MethodGet, "/api/node-fields?board_id=default&node_id=task:a", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"key":"points"`) {
+		t.Fatalf("read status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/api/fields?board_id=default", "")
+	var listed struct {
+		Fields []core.FieldDefinition `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Fields) != 1 || listed.Fields[0].Key != "points" {
+		t.Fatalf("fields = %+v, want points", listed.Fields)
+	}
+	payload, err := store.BuildExport(ctx, core.DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range payload.Snapshot.Nodes {
+		if n.ID == "task:a" && n.Fields["points"] != float64(3) {
+			t.Fatalf("task:a fields = %v, want points 3", n.Fields)
+		}
+	}
+}
+
 func TestGitHubDiscoveryRequiresConnectedOAuth(t *testing.T) {
 	ctx := context.Background()
 	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
@@ -413,6 +531,43 @@ func TestGitHubWebhookSignature(t *testing.T) {
 	}
 }
 
+func TestGitHubWebhookRoutesIssueToBoard(t *testing.T) {
+	ctx := context.Background()
+	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	board, err := store.CreateBoardWithConfig(ctx, "Roadmap", "", "repo:moul/depviz", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "test-secret"
+	srv := NewServer(store, Config{
+		GitHubClientID:          "client-id",
+		GitHubClientSecret:      "client-secret",
+		GitHubAppID:             "123",
+		GitHubAppPrivateKeyFile: "/tmp/depviz-test-key.pem",
+		GitHubWebhookSecret:     secret,
+	})
+	body := []byte(`{"action":"labeled","repository":{"full_name":"moul/depviz"},"issue":{"number":1,"title":"Ship","state":"open","body":"blocked by #2","labels":[{"name":"bug"}]}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/github/webhook", strings.NewReader(string(body)))
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("X-Hub-Signature-256", testSignature(body, secret))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	snap, err := store.Snapshot(ctx, board.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Nodes) != 2 || len(snap.Edges) != 1 {
+		t.Fatalf("board got %d nodes and %d edges, want the issue, its blocker and one edge", len(snap.Nodes), len(snap.Edges))
+	}
+}
+
 func TestLogoutClearsSessionCookie(t *testing.T) {
 	ctx := context.Background()
 	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
@@ -446,6 +601,122 @@ func TestLogoutClearsSessionCookie(t *testing.T) {
 	}
 }
 
+func TestGitHubCommentUsesConfiguredAPI(t *testing.T) {
+	ctx := context.Background()
+	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	account, err := store.UpsertOAuthAccount(ctx, core.OAuthAccountInput{
+		Provider:   "github",
+		ExternalID: "42",
+		Login:      "moul",
+		TokenJSON:  `{"access_token":"gho_test"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateWebSession(ctx, account.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var posted map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/moul/depviz/issues/7/comments" {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer gho_test" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+		if posted["body"] == "forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":99,"html_url":"https://github.com/moul/depviz/issues/7#issuecomment-99"}`))
+	}))
+	defer api.Close()
+	srv := NewServer(store, Config{GitHubAPIURL: api.URL})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/github/comment", strings.NewReader(`{"repo":"moul/depviz","issue_number":7,"body":"`+body+`"}`))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	rec := post("LGTM")
+	if rec.Code != http.StatusOK || posted["body"] != "LGTM" || !strings.Contains(rec.Body.String(), "issuecomment-99") {
+		t.Fatalf("status = %d, body = %s, posted %v", rec.Code, rec.Body.String(), posted)
+	}
+	rec = post("forbidden")
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "lacks write permission") {
+		t.Fatalf("forbidden: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGitHubPushDryRun(t *testing.T) {
+	ctx := context.Background()
+	store, err := core.OpenStore(ctx, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	account, err := store.UpsertOAuthAccount(ctx, core.OAuthAccountInput{
+		Provider:   "github",
+		ExternalID: "42",
+		Login:      "moul",
+		TokenJSON:  `{"access_token":"gho_test"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateWebSession(ctx, account.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			t.Errorf("dry run sent %s %s", r.Method, r.URL.Path)
+		case r.URL.Path == "/repos/moul/depviz/issues":
+			_, _ = w.Write([]byte(`[{"number":1,"title":"Ship","state":"open"},{"number":2,"title":"Schema","state":"open"}]`))
+		case strings.HasPrefix(r.URL.Path, "/repos/moul/depviz/issues/"):
+			_, _ = w.Write([]byte(`{"body":"Existing text"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer api.Close()
+	client := github.NewClient("")
+	client.BaseURL = api.URL
+	if _, err := core.SyncGitHub(ctx, store, core.GitHubSyncOptions{Scope: "repo:moul/depviz", BodiesOnly: true, Fetcher: core.GitHubRESTFetcher{Client: client}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddEdge(ctx, core.DefaultBoardID, "gh:moul/depviz#1", "gh:moul/depviz#2", "blocked_by", "user", nil); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(store, Config{GitHubAPIURL: api.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/github/push", strings.NewReader(`{"board_id":"default","dry_run":true}`))
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var res core.GitHubPushResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || len(res.Changes) != 2 || res.Changes[0].Added[0] != "- [ ] Blocked by #2" {
+		t.Fatalf("result = %+v", res)
+	}
+}
+
 func testSignature(body []byte, secret string) string {
 	mac := hmac.New(sha256.New, []byte(secret))
 	_, _ = mac.Write(body)
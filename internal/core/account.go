@@ -357,6 +357,20 @@ func (s *Store) UpsertGitHubCache(ctx context.Context, accountID, repo, refID, p
 	return err
 }
 
+// GitHubCache returns a cached GitHub response and its ETag, expired or not,
+// so callers can revalidate it with If-None-Match.
+func (s *Store) GitHubCache(ctx context.Context, accountID, repo, refID string) (payloadJSON, etag string, ok bool, err error) {
+	err = s.db.QueryRowContext(ctx, `SELECT payload_json, etag FROM github_cache WHERE account_id = ? AND repo = ? AND ref_id = ?`,
+		accountID, repo, refID).Scan(&payloadJSON, &etag)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return payloadJSON, etag, true, nil
+}
+
 func (s *Store) ListWorkspacesForAccount(ctx context.Context, accountID string) ([]Workspace, error) {
 	rows, err := s.db.QueryContext(ctx, `SELECT w.id, w.provider, w.external_id, w.kind, w.name, w.url, w.data_json, w.created_at, w.updated_at
 		FROM workspaces w
@@ -1,15 +1,15 @@
 package core
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
+
+	"moul.io/depviz/v4/internal/core/github"
 )
 
 type BoardStatusBrief struct {
@@ -20,6 +20,7 @@ type BoardStatusBrief struct {
 	Pullable      []BriefItem        `json:"pullable"`
 	Blocked       []BriefItem        `json:"blocked"`
 	Untriaged     []BriefItem        `json:"untriaged"`
+	Cycles        []Cycle            `json:"cycles,omitempty"`
 	SnapshotCheck *SnapshotFreshness `json:"snapshot_check,omitempty"`
 	Warnings      []string           `json:"warnings,omitempty"`
 }
@@ -128,6 +129,7 @@ func BuildBoardStatusBriefFromSnapshot(snap Snapshot, previous map[string]int) B
 		Pullable:  limitItems(pullable, 12),
 		Blocked:   limitItems(blocked, 12),
 		Untriaged: limitItems(untriaged, 12),
+		Cycles:    findCycles(snap, boardStatusCycleEdge),
 	}
 	return b
 }
@@ -159,6 +161,9 @@ func RenderBoardStatusBrief(w io.Writer, b BoardStatusBrief) error {
 	writeSection(w, "Pullable now", b.Pullable, false, true)
 	writeSection(w, "Blocked ready", b.Blocked, false, true)
 	writeSection(w, "Untriaged", b.Untriaged, false, true)
+	if len(b.Cycles) > 0 {
+		writeCycles(w, b.Cycles)
+	}
 	if b.SnapshotCheck != nil {
 		write("Snapshot freshness\n")
 		write("  %s\n", b.SnapshotCheck.Message)
@@ -238,6 +243,15 @@ func boardStatusBlockedAndBlocker(e Edge) (blocked string, blocker string) {
 	}
 }
 
+// boardStatusCycleEdge orients the edges boardStatusBlockers keeps, so the
+// cycles it reports are the ones that actually hold status:ready cards back.
+func boardStatusCycleEdge(e Edge) (blocked string, blocker string) {
+	if !boardStatusExplicitBlocker(e) {
+		return "", ""
+	}
+	return boardStatusBlockedAndBlocker(e)
+}
+
 func boardStatusExplicitBlocker(e Edge) bool {
 	if !edgeIsSoft(e) {
 		return true
@@ -269,7 +283,12 @@ func boardStatusItem(n Node, reason string, blockerCount int) BriefItem {
 	}
 }
 
+// statusLabels returns a node's statuses: its status field, as set by a
+// GitHub project's Status column, or else its status:* labels.
 func statusLabels(n Node) []string {
+	if status, ok := n.Fields["status"].(string); ok && strings.TrimSpace(status) != "" {
+		return []string{normalizeStatus(status)}
+	}
 	var statuses []string
 	for _, label := range n.Labels() {
 		if status, ok := strings.CutPrefix(label, "status:"); ok && strings.TrimSpace(status) != "" {
@@ -280,6 +299,27 @@ func statusLabels(n Node) []string {
 	return statuses
 }
 
+// normalizeStatus maps common project column names such as "Todo" or "In
+// Progress" onto the statuses the brief ranks; other names are slugged.
+func normalizeStatus(name string) string {
+	slug := strings.Join(strings.Fields(strings.ToLower(name)), "-")
+	switch slug {
+	case "todo", "to-do", "ready", "up-next", "next":
+		return "ready"
+	case "in-progress", "doing", "active", "started", "wip":
+		return "active"
+	case "in-review", "review", "reviewing", "needs-review":
+		return "review"
+	case "blocked", "waiting":
+		return "blocked"
+	case "backlog", "icebox", "parked", "later", "on-hold":
+		return "parked"
+	case "done", "closed", "complete", "completed", "shipped":
+		return "done"
+	}
+	return slug
+}
+
 func boardStatusCounts(counts map[string]int) []BoardStatusCount {
 	statuses := make([]BoardStatusCount, 0, len(counts))
 	for status, count := range counts {
@@ -360,14 +400,12 @@ func boardStatusRepo(nodes []Node) string {
 func checkBoardSnapshot(ctx context.Context, repo string, pullable []BriefItem) SnapshotFreshness {
 	liveReady := issueNumbers(pullable)
 	check := SnapshotFreshness{Checked: true, LiveReady: liveReady}
-	cmd := exec.CommandContext(ctx, "gh", "api", "--header", "Accept: application/vnd.github.raw", "/repos/moul/1789.tech/contents/hermes/state/board-snapshot.json")
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output()
+	res, err := github.FromEnv(ctx).Do(ctx, github.Request{Path: "/repos/moul/1789.tech/contents/hermes/state/board-snapshot.json", Accept: "application/vnd.github.raw"}, nil)
 	if err != nil {
-		check.Message = fmt.Sprintf("snapshot unavailable: %s", strings.TrimSpace(stderr.String()))
+		check.Message = fmt.Sprintf("snapshot unavailable: %s", err)
 		return check
 	}
+	out := res.Body
 	var payload struct {
 		GeneratedAt string `json:"generated_at"`
 		Queue       []struct {
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -14,37 +15,14 @@ func (s *Store) BuildBrief(ctx context.Context, boardID string) (Brief, error) {
 	if err != nil {
 		return Brief{}, err
 	}
-	nodes := map[string]Node{}
-	for _, n := range snap.Nodes {
-		nodes[n.ID] = n
-	}
-	blockersByNode := map[string]map[string]bool{}
-	blockedByNode := map[string]map[string]bool{}
-	for _, e := range snap.Edges {
-		blocked, blocker := edgeBlockedAndBlocker(e)
-		if blocked == "" || blocker == "" {
-			continue
-		}
-		if _, ok := nodes[blocked]; !ok {
-			continue
-		}
-		if _, ok := nodes[blocker]; !ok {
-			continue
-		}
-		if blockersByNode[blocked] == nil {
-			blockersByNode[blocked] = map[string]bool{}
-		}
-		if blockedByNode[blocker] == nil {
-			blockedByNode[blocker] = map[string]bool{}
-		}
-		blockersByNode[blocked][blocker] = true
-		blockedByNode[blocker][blocked] = true
-	}
+	nodes, blockersByNode, blockedByNode := blockingMaps(snap)
 	var ready, blockers, localOnly, stale []BriefItem
 	blockedCount := 0
 	cutoff := nowUTC().Add(-30 * 24 * time.Hour)
 	for _, n := range snap.Nodes {
-		if n.IsClosed() {
+		// Commits and branches are work on cards, not cards to pick up, and
+		// code comments only matter once what they point at is closed.
+		if n.IsClosed() || n.Kind == "commit" || n.Kind == "branch" || n.Kind == "code" {
 			continue
 		}
 		activeBlockers := activeBlockers(n.ID, nodes, blockersByNode)
@@ -72,6 +50,31 @@ func (s *Store) BuildBrief(ctx context.Context, boardID string) (Brief, error) {
 			stale = append(stale, BriefItem{ID: n.ID, Title: n.Title, Kind: n.Kind, State: n.State, URL: n.URL, Reason: "not updated in 30+ days"})
 		}
 	}
+	// Code comments still pointing at closed issues are debt nobody tracks.
+	closedRefs := map[string][]string{}
+	for _, e := range snap.Edges {
+		from, to := nodes[e.FromID], nodes[e.ToID]
+		if e.Kind == "addresses" && from.Kind == "code" && !from.IsClosed() && to.IsClosed() {
+			closedRefs[from.ID] = append(closedRefs[from.ID], to.ID)
+		}
+	}
+	var branches []BriefItem
+	for _, e := range snap.Edges {
+		from, to := nodes[e.FromID], nodes[e.ToID]
+		if e.Kind != "addresses" || from.Kind != "branch" || from.IsClosed() || to.ID == "" || to.IsClosed() {
+			continue
+		}
+		reason := "has an active branch " + from.Title
+		if unpushed := gitUnpushed(from); unpushed > 0 {
+			reason += fmt.Sprintf(" with %d unpushed commit%s", unpushed, plural(unpushed))
+		}
+		branches = append(branches, BriefItem{ID: to.ID, Title: to.Title, Kind: to.Kind, State: to.State, URL: to.URL, Reason: reason})
+	}
+	for id, refs := range closedRefs {
+		n := nodes[id]
+		sort.Strings(refs)
+		stale = append(stale, BriefItem{ID: n.ID, Title: n.Title, Kind: n.Kind, State: n.State, URL: n.URL, Reason: "still points at closed " + strings.Join(refs, ", ")})
+	}
 	for blockerID := range blockedByNode {
 		n := nodes[blockerID]
 		if n.IsClosed() {
@@ -81,26 +84,35 @@ func (s *Store) BuildBrief(ctx context.Context, boardID string) (Brief, error) {
 		if count == 0 {
 			continue
 		}
+		reach := transitiveBlockedCount(blockerID, nodes, blockedByNode)
+		reason := fmt.Sprintf("blocks %d active card%s", count, plural(count))
+		if reach > count {
+			reason += fmt.Sprintf(", %d downstream", reach)
+		}
 		blockers = append(blockers, BriefItem{
-			ID:     n.ID,
-			Title:  n.Title,
-			Kind:   n.Kind,
-			State:  n.State,
-			URL:    n.URL,
-			Impact: count,
-			Reason: fmt.Sprintf("blocks %d active card%s", count, plural(count)),
+			ID:               n.ID,
+			Title:            n.Title,
+			Kind:             n.Kind,
+			State:            n.State,
+			URL:              n.URL,
+			Impact:           count,
+			TransitiveImpact: reach,
+			Reason:           reason,
 		})
 	}
 	sortBriefItems(ready)
-	sortBriefItems(blockers)
+	sortBriefItemsByReach(blockers)
 	sortBriefItems(localOnly)
 	sortBriefItems(stale)
+	sortBriefItems(branches)
 	b := Brief{
 		BoardName: snap.Board.Name,
 		Ready:     limitItems(ready, 12),
 		Blockers:  limitItems(blockers, 12),
 		LocalOnly: limitItems(localOnly, 12),
 		Stale:     limitItems(stale, 12),
+		Branches:  limitItems(branches, 12),
+		Cycles:    FindCycles(snap),
 		Counts: BriefCounts{
 			Nodes:     len(snap.Nodes),
 			Edges:     len(snap.Edges),
@@ -123,6 +135,9 @@ func RenderBrief(w io.Writer, b Brief) error {
 	}
 	write("DepViz brief: %s\n", b.BoardName)
 	write("Nodes: %d - edges: %d - ready: %d - blocked: %d - local: %d\n\n", b.Counts.Nodes, b.Counts.Edges, b.Counts.Ready, b.Counts.Blocked, b.Counts.LocalOnly)
+	if len(b.Cycles) > 0 {
+		writeCycles(w, b.Cycles)
+	}
 	if b.NextMove != nil {
 		write("Next move\n")
 		writeItem(w, *b.NextMove, true)
@@ -131,6 +146,9 @@ func RenderBrief(w io.Writer, b Brief) error {
 	writeSection(w, "Ready now", b.Ready, false, true)
 	writeSection(w, "Blocking most work", b.Blockers, true, true)
 	writeSection(w, "Local-only", b.LocalOnly, false, true)
+	if len(b.Branches) > 0 {
+		writeSection(w, "Active branches", b.Branches, false, true)
+	}
 	writeSection(w, "Stale external state", b.Stale, false, false)
 	return nil
 }
@@ -165,6 +183,39 @@ func writeItem(w io.Writer, item BriefItem, showImpact bool) {
 	}
 }
 
+// blockingMaps indexes the hard blocking edges of a snapshot both ways:
+// blockersByNode[blocked][blocker] and blockedByNode[blocker][blocked]. Edges
+// pointing outside the snapshot are ignored.
+func blockingMaps(snap Snapshot) (map[string]Node, map[string]map[string]bool, map[string]map[string]bool) {
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	blockersByNode := map[string]map[string]bool{}
+	blockedByNode := map[string]map[string]bool{}
+	for _, e := range snap.Edges {
+		blocked, blocker := edgeBlockedAndBlocker(e)
+		if blocked == "" || blocker == "" {
+			continue
+		}
+		if _, ok := nodes[blocked]; !ok {
+			continue
+		}
+		if _, ok := nodes[blocker]; !ok {
+			continue
+		}
+		if blockersByNode[blocked] == nil {
+			blockersByNode[blocked] = map[string]bool{}
+		}
+		if blockedByNode[blocker] == nil {
+			blockedByNode[blocker] = map[string]bool{}
+		}
+		blockersByNode[blocked][blocker] = true
+		blockedByNode[blocker][blocked] = true
+	}
+	return nodes, blockersByNode, blockedByNode
+}
+
 func edgeBlockedAndBlocker(e Edge) (blocked string, blocker string) {
 	if edgeIsSoft(e) {
 		return "", ""
@@ -212,6 +263,36 @@ func activeBlockedCount(nodeID string, nodes map[string]Node, blockedByNode map[
 	return count
 }
 
+// transitiveBlockedCount counts the open cards a node holds up, directly or
+// through other open cards. Closed cards stop the walk since they no longer
+// wait on anything.
+func transitiveBlockedCount(nodeID string, nodes map[string]Node, blockedByNode map[string]map[string]bool) int {
+	seen := map[string]bool{nodeID: true}
+	queue := []string{nodeID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for blockedID := range blockedByNode[id] {
+			blocked, ok := nodes[blockedID]
+			if seen[blockedID] || !ok || blocked.IsClosed() {
+				continue
+			}
+			seen[blockedID] = true
+			queue = append(queue, blockedID)
+		}
+	}
+	return len(seen) - 1
+}
+
+// sortBriefItemsByReach ranks blockers by transitive impact first, so a card
+// at the root of a long chain outranks one with a wide but shallow fan-out.
+func sortBriefItemsByReach(items []BriefItem) {
+	sortBriefItems(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].TransitiveImpact > items[j].TransitiveImpact
+	})
+}
+
 func readyReason(n Node, blocked map[string]bool) string {
 	impact := len(blocked)
 	switch {
@@ -226,6 +307,15 @@ func readyReason(n Node, blocked map[string]bool) string {
 	}
 }
 
+// gitUnpushed reads how many commits of a branch node no remote has.
+func gitUnpushed(n Node) int {
+	var payload struct {
+		Unpushed int `json:"unpushed"`
+	}
+	_ = json.Unmarshal([]byte(n.DataJSON), &payload)
+	return payload.Unpushed
+}
+
 func plural(n int) string {
 	if n == 1 {
 		return ""
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CodeSyncOptions selects the source tree SyncCode scans and where its
+// comments go.
+type CodeSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Dir is the directory scanned recursively, or a single file. A trailing
+	// /... as in ./... is accepted.
+	Dir string
+	// Repo is the owner/name short refs such as #123 point at; without it
+	// they are ignored.
+	Repo string
+}
+
+// CodeSyncResult counts what a code sync imported.
+type CodeSyncResult struct {
+	Source  string `json:"source"`
+	Files   int    `json:"files"`
+	Items   int    `json:"items"`
+	Links   int    `json:"links"`
+	Removed int    `json:"removed"`
+}
+
+var (
+	// codeCommentRE finds a line or block comment: // and # after a space or
+	// at the start of the line, and /*, *, --, ; and <!-- at its start. #
+	// needs a space after it so #123 is not taken for a comment.
+	codeCommentRE = regexp.MustCompile(`(?:^|\s)(?://+|#+(?:\s|$))\s*(.*?)\s*$|^\s*(?:/\*+|\*+|--+|;+|<!--)\s*(.*?)\s*$`)
+	codeTagRE     = regexp.MustCompile(`^(TODO|FIXME|XXX|HACK)(?:\(([^)]*)\))?:\s*(.*?)$`)
+	codeCloseRE   = regexp.MustCompile(`\s*(?:\*+/|-->)$`)
+)
+
+// codeComment is a TODO-style comment, or one stating a relation, of a
+// source file.
+type codeComment struct {
+	id    string
+	tag   string
+	owner string
+	text  string
+	refs  []string
+	file  string
+	line  int
+}
+
+// SyncCode turns the TODO, FIXME, XXX and HACK comments of a source tree, as
+// in "TODO(#123): drop once migrated" or "FIXME(ada): retry", and comments
+// stating a relation, as in "// blocked by gh:acme/api#9", into code: nodes.
+// Each points at the issues it names with an addresses edge with authority
+// code and the file and line as evidence; a tag that is not a ref is the
+// comment's owner. IDs come from the file path and the comment text, so
+// re-running the sync updates the same nodes and comments gone from the tree
+// leave the board.
+func SyncCode(ctx context.Context, s *Store, opts CodeSyncOptions) (CodeSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	var res CodeSyncResult
+	err := s.runSync(ctx, opts.Board, "code", func() (int, int, error) {
+		var err error
+		res, err = syncCode(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncCode(ctx context.Context, s *Store, opts CodeSyncOptions) (CodeSyncResult, error) {
+	dir := strings.TrimSuffix(strings.TrimSpace(opts.Dir), "...")
+	if dir == "" {
+		dir = "."
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return CodeSyncResult{}, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return CodeSyncResult{}, err
+	}
+	base := root
+	if !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+	sourceID := "code:" + filepath.ToSlash(root)
+	res := CodeSyncResult{Source: sourceID}
+	if err := s.UpsertSource(ctx, Source{
+		ID:           sourceID,
+		Kind:         "code",
+		Name:         opts.Dir,
+		URL:          "file://" + filepath.ToSlash(root),
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"files"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	// Markdown has its own source, whose checklists read like comments here.
+	files, err := sourceFiles(root, func(path string) bool {
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext != ".md" && ext != ".markdown"
+	})
+	if err != nil {
+		return res, err
+	}
+	seen := map[string]bool{}
+	for _, path := range files {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return res, err
+		}
+		comments, ok, err := readCodeComments(path, filepath.ToSlash(rel), opts.Repo)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			continue
+		}
+		res.Files++
+		for _, c := range comments {
+			for n, base := 2, c.id; seen[c.id]; n++ {
+				c.id = fmt.Sprintf("%s-%d", base, n)
+			}
+			seen[c.id] = true
+			links, err := s.upsertCodeComment(ctx, opts.Board, sourceID, "file://"+filepath.ToSlash(path), c)
+			if err != nil {
+				return res, err
+			}
+			res.Items++
+			res.Links += links
+		}
+	}
+	res.Removed, err = s.removeUnseenSourceNodes(ctx, opts.Board, sourceID, seen)
+	return res, err
+}
+
+// readCodeComments reads the TODO-style and relation comments of a file. It
+// reports false for binary files, which it skips.
+func readCodeComments(path, rel, repo string) ([]codeComment, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, false, nil
+	}
+	fileSlug := slug(rel)
+	var comments []codeComment
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		src := scanner.Text()
+		m := codeCommentRE.FindStringSubmatchIndex(src)
+		// A marker after an unclosed quote is inside a string literal.
+		if m == nil || strings.Count(src[:m[0]], `"`)%2 == 1 || strings.Count(src[:m[0]], "`")%2 == 1 {
+			continue
+		}
+		text := codeCloseRE.ReplaceAllString(src[max(m[2], 0):max(m[3], 0)]+src[max(m[4], 0):max(m[5], 0)], "")
+		c := codeComment{file: rel, line: line, text: text}
+		if t := codeTagRE.FindStringSubmatch(text); t != nil {
+			c.tag, c.text = t[1], t[3]
+			if refs := codeRefs(repo, t[2]); len(refs) > 0 {
+				c.refs = refs
+			} else {
+				c.owner = strings.TrimPrefix(strings.TrimSpace(t[2]), "@")
+			}
+			for _, ref := range codeRefs(repo, c.text) {
+				c.refs = appendUnique(c.refs, ref)
+			}
+		} else {
+			for _, e := range extractDependencyEdges(repo, "code:", text) {
+				if !strings.HasPrefix(e.To, "gh:#") && !strings.HasPrefix(e.To, "gh:!") {
+					c.refs = appendUnique(c.refs, e.To)
+				}
+			}
+			if len(c.refs) == 0 {
+				continue
+			}
+		}
+		name := c.text
+		if c.tag != "" {
+			name = c.tag + " " + c.text
+		}
+		c.id = "code:" + fileSlug + ":" + slug(name)
+		if strings.HasSuffix(c.id, ":") {
+			c.id += strings.ToLower(c.tag)
+		}
+		comments = append(comments, c)
+	}
+	if err := scanner.Err(); err != nil {
+		// Minified or generated files may have lines past the buffer.
+		return nil, false, nil
+	}
+	return comments, true, nil
+}
+
+// codeRefs lists the issue refs of text, leaving out short refs when there
+// is no repo for them.
+func codeRefs(repo, text string) []string {
+	var refs []string
+	for _, ref := range itemRefs("gh:", repo, text) {
+		if !strings.HasPrefix(ref, "gh:#") && !strings.HasPrefix(ref, "gh:!") {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// upsertCodeComment stores a comment and replaces the addresses edges to the
+// issues it names.
+func (s *Store) upsertCodeComment(ctx context.Context, boardID, sourceID, fileURL string, c codeComment) (int, error) {
+	title := c.text
+	if c.tag != "" {
+		title = strings.TrimSpace(c.tag + ": " + c.text)
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "code",
+		"tag":       c.tag,
+		"text":      c.text,
+		"file":      c.file,
+		"line":      c.line,
+		"synced_at": formatTime(nowUTC()),
+	})
+	if err := s.UpsertNode(ctx, Node{ID: c.id, Kind: "code", Title: title, State: "open", Owner: c.owner, DataJSON: string(payload), UpdatedAt: nowUTC()}); err != nil {
+		return 0, err
+	}
+	if err := s.UpsertSourceRef(ctx, c.id, sourceID, c.id, fmt.Sprintf("%s#L%d", fileURL, c.line)); err != nil {
+		return 0, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, c.id, "card", ""); err != nil {
+		return 0, err
+	}
+	keep := map[string]bool{}
+	for _, ref := range c.refs {
+		edge, err := s.AddEdgeWithConfidence(ctx, boardID, c.id, ref, "addresses", "code", 1, map[string]any{
+			"file": c.file,
+			"line": c.line,
+			"at":   fmt.Sprintf("%s:%d", c.file, c.line),
+			"text": c.text,
+		})
+		if err != nil {
+			return 0, err
+		}
+		keep[edge.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, c.id, "code", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSyncCodeTurnsTodosIntoNodes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	auth := "package auth\n\n// TODO(#123): drop the legacy token path\nfunc login() {\n\turl := \"https://example.com\" // FIXME(@ada): retry on 503\n\t// blocked by gh:acme/web#9\n\t// just a note about #5\n}\n"
+	write("auth.go", auth)
+	write("scripts/run.sh", "#!/bin/sh\n# TODO: clean up\n")
+	write("README.md", "- [ ] TODO: not code\n")
+	write("logo.png", "\x00// TODO: binary\n")
+	write(".git/hooks/pre-commit", "# TODO: hidden\n")
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := CodeSyncOptions{Dir: dir + "/...", Repo: "acme/api"}
+	res, err := SyncCode(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Files != 2 || res.Items != 4 || res.Links != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []string
+	for _, n := range snap.Nodes {
+		if n.Kind == "code" {
+			nodes = append(nodes, fmt.Sprintf("%s %q %s", n.ID, n.Title, n.Owner))
+		}
+	}
+	sort.Strings(nodes)
+	want := []string{
+		`code:auth-go:blocked-by-gh-acme-web-9 "blocked by gh:acme/web#9" `,
+		`code:auth-go:fixme-retry-on-503 "FIXME: retry on 503" ada`,
+		`code:auth-go:todo-drop-the-legacy-token-path "TODO: drop the legacy token path" `,
+		`code:scripts-run-sh:todo-clean-up "TODO: clean up" `,
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("nodes = %q, want %q", nodes, want)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority, e.EvidenceJSON))
+	}
+	sort.Strings(edges)
+	wantEdges := []string{
+		`code:auth-go:blocked-by-gh-acme-web-9 addresses gh:acme/web#9 code {"at":"auth.go:6","file":"auth.go","line":6,"text":"blocked by gh:acme/web#9"}`,
+		`code:auth-go:todo-drop-the-legacy-token-path addresses gh:acme/api#123 code {"at":"auth.go:3","file":"auth.go","line":3,"text":"drop the legacy token path"}`,
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Fatalf("edges = %q, want %q", edges, wantEdges)
+	}
+
+	// Closing the issue leaves the TODO stale.
+	if err := s.UpsertNode(ctx, Node{ID: "gh:acme/api#123", Kind: "issue", Title: "Legacy tokens", State: "closed", UpdatedAt: nowUTC()}); err != nil {
+		t.Fatal(err)
+	}
+	brief, err := s.BuildBrief(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, item := range brief.Stale {
+		if item.ID == "code:auth-go:todo-drop-the-legacy-token-path" {
+			found = item.Reason == "still points at closed gh:acme/api#123"
+		}
+	}
+	if !found {
+		t.Fatalf("stale = %+v", brief.Stale)
+	}
+	for _, item := range brief.Ready {
+		if item.Kind == "code" {
+			t.Fatalf("ready = %+v, want no code comments", brief.Ready)
+		}
+	}
+
+	// Moving code around keeps IDs; removed comments leave the board.
+	write("auth.go", "package auth\n\nfunc login() {\n\t// FIXME(@ada): retry on 503\n}\n")
+	if res, err = SyncCode(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 2 || res.Links != 0 || res.Removed != 2 {
+		t.Fatalf("resync = %+v", res)
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// CriticalPath is the longest chain of open, hard-blocking edges that ends at a
+// goal: an open card that waits on others but holds nothing up itself. Chain
+// runs from the first card to start down to the goal, and Length counts its
+// edges.
+type CriticalPath struct {
+	Goal   string      `json:"goal"`
+	Length int         `json:"length"`
+	Chain  []BriefItem `json:"chain"`
+}
+
+// CriticalPaths returns the critical path to every goal of a board, longest
+// first.
+func (s *Store) CriticalPaths(ctx context.Context, boardID string) ([]CriticalPath, error) {
+	snap, err := s.Snapshot(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCriticalPaths(snap), nil
+}
+
+// BuildCriticalPaths computes critical paths with the same edge semantics as
+// the brief. Edges inside a dependency cycle are skipped, since a loop has no
+// longest chain; FindCycles reports those separately.
+func BuildCriticalPaths(snap Snapshot) []CriticalPath {
+	nodes, blockersByNode, blockedByNode := blockingMaps(snap)
+	cycleOf := map[string]int{}
+	for i, c := range FindCycles(snap) {
+		for _, id := range c.Nodes {
+			cycleOf[id] = i + 1
+		}
+	}
+	open := func(id string) bool {
+		n, ok := nodes[id]
+		return ok && !n.IsClosed()
+	}
+	inCycle := func(a, b string) bool {
+		return cycleOf[a] != 0 && cycleOf[a] == cycleOf[b]
+	}
+	blockers := func(id string) []string {
+		var out []string
+		for blocker := range blockersByNode[id] {
+			if open(blocker) && !inCycle(id, blocker) {
+				out = append(out, blocker)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	depth := map[string]int{}
+	prev := map[string]string{}
+	var longest func(string) int
+	longest = func(id string) int {
+		if d, ok := depth[id]; ok {
+			return d
+		}
+		best := 0
+		for _, blocker := range blockers(id) {
+			if d := longest(blocker) + 1; d > best {
+				best = d
+				prev[id] = blocker
+			}
+		}
+		depth[id] = best
+		return best
+	}
+
+	var paths []CriticalPath
+	for _, n := range snap.Nodes {
+		if n.IsClosed() || activeBlockedCount(n.ID, nodes, blockedByNode) > 0 || longest(n.ID) == 0 {
+			continue
+		}
+		var chain []BriefItem
+		for id := n.ID; id != ""; id = prev[id] {
+			chain = append(chain, criticalPathItem(nodes[id], id == n.ID, nodes, blockedByNode))
+		}
+		for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+			chain[i], chain[j] = chain[j], chain[i]
+		}
+		paths = append(paths, CriticalPath{Goal: n.ID, Length: len(chain) - 1, Chain: chain})
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Length != paths[j].Length {
+			return paths[i].Length > paths[j].Length
+		}
+		return paths[i].Goal < paths[j].Goal
+	})
+	return paths
+}
+
+func criticalPathItem(n Node, goal bool, nodes map[string]Node, blockedByNode map[string]map[string]bool) BriefItem {
+	item := BriefItem{
+		ID:               n.ID,
+		Title:            n.Title,
+		Kind:             n.Kind,
+		State:            n.State,
+		URL:              n.URL,
+		Impact:           activeBlockedCount(n.ID, nodes, blockedByNode),
+		TransitiveImpact: transitiveBlockedCount(n.ID, nodes, blockedByNode),
+	}
+	if goal {
+		item.Reason = "goal"
+	} else {
+		item.Reason = fmt.Sprintf("holds up %d card%s", item.TransitiveImpact, plural(item.TransitiveImpact))
+	}
+	return item
+}
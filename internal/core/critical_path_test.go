@@ -0,0 +1,38 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildCriticalPathsFollowsLongestChain(t *testing.T) {
+	snap := Snapshot{
+		Nodes: []Node{
+			{ID: "root", State: "open"}, {ID: "mid", State: "open"}, {ID: "goal", State: "open"},
+			{ID: "side", State: "open"}, {ID: "done", State: "closed"}, {ID: "loop-a", State: "open"}, {ID: "loop-b", State: "open"},
+		},
+		Edges: []Edge{
+			{ID: "e1", FromID: "mid", ToID: "root", Kind: "blocked_by", Confidence: 1},
+			{ID: "e2", FromID: "mid", ToID: "goal", Kind: "blocks", Confidence: 1},
+			{ID: "e3", FromID: "goal", ToID: "side", Kind: "depends_on", Confidence: 1},
+			{ID: "e4", FromID: "root", ToID: "done", Kind: "blocked_by", Confidence: 1},
+			{ID: "e5", FromID: "goal", ToID: "loop-a", Kind: "blocked_by", Authority: "github-inferred", Confidence: 0.6},
+			{ID: "e6", FromID: "loop-a", ToID: "loop-b", Kind: "blocked_by", Confidence: 1},
+			{ID: "e7", FromID: "loop-b", ToID: "loop-a", Kind: "blocked_by", Confidence: 1},
+		},
+	}
+	paths := BuildCriticalPaths(snap)
+	if len(paths) != 1 {
+		t.Fatalf("paths = %+v, want only the path to goal", paths)
+	}
+	var chain []string
+	for _, item := range paths[0].Chain {
+		chain = append(chain, item.ID)
+	}
+	if paths[0].Goal != "goal" || paths[0].Length != 2 || strings.Join(chain, ",") != "root,mid,goal" {
+		t.Fatalf("path = %+v", paths[0])
+	}
+	if root := paths[0].Chain[0]; root.Impact != 1 || root.TransitiveImpact != 2 {
+		t.Fatalf("root impact = %d direct, %d transitive; want 1 and 2", root.Impact, root.TransitiveImpact)
+	}
+}
@@ -0,0 +1,377 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CSVFields are the board item fields a CSV file can carry, in the column
+// order RenderCSV writes them.
+var CSVFields = []string{"id", "title", "kind", "state", "owner", "labels", "role", "local_state", "depends_on", "blocks", "url"}
+
+// csvImportFields are the CSVFields IngestCSV reads; url comes from sources.
+var csvImportFields = CSVFields[:len(CSVFields)-1]
+
+// CSVImportOptions maps CSV columns onto board item fields.
+type CSVImportOptions struct {
+	// Map names the column of each field, as in {"title": "Summary"}. Fields
+	// left out are read from a column of their own name, if any, so files
+	// written by RenderCSV import as is.
+	Map map[string]string
+	// Name identifies the file in edge evidence.
+	Name string
+}
+
+// CSVImportResult counts what a CSV import wrote.
+type CSVImportResult struct {
+	Rows  int `json:"rows"`
+	Links int `json:"links"`
+}
+
+// ParseCSVMap reads a column mapping written as field=Column pairs separated
+// by commas, as in "id=Key,title=Summary".
+func ParseCSVMap(text string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range strings.Split(text, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("bad mapping %q: use field=Column", pair)
+		}
+		m[field] = column
+	}
+	return m, nil
+}
+
+// csvRow is a CSV record read through a column mapping.
+type csvRow struct {
+	line   int
+	id     string
+	values map[string]string
+}
+
+func (r csvRow) has(field string) bool {
+	_, ok := r.values[field]
+	return ok
+}
+
+// list splits a multi-value cell on commas.
+func (r csvRow) list(field string) []string {
+	out := []string{}
+	for _, v := range strings.Split(r.values[field], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// csvNodeID reads a cell naming an item: node IDs such as gh:acme/api#1 are
+// kept and bare keys such as OPS-12 name local tasks.
+func csvNodeID(key string) string {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.Contains(key, ":") {
+		return key
+	}
+	return "task:" + slug(key)
+}
+
+// IngestCSV imports the rows of a CSV file as board items: their title, kind,
+// state, owner and labels, their board role and local state, and blocked_by
+// and blocks edges to the items their depends_on and blocks cells list. The
+// whole file is applied in one transaction, as board source patches are, so a
+// bad row leaves the board untouched. Rows update existing items by ID and
+// re-importing a file replaces the edges it stated before.
+func (s *Store) IngestCSV(ctx context.Context, r io.Reader, boardID string, opts CSVImportOptions) (CSVImportResult, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	rows, err := readCSVRows(r, opts.Map)
+	if err != nil {
+		return CSVImportResult{}, err
+	}
+	var res CSVImportResult
+	err = s.WithTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		a := &boardSourcePatchApplier{ctx: ctx, tx: tx, boardID: boardID}
+		if err := a.ensureBoard(); err != nil {
+			return fmt.Errorf("board %s: %w", boardID, err)
+		}
+		for _, row := range rows {
+			if err := a.applyCSVRow(row); err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+		}
+		for _, row := range rows {
+			links, err := a.applyCSVLinks(row, opts.Name)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+			res.Links += links
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE boards SET updated_at = ? WHERE id = ?`, formatTime(nowUTC()), boardID)
+		return err
+	})
+	if err != nil {
+		return CSVImportResult{}, err
+	}
+	res.Rows = len(rows)
+	return res, nil
+}
+
+// readCSVRows reads a CSV file with a header row through a column mapping.
+func readCSVRows(r io.Reader, mapping map[string]string) ([]csvRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	index := map[string]int{}
+	for field, column := range mapping {
+		if !containsString(csvImportFields, field) {
+			return nil, fmt.Errorf("unknown field %q: use one of %s", field, strings.Join(csvImportFields, ", "))
+		}
+		i, ok := columns[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("no column %q for %s", column, field)
+		}
+		index[field] = i
+	}
+	for _, field := range csvImportFields {
+		if _, ok := mapping[field]; !ok {
+			if i, ok := columns[field]; ok {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["id"]; !ok {
+		if _, ok := index["title"]; !ok {
+			return nil, errors.New("an id or title column is required")
+		}
+	}
+	var rows []csvRow
+	seen := map[string]int{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := csvRow{line: line, values: map[string]string{}}
+		blank := true
+		for field, i := range index {
+			row.values[field] = strings.TrimSpace(record[i])
+			blank = blank && row.values[field] == ""
+		}
+		if blank {
+			continue
+		}
+		row.id = csvNodeID(row.values["id"])
+		if row.id == "" {
+			row.id = csvNodeID(row.values["title"])
+		}
+		if row.id == "" || row.id == "task:" {
+			return nil, fmt.Errorf("line %d: an id or title is required", line)
+		}
+		if prev, ok := seen[row.id]; ok {
+			return nil, fmt.Errorf("line %d: %s already on line %d", line, row.id, prev)
+		}
+		seen[row.id] = line
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// applyCSVRow creates or updates a row's item and puts it on the board.
+// Cells left empty keep what the item had.
+func (a *boardSourcePatchApplier) applyCSVRow(row csvRow) error {
+	exists, err := a.nodeExists(row.id)
+	if err != nil {
+		return err
+	}
+	n := Node{ID: row.id, Kind: "task", State: "open", DataJSON: `{}`}
+	if exists {
+		if n, err = a.nodeByID(row.id); err != nil {
+			return err
+		}
+	}
+	if v := row.values["title"]; v != "" {
+		n.Title = v
+	}
+	if n.Title == "" {
+		n.Title = row.values["id"]
+	}
+	if v := row.values["kind"]; v != "" {
+		n.Kind = strings.ToLower(v)
+	}
+	if v := row.values["state"]; v != "" {
+		n.State = strings.ReplaceAll(strings.ToLower(v), " ", "_")
+	}
+	if row.has("owner") {
+		n.Owner = row.values["owner"]
+	}
+	data := map[string]any{}
+	_ = json.Unmarshal([]byte(n.DataJSON), &data)
+	if !exists {
+		data["source"] = "csv"
+	}
+	if row.has("labels") {
+		data["labels"] = row.list("labels")
+	}
+	payload, _ := json.Marshal(data)
+	n.DataJSON = string(payload)
+	n.UpdatedAt = nowUTC()
+	if err := a.upsertNode(n); err != nil {
+		return err
+	}
+	if !exists {
+		if err := a.upsertSourceRef(n.ID, LocalSourceID, n.ID, ""); err != nil {
+			return err
+		}
+	}
+	role := row.values["role"]
+	if role == "" {
+		// Keep the role of items already on the board.
+		err := a.tx.QueryRowContext(a.ctx, `SELECT role FROM board_items WHERE board_id = ? AND node_id = ?`, a.boardID, n.ID).Scan(&role)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	if err := a.addNodeToBoard(n.ID, role, ""); err != nil {
+		return err
+	}
+	if row.has("local_state") {
+		if _, err := a.tx.ExecContext(a.ctx, `UPDATE board_items SET local_state = ? WHERE board_id = ? AND node_id = ?`, row.values["local_state"], a.boardID, n.ID); err != nil {
+			return err
+		}
+	}
+	evPayload, _ := json.Marshal(n)
+	return a.recordEvent("depviz.node_update.v1", n.ID, evPayload)
+}
+
+// applyCSVLinks replaces the edges with authority csv a row states. Both ends
+// must be on the board, rows of the file included.
+func (a *boardSourcePatchApplier) applyCSVLinks(row csvRow, name string) (int, error) {
+	if !row.has("depends_on") && !row.has("blocks") {
+		return 0, nil
+	}
+	keep := map[string]bool{}
+	for _, spec := range []struct{ field, kind string }{{"depends_on", "blocked_by"}, {"blocks", "blocks"}} {
+		for _, ref := range row.list(spec.field) {
+			to := csvNodeID(ref)
+			ok, err := a.boardItemExists(to)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return 0, fmt.Errorf("%s %s: no such item on the board", spec.field, ref)
+			}
+			id := stableID("edge", a.boardID, row.id, to, spec.kind)
+			var authority string
+			err = a.tx.QueryRowContext(a.ctx, `SELECT authority FROM edges WHERE id = ?`, id).Scan(&authority)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return 0, err
+			}
+			if err == nil && authority != "csv" {
+				// Another source already states this edge; leave it to it.
+				keep[id] = true
+				continue
+			}
+			evidence, _ := json.Marshal(map[string]any{"source": "csv", "file": name, "line": row.line, "column": spec.field})
+			e := Edge{
+				ID:           id,
+				FromID:       row.id,
+				ToID:         to,
+				Kind:         spec.kind,
+				ScopeBoardID: a.boardID,
+				Confidence:   1,
+				Authority:    "csv",
+				EvidenceJSON: string(evidence),
+				ObservedAt:   nowUTC(),
+			}
+			if err := a.upsertEdge(e); err != nil {
+				return 0, err
+			}
+			payload, _ := json.Marshal(e)
+			if err := a.recordEvent("depviz.edge.v1", e.ID, payload); err != nil {
+				return 0, err
+			}
+			keep[e.ID] = true
+		}
+	}
+	ids, err := a.tx.QueryContext(a.ctx, `SELECT id FROM edges WHERE scope_board_id = ? AND authority = 'csv' AND from_id = ?`, a.boardID, row.id)
+	if err != nil {
+		return 0, err
+	}
+	var stale []string
+	for ids.Next() {
+		var id string
+		if err := ids.Scan(&id); err != nil {
+			ids.Close()
+			return 0, err
+		}
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	ids.Close()
+	if err := ids.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range stale {
+		if _, err := a.tx.ExecContext(a.ctx, `DELETE FROM edges WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(keep), nil
+}
+
+// RenderCSV writes a snapshot's items as CSV, one row per item with the
+// CSVFields columns. blocked_by edges fill depends_on and blocks edges
+// blocks, so IngestCSV reads the file back into the same board.
+func RenderCSV(w io.Writer, snap Snapshot) error {
+	deps := map[string][]string{}
+	for _, e := range snap.Edges {
+		switch e.Kind {
+		case "blocked_by", "depends_on":
+			deps[e.FromID+"\x00depends_on"] = append(deps[e.FromID+"\x00depends_on"], e.ToID)
+		case "blocks":
+			deps[e.FromID+"\x00blocks"] = append(deps[e.FromID+"\x00blocks"], e.ToID)
+		}
+	}
+	cell := func(values []string) string {
+		sort.Strings(values)
+		return strings.Join(values, ", ")
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVFields); err != nil {
+		return err
+	}
+	for _, n := range snap.Nodes {
+		if err := cw.Write([]string{n.ID, n.Title, n.Kind, n.State, n.Owner, cell(n.Labels()), n.BoardRole, n.LocalState, cell(deps[n.ID+"\x00depends_on"]), cell(deps[n.ID+"\x00blocks"]), n.URL}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIngestCSVMapsColumnsAndRoundTrips(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	sheet := "Key,Summary,Status,Deps,Labels,Lane\n" +
+		"OPS-1,Migrate auth,In Progress,\"OPS-2, OPS-3\",backend,now\n" +
+		"OPS-2,Schema,Done,,,\n" +
+		"OPS-3,Docs,To Do,,\"docs, writing\",later\n"
+	m, err := ParseCSVMap("id=Key,title=Summary,state=Status,depends_on=Deps,labels=Labels,local_state=Lane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.IngestCSV(ctx, strings.NewReader(sheet), DefaultBoardID, CSVImportOptions{Map: m, Name: "plan.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 3 || res.Links != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	if n := nodes["task:ops-1"]; n.Title != "Migrate auth" || n.State != "in_progress" || n.LocalState != "now" || n.BoardRole != "card" || fmt.Sprint(n.Labels()) != "[backend]" {
+		t.Fatalf("ops-1 = %+v", n)
+	}
+	if n := nodes["task:ops-3"]; fmt.Sprint(n.Labels()) != "[docs writing]" || !nodes["task:ops-2"].IsClosed() {
+		t.Fatalf("ops-3 = %+v", n)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority))
+	}
+	sort.Strings(edges)
+	if got := strings.Join(edges, "; "); got != "task:ops-1 blocked_by task:ops-2 csv; task:ops-1 blocked_by task:ops-3 csv" {
+		t.Fatalf("edges = %s", got)
+	}
+
+	// A bad row rolls the whole file back.
+	bad := "Key,Summary,Deps\nOPS-4,Launch,\nOPS-1,Migrate auth,OPS-9\n"
+	m, _ = ParseCSVMap("id=Key,title=Summary,depends_on=Deps")
+	if _, err := s.IngestCSV(ctx, strings.NewReader(bad), DefaultBoardID, CSVImportOptions{Map: m}); err == nil || !strings.Contains(err.Error(), "line 3: depends_on OPS-9") {
+		t.Fatalf("err = %v", err)
+	}
+	if ok, _ := s.nodeExists(ctx, "task:ops-4"); ok {
+		t.Fatal("task:ops-4 was created by a failed import")
+	}
+	if _, err := s.IngestCSV(ctx, strings.NewReader("Key\nOPS-1\n"), DefaultBoardID, CSVImportOptions{Map: map[string]string{"estimate": "Key"}}); err == nil {
+		t.Fatal("unknown field accepted")
+	}
+
+	// gen csv output imports into another board unchanged.
+	var out bytes.Buffer
+	if err := RenderCSV(&out, snap); err != nil {
+		t.Fatal(err)
+	}
+	copyBoard, err := s.CreateBoard(ctx, "Copy", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.IngestCSV(ctx, bytes.NewReader(out.Bytes()), copyBoard.ID, CSVImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	copySnap, err := s.Snapshot(ctx, copyBoard.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if err := RenderCSV(&again, copySnap); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != out.String() {
+		t.Fatalf("round trip:\n%s\nwant:\n%s", again.String(), out.String())
+	}
+}
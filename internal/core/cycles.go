@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Cycle is a strongly connected component of hard blocking edges between open
+// cards: every card in it transitively waits on itself, so none of them can
+// ever become ready until one of the listed edges is removed.
+type Cycle struct {
+	Nodes []string    `json:"nodes"`
+	Edges []CycleEdge `json:"edges"`
+}
+
+// CycleEdge is one edge that closes a cycle, oriented blocked -> blocker.
+type CycleEdge struct {
+	ID        string `json:"id"`
+	Blocked   string `json:"blocked"`
+	Blocker   string `json:"blocker"`
+	Kind      string `json:"kind"`
+	Authority string `json:"authority"`
+}
+
+// CycleError is returned when an edge is refused because it would close a
+// cycle.
+type CycleError struct {
+	Cycle Cycle
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("edge would create a dependency cycle: %s", strings.Join(e.Cycle.Nodes, " -> "))
+}
+
+// FindCycles returns the dependency cycles of a snapshot, largest first. Only
+// hard edges count, and closed cards break a loop since they no longer block.
+func FindCycles(snap Snapshot) []Cycle {
+	return findCycles(snap, edgeBlockedAndBlocker)
+}
+
+func findCycles(snap Snapshot, blockedAndBlocker func(Edge) (string, string)) []Cycle {
+	open := map[string]bool{}
+	for _, n := range snap.Nodes {
+		if !n.IsClosed() {
+			open[n.ID] = true
+		}
+	}
+	graph := map[string][]string{}
+	var cycleEdges []CycleEdge
+	selfLoops := map[string]bool{}
+	for _, e := range snap.Edges {
+		blocked, blocker := blockedAndBlocker(e)
+		if blocked == "" || blocker == "" || !open[blocked] || !open[blocker] {
+			continue
+		}
+		graph[blocked] = append(graph[blocked], blocker)
+		cycleEdges = append(cycleEdges, CycleEdge{ID: e.ID, Blocked: blocked, Blocker: blocker, Kind: e.Kind, Authority: e.Authority})
+		if blocked == blocker {
+			selfLoops[blocked] = true
+		}
+	}
+	var cycles []Cycle
+	component := map[string]int{}
+	for _, scc := range stronglyConnected(graph) {
+		if len(scc) == 1 && !selfLoops[scc[0]] {
+			continue
+		}
+		sort.Strings(scc)
+		for _, id := range scc {
+			component[id] = len(cycles)
+		}
+		cycles = append(cycles, Cycle{Nodes: scc})
+	}
+	for _, e := range cycleEdges {
+		i, ok := component[e.Blocked]
+		if !ok {
+			continue
+		}
+		if j, ok := component[e.Blocker]; ok && i == j {
+			cycles[i].Edges = append(cycles[i].Edges, e)
+		}
+	}
+	for _, c := range cycles {
+		sort.Slice(c.Edges, func(i, j int) bool { return c.Edges[i].ID < c.Edges[j].ID })
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		if len(cycles[i].Nodes) != len(cycles[j].Nodes) {
+			return len(cycles[i].Nodes) > len(cycles[j].Nodes)
+		}
+		return cycles[i].Nodes[0] < cycles[j].Nodes[0]
+	})
+	return cycles
+}
+
+// stronglyConnected runs Tarjan's algorithm over an adjacency list.
+func stronglyConnected(graph map[string][]string) [][]string {
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		out     [][]string
+		next    int
+		visit   func(string)
+	)
+	visit = func(v string) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range graph[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		out = append(out, scc)
+	}
+	vertices := make([]string, 0, len(graph))
+	for v := range graph {
+		vertices = append(vertices, v)
+	}
+	sort.Strings(vertices)
+	for _, v := range vertices {
+		if _, seen := index[v]; !seen {
+			visit(v)
+		}
+	}
+	return out
+}
+
+// CyclesCreatedBy returns the cycles that would contain at least one of the
+// added edges once they join the snapshot. Endpoints missing from the snapshot
+// are treated as open cards, as AddEdge would create them as placeholders.
+func CyclesCreatedBy(snap Snapshot, added []Edge) []Cycle {
+	known := map[string]bool{}
+	for _, n := range snap.Nodes {
+		known[n.ID] = true
+	}
+	next := Snapshot{Board: snap.Board, Nodes: append([]Node(nil), snap.Nodes...), Edges: append(append([]Edge(nil), snap.Edges...), added...)}
+	addedIDs := map[string]bool{}
+	for _, e := range added {
+		addedIDs[e.ID] = true
+		for _, id := range []string{e.FromID, e.ToID} {
+			if !known[id] {
+				known[id] = true
+				next.Nodes = append(next.Nodes, Node{ID: id, State: "open"})
+			}
+		}
+	}
+	return cyclesThrough(FindCycles(next), addedIDs)
+}
+
+// cyclesThrough keeps the cycles that contain one of the given edges.
+func cyclesThrough(cycles []Cycle, edgeIDs map[string]bool) []Cycle {
+	var out []Cycle
+	for _, c := range cycles {
+		for _, e := range c.Edges {
+			if edgeIDs[e.ID] {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// AddEdgeAcyclic adds a hard edge like AddEdge, but refuses it with a
+// *CycleError when it would close a dependency cycle in the board. The check
+// and the write share a transaction, so two concurrent calls cannot close a
+// cycle between them.
+func (s *Store) AddEdgeAcyclic(ctx context.Context, boardID, fromID, toID, kind, authority string, evidence any) (Edge, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	if kind == "" {
+		kind = "blocked_by"
+	}
+	var edge Edge
+	err := s.WithTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		a := &boardSourcePatchApplier{ctx: ctx, tx: tx, boardID: boardID}
+		if err := a.ensureBoard(); err != nil {
+			return err
+		}
+		snap, err := a.cycleSnapshot()
+		if err != nil {
+			return err
+		}
+		candidate := Edge{ID: stableID("edge", boardID, fromID, toID, kind), FromID: fromID, ToID: toID, Kind: kind, Confidence: 1, Authority: authority}
+		if cycles := CyclesCreatedBy(snap, []Edge{candidate}); len(cycles) > 0 {
+			return &CycleError{Cycle: cycles[0]}
+		}
+		edge, err = a.addEdge(fromID, toID, kind, authority, 1, evidence)
+		return err
+	})
+	if err != nil {
+		return Edge{}, err
+	}
+	return edge, nil
+}
+
+// cycleSnapshot reads the board's nodes and edges as Snapshot does, with only
+// what FindCycles looks at.
+func (a *boardSourcePatchApplier) cycleSnapshot() (Snapshot, error) {
+	snap := Snapshot{Board: Board{ID: a.boardID}}
+	rows, err := a.tx.QueryContext(a.ctx, `SELECT n.id, n.state
+		FROM board_items bi
+		JOIN nodes n ON n.id = bi.node_id
+		WHERE bi.board_id = ? AND (n.archived_at IS NULL OR n.archived_at = '')`, a.boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n Node
+		if err := rows.Scan(&n.ID, &n.State); err != nil {
+			return Snapshot{}, err
+		}
+		snap.Nodes = append(snap.Nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return Snapshot{}, err
+	}
+	edgeRows, err := a.tx.QueryContext(a.ctx, `SELECT id, from_id, to_id, kind, confidence, authority
+		FROM edges
+		WHERE scope_board_id = ? OR scope_board_id = ''`, a.boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer edgeRows.Close()
+	for edgeRows.Next() {
+		var e Edge
+		if err := edgeRows.Scan(&e.ID, &e.FromID, &e.ToID, &e.Kind, &e.Confidence, &e.Authority); err != nil {
+			return Snapshot{}, err
+		}
+		snap.Edges = append(snap.Edges, e)
+	}
+	return snap, edgeRows.Err()
+}
+
+func writeCycles(w io.Writer, cycles []Cycle) {
+	_, _ = fmt.Fprintln(w, "Cycles")
+	for _, c := range cycles {
+		_, _ = fmt.Fprintf(w, "  %d card%s wait on each other: %s\n", len(c.Nodes), plural(len(c.Nodes)), strings.Join(c.Nodes, ", "))
+		for _, e := range c.Edges {
+			_, _ = fmt.Fprintf(w, "    %s waits on %s via %s (%s, %s)\n", e.Blocked, e.Blocker, e.ID, e.Kind, e.Authority)
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+}
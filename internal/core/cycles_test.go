@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFindCyclesReportsClosingEdges(t *testing.T) {
+	snap := Snapshot{
+		Nodes: []Node{
+			{ID: "a", State: "open"}, {ID: "b", State: "open"}, {ID: "c", State: "open"},
+			{ID: "d", State: "open"}, {ID: "e", State: "closed"}, {ID: "f", State: "open"},
+		},
+		Edges: []Edge{
+			{ID: "e1", FromID: "a", ToID: "b", Kind: "blocked_by", Authority: "local", Confidence: 1},
+			{ID: "e2", FromID: "b", ToID: "c", Kind: "blocked_by", Authority: "github", Confidence: 1},
+			{ID: "e3", FromID: "a", ToID: "c", Kind: "blocks", Authority: "flow", Confidence: 1},
+			{ID: "e4", FromID: "d", ToID: "e", Kind: "blocked_by", Authority: "local", Confidence: 1},
+			{ID: "e5", FromID: "e", ToID: "d", Kind: "blocked_by", Authority: "local", Confidence: 1},
+			{ID: "e6", FromID: "f", ToID: "a", Kind: "blocked_by", Authority: "github-inferred", Confidence: 0.6},
+			{ID: "e7", FromID: "a", ToID: "f", Kind: "blocked_by", Authority: "local", Confidence: 1},
+			{ID: "e8", FromID: "d", ToID: "d", Kind: "depends_on", Authority: "local", Confidence: 1},
+		},
+	}
+	cycles := FindCycles(snap)
+	if len(cycles) != 2 {
+		t.Fatalf("cycles = %+v, want 2 (closed card and soft edge break the others)", cycles)
+	}
+	if got := strings.Join(cycles[0].Nodes, ","); got != "a,b,c" {
+		t.Fatalf("first cycle nodes = %s, want a,b,c", got)
+	}
+	var ids []string
+	for _, e := range cycles[0].Edges {
+		ids = append(ids, e.ID+"/"+e.Authority)
+	}
+	if got := strings.Join(ids, " "); got != "e1/local e2/github e3/flow" {
+		t.Fatalf("closing edges = %s", got)
+	}
+	if got := strings.Join(cycles[1].Nodes, ","); got != "d" || len(cycles[1].Edges) != 1 || cycles[1].Edges[0].ID != "e8" {
+		t.Fatalf("self loop = %+v", cycles[1])
+	}
+}
+
+func TestAddEdgeAcyclicRefusesCycle(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.AddEdgeAcyclic(ctx, DefaultBoardID, "task:a", "task:b", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.AddEdgeAcyclic(ctx, DefaultBoardID, "task:a", "task:b", "blocks", "local", nil)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) || strings.Join(cycleErr.Cycle.Nodes, ",") != "task:a,task:b" {
+		t.Fatalf("err = %v, want cycle between task:a and task:b", err)
+	}
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "task:b", "task:a", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	brief, err := s.BuildBrief(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brief.Cycles) != 1 {
+		t.Fatalf("brief cycles = %+v, want 1", brief.Cycles)
+	}
+	var out strings.Builder
+	if err := RenderBrief(&out, brief); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Cycles\n  2 cards wait on each other: task:a, task:b\n") {
+		t.Fatalf("brief output missing cycles:\n%s", out.String())
+	}
+}
+
+func TestAddEdgeAcyclicConcurrentCallsCannotCloseACycle(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := range 20 {
+		a, b := fmt.Sprintf("task:a%d", i), fmt.Sprintf("task:b%d", i)
+		var wg sync.WaitGroup
+		for _, e := range [][2]string{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Losing the race is either a cycle error or a busy store.
+				_, _ = s.AddEdgeAcyclic(ctx, DefaultBoardID, e[0], e[1], "blocked_by", "local", nil)
+			}()
+		}
+		wg.Wait()
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycles := FindCycles(snap); len(cycles) != 0 {
+		t.Fatalf("cycles = %+v, want none", cycles)
+	}
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is one problem found while checking an ingest file. Line and
+// Column are 1-based and point into the original file, blank lines and
+// comments included.
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	out := fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+	if d.Fix != "" {
+		out += " (fix: " + d.Fix + ")"
+	}
+	return out
+}
+
+// Diagnostics is a list of problems in file order. As an error it reports every
+// entry, so callers that stop on the first failure still show them all.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	parts := make([]string, 0, len(ds))
+	for _, d := range ds {
+		parts = append(parts, fmt.Sprintf("line %d, column %d: %s", d.Line, d.Column, d.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func (ds Diagnostics) HasErrors() bool {
+	return len(ds.Errors()) > 0
+}
+
+// Errors returns the error-severity diagnostics.
+func (ds Diagnostics) Errors() Diagnostics {
+	var out Diagnostics
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func (ds Diagnostics) sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].Line != ds[j].Line {
+			return ds[i].Line < ds[j].Line
+		}
+		return ds[i].Column < ds[j].Column
+	})
+}
+
+var eventTypes = []string{"node", "note", "edge", "source", "depviz.node.v1", "depviz.note.v1", "depviz.edge.v1", "depviz.source.v1"}
+
+// edgeKinds are the stored edge kinds DepViz understands, for suggestions.
+var edgeKinds = func() []string {
+	kinds := make([]string, 0, len(flowEdgeVerbs))
+	for kind := range flowEdgeVerbs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}()
+
+// CheckEvents validates a JSONL event stream without writing anything. Unlike
+// IngestEvents it keeps going after a bad line and reports every problem it
+// finds: malformed JSON, unknown event types and edge kinds, missing IDs,
+// duplicate IDs and edges whose endpoints no event in the file declares.
+// Sources and nodes live in separate tables, so they may share an ID.
+func CheckEvents(r io.Reader) (Diagnostics, error) {
+	type pendingRef struct {
+		id     string
+		line   int
+		column int
+	}
+	var (
+		diags   Diagnostics
+		nodes   = map[string]int{}
+		sources = map[string]int{}
+		refs    []pendingRef
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		indent := strings.Index(raw, line)
+		var ev struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+			Kind string `json:"kind"`
+			From string `json:"from"`
+			To   string `json:"to"`
+		}
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			diags = append(diags, jsonDiagnostic(lineNo, indent, line, err))
+			continue
+		}
+		column := func(field string) int {
+			return indent + jsonValueColumn(line, field)
+		}
+		switch ev.Type {
+		case "depviz.edge.v1", "edge":
+			for _, end := range []struct{ field, id string }{{"from", ev.From}, {"to", ev.To}} {
+				if end.id == "" {
+					diags = append(diags, Diagnostic{Line: lineNo, Column: column(end.field), Severity: SeverityError, Message: "edge is missing " + end.field, Fix: fmt.Sprintf(`add a "%s" node id`, end.field)})
+					continue
+				}
+				refs = append(refs, pendingRef{id: end.id, line: lineNo, column: column(end.field)})
+			}
+			if _, ok := flowEdgeVerbs[ev.Kind]; ev.Kind != "" && !ok {
+				d := Diagnostic{Line: lineNo, Column: column("kind"), Severity: SeverityError, Message: fmt.Sprintf("unknown relation verb %q", ev.Kind), Fix: "use blocked_by, blocks, addresses, mentions, relates_to or closes"}
+				if guess := closestWord(ev.Kind, edgeKinds); guess != "" {
+					d.Fix = fmt.Sprintf("did you mean %q?", guess)
+				}
+				diags = append(diags, d)
+			}
+		case "depviz.note.v1", "note", "depviz.node.v1", "node", "", "depviz.source.v1", "source":
+			if ev.ID == "" {
+				if ev.Type != "note" && ev.Type != "depviz.note.v1" {
+					diags = append(diags, Diagnostic{Line: lineNo, Column: indent + 1, Severity: SeverityError, Message: "event is missing id", Fix: `add an "id" field`})
+				}
+				continue
+			}
+			declared := nodes
+			if ev.Type == "source" || ev.Type == "depviz.source.v1" {
+				declared = sources
+			}
+			if first, ok := declared[ev.ID]; ok {
+				diags = append(diags, Diagnostic{Line: lineNo, Column: column("id"), Severity: SeverityWarning, Message: fmt.Sprintf("duplicate id %q, first declared on line %d", ev.ID, first), Fix: "merge both events; the later one overwrites the earlier"})
+				continue
+			}
+			declared[ev.ID] = lineNo
+		default:
+			d := Diagnostic{Line: lineNo, Column: column("type"), Severity: SeverityError, Message: fmt.Sprintf("unknown event type %q", ev.Type), Fix: "use one of node, note, edge or source"}
+			if guess := closestWord(ev.Type, eventTypes); guess != "" {
+				d.Fix = fmt.Sprintf("did you mean %q?", guess)
+			}
+			diags = append(diags, d)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return diags, err
+	}
+	for _, ref := range refs {
+		if _, ok := nodes[ref.id]; ok {
+			continue
+		}
+		diags = append(diags, Diagnostic{Line: ref.line, Column: ref.column, Severity: SeverityWarning, Message: fmt.Sprintf("edge points at undeclared node %q", ref.id), Fix: "add a node event for it, or sync its source first; ingest creates a placeholder otherwise"})
+	}
+	diags.sort()
+	return diags, nil
+}
+
+func jsonDiagnostic(lineNo, indent int, line string, err error) Diagnostic {
+	d := Diagnostic{Line: lineNo, Column: indent + 1, Severity: SeverityError, Message: err.Error()}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		d.Column = indent + int(syntaxErr.Offset)
+		d.Message = "invalid JSON: " + syntaxErr.Error()
+		d.Fix = "check for a missing comma, quote or brace; each event must fit on one line"
+		if syntaxErr.Offset >= int64(len(line)) {
+			d.Fix = "the event is cut short; close every string, object and array on the same line"
+		}
+	case errors.As(err, &typeErr):
+		d.Column = indent + int(typeErr.Offset)
+		if typeErr.Field != "" {
+			d.Column = indent + jsonValueColumn(line, typeErr.Field)
+		}
+		d.Message = fmt.Sprintf("field %q must be a %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		d.Fix = "quote the value"
+	}
+	return d
+}
+
+// jsonValueColumn returns the 1-based column of a top-level field's value in a
+// one-line JSON object, or 1 when the field cannot be found.
+func jsonValueColumn(line, field string) int {
+	re := regexp.MustCompile(`"` + regexp.QuoteMeta(field) + `"\s*:\s*`)
+	if loc := re.FindStringIndex(line); loc != nil {
+		return loc[1] + 1
+	}
+	return 1
+}
+
+// closestWord returns the candidate within a small edit distance of word, or ""
+// when nothing is close enough to be a plausible typo.
+func closestWord(word string, candidates []string) string {
+	word = strings.ToLower(word)
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(word, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
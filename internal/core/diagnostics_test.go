@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckEventsReportsEveryProblem(t *testing.T) {
+	events := strings.Join([]string{
+		`# comment`,
+		``,
+		`{"type":"node","id":"a","title":"A"}`,
+		`{"type":"nod","id":"b"}`,
+		`{"type":"node","id":"a","title":"A again"}`,
+		`{"type":"edge","from":"a","to":"missing"}`,
+		`{"type":"node","id":"c",}`,
+		`{"type":"source","id":"a","title":"Repo A"}`,
+		`{"type":"node","id":"d","title":"D"}`,
+		`{"type":"edge","from":"a","to":"d","kind":"blokced_by"}`,
+	}, "\n")
+	diags, err := CheckEvents(strings.NewReader(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Diagnostic{
+		{Line: 4, Column: 9, Severity: SeverityError},
+		{Line: 5, Column: 21, Severity: SeverityWarning},
+		{Line: 6, Column: 32, Severity: SeverityWarning},
+		{Line: 7, Column: 25, Severity: SeverityError},
+		{Line: 10, Column: 43, Severity: SeverityError},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("diagnostics = %+v, want %d", diags, len(want))
+	}
+	for i, w := range want {
+		if diags[i].Line != w.Line || diags[i].Column != w.Column || diags[i].Severity != w.Severity {
+			t.Fatalf("diagnostic %d = %+v, want %+v", i, diags[i], w)
+		}
+	}
+	if diags[0].Fix != `did you mean "node"?` {
+		t.Fatalf("unknown type fix = %q", diags[0].Fix)
+	}
+	if diags[4].Message != `unknown relation verb "blokced_by"` || diags[4].Fix != `did you mean "blocked_by"?` {
+		t.Fatalf("unknown kind = %+v", diags[4])
+	}
+}
+
+func TestIngestEventsReportsPhysicalLine(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	events := "# header\n\n{\"type\":\"node\",\"id\":\"a\"}\n{\"type\":\"bogus\"}\n"
+	_, err = s.IngestEvents(ctx, strings.NewReader(events), DefaultBoardID)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Fatalf("err = %v, want line 4", err)
+	}
+}
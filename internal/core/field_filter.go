@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldFilter is one condition on a custom field, such as priority=high or
+// points>=3. An empty Value with = matches nodes where the field is unset, and
+// with != nodes where it is set.
+type FieldFilter struct {
+	Key   string `json:"key"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+func (f FieldFilter) String() string {
+	return f.Key + f.Op + f.Value
+}
+
+// fieldFilterOps is ordered so two-character operators win over their prefix.
+var fieldFilterOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// ParseFieldFilter reads key=value, key!=value, key<value, key<=value,
+// key>value or key>=value.
+func ParseFieldFilter(text string) (FieldFilter, error) {
+	at, op := -1, ""
+	for _, candidate := range fieldFilterOps {
+		if i := strings.Index(text, candidate); i > 0 && (at < 0 || i < at || (i == at && len(candidate) > len(op))) {
+			at, op = i, candidate
+		}
+	}
+	if at < 0 {
+		return FieldFilter{}, fmt.Errorf("invalid filter %q: use key=value, key!=value, key<value or key>value", text)
+	}
+	f := FieldFilter{
+		Key:   strings.ToLower(strings.TrimSpace(text[:at])),
+		Op:    op,
+		Value: strings.TrimSpace(text[at+len(op):]),
+	}
+	if !fieldKeyRE.MatchString(f.Key) {
+		return FieldFilter{}, fmt.Errorf("invalid filter %q: bad field key %q", text, f.Key)
+	}
+	if f.Value == "" && op != "=" && op != "!=" {
+		return FieldFilter{}, fmt.Errorf("invalid filter %q: %s needs a value", text, op)
+	}
+	return f, nil
+}
+
+// MatchFieldFilters returns the IDs of snapshot nodes that satisfy every
+// filter. Filters on keys the board does not define are an error, so a typo
+// does not silently match nothing.
+func MatchFieldFilters(snap Snapshot, filters []FieldFilter) (map[string]bool, error) {
+	defs := make([]FieldDefinition, len(filters))
+	for i, f := range filters {
+		def, ok := fieldDefinition(snap.Fields, f.Key)
+		if !ok {
+			return nil, fmt.Errorf("filter %s: field %s is not defined on board %s", f, f.Key, snap.Board.ID)
+		}
+		defs[i] = def
+	}
+	out := map[string]bool{}
+	for _, n := range snap.Nodes {
+		match := true
+		for i, f := range filters {
+			ok, err := matchFieldFilter(defs[i], f, n.Fields[f.Key])
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			out[n.ID] = true
+		}
+	}
+	return out, nil
+}
+
+func matchFieldFilter(def FieldDefinition, f FieldFilter, value any) (bool, error) {
+	if f.Value == "" {
+		return (value == nil) == (f.Op == "="), nil
+	}
+	if value == nil {
+		return f.Op == "!=", nil
+	}
+	want, err := CoerceFieldValue(def, f.Value)
+	if err != nil {
+		return false, fmt.Errorf("filter %s: %w", f, err)
+	}
+	cmp := compareFieldValues(def, value, want)
+	switch f.Op {
+	case "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// compareFieldValues orders numbers numerically, enum values by their position
+// in the definition, estimates by duration, and everything else as
+// case-insensitive text; YYYY-MM-DD dates sort correctly as text.
+func compareFieldValues(def FieldDefinition, a, b any) int {
+	switch def.Type {
+	case FieldTypeNumber:
+		return compareFloats(fieldFloat(a), fieldFloat(b))
+	case FieldTypeEnum:
+		return fieldOptionIndex(def, fmt.Sprint(a)) - fieldOptionIndex(def, fmt.Sprint(b))
+	}
+	if def.Key == estimateField.Key {
+		x, okX := ParseEstimate(fmt.Sprint(a))
+		y, okY := ParseEstimate(fmt.Sprint(b))
+		if okX && okY {
+			return compareFloats(x, y)
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func fieldFloat(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+func fieldOptionIndex(def FieldDefinition, value string) int {
+	for i, opt := range def.Options {
+		if strings.EqualFold(opt, value) {
+			return i
+		}
+	}
+	return len(def.Options)
+}
@@ -0,0 +1,456 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldNamespace is the field_values namespace for fields DepViz itself reads,
+// such as estimates.
+const FieldNamespace = "depviz"
+
+// FieldValue is one custom value attached to an object, usually a node. The
+// value is stored as JSON so typed fields can round-trip without a schema
+// change. Values of board-defined fields, in FieldNamespace, are scoped to the
+// board that defines them, so two boards can type the same key differently;
+// values BoardID leaves empty belong to the object everywhere.
+type FieldValue struct {
+	ID        string    `json:"id"`
+	BoardID   string    `json:"board_id,omitempty"`
+	OwnerType string    `json:"owner_type"`
+	OwnerID   string    `json:"owner_id"`
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	ValueJSON string    `json:"value_json"`
+	Authority string    `json:"authority"`
+	SourceID  string    `json:"source_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetFieldValue inserts or replaces the value of one field. The row ID is
+// derived from board, owner, namespace and key, so each field has a single
+// value per board.
+func (s *Store) SetFieldValue(ctx context.Context, fv FieldValue) error {
+	fv.BoardID = strings.TrimSpace(fv.BoardID)
+	fv.OwnerType = strings.TrimSpace(fv.OwnerType)
+	fv.OwnerID = strings.TrimSpace(fv.OwnerID)
+	fv.Key = strings.TrimSpace(fv.Key)
+	if fv.OwnerType == "" || fv.OwnerID == "" || fv.Key == "" {
+		return errors.New("field owner and key are required")
+	}
+	if fv.Namespace == "" {
+		fv.Namespace = FieldNamespace
+	}
+	if fv.Authority == "" {
+		fv.Authority = "local"
+	}
+	if !json.Valid([]byte(fv.ValueJSON)) {
+		return errors.New("field value must be json")
+	}
+	if fv.UpdatedAt.IsZero() {
+		fv.UpdatedAt = nowUTC()
+	}
+	fv.ID = fieldValueID(fv.BoardID, fv.OwnerType, fv.OwnerID, fv.Namespace, fv.Key)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO field_values(id, board_id, owner_type, owner_id, namespace, key, value_json, authority, source_id, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			value_json=excluded.value_json,
+			authority=excluded.authority,
+			source_id=excluded.source_id,
+			updated_at=excluded.updated_at`,
+		fv.ID, fv.BoardID, fv.OwnerType, fv.OwnerID, fv.Namespace, fv.Key, fv.ValueJSON, fv.Authority, fv.SourceID, formatTime(fv.UpdatedAt))
+	if err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(fv)
+	return s.RecordEvent(ctx, "depviz.field_value.v1", fv.OwnerID, payload)
+}
+
+// fieldValueID keeps the IDs of unscoped values as they were before values
+// were scoped to boards.
+func fieldValueID(boardID, ownerType, ownerID, namespace, key string) string {
+	if boardID == "" {
+		return stableID("field", ownerType, ownerID, namespace, key)
+	}
+	return stableID("field", boardID, ownerType, ownerID, namespace, key)
+}
+
+// scopeFieldValues adds field_values.board_id and moves node values of
+// board-defined fields written before it onto every board the node is on.
+func (s *Store) scopeFieldValues(ctx context.Context) error {
+	_, _ = s.db.ExecContext(ctx, `ALTER TABLE field_values ADD COLUMN board_id TEXT NOT NULL DEFAULT ''`)
+	return s.WithTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT fv.id, bi.board_id, fv.owner_id, fv.key
+			FROM field_values fv
+			JOIN board_items bi ON bi.node_id = fv.owner_id
+			WHERE fv.board_id = '' AND fv.owner_type = 'node' AND fv.namespace = ?`, FieldNamespace)
+		if err != nil {
+			return err
+		}
+		var legacy []FieldValue
+		for rows.Next() {
+			var fv FieldValue
+			if err := rows.Scan(&fv.ID, &fv.BoardID, &fv.OwnerID, &fv.Key); err != nil {
+				rows.Close()
+				return err
+			}
+			legacy = append(legacy, fv)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, fv := range legacy {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO field_values(id, board_id, owner_type, owner_id, namespace, key, value_json, authority, source_id, updated_at)
+				SELECT ?, ?, owner_type, owner_id, namespace, key, value_json, authority, source_id, updated_at FROM field_values WHERE id = ?`,
+				fieldValueID(fv.BoardID, "node", fv.OwnerID, FieldNamespace, fv.Key), fv.BoardID, fv.ID); err != nil {
+				return err
+			}
+		}
+		for _, fv := range legacy {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM field_values WHERE id = ?`, fv.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FieldValuesByKey returns the values of one field on a board, or the
+// unscoped ones when boardID is empty, for every owner of a type, keyed by
+// owner ID.
+func (s *Store) FieldValuesByKey(ctx context.Context, boardID, ownerType, namespace, key string) (map[string]FieldValue, error) {
+	if namespace == "" {
+		namespace = FieldNamespace
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, board_id, owner_type, owner_id, namespace, key, value_json, authority, source_id, updated_at
+		FROM field_values
+		WHERE board_id = ? AND owner_type = ? AND namespace = ? AND key = ?`, boardID, ownerType, namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]FieldValue{}
+	for rows.Next() {
+		var fv FieldValue
+		var updated string
+		if err := rows.Scan(&fv.ID, &fv.BoardID, &fv.OwnerType, &fv.OwnerID, &fv.Namespace, &fv.Key, &fv.ValueJSON, &fv.Authority, &fv.SourceID, &updated); err != nil {
+			return nil, err
+		}
+		fv.UpdatedAt = parseTime(updated)
+		out[fv.OwnerID] = fv
+	}
+	return out, rows.Err()
+}
+
+// Field types supported by FieldDefinition.
+const (
+	FieldTypeEnum   = "enum"
+	FieldTypeNumber = "number"
+	FieldTypeDate   = "date"
+	FieldTypePerson = "person"
+	FieldTypeText   = "text"
+)
+
+var fieldKeyRE = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var fieldPersonRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// FieldDefinition declares a typed custom field on a board. Values live in
+// field_values against each node and the board, under FieldNamespace.
+type FieldDefinition struct {
+	BoardID   string    `json:"board_id"`
+	Key       string    `json:"key"`
+	Type      string    `json:"type"`
+	Label     string    `json:"label,omitempty"`
+	Options   []string  `json:"options,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// estimateField is the built-in definition behind `depviz estimate`; boards
+// can still redefine the key.
+var estimateField = FieldDefinition{Key: "estimate", Type: FieldTypeText, Label: "Estimate"}
+
+// DefineField creates or replaces a field definition. Enum fields need at
+// least one option. Existing values are kept even if they no longer validate.
+func (s *Store) DefineField(ctx context.Context, def FieldDefinition) (FieldDefinition, error) {
+	def.BoardID = strings.TrimSpace(def.BoardID)
+	if def.BoardID == "" {
+		def.BoardID = DefaultBoardID
+	}
+	def.Key = strings.ToLower(strings.TrimSpace(def.Key))
+	if !fieldKeyRE.MatchString(def.Key) {
+		return FieldDefinition{}, fmt.Errorf("invalid field key %q: use lowercase letters, digits and _", def.Key)
+	}
+	def.Type = strings.ToLower(strings.TrimSpace(def.Type))
+	if def.Type == "" {
+		def.Type = FieldTypeText
+	}
+	var options []string
+	seen := map[string]bool{}
+	for _, opt := range def.Options {
+		opt = strings.TrimSpace(opt)
+		if opt != "" && !seen[strings.ToLower(opt)] {
+			seen[strings.ToLower(opt)] = true
+			options = append(options, opt)
+		}
+	}
+	def.Options = options
+	switch def.Type {
+	case FieldTypeEnum:
+		if len(def.Options) == 0 {
+			return FieldDefinition{}, fmt.Errorf("enum field %s needs options", def.Key)
+		}
+	case FieldTypeNumber, FieldTypeDate, FieldTypePerson, FieldTypeText:
+		def.Options = nil
+	default:
+		return FieldDefinition{}, fmt.Errorf("unknown field type %q: use enum, number, date, person or text", def.Type)
+	}
+	def.Label = strings.TrimSpace(def.Label)
+	def.UpdatedAt = nowUTC()
+	optionsJSON, _ := json.Marshal(def.Options)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO field_definitions(board_id, key, type, label, options_json, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(board_id, key) DO UPDATE SET
+			type=excluded.type,
+			label=excluded.label,
+			options_json=excluded.options_json,
+			updated_at=excluded.updated_at`,
+		def.BoardID, def.Key, def.Type, def.Label, string(optionsJSON), formatTime(def.UpdatedAt))
+	if err != nil {
+		return FieldDefinition{}, err
+	}
+	payload, _ := json.Marshal(def)
+	return def, s.RecordEvent(ctx, "depviz.field_definition.v1", def.BoardID, payload)
+}
+
+// DeleteFieldDefinition removes a field from a board. Values stay in
+// field_values so redefining the field brings them back.
+func (s *Store) DeleteFieldDefinition(ctx context.Context, boardID, key string) error {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM field_definitions WHERE board_id = ? AND key = ?`, boardID, strings.ToLower(strings.TrimSpace(key)))
+	return err
+}
+
+// FieldDefinitions returns the fields defined on a board, by key.
+func (s *Store) FieldDefinitions(ctx context.Context, boardID string) ([]FieldDefinition, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT board_id, key, type, label, options_json, updated_at
+		FROM field_definitions
+		WHERE board_id = ?
+		ORDER BY key`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FieldDefinition
+	for rows.Next() {
+		var def FieldDefinition
+		var optionsJSON, updated string
+		if err := rows.Scan(&def.BoardID, &def.Key, &def.Type, &def.Label, &optionsJSON, &updated); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(optionsJSON), &def.Options)
+		def.UpdatedAt = parseTime(updated)
+		out = append(out, def)
+	}
+	return out, rows.Err()
+}
+
+// fieldDefinition looks a key up in a board's definitions, falling back to
+// the built-in estimate field.
+func fieldDefinition(defs []FieldDefinition, key string) (FieldDefinition, bool) {
+	for _, def := range defs {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	if key == estimateField.Key {
+		return estimateField, true
+	}
+	return FieldDefinition{}, false
+}
+
+// CoerceFieldValue validates raw text against a definition and returns the
+// typed value to store: a float64 for numbers, a YYYY-MM-DD string for dates,
+// a login without "@" for people and the canonical option for enums.
+func CoerceFieldValue(def FieldDefinition, raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("field %s: value is required", def.Key)
+	}
+	switch def.Type {
+	case FieldTypeNumber:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("field %s: %q is not a number", def.Key, raw)
+		}
+		return v, nil
+	case FieldTypeDate:
+		d, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %q is not a date, use YYYY-MM-DD", def.Key, raw)
+		}
+		return d.Format("2006-01-02"), nil
+	case FieldTypePerson:
+		login := strings.TrimPrefix(raw, "@")
+		if !fieldPersonRE.MatchString(login) {
+			return nil, fmt.Errorf("field %s: %q is not a login", def.Key, raw)
+		}
+		return login, nil
+	case FieldTypeEnum:
+		for _, opt := range def.Options {
+			if strings.EqualFold(opt, raw) {
+				return opt, nil
+			}
+		}
+		return nil, fmt.Errorf("field %s: %q is not one of %s", def.Key, raw, strings.Join(def.Options, ", "))
+	default:
+		if def.Key == estimateField.Key {
+			if _, ok := ParseEstimate(raw); !ok {
+				return nil, fmt.Errorf("field %s: %q is not an estimate, use 3d, 4h, 2w or XS..XXL", def.Key, raw)
+			}
+		}
+		return raw, nil
+	}
+}
+
+// SetNodeField validates a value against the board's definition of key and
+// stores it on the node. Keys the board does not define are refused, except
+// the built-in estimate.
+func (s *Store) SetNodeField(ctx context.Context, boardID, nodeID, key, raw, authority string) (FieldValue, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	nodeID = strings.TrimSpace(nodeID)
+	if nodeID == "" {
+		return FieldValue{}, errors.New("node is required")
+	}
+	if err := s.requireBoardNode(ctx, boardID, nodeID); err != nil {
+		return FieldValue{}, err
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	defs, err := s.FieldDefinitions(ctx, boardID)
+	if err != nil {
+		return FieldValue{}, err
+	}
+	def, ok := fieldDefinition(defs, key)
+	if !ok {
+		return FieldValue{}, fmt.Errorf("field %s is not defined on board %s", key, boardID)
+	}
+	value, err := CoerceFieldValue(def, raw)
+	if err != nil {
+		return FieldValue{}, err
+	}
+	valueJSON, _ := json.Marshal(value)
+	fv := FieldValue{BoardID: boardID, OwnerType: "node", OwnerID: nodeID, Namespace: FieldNamespace, Key: key, ValueJSON: string(valueJSON), Authority: authority}
+	if err := s.SetFieldValue(ctx, fv); err != nil {
+		return FieldValue{}, err
+	}
+	fv.ID = fieldValueID(fv.BoardID, fv.OwnerType, fv.OwnerID, fv.Namespace, fv.Key)
+	if fv.Authority == "" {
+		fv.Authority = "local"
+	}
+	return fv, nil
+}
+
+// ClearNodeField removes one field value from a node on a board.
+func (s *Store) ClearNodeField(ctx context.Context, boardID, nodeID, key string) error {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	nodeID = strings.TrimSpace(nodeID)
+	if err := s.requireBoardNode(ctx, boardID, nodeID); err != nil {
+		return err
+	}
+	return s.clearNodeField(ctx, boardID, nodeID, key)
+}
+
+// clearNodeField is ClearNodeField for syncs, which only clear fields of the
+// items they just put on the board.
+func (s *Store) clearNodeField(ctx context.Context, boardID, nodeID, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM field_values WHERE id = ?`,
+		fieldValueID(boardID, "node", strings.TrimSpace(nodeID), FieldNamespace, strings.ToLower(strings.TrimSpace(key))))
+	return err
+}
+
+// requireBoardNode refuses nodes that are not items of a board, so a mistyped
+// node ID does not leave values nothing shows.
+func (s *Store) requireBoardNode(ctx context.Context, boardID, nodeID string) error {
+	ok, err := s.boardItemExists(ctx, boardID, nodeID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no such node %s on board %s", nodeID, boardID)
+	}
+	return nil
+}
+
+// NodeFields returns every DepViz field value a board stores on a node, by
+// key.
+func (s *Store) NodeFields(ctx context.Context, boardID, nodeID string) ([]FieldValue, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, board_id, owner_type, owner_id, namespace, key, value_json, authority, source_id, updated_at
+		FROM field_values
+		WHERE board_id = ? AND owner_type = 'node' AND owner_id = ? AND namespace = ?
+		ORDER BY key`, boardID, nodeID, FieldNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FieldValue
+	for rows.Next() {
+		var fv FieldValue
+		var updated string
+		if err := rows.Scan(&fv.ID, &fv.BoardID, &fv.OwnerType, &fv.OwnerID, &fv.Namespace, &fv.Key, &fv.ValueJSON, &fv.Authority, &fv.SourceID, &updated); err != nil {
+			return nil, err
+		}
+		fv.UpdatedAt = parseTime(updated)
+		out = append(out, fv)
+	}
+	return out, rows.Err()
+}
+
+// boardFieldValues returns the decoded field values of every node on a board,
+// keyed by node ID and then field key.
+func (s *Store) boardFieldValues(ctx context.Context, boardID string) (map[string]map[string]any, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT fv.owner_id, fv.key, fv.value_json
+		FROM field_values fv
+		JOIN board_items bi ON bi.node_id = fv.owner_id AND bi.board_id = fv.board_id
+		WHERE bi.board_id = ? AND fv.owner_type = 'node' AND fv.namespace = ?`, boardID, FieldNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]map[string]any{}
+	for rows.Next() {
+		var nodeID, key, valueJSON string
+		if err := rows.Scan(&nodeID, &key, &valueJSON); err != nil {
+			return nil, err
+		}
+		var value any
+		if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+			continue
+		}
+		if out[nodeID] == nil {
+			out[nodeID] = map[string]any{}
+		}
+		out[nodeID][key] = value
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNodeFieldsAreTypedAndFilterable(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, id := range []string{"task:a", "task:b", "task:c"} {
+		if _, err := s.AddEdge(ctx, DefaultBoardID, id, "task:root", "blocked_by", "local", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{Key: "priority", Type: "enum", Options: []string{"P0", "P1", "P2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{Key: "points", Type: "number"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{Key: "due", Type: "date"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{Key: "bad", Type: "enum"}); err == nil {
+		t.Fatal("enum without options was accepted")
+	}
+
+	sets := []struct{ node, key, value string }{
+		{"task:a", "priority", "p0"},
+		{"task:a", "points", "5"},
+		{"task:b", "priority", "P2"},
+		{"task:b", "points", "2"},
+		{"task:b", "due", "2026-03-01"},
+		{"task:c", "estimate", "3d"},
+	}
+	for _, set := range sets {
+		if _, err := s.SetNodeField(ctx, DefaultBoardID, set.node, set.key, set.value, ""); err != nil {
+			t.Fatalf("SetNodeField(%s, %s) error = %v", set.node, set.key, err)
+		}
+	}
+	for _, bad := range []struct{ key, value, want string }{
+		{"priority", "P9", "not one of P0, P1, P2"},
+		{"points", "lots", "not a number"},
+		{"due", "March", "YYYY-MM-DD"},
+		{"estimate", "soon", "not an estimate"},
+		{"owner_team", "infra", "not defined"},
+	} {
+		_, err := s.SetNodeField(ctx, DefaultBoardID, "task:a", bad.key, bad.value, "")
+		if err == nil || !strings.Contains(err.Error(), bad.want) {
+			t.Fatalf("SetNodeField(%s=%s) error = %v, want %q", bad.key, bad.value, err, bad.want)
+		}
+	}
+	if _, err := s.SetNodeField(ctx, DefaultBoardID, "task:typo", "points", "3", ""); err == nil || !strings.Contains(err.Error(), "no such node task:typo") {
+		t.Fatalf("SetNodeField on a missing node error = %v", err)
+	}
+	if err := s.ClearNodeField(ctx, DefaultBoardID, "task:typo", "points"); err == nil || !strings.Contains(err.Error(), "no such node task:typo") {
+		t.Fatalf("ClearNodeField on a missing node error = %v", err)
+	}
+
+	values, err := s.NodeFields(ctx, DefaultBoardID, "task:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0].Key != "points" || values[0].ValueJSON != "5" || values[1].ValueJSON != `"P0"` || values[1].Authority != "local" {
+		t.Fatalf("NodeFields = %+v", values)
+	}
+
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Fields) != 3 {
+		t.Fatalf("snapshot fields = %+v, want 3 definitions", snap.Fields)
+	}
+	for _, tc := range []struct {
+		filters []string
+		want    string
+	}{
+		{[]string{"priority=P0"}, "task:a"},
+		{[]string{"priority<=p1"}, "task:a"},
+		{[]string{"points>=2", "priority!=P0"}, "task:b"},
+		{[]string{"due<2026-06-01"}, "task:b"},
+		{[]string{"estimate>1d"}, "task:c"},
+		{[]string{"points=", "estimate="}, "task:root"},
+	} {
+		var filters []FieldFilter
+		for _, text := range tc.filters {
+			f, err := ParseFieldFilter(text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filters = append(filters, f)
+		}
+		match, err := MatchFieldFilters(snap, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(match) != 1 || !match[tc.want] {
+			t.Fatalf("filters %v matched %v, want %s", tc.filters, match, tc.want)
+		}
+	}
+	if _, err := MatchFieldFilters(snap, []FieldFilter{{Key: "team", Op: "=", Value: "infra"}}); err == nil {
+		t.Fatal("filter on an undefined field was accepted")
+	}
+}
+
+func TestNodeFieldValuesAreScopedToBoards(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/state.db"
+	s, err := OpenStore(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { s.Close() }()
+	other, err := s.CreateBoard(ctx, "Other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "task:a", "task:b", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddNodeToBoard(ctx, other.ID, "task:a", "card", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{Key: "priority", Type: "enum", Options: []string{"P0", "P1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DefineField(ctx, FieldDefinition{BoardID: other.ID, Key: "priority", Type: "number"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetNodeField(ctx, DefaultBoardID, "task:a", "priority", "p1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetNodeField(ctx, other.ID, "task:a", "priority", "7", ""); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, board := range []string{DefaultBoardID, other.ID} {
+		got, err := s.NodeFields(ctx, board, "task:a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%s fields = %+v", board, got)
+		}
+		values[board] = got[0].ValueJSON
+	}
+	if values[DefaultBoardID] != `"P1"` || values[other.ID] != "7" {
+		t.Fatalf("values = %v", values)
+	}
+	if err := s.ClearNodeField(ctx, other.ID, "task:a", "priority"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.NodeFields(ctx, DefaultBoardID, "task:a"); len(got) != 1 {
+		t.Fatalf("clearing on %s cleared default: %+v", other.ID, got)
+	}
+
+	// Values written before the scoping move onto each board of their node.
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO field_values(id, owner_type, owner_id, namespace, key, value_json, updated_at)
+		VALUES('field:legacy', 'node', 'task:a', ?, 'estimate', '"2d"', ?)`, FieldNamespace, formatTime(nowUTC())); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if s, err = OpenStore(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, board := range []string{DefaultBoardID, other.ID} {
+		estimates, err := s.FieldValuesByKey(ctx, board, "node", FieldNamespace, "estimate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimates["task:a"].ValueJSON != `"2d"` {
+			t.Fatalf("%s estimates = %+v", board, estimates)
+		}
+	}
+	if legacy, _ := s.FieldValuesByKey(ctx, "", "node", FieldNamespace, "estimate"); len(legacy) != 0 {
+		t.Fatalf("legacy values left: %+v", legacy)
+	}
+}
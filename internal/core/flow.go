@@ -0,0 +1,810 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// FlowDocument is a parsed DepViz Flow source: the nodes it declares or
+// references and the relations between them, with canonical node IDs.
+type FlowDocument struct {
+	BoardName   string            `json:"board_name,omitempty"`
+	DefaultRepo string            `json:"default_repo,omitempty"`
+	Aliases     map[string]string `json:"aliases,omitempty"`
+	Nodes       []FlowNode        `json:"nodes"`
+	Edges       []FlowEdge        `json:"edges"`
+}
+
+// FlowNode is a node mentioned in Flow. Declared nodes carry the title, state,
+// owner and labels written next to them; referenced-only nodes carry just the ID
+// so synced sources keep owning their external state.
+type FlowNode struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind"`
+	Title       string   `json:"title,omitempty"`
+	State       string   `json:"state,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	TimeHorizon string   `json:"time_horizon,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Repo        string   `json:"repo,omitempty"`
+	Declared    bool     `json:"declared"`
+	Line        int      `json:"line"`
+}
+
+// FlowEdge is one relation produced by a Flow statement.
+type FlowEdge struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Kind       string  `json:"kind"`
+	Authority  string  `json:"authority"`
+	Confidence float64 `json:"confidence"`
+	Relation   string  `json:"relation"`
+	Text       string  `json:"text"`
+	Line       int     `json:"line"`
+}
+
+var (
+	flowLocalKindRE     = regexp.MustCompile(`(?i)^(note|task|strategy|initiative|bet|project|workstream|risk|decision|question|metric)(?:\s+|$)`)
+	flowLocalDeclRE     = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_.:-]*)(?:\s+(.*))?$`)
+	flowLocalIDRE       = regexp.MustCompile(`^(note|task|strategy|initiative|bet|project|workstream|risk|decision|question|metric):[A-Za-z0-9_.:-]+$`)
+	flowBareWordRE      = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.:-]*$`)
+	flowRepoRE          = regexp.MustCompile(`(?i)^repo\s+([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+|gl:(?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+|gitea:[A-Za-z0-9.:-]+/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)(?:\s+as\s+([A-Za-z][A-Za-z0-9_.-]*))?\s*$`)
+	flowCanonicalRefRE  = regexp.MustCompile(`^gh:([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)$`)
+	flowGitLabRefRE     = regexp.MustCompile(`^(gl:(?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+)([#!])([0-9]+)$`)
+	flowGiteaRefRE      = regexp.MustCompile(`^(gitea:[A-Za-z0-9.:-]+/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)$`)
+	flowTrackerRefRE    = regexp.MustCompile(`^(jira|linear):([A-Z][A-Z0-9_]*)-[0-9]+$`)
+	flowRepoRefRE       = regexp.MustCompile(`^([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)$`)
+	flowAliasRefRE      = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_.-]*)([#!])([0-9]+)$`)
+	flowShortRefRE      = regexp.MustCompile(`^([#!])([0-9]+)$`)
+	flowArrowRE         = regexp.MustCompile(`^(\S+)\s+(->|<-|~>)\s+(\S+)(?:\s+(.*))?$`)
+	flowRelationVerbRE  = regexp.MustCompile(`(?i)(?:^|\s)(depends\s+on|depends|blocks|addresses|mentions|relates\s+to|relates|closes|fixes|resolves)(?:\s|$)`)
+	flowQuotedRE        = regexp.MustCompile(`"((?:\\.|[^"\\])*)"`)
+	flowStateRE         = regexp.MustCompile(`\[([A-Za-z0-9_.:-]+)\]`)
+	flowLabelRE         = regexp.MustCompile(`@([A-Za-z0-9_.:-]+)`)
+	flowOwnerRE         = regexp.MustCompile(`(?:^|\s)\+([A-Za-z0-9_.-]+)`)
+	flowListSeparatorRE = regexp.MustCompile(`(?i),|(?:^|\s)and(?:\s|$)`)
+	flowVerbWordRE      = regexp.MustCompile(`^[A-Za-z]+$`)
+	markdownFenceOpenRE = regexp.MustCompile("^\\s*(```+|~~~+)\\s*depviz\\b")
+)
+
+// ExtractFlowFences returns the content of the ```depviz fences in a Markdown
+// document. Lines outside the fences are blanked rather than dropped so parse
+// errors keep pointing at the original line numbers. Text without any depviz
+// fence is returned unchanged and treated as plain Flow.
+func ExtractFlowFences(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, len(lines))
+	found := false
+	fence := ""
+	for i, line := range lines {
+		if fence == "" {
+			if m := markdownFenceOpenRE.FindStringSubmatch(line); m != nil {
+				fence = m[1]
+				found = true
+			}
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			fence = ""
+			continue
+		}
+		out[i] = line
+	}
+	if !found {
+		return text
+	}
+	return strings.Join(out, "\n")
+}
+
+// ParseFlow parses DepViz Flow text. Repo directives and local node
+// declarations are collected first, so aliases and slugs can be used before the
+// line that declares them. When the text has errors, the returned error is a
+// Diagnostics value listing every one of them.
+func ParseFlow(text string) (FlowDocument, error) {
+	doc, diags := parseFlow(text)
+	if errs := diags.Errors(); len(errs) > 0 {
+		return FlowDocument{}, errs
+	}
+	return doc, nil
+}
+
+// CheckFlow parses Flow text, or the depviz fences of a Markdown document, and
+// returns every error and warning without writing anything.
+func CheckFlow(text string) Diagnostics {
+	_, diags := parseFlow(ExtractFlowFences(text))
+	return diags
+}
+
+func parseFlow(text string) (FlowDocument, Diagnostics) {
+	p := &flowParser{
+		doc:      FlowDocument{Aliases: map[string]string{}},
+		locals:   map[string]string{},
+		declared: map[string]int{},
+		nodes:    map[string]int{},
+		warned:   map[string]bool{},
+	}
+	var lines []flowLine
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		stripped := stripFlowComment(raw)
+		trimmed := strings.TrimSpace(stripped)
+		lines = append(lines, flowLine{text: trimmed, no: i + 1, indent: len(stripped) - len(strings.TrimLeft(stripped, " \t"))})
+	}
+	for _, l := range lines {
+		p.declare(l)
+	}
+	for _, l := range lines {
+		p.statement(l)
+	}
+	if len(p.doc.Aliases) == 0 {
+		p.doc.Aliases = nil
+	}
+	p.diags.sort()
+	return p.doc, p.diags
+}
+
+type flowParser struct {
+	doc      FlowDocument
+	locals   map[string]string
+	declared map[string]int
+	nodes    map[string]int
+	warned   map[string]bool
+	diags    Diagnostics
+}
+
+// flowLine is one comment-stripped, trimmed Flow line. indent is the byte
+// offset of text in the original line, so offsets into text map back to
+// columns in the file.
+type flowLine struct {
+	text   string
+	no     int
+	indent int
+}
+
+func (l flowLine) column(offset int) int {
+	return l.indent + offset + 1
+}
+
+type flowRef struct {
+	id     string
+	kind   string
+	repo   string
+	marker string
+	number string
+}
+
+func (r flowRef) local() bool {
+	return r.repo == ""
+}
+
+func (p *flowParser) report(l flowLine, offset int, severity, fix, format string, args ...any) {
+	p.diags = append(p.diags, Diagnostic{Line: l.no, Column: l.column(offset), Severity: severity, Message: fmt.Sprintf(format, args...), Fix: fix})
+}
+
+func (p *flowParser) declare(l flowLine) {
+	line := l.text
+	switch {
+	case line == "":
+	case flowKeyword(line, "repo"):
+		m := flowRepoRE.FindStringSubmatch(line)
+		if m == nil {
+			p.report(l, 0, SeverityError, "write repo owner/name, repo gl:group/project or repo gitea:host/owner/name, optionally followed by as alias", "expected repo owner/name [as alias]")
+			return
+		}
+		if p.doc.DefaultRepo == "" {
+			p.doc.DefaultRepo = m[1]
+		}
+		if m[2] != "" {
+			if prev, ok := p.doc.Aliases[m[2]]; ok && prev != m[1] {
+				p.report(l, strings.LastIndex(line, m[2]), SeverityError, "pick a different alias", "alias %s already points at %s", m[2], prev)
+				return
+			}
+			p.doc.Aliases[m[2]] = m[1]
+		}
+	case flowLocalKindRE.MatchString(line):
+		kind, slugID, _, ok := splitFlowLocalDecl(line)
+		if !ok {
+			return
+		}
+		if first, ok := p.declared[slugID]; ok {
+			p.report(l, strings.Index(line, slugID), SeverityWarning, "merge both definitions; the later one wins", "duplicate declaration of %s, first declared on line %d", slugID, first)
+		}
+		p.declared[slugID] = l.no
+		p.locals[slugID] = kind + ":" + slugID
+	}
+}
+
+func (p *flowParser) statement(l flowLine) {
+	line := l.text
+	switch {
+	case line == "", flowKeyword(line, "depviz"), flowKeyword(line, "repo"):
+		return
+	case flowKeyword(line, "board"):
+		p.board(l)
+		return
+	case flowLocalKindRE.MatchString(line):
+		p.localNode(l)
+		return
+	}
+	if p.relation(l) || p.arrow(l) || p.externalNode(l) || p.unknownVerb(l) {
+		return
+	}
+	p.report(l, 0, SeverityError, "write <ref> <verb> <refs>, <ref> -> <ref>, or a node definition", "unsupported DepViz Flow statement")
+}
+
+func (p *flowParser) board(l flowLine) {
+	rest := strings.TrimSpace(l.text[len("board"):])
+	title, ok := p.quoted(l, rest, len(l.text)-len(rest))
+	if !ok {
+		return
+	}
+	if title == "" {
+		title = rest
+	}
+	if title == "" {
+		p.report(l, 0, SeverityError, `write board "Name"`, "board needs a title")
+		return
+	}
+	p.doc.BoardName = title
+}
+
+func (p *flowParser) localNode(l flowLine) {
+	line := l.text
+	kind, slugID, tail, ok := splitFlowLocalDecl(line)
+	if !ok {
+		p.report(l, len(kind), SeverityError, fmt.Sprintf(`write %s slug "Title"`, kind), "expected %s slug \"title\"", kind)
+		return
+	}
+	title, ok := p.quoted(l, tail, len(line)-len(tail))
+	if !ok {
+		return
+	}
+	if title == "" {
+		title = slugID
+	}
+	attrs := stripFlowQuoted(tail)
+	state := readFlowState(attrs)
+	if kind == "note" {
+		state = "local"
+	} else if state == "" {
+		state = "open"
+	}
+	p.upsertNode(FlowNode{
+		ID:          kind + ":" + slugID,
+		Kind:        kind,
+		Title:       title,
+		State:       state,
+		Owner:       readFlowOwner(attrs),
+		Labels:      readFlowLabels(attrs),
+		TimeHorizon: readFlowAttribute(attrs, "horizon"),
+		Priority:    readFlowAttribute(attrs, "priority"),
+		Declared:    true,
+		Line:        l.no,
+	})
+}
+
+func (p *flowParser) externalNode(l flowLine) bool {
+	token, tail, _ := strings.Cut(l.text, " ")
+	ref, msg, _ := p.lookup(token)
+	if msg != "" || ref.local() {
+		return false
+	}
+	title, ok := p.quoted(l, tail, len(l.text)-len(tail))
+	if !ok {
+		return true
+	}
+	attrs := stripFlowQuoted(tail)
+	p.upsertNode(FlowNode{
+		ID:       ref.id,
+		Kind:     ref.kind,
+		Title:    title,
+		State:    readFlowState(attrs),
+		Owner:    readFlowOwner(attrs),
+		Labels:   readFlowLabels(attrs),
+		Repo:     ref.repo,
+		Declared: true,
+		Line:     l.no,
+	})
+	return true
+}
+
+func (p *flowParser) arrow(l flowLine) bool {
+	m := flowArrowRE.FindStringSubmatchIndex(l.text)
+	if m == nil {
+		return false
+	}
+	left, ok := p.resolve(l, l.text[m[2]:m[3]], m[2])
+	if !ok {
+		return true
+	}
+	right, ok := p.resolve(l, l.text[m[6]:m[7]], m[6])
+	if !ok {
+		return true
+	}
+	p.reference(left, l.no)
+	p.reference(right, l.no)
+	arrow := l.text[m[4]:m[5]]
+	edge := FlowEdge{From: left.id, To: right.id, Kind: "blocks", Authority: "flow", Confidence: 1, Relation: arrow, Text: l.text, Line: l.no}
+	switch arrow {
+	case "<-":
+		edge.Kind = "blocked_by"
+	case "~>":
+		edge.Authority = "flow-soft"
+		edge.Confidence = 0.5
+	}
+	p.doc.Edges = append(p.doc.Edges, edge)
+	return true
+}
+
+func (p *flowParser) relation(l flowLine) bool {
+	line := l.text
+	subjectToken, after, ok := strings.Cut(line, " ")
+	if !ok {
+		return false
+	}
+	rest := strings.TrimSpace(after)
+	restOff := len(line) - len(rest)
+	matches := flowRelationVerbRE.FindAllStringSubmatchIndex(rest, -1)
+	if len(matches) == 0 || matches[0][0] != 0 {
+		return false
+	}
+	subject, ok := p.resolve(l, subjectToken, 0)
+	if !ok {
+		return true
+	}
+	p.reference(subject, l.no)
+	for i, match := range matches {
+		end := len(rest)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		verb := normalizeFlowVerb(rest[match[2]:match[3]])
+		targets, ok := p.resolveList(l, rest[match[1]:end], restOff+match[1])
+		if !ok {
+			return true
+		}
+		if len(targets) == 0 {
+			p.report(l, restOff+match[1], SeverityError, "list the refs after the verb", "relation %q needs at least one ref", rest[match[2]:match[3]])
+			return true
+		}
+		for _, target := range targets {
+			p.reference(target, l.no)
+			p.doc.Edges = append(p.doc.Edges, FlowEdge{
+				From:       subject.id,
+				To:         target.id,
+				Kind:       flowVerbEdgeKind(verb),
+				Authority:  "flow",
+				Confidence: 1,
+				Relation:   verb,
+				Text:       line,
+				Line:       l.no,
+			})
+		}
+	}
+	return true
+}
+
+// unknownVerb recognizes "<ref> <words> <ref>" lines whose middle words are not
+// a relation verb, so the diagnostic can name the verb instead of rejecting the
+// whole statement.
+func (p *flowParser) unknownVerb(l flowLine) bool {
+	fields := strings.Fields(l.text)
+	if len(fields) < 3 || len(fields) > 8 {
+		return false
+	}
+	if _, msg, _ := p.lookup(fields[0]); msg != "" {
+		return false
+	}
+	verbEnd := 0
+	for i := 2; i < len(fields); i++ {
+		token := strings.TrimRight(fields[i], ",.;")
+		if ref, msg, _ := p.lookup(token); msg == "" && (!flowBareWordRE.MatchString(token) || p.locals[token] != "" || ref.id == token) {
+			verbEnd = i
+			break
+		}
+	}
+	if verbEnd == 0 {
+		verbEnd = len(fields) - 1
+	}
+	words := fields[1:verbEnd]
+	if len(words) > 3 {
+		return false
+	}
+	for _, w := range words {
+		if !flowVerbWordRE.MatchString(w) {
+			return false
+		}
+	}
+	verb := strings.Join(words, " ")
+	fix := "use depends on, blocks, addresses, mentions, relates to or closes"
+	if guess := suggestFlowVerb(verb); guess != "" {
+		fix = fmt.Sprintf("did you mean %q?", guess)
+	}
+	p.report(l, len(fields[0])+strings.Index(l.text[len(fields[0]):], words[0]), SeverityError, fix, "unknown relation verb %q", verb)
+	return true
+}
+
+func (p *flowParser) resolveList(l flowLine, text string, offset int) ([]flowRef, bool) {
+	var refs []flowRef
+	start := 0
+	bounds := append(flowListSeparatorRE.FindAllStringIndex(text, -1), []int{len(text), len(text)})
+	for _, b := range bounds {
+		part := text[start:b[0]]
+		partOff := start
+		start = b[1]
+		token := strings.TrimRight(strings.TrimSpace(part), ".;")
+		if token == "" {
+			continue
+		}
+		ref, ok := p.resolve(l, token, offset+partOff+strings.Index(part, token))
+		if !ok {
+			return nil, false
+		}
+		refs = append(refs, ref)
+	}
+	return refs, true
+}
+
+// resolve turns a ref token into a canonical node ID, reporting a diagnostic at
+// offset when it cannot.
+func (p *flowParser) resolve(l flowLine, token string, offset int) (flowRef, bool) {
+	ref, msg, fix := p.lookup(token)
+	if msg != "" {
+		p.report(l, offset, SeverityError, fix, "%s", msg)
+		return flowRef{}, false
+	}
+	if ref.kind == "task" && strings.TrimPrefix(ref.id, "task:") == token && p.locals[token] == "" && !p.warned[token] {
+		p.warned[token] = true
+		p.report(l, offset, SeverityWarning, fmt.Sprintf(`declare it with task %s "Title", or write the full kind:slug id`, token), "%s is not declared; it becomes task:%s", token, token)
+	}
+	return ref, true
+}
+
+func (p *flowParser) lookup(token string) (ref flowRef, msg, fix string) {
+	if flowLocalIDRE.MatchString(token) {
+		kind, _, _ := strings.Cut(token, ":")
+		return flowRef{id: token, kind: kind}, "", ""
+	}
+	if id, ok := p.locals[token]; ok {
+		kind, _, _ := strings.Cut(id, ":")
+		return flowRef{id: id, kind: kind}, "", ""
+	}
+	if m := flowTrackerRefRE.FindStringSubmatch(token); m != nil {
+		return flowRef{id: token, kind: "issue", repo: m[1] + ":" + m[2]}, "", ""
+	}
+	if flowBareWordRE.MatchString(token) {
+		if _, ok := p.doc.Aliases[token]; !ok {
+			return flowRef{id: "task:" + token, kind: "task"}, "", ""
+		}
+	}
+	if m := flowExternalRef(token); m != nil {
+		return newFlowGitHubRef(m[1], m[2], m[3]), "", ""
+	}
+	if m := flowRepoRefRE.FindStringSubmatch(token); m != nil {
+		return newFlowGitHubRef(m[1], m[2], m[3]), "", ""
+	}
+	if m := flowAliasRefRE.FindStringSubmatch(token); m != nil {
+		if repo, ok := p.doc.Aliases[m[1]]; ok {
+			return newFlowGitHubRef(repo, m[2], m[3]), "", ""
+		}
+		return flowRef{}, fmt.Sprintf("unknown repo alias %s in %s", m[1], token), fmt.Sprintf("add repo owner/name as %s", m[1])
+	}
+	if m := flowShortRefRE.FindStringSubmatch(token); m != nil {
+		if p.doc.DefaultRepo == "" {
+			return flowRef{}, fmt.Sprintf("%s needs a default repo", token), "add a repo owner/name line, or write owner/repo" + token
+		}
+		return newFlowGitHubRef(p.doc.DefaultRepo, m[1], m[2]), "", ""
+	}
+	return flowRef{}, fmt.Sprintf("cannot resolve ref %s", token), "use #N, !N, alias#N, owner/repo#N, gh:owner/repo#N, gl:group/project#N, gitea:host/owner/repo#N, jira:KEY-N, linear:KEY-N or a declared slug"
+}
+
+// flowExternalRef matches a canonical gh:, gl: or gitea: node ID into its repo,
+// marker and number. GitLab and Gitea repos keep their prefix, which is how
+// Flow tells the forges apart in repo directives and aliases.
+func flowExternalRef(id string) []string {
+	if m := flowCanonicalRefRE.FindStringSubmatch(id); m != nil {
+		return m
+	}
+	if m := flowGitLabRefRE.FindStringSubmatch(id); m != nil {
+		return m
+	}
+	return flowGiteaRefRE.FindStringSubmatch(id)
+}
+
+// newFlowGitHubRef builds the ref of an issue or PR; repo is owner/name for
+// GitHub, gl:group/project for GitLab or gitea:host/owner/name for Gitea.
+func newFlowGitHubRef(repo, marker, number string) flowRef {
+	kind := "issue"
+	if marker == "!" {
+		kind = "pr"
+	}
+	id := "gh:" + repo + marker + number
+	if strings.HasPrefix(repo, "gl:") || strings.HasPrefix(repo, "gitea:") {
+		id = repo + marker + number
+	}
+	return flowRef{id: id, kind: kind, repo: repo, marker: marker, number: number}
+}
+
+// reference records a node that a relation points at without declaring it.
+func (p *flowParser) reference(ref flowRef, lineNo int) {
+	if _, ok := p.nodes[ref.id]; ok {
+		return
+	}
+	p.nodes[ref.id] = len(p.doc.Nodes)
+	p.doc.Nodes = append(p.doc.Nodes, FlowNode{ID: ref.id, Kind: ref.kind, Repo: ref.repo, Line: lineNo})
+}
+
+func (p *flowParser) upsertNode(n FlowNode) {
+	i, ok := p.nodes[n.ID]
+	if !ok {
+		p.nodes[n.ID] = len(p.doc.Nodes)
+		p.doc.Nodes = append(p.doc.Nodes, n)
+		return
+	}
+	p.doc.Nodes[i] = n
+}
+
+// quoted reads the first quoted string of text, which starts at offset in the
+// line.
+func (p *flowParser) quoted(l flowLine, text string, offset int) (string, bool) {
+	m := flowQuotedRE.FindStringSubmatchIndex(text)
+	if m == nil {
+		return "", true
+	}
+	var out string
+	if err := json.Unmarshal([]byte(`"`+text[m[2]:m[3]]+`"`), &out); err != nil {
+		p.report(l, offset+m[0], SeverityError, `escape quotes and backslashes as \" and \\`, "invalid quoted string")
+		return "", false
+	}
+	return out, true
+}
+
+// flowVerbSynonyms maps verbs people reach for to the Flow verb that means the
+// same thing.
+var flowVerbSynonyms = map[string]string{
+	"needs":      "depends on",
+	"requires":   "depends on",
+	"blocked by": "depends on",
+	"waits on":   "depends on",
+	"waits for":  "depends on",
+	"after":      "depends on",
+	"unblocks":   "blocks",
+	"precedes":   "blocks",
+	"before":     "blocks",
+	"refs":       "mentions",
+	"references": "mentions",
+	"see":        "mentions",
+	"related to": "relates to",
+	"implements": "addresses",
+}
+
+var flowVerbs = []string{"depends on", "depends", "blocks", "addresses", "mentions", "relates to", "relates", "closes", "fixes", "resolves"}
+
+func suggestFlowVerb(verb string) string {
+	verb = strings.ToLower(verb)
+	if s, ok := flowVerbSynonyms[verb]; ok {
+		return s
+	}
+	return closestWord(verb, flowVerbs)
+}
+
+func splitFlowLocalDecl(line string) (kind, slugID, tail string, ok bool) {
+	k := flowLocalKindRE.FindStringSubmatch(line)
+	if k == nil {
+		return "", "", "", false
+	}
+	kind = strings.ToLower(k[1])
+	m := flowLocalDeclRE.FindStringSubmatch(line[len(k[0]):])
+	if m == nil {
+		return kind, "", "", false
+	}
+	return kind, m[1], m[2], true
+}
+
+func flowKeyword(line, keyword string) bool {
+	if len(line) < len(keyword) || !strings.EqualFold(line[:len(keyword)], keyword) {
+		return false
+	}
+	return len(line) == len(keyword) || line[len(keyword)] == ' ' || line[len(keyword)] == '\t'
+}
+
+func normalizeFlowVerb(verb string) string {
+	verb = strings.Join(strings.Fields(strings.ToLower(verb)), " ")
+	switch verb {
+	case "depends", "depends on":
+		return "depends_on"
+	case "fixes", "resolves":
+		return "closes"
+	case "relates", "relates to":
+		return "relates_to"
+	default:
+		return verb
+	}
+}
+
+func flowVerbEdgeKind(verb string) string {
+	if verb == "depends_on" {
+		return "blocked_by"
+	}
+	return verb
+}
+
+// stripFlowComment drops "// ..." and "# ..." comments that sit outside quoted
+// strings. A "#" only starts a comment when followed by whitespace, so "#12"
+// stays a ref.
+func stripFlowComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"' && (i == 0 || line[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return line[:i]
+		case c == '#' && (i+1 == len(line) || isFlowSpace(line[i+1])) && (i == 0 || isFlowSpace(line[i-1])):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isFlowSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+func stripFlowQuoted(text string) string {
+	return flowQuotedRE.ReplaceAllString(text, " ")
+}
+
+func readFlowState(text string) string {
+	if m := flowStateRE.FindStringSubmatch(text); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
+
+func readFlowLabels(text string) []string {
+	var labels []string
+	for _, m := range flowLabelRE.FindAllStringSubmatch(text, -1) {
+		labels = append(labels, m[1])
+	}
+	return labels
+}
+
+func readFlowOwner(text string) string {
+	if m := flowOwnerRE.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func readFlowAttribute(text, key string) string {
+	re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(key) + `:([A-Za-z0-9_.-]+)`)
+	if m := re.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// IngestFlow parses Flow text, or the depviz fences of a Markdown document, and
+// writes its nodes and edges into a board. The whole input is parsed before
+// anything is written, and written in one transaction, so a syntax or write
+// error leaves the store untouched.
+func (s *Store) IngestFlow(ctx context.Context, r io.Reader, boardID string) (FlowDocument, error) {
+	if boardID == "" {
+		boardID = DefaultBoardID
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return FlowDocument{}, err
+	}
+	doc, err := ParseFlow(ExtractFlowFences(string(data)))
+	if err != nil {
+		return FlowDocument{}, err
+	}
+	err = s.WithTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		a := &boardSourcePatchApplier{ctx: ctx, tx: tx, boardID: boardID}
+		if err := a.ensureBoard(); err != nil {
+			return fmt.Errorf("board %s: %w", boardID, err)
+		}
+		for _, n := range doc.Nodes {
+			if err := a.applyFlowNode(n); err != nil {
+				return fmt.Errorf("line %d: %w", n.Line, err)
+			}
+		}
+		for _, e := range doc.Edges {
+			evidence := map[string]any{"source": "flow", "line": e.Text, "flow_line": e.Line, "relation": e.Relation}
+			if _, err := a.addEdge(e.From, e.To, e.Kind, e.Authority, e.Confidence, evidence); err != nil {
+				return fmt.Errorf("line %d: %w", e.Line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return FlowDocument{}, err
+	}
+	return doc, nil
+}
+
+// applyFlowNode writes one Flow node. Fields the Flow line leaves out keep the
+// stored value, so relation-only Flow never clobbers synced titles or states.
+func (a *boardSourcePatchApplier) applyFlowNode(fn FlowNode) error {
+	existing, err := a.nodeByID(fn.ID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	local := fn.Repo == ""
+	if !fn.Declared && (exists || !local) {
+		return a.ensureNodeInBoard(fn.ID)
+	}
+	n := existing
+	if !exists {
+		n = Node{ID: fn.ID, Kind: fn.Kind, Title: strings.TrimPrefix(fn.ID, fn.Kind+":"), State: "open"}
+		if fn.Kind == "note" {
+			n.State = "local"
+		}
+	}
+	var data map[string]any
+	_ = json.Unmarshal([]byte(n.DataJSON), &data)
+	if data == nil {
+		data = map[string]any{}
+	}
+	if _, ok := data["source"]; !ok {
+		data["source"] = "flow"
+	}
+	if local {
+		data["kind"] = fn.Kind
+	}
+	if fn.Title != "" {
+		n.Title = fn.Title
+		if local {
+			data["text"] = fn.Title
+		}
+	}
+	if fn.State != "" {
+		n.State = fn.State
+	}
+	if fn.Owner != "" {
+		n.Owner = fn.Owner
+		data["owner"] = fn.Owner
+	}
+	if len(fn.Labels) > 0 {
+		data["labels"] = fn.Labels
+	}
+	if fn.TimeHorizon != "" {
+		data["time_horizon"] = fn.TimeHorizon
+	}
+	if fn.Priority != "" {
+		data["priority"] = fn.Priority
+	}
+	if fn.Declared {
+		delete(data, "placeholder")
+	}
+	payload, _ := json.Marshal(data)
+	n.DataJSON = string(payload)
+	n.UpdatedAt = nowUTC()
+	sourceID, externalID, url := LocalSourceID, n.ID, ""
+	if !local {
+		if _, sourceID, externalID, url, err = a.placeholder(n.ID); err != nil {
+			return err
+		}
+	}
+	if err := a.upsertNode(n); err != nil {
+		return err
+	}
+	if err := a.upsertSourceRef(n.ID, sourceID, externalID, url); err != nil {
+		return err
+	}
+	if err := a.ensureNodeInBoard(n.ID); err != nil {
+		return err
+	}
+	evPayload, _ := json.Marshal(n)
+	return a.recordEvent("depviz.flow_node.v1", n.ID, evPayload)
+}
@@ -0,0 +1,312 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FlowRenderOptions tunes RenderFlow.
+type FlowRenderOptions struct {
+	// Repo is the default repo written as the first repo directive, so its refs
+	// render as #N and !N. When empty, the repo with the most nodes wins.
+	Repo string
+	// Inferred adds soft and inferred relations as comments. They stay out of
+	// the parsed graph, but a reviewer can promote one by uncommenting it; its
+	// authority and confidence follow in a comment of their own.
+	Inferred bool
+}
+
+var (
+	flowRenderSlugRE  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.:-]*$`)
+	flowRenderTokenRE = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+	flowRenderOwnerRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	flowAliasCleanRE  = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// flowEdgeVerbs maps stored edge kinds to the Flow verb that parses back to the
+// same relation. Kinds that only differ by name (depends_on, unblocks, ...) are
+// written with the verb of their canonical kind.
+var flowEdgeVerbs = map[string]string{
+	"blocked_by": "depends on",
+	"depends_on": "depends on",
+	"depends":    "depends on",
+	"after":      "depends on",
+	"blocks":     "blocks",
+	"unblocks":   "blocks",
+	"precedes":   "blocks",
+	"addresses":  "addresses",
+	"mentions":   "mentions",
+	"relates_to": "relates to",
+	"related_to": "relates to",
+	"closes":     "closes",
+}
+
+// RenderFlow writes a snapshot as DepViz Flow. GitHub and GitLab refs are
+// compressed to #N and !N under a repo directive, secondary repos get aliases,
+// and every local node is declared with its title, state, owner and labels.
+// Parsing the output with ParseFlow yields the same node IDs and hard edges as
+// the snapshot; soft and inferred relations are left out, or written as
+// comments with opts.Inferred. Values Flow has no syntax for, such as
+// multi-word labels, are dropped.
+func RenderFlow(snap Snapshot, opts FlowRenderOptions) string {
+	r := newFlowRenderer(snap, opts)
+	var sb strings.Builder
+	if snap.Board.Name != "" {
+		fmt.Fprintf(&sb, "board %s\n", flowQuote(snap.Board.Name))
+	}
+	for _, repo := range r.repos {
+		if alias := r.aliases[repo]; alias != "" {
+			fmt.Fprintf(&sb, "repo %s as %s\n", repo, alias)
+		} else {
+			fmt.Fprintf(&sb, "repo %s\n", repo)
+		}
+	}
+
+	linked := map[string]bool{}
+	for _, e := range snap.Edges {
+		linked[e.FromID] = true
+		linked[e.ToID] = true
+	}
+	var locals, externals, skipped []string
+	for _, n := range snap.Nodes {
+		ref, ok := r.refs[n.ID]
+		switch {
+		case !ok:
+			skipped = append(skipped, fmt.Sprintf("// %s has no Flow syntax", n.ID))
+		case flowExternalRef(n.ID) != nil || flowTrackerRefRE.MatchString(n.ID):
+			if n.IsPlaceholder() {
+				if !linked[n.ID] {
+					externals = append(externals, ref)
+				}
+				continue
+			}
+			externals = append(externals, ref+flowNodeAttrs(n, ""))
+		default:
+			kind, slugID, _ := strings.Cut(n.ID, ":")
+			if !flowRenderSlugRE.MatchString(slugID) {
+				skipped = append(skipped, fmt.Sprintf("// %s cannot be declared in Flow; relations still reference it", n.ID))
+				continue
+			}
+			locals = append(locals, kind+" "+slugID+flowNodeAttrs(n, slugID))
+		}
+	}
+	writeFlowBlock(&sb, locals)
+	writeFlowBlock(&sb, externals)
+	writeFlowBlock(&sb, skipped)
+
+	var relations, comments []string
+	type group struct {
+		subject, verb string
+		targets       []string
+	}
+	var groups []*group
+	index := map[string]*group{}
+	for _, e := range snap.Edges {
+		from, okFrom := r.refs[e.FromID]
+		to, okTo := r.refs[e.ToID]
+		verb, okVerb := flowEdgeVerbs[strings.ToLower(e.Kind)]
+		if !okFrom || !okTo {
+			comments = append(comments, fmt.Sprintf("// %s %s %s has no Flow syntax", e.FromID, e.Kind, e.ToID))
+			continue
+		}
+		if edgeIsSoft(e) {
+			if e.Authority == "flow-soft" && verb == "blocks" {
+				relations = append(relations, from+" ~> "+to)
+				continue
+			}
+			if opts.Inferred && okVerb {
+				comments = append(comments, fmt.Sprintf("// %s %s %s // %s, %.2f", from, verb, to, e.Authority, e.Confidence))
+			}
+			continue
+		}
+		if !okVerb {
+			comments = append(comments, fmt.Sprintf("// %s %s %s has no Flow verb", from, e.Kind, to))
+			continue
+		}
+		key := from + "\x00" + verb
+		g, ok := index[key]
+		if !ok {
+			g = &group{subject: from, verb: verb}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.targets = append(g.targets, to)
+	}
+	var grouped []string
+	for _, g := range groups {
+		grouped = append(grouped, g.subject+" "+g.verb+" "+strings.Join(g.targets, ", "))
+	}
+	writeFlowBlock(&sb, append(grouped, relations...))
+	writeFlowBlock(&sb, comments)
+	return sb.String()
+}
+
+type flowRenderer struct {
+	repos   []string
+	aliases map[string]string
+	refs    map[string]string
+}
+
+func newFlowRenderer(snap Snapshot, opts FlowRenderOptions) *flowRenderer {
+	r := &flowRenderer{aliases: map[string]string{}, refs: map[string]string{}}
+	counts := map[string]int{}
+	ids := map[string]bool{}
+	for _, n := range snap.Nodes {
+		ids[n.ID] = true
+	}
+	for _, e := range snap.Edges {
+		ids[e.FromID] = true
+		ids[e.ToID] = true
+	}
+	for id := range ids {
+		if m := flowExternalRef(id); m != nil {
+			counts[m[1]]++
+		}
+	}
+	for repo := range counts {
+		r.repos = append(r.repos, repo)
+	}
+	sort.Slice(r.repos, func(i, j int) bool {
+		a, b := r.repos[i], r.repos[j]
+		switch {
+		case a == opts.Repo || b == opts.Repo:
+			return a == opts.Repo
+		case counts[a] != counts[b]:
+			return counts[a] > counts[b]
+		default:
+			return a < b
+		}
+	})
+	if opts.Repo != "" && counts[opts.Repo] == 0 {
+		r.repos = append([]string{opts.Repo}, r.repos...)
+	}
+
+	// Local slugs are used bare, so aliases must not shadow them.
+	slugs := map[string]int{}
+	for id := range ids {
+		if flowLocalIDRE.MatchString(id) {
+			_, slugID, _ := strings.Cut(id, ":")
+			slugs[slugID]++
+		}
+	}
+	taken := map[string]bool{}
+	for i, repo := range r.repos {
+		if i == 0 {
+			continue
+		}
+		name := repo[strings.LastIndex(repo, "/")+1:]
+		base := strings.Trim(flowAliasCleanRE.ReplaceAllString(name, "-"), "-.")
+		if base == "" || !flowBareWordRE.MatchString(base) {
+			base = "repo"
+		}
+		alias := base
+		for n := 2; taken[alias] || slugs[alias] > 0 || flowReservedWord(alias); n++ {
+			alias = fmt.Sprintf("%s%d", base, n)
+		}
+		taken[alias] = true
+		r.aliases[repo] = alias
+	}
+
+	for id := range ids {
+		if m := flowExternalRef(id); m != nil {
+			switch {
+			case m[1] == r.repos[0]:
+				r.refs[id] = m[2] + m[3]
+			default:
+				r.refs[id] = r.aliases[m[1]] + m[2] + m[3]
+			}
+			continue
+		}
+		if flowTrackerRefRE.MatchString(id) {
+			r.refs[id] = id
+			continue
+		}
+		if !flowLocalIDRE.MatchString(id) {
+			continue
+		}
+		_, slugID, _ := strings.Cut(id, ":")
+		if slugs[slugID] == 1 && !taken[slugID] && flowRenderSlugRE.MatchString(slugID) && !flowLocalIDRE.MatchString(slugID) && !flowReservedWord(slugID) {
+			r.refs[id] = slugID
+		} else {
+			r.refs[id] = id
+		}
+	}
+	return r
+}
+
+// flowNodeAttrs renders the "title" [state] @label +owner horizon: priority:
+// suffix of a node definition. Local declarations (slug set) default to their
+// slug as title and to the open state, so those are left out; notes are
+// always local.
+func flowNodeAttrs(n Node, slugID string) string {
+	local := slugID != ""
+	var parts []string
+	if n.Title != "" && n.Title != slugID {
+		parts = append(parts, flowQuote(n.Title))
+	}
+	state := strings.ToLower(n.State)
+	if flowRenderTokenRE.MatchString(state) && !(local && (state == "open" || n.Kind == "note")) {
+		parts = append(parts, "["+state+"]")
+	}
+	for _, label := range n.Labels() {
+		if flowRenderTokenRE.MatchString(label) {
+			parts = append(parts, "@"+label)
+		}
+	}
+	if flowRenderOwnerRE.MatchString(n.Owner) {
+		parts = append(parts, "+"+n.Owner)
+	}
+	var data struct {
+		TimeHorizon string `json:"time_horizon"`
+		Priority    string `json:"priority"`
+	}
+	_ = json.Unmarshal([]byte(n.DataJSON), &data)
+	if flowRenderOwnerRE.MatchString(data.TimeHorizon) {
+		parts = append(parts, "horizon:"+data.TimeHorizon)
+	}
+	if flowRenderOwnerRE.MatchString(data.Priority) {
+		parts = append(parts, "priority:"+data.Priority)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// flowReservedWord reports words that would be read as a keyword, a node kind,
+// a relation verb or a list separator if a bare slug or alias used them.
+func flowReservedWord(word string) bool {
+	if flowLocalKindRE.MatchString(word) {
+		return true
+	}
+	switch strings.ToLower(word) {
+	case "repo", "board", "depviz", "as", "and", "on", "to", "depends", "blocks", "addresses", "mentions", "relates", "closes", "fixes", "resolves":
+		return true
+	}
+	return false
+}
+
+func flowQuote(text string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(text)
+	return strings.TrimSpace(buf.String())
+}
+
+func writeFlowBlock(sb *strings.Builder, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseFlowRelationsAndDefinitions(t *testing.T) {
+	doc, err := ParseFlow(`
+repo moul/depviz
+repo moul/depviz2 as d2
+
+#679 "Bootstrap depviz v4" [open] @v4 +moul // inline comment
+note flow "Design DepViz Flow"
+
+#679 depends on #80, d2#81 and blocks !85
+flow blocks release
+release -> #679
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.DefaultRepo != "moul/depviz" || doc.Aliases["d2"] != "moul/depviz2" {
+		t.Fatalf("repo directives = %q %+v", doc.DefaultRepo, doc.Aliases)
+	}
+	want := []FlowEdge{
+		{From: "gh:moul/depviz#679", To: "gh:moul/depviz#80", Kind: "blocked_by"},
+		{From: "gh:moul/depviz#679", To: "gh:moul/depviz2#81", Kind: "blocked_by"},
+		{From: "gh:moul/depviz#679", To: "gh:moul/depviz!85", Kind: "blocks"},
+		{From: "note:flow", To: "task:release", Kind: "blocks"},
+		{From: "task:release", To: "gh:moul/depviz#679", Kind: "blocks"},
+	}
+	if len(doc.Edges) != len(want) {
+		t.Fatalf("edges = %+v, want %+v", doc.Edges, want)
+	}
+	for i := range want {
+		if doc.Edges[i].From != want[i].From || doc.Edges[i].To != want[i].To || doc.Edges[i].Kind != want[i].Kind {
+			t.Fatalf("edge %d = %+v, want %+v", i, doc.Edges[i], want[i])
+		}
+	}
+	nodes := map[string]FlowNode{}
+	for _, n := range doc.Nodes {
+		nodes[n.ID] = n
+	}
+	issue := nodes["gh:moul/depviz#679"]
+	if !issue.Declared || issue.Title != "Bootstrap depviz v4" || issue.State != "open" || issue.Owner != "moul" || len(issue.Labels) != 1 || issue.Labels[0] != "v4" {
+		t.Fatalf("issue definition = %+v", issue)
+	}
+	if note := nodes["note:flow"]; !note.Declared || note.State != "local" || note.Title != "Design DepViz Flow" {
+		t.Fatalf("note definition = %+v", note)
+	}
+	if ref := nodes["gh:moul/depviz#80"]; ref.Declared || ref.Repo != "moul/depviz" {
+		t.Fatalf("referenced-only node = %+v", ref)
+	}
+}
+
+func TestFlowGitLabRefsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	doc, err := s.IngestFlow(ctx, strings.NewReader(`
+repo gl:acme/platform/api
+repo moul/depviz as dv
+
+!2 "Schema" [merged]
+#1 depends on !2, gl:acme/web#7
+dv#3 blocks #1
+`), DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gl:acme/platform/api#1 blocked_by gl:acme/platform/api!2",
+		"gl:acme/platform/api#1 blocked_by gl:acme/web#7",
+		"gh:moul/depviz#3 blocks gl:acme/platform/api#1",
+	}
+	var got []string
+	for _, e := range doc.Edges {
+		got = append(got, e.From+" "+e.Kind+" "+e.To)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("edges = %q, want %q", got, want)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := map[string]string{}
+	for _, n := range snap.Nodes {
+		urls[n.ID] = n.URL
+	}
+	if got := urls["gl:acme/web#7"]; got != "https://gitlab.com/acme/web/-/issues/7" {
+		t.Fatalf("placeholder URL = %q", got)
+	}
+	text := RenderFlow(snap, FlowRenderOptions{Repo: "gl:acme/platform/api"})
+	for _, line := range []string{"repo gl:acme/platform/api\n", "repo gl:acme/web as web\n", "!2 \"Schema\" [merged]\n", "web#7", "#3 blocks #1\n"} {
+		if !strings.Contains(text, line) {
+			t.Fatalf("flow missing %q:\n%s", line, text)
+		}
+	}
+	again, err := ParseFlow(text)
+	if err != nil {
+		t.Fatalf("parse rendered flow: %v\n%s", err, text)
+	}
+	if len(again.Edges) != len(want) {
+		t.Fatalf("round trip edges = %+v\n%s", again.Edges, text)
+	}
+}
+
+func TestParseFlowReportsLine(t *testing.T) {
+	_, err := ParseFlow("note flow\n\n  #12 depends on #13\n")
+	if err == nil || !strings.Contains(err.Error(), "line 3, column 3: #12 needs a default repo") {
+		t.Fatalf("err = %v, want line 3 default repo error", err)
+	}
+}
+
+func TestCheckFlowReportsEveryProblem(t *testing.T) {
+	diags := CheckFlow(`repo moul/depviz
+task ship "Ship it"
+task ship "Ship it again"
+ship needs #12
+ship depends on x#3
+ship blocks release
+`)
+	want := []Diagnostic{
+		{Line: 3, Column: 6, Severity: SeverityWarning},
+		{Line: 4, Column: 6, Severity: SeverityError, Message: `unknown relation verb "needs"`, Fix: `did you mean "depends on"?`},
+		{Line: 5, Column: 17, Severity: SeverityError, Message: "unknown repo alias x in x#3"},
+		{Line: 6, Column: 13, Severity: SeverityWarning, Message: "release is not declared; it becomes task:release"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("diagnostics = %+v, want %d", diags, len(want))
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Line != w.Line || d.Column != w.Column || d.Severity != w.Severity || (w.Message != "" && d.Message != w.Message) || (w.Fix != "" && d.Fix != w.Fix) {
+			t.Fatalf("diagnostic %d = %+v, want %+v", i, d, w)
+		}
+	}
+}
+
+func TestExtractFlowFencesKeepsLineNumbers(t *testing.T) {
+	md := "# Plan\n\n```go\nfmt.Println()\n```\n\n```depviz\nrepo moul/depviz\n#1 blocks #2\n```\n"
+	got := ExtractFlowFences(md)
+	lines := strings.Split(got, "\n")
+	if lines[7] != "repo moul/depviz" || lines[8] != "#1 blocks #2" {
+		t.Fatalf("fence lines = %q", lines)
+	}
+	if strings.Contains(got, "Println") || strings.Contains(got, "Plan") {
+		t.Fatalf("non-depviz content leaked: %q", got)
+	}
+}
+
+func TestIngestFlowMarkdownKeepsSyncedState(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.IngestEvents(ctx, strings.NewReader(`{"type":"node","id":"gh:moul/depviz#2","kind":"issue","title":"Synced title","state":"closed","source":"github:moul/depviz","external_id":"#2"}`), DefaultBoardID); err != nil {
+		t.Fatal(err)
+	}
+	md := "Plan\n\n```depviz\nrepo moul/depviz\ntask ship \"Ship it\" @release\n\nship depends on #1, #2\n```\n"
+	doc, err := s.IngestFlow(ctx, strings.NewReader(md), DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != 3 || len(doc.Edges) != 2 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	synced, err := s.nodeByID(ctx, "gh:moul/depviz#2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced.Title != "Synced title" || synced.State != "closed" {
+		t.Fatalf("relation-only flow overwrote synced node: %+v", synced)
+	}
+	brief, err := s.BuildBrief(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brief.Counts.Blocked != 1 {
+		t.Fatalf("blocked = %d, want 1 (task:ship waits on placeholder #1)", brief.Counts.Blocked)
+	}
+	ship, err := s.nodeByID(ctx, "task:ship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ship.Title != "Ship it" || len(ship.Labels()) != 1 || ship.Labels()[0] != "release" {
+		t.Fatalf("task:ship = %+v", ship)
+	}
+}
+
+func TestIngestFlowWritesNothingOnError(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.db.ExecContext(ctx, `CREATE TRIGGER no_edges BEFORE INSERT ON edges BEGIN SELECT RAISE(ABORT, 'no edges'); END`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.IngestFlow(ctx, strings.NewReader("repo moul/depviz\ntask ship \"Ship it\"\nship depends on #1\n"), DefaultBoardID); err == nil {
+		t.Fatal("IngestFlow succeeded, want the edge write to fail")
+	}
+	for _, id := range []string{"task:ship", "gh:moul/depviz#1"} {
+		if exists, err := s.nodeExists(ctx, id); err != nil || exists {
+			t.Fatalf("%s exists = %v (%v), want the failed ingest rolled back", id, exists, err)
+		}
+	}
+}
+
+func TestRenderFlowRoundTrips(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	events := strings.Join([]string{
+		`{"type":"node","id":"gh:moul/depviz#1","kind":"issue","title":"Parser","state":"open","owner":"moul","source":"github:moul/depviz","external_id":"#1","labels":["flow"]}`,
+		`{"type":"node","id":"gh:moul/depviz!2","kind":"pr","title":"Render \"Flow\" // export","state":"merged","source":"github:moul/depviz","external_id":"!2"}`,
+		`{"type":"node","id":"gh:moul/depviz#3","kind":"issue","title":"Docs","state":"open","source":"github:moul/depviz","external_id":"#3"}`,
+		`{"type":"node","id":"gh:moul/other#9","kind":"issue","title":"Other repo","state":"closed","source":"github:moul/other","external_id":"#9"}`,
+		`{"type":"note","id":"note:decide","title":"Decide format"}`,
+		`{"type":"node","id":"task:blocks","kind":"task","title":"Reserved slug","state":"open"}`,
+		`{"type":"edge","from":"gh:moul/depviz#1","to":"gh:moul/depviz!2","kind":"blocked_by"}`,
+		`{"type":"edge","from":"gh:moul/depviz#1","to":"gh:moul/other#9","kind":"blocked_by"}`,
+		`{"type":"edge","from":"note:decide","to":"gh:moul/depviz#1","kind":"blocks"}`,
+		`{"type":"edge","from":"task:blocks","to":"gh:moul/depviz#3","kind":"relates_to"}`,
+		`{"type":"edge","from":"gh:moul/depviz#3","to":"gh:moul/depviz#1","kind":"mentions","authority":"github-inferred","confidence":0.6}`,
+	}, "\n")
+	if _, err := s.IngestEvents(ctx, strings.NewReader(events), DefaultBoardID); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := RenderFlow(snap, FlowRenderOptions{Inferred: true})
+	for _, want := range []string{"repo moul/depviz\n", "repo moul/other as other\n", "#1 depends on !2, other#9\n", "decide blocks #1\n", "// #3 mentions #1 // github-inferred, 0.60\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("flow missing %q:\n%s", want, text)
+		}
+	}
+	doc, err := ParseFlow(text)
+	if err != nil {
+		t.Fatalf("parse rendered flow: %v\n%s", err, text)
+	}
+	nodes := map[string]FlowNode{}
+	for _, n := range doc.Nodes {
+		nodes[n.ID] = n
+	}
+	for _, n := range snap.Nodes {
+		got, ok := nodes[n.ID]
+		if !ok {
+			t.Fatalf("node %s lost in round trip:\n%s", n.ID, text)
+		}
+		if got.Title != n.Title {
+			t.Fatalf("node %s title = %q, want %q", n.ID, got.Title, n.Title)
+		}
+	}
+	edges := map[string]bool{}
+	for _, e := range doc.Edges {
+		edges[e.From+" "+e.Kind+" "+e.To] = true
+	}
+	hard := 0
+	for _, e := range snap.Edges {
+		if edgeIsSoft(e) {
+			continue
+		}
+		hard++
+		if !edges[e.FromID+" "+e.Kind+" "+e.ToID] {
+			t.Fatalf("edge %s %s %s lost in round trip:\n%s", e.FromID, e.Kind, e.ToID, text)
+		}
+	}
+	if len(doc.Edges) != hard {
+		t.Fatalf("parsed %d edges, want %d:\n%s", len(doc.Edges), hard, text)
+	}
+
+	// Uncommenting an inferred relation promotes it.
+	promoted := strings.Replace(text, "// #3 mentions #1", "#3 mentions #1", 1)
+	if doc, err = ParseFlow(promoted); err != nil {
+		t.Fatalf("parse promoted flow: %v\n%s", err, promoted)
+	}
+	found := false
+	for _, e := range doc.Edges {
+		found = found || e.From == "gh:moul/depviz#3" && e.Kind == "mentions" && e.To == "gh:moul/depviz#1"
+	}
+	if !found || len(doc.Edges) != hard+1 {
+		t.Fatalf("promoted edges = %+v:\n%s", doc.Edges, promoted)
+	}
+}
@@ -0,0 +1,377 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ForecastOptions tunes a Monte Carlo completion forecast.
+type ForecastOptions struct {
+	Target string
+	// Trials is the number of simulated schedules, 2000 by default.
+	Trials int
+	// PerOwner is how many cards one owner works on at a time, 1 by default.
+	// Cards without an owner share a single pool of the same size.
+	PerOwner int
+	// Start is the day work starts, today by default.
+	Start time.Time
+	// Seed makes a run reproducible; zero picks a random seed.
+	Seed uint64
+}
+
+// Forecast is the simulated completion of a target card and everything it
+// transitively waits on.
+type Forecast struct {
+	Target      string               `json:"target"`
+	Title       string               `json:"title"`
+	Start       time.Time            `json:"start"`
+	Trials      int                  `json:"trials"`
+	Cards       int                  `json:"cards"`
+	Unestimated []string             `json:"unestimated,omitempty"`
+	Percentiles []ForecastPercentile `json:"percentiles"`
+	Drivers     []ForecastDriver     `json:"drivers"`
+}
+
+// ForecastPercentile is a completion estimate in working days from Start.
+type ForecastPercentile struct {
+	Percentile int       `json:"percentile"`
+	Days       float64   `json:"days"`
+	Date       time.Time `json:"date"`
+}
+
+// ForecastDriver is a card that sat on the simulated critical chain; Share is
+// the fraction of trials where it did.
+type ForecastDriver struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Owner    string  `json:"owner,omitempty"`
+	Estimate float64 `json:"estimate_days"`
+	Share    float64 `json:"share"`
+}
+
+var (
+	estimateRE       = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*(h|d|w|pts?|points?)?$`)
+	tShirtEstimates  = map[string]float64{"xs": 0.5, "s": 1, "m": 3, "l": 5, "xl": 10, "xxl": 20}
+	forecastQuantile = []int{50, 85, 95}
+)
+
+// ParseEstimate reads an effort estimate in working days: "3d", "4h", "2w",
+// a bare number of days or points, or a T-shirt size from XS to XXL.
+func ParseEstimate(text string) (float64, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if days, ok := tShirtEstimates[text]; ok {
+		return days, true
+	}
+	m := estimateRE.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	switch m[2] {
+	case "h":
+		value /= 8
+	case "w":
+		value *= 5
+	}
+	return value, true
+}
+
+// NodeEstimate returns a node's estimate in working days from its
+// size:/estimate: labels.
+func NodeEstimate(n Node) (float64, bool) {
+	for _, label := range n.Labels() {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "size", "estimate":
+			if days, ok := ParseEstimate(value); ok {
+				return days, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Forecast simulates when opts.Target completes. Estimates come from the
+// "estimate" field first and size:/estimate: labels second.
+func (s *Store) Forecast(ctx context.Context, boardID string, opts ForecastOptions) (Forecast, error) {
+	snap, err := s.Snapshot(ctx, boardID)
+	if err != nil {
+		return Forecast{}, err
+	}
+	fields, err := s.FieldValuesByKey(ctx, snap.Board.ID, "node", FieldNamespace, "estimate")
+	if err != nil {
+		return Forecast{}, err
+	}
+	estimates := map[string]float64{}
+	for _, n := range snap.Nodes {
+		if days, ok := NodeEstimate(n); ok {
+			estimates[n.ID] = days
+		}
+		if fv, ok := fields[n.ID]; ok {
+			if days, ok := fieldEstimate(fv.ValueJSON); ok {
+				estimates[n.ID] = days
+			}
+		}
+	}
+	return BuildForecast(snap, estimates, opts)
+}
+
+func fieldEstimate(valueJSON string) (float64, bool) {
+	var value any
+	if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, v > 0
+	case string:
+		return ParseEstimate(v)
+	}
+	return 0, false
+}
+
+// BuildForecast runs the simulation over a snapshot. Each trial samples every
+// open card in the target's dependency closure from a triangular distribution
+// (0.6x, 1x, 2x its estimate) and schedules cards as soon as their blockers
+// are done and their owner has a free slot. Cards without an estimate use the
+// median of the estimated ones, or one day.
+func BuildForecast(snap Snapshot, estimates map[string]float64, opts ForecastOptions) (Forecast, error) {
+	nodes, blockersByNode, _ := blockingMaps(snap)
+	target, ok := nodes[opts.Target]
+	if !ok {
+		return Forecast{}, fmt.Errorf("target %s is not on board %s", opts.Target, snap.Board.ID)
+	}
+	if opts.Trials <= 0 {
+		opts.Trials = 2000
+	}
+	if opts.PerOwner <= 0 {
+		opts.PerOwner = 1
+	}
+	if opts.Start.IsZero() {
+		opts.Start = nowUTC().Truncate(24 * time.Hour)
+	}
+	if opts.Seed == 0 {
+		opts.Seed = rand.Uint64()
+	}
+	f := Forecast{Target: target.ID, Title: target.Title, Start: opts.Start, Trials: opts.Trials, Drivers: []ForecastDriver{}}
+
+	cycleOf := map[string]int{}
+	for i, c := range FindCycles(snap) {
+		for _, id := range c.Nodes {
+			cycleOf[id] = i + 1
+		}
+	}
+	blockers := map[string][]string{}
+	var scope []string
+	seen := map[string]bool{}
+	queue := []string{target.ID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] || nodes[id].IsClosed() {
+			continue
+		}
+		seen[id] = true
+		scope = append(scope, id)
+		for blocker := range blockersByNode[id] {
+			if nodes[blocker].IsClosed() || (cycleOf[id] != 0 && cycleOf[id] == cycleOf[blocker]) {
+				continue
+			}
+			blockers[id] = append(blockers[id], blocker)
+			queue = append(queue, blocker)
+		}
+	}
+	sort.Strings(scope)
+	f.Cards = len(scope)
+	if len(scope) == 0 {
+		for _, q := range forecastQuantile {
+			f.Percentiles = append(f.Percentiles, ForecastPercentile{Percentile: q, Date: opts.Start})
+		}
+		return f, nil
+	}
+
+	var known []float64
+	for _, id := range scope {
+		if days, ok := estimates[id]; ok && days > 0 {
+			known = append(known, days)
+		}
+	}
+	fallback := 1.0
+	if len(known) > 0 {
+		sort.Float64s(known)
+		fallback = known[len(known)/2]
+	}
+	est := map[string]float64{}
+	for _, id := range scope {
+		if days, ok := estimates[id]; ok && days > 0 {
+			est[id] = days
+			continue
+		}
+		est[id] = fallback
+		f.Unestimated = append(f.Unestimated, id)
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	completions := make([]float64, 0, opts.Trials)
+	drives := map[string]int{}
+	for trial := 0; trial < opts.Trials; trial++ {
+		finish, cause := simulateSchedule(scope, blockers, nodes, est, opts.PerOwner, rng)
+		completions = append(completions, finish[target.ID])
+		for id := cause[target.ID]; id != ""; id = cause[id] {
+			drives[id]++
+		}
+	}
+	sort.Float64s(completions)
+	for _, q := range forecastQuantile {
+		i := int(math.Ceil(float64(q)/100*float64(len(completions)))) - 1
+		days := math.Round(completions[max(i, 0)]*10) / 10
+		f.Percentiles = append(f.Percentiles, ForecastPercentile{Percentile: q, Days: days, Date: addWorkdays(opts.Start, days)})
+	}
+	for id, count := range drives {
+		n := nodes[id]
+		f.Drivers = append(f.Drivers, ForecastDriver{ID: id, Title: n.Title, Owner: n.Owner, Estimate: est[id], Share: float64(count) / float64(opts.Trials)})
+	}
+	sort.Slice(f.Drivers, func(i, j int) bool {
+		if f.Drivers[i].Share != f.Drivers[j].Share {
+			return f.Drivers[i].Share > f.Drivers[j].Share
+		}
+		return f.Drivers[i].ID < f.Drivers[j].ID
+	})
+	if len(f.Drivers) > 5 {
+		f.Drivers = f.Drivers[:5]
+	}
+	return f, nil
+}
+
+// simulateSchedule runs one trial. It returns each card's finish time in
+// working days and, for each card, the card whose completion it waited on
+// last: a blocker, or the owner's previous card when the owner was busy.
+func simulateSchedule(scope []string, blockers map[string][]string, nodes map[string]Node, est map[string]float64, perOwner int, rng *rand.Rand) (map[string]float64, map[string]string) {
+	finish := make(map[string]float64, len(scope))
+	cause := make(map[string]string, len(scope))
+	type slot struct {
+		free float64
+		last string
+	}
+	slots := map[string][]slot{}
+	pending := append([]string(nil), scope...)
+	for len(pending) > 0 {
+		best, bestStart, bestSlot := -1, math.Inf(1), 0
+		var bestCause string
+		for i, id := range pending {
+			readyAt, readyCause, ready := 0.0, "", true
+			for _, blocker := range blockers[id] {
+				done, ok := finish[blocker]
+				if !ok {
+					ready = false
+					break
+				}
+				if done > readyAt || readyCause == "" {
+					readyAt, readyCause = done, blocker
+				}
+			}
+			if !ready {
+				continue
+			}
+			owner := nodes[id].Owner
+			if slots[owner] == nil {
+				slots[owner] = make([]slot, perOwner)
+			}
+			slotIndex := 0
+			for j, sl := range slots[owner] {
+				if sl.free < slots[owner][slotIndex].free {
+					slotIndex = j
+				}
+			}
+			start, why := readyAt, readyCause
+			if sl := slots[owner][slotIndex]; sl.free > readyAt {
+				start, why = sl.free, sl.last
+			}
+			if start < bestStart {
+				best, bestStart, bestSlot, bestCause = i, start, slotIndex, why
+			}
+		}
+		if best < 0 {
+			// Unreachable once cycle edges are dropped, but never spin.
+			break
+		}
+		id := pending[best]
+		pending = append(pending[:best], pending[best+1:]...)
+		finish[id] = bestStart + sampleTriangular(rng, 0.6*est[id], est[id], 2*est[id])
+		cause[id] = bestCause
+		owner := nodes[id].Owner
+		slots[owner][bestSlot] = slot{free: finish[id], last: id}
+	}
+	return finish, cause
+}
+
+func sampleTriangular(rng *rand.Rand, low, mode, high float64) float64 {
+	u := rng.Float64()
+	if high <= low {
+		return mode
+	}
+	split := (mode - low) / (high - low)
+	if u < split {
+		return low + math.Sqrt(u*(high-low)*(mode-low))
+	}
+	return high - math.Sqrt((1-u)*(high-low)*(high-mode))
+}
+
+// addWorkdays moves start forward by a number of working days, rounding
+// partial days up and skipping weekends.
+func addWorkdays(start time.Time, days float64) time.Time {
+	d := start
+	for remaining := int(math.Ceil(days)); remaining > 0; {
+		d = d.AddDate(0, 0, 1)
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			remaining--
+		}
+	}
+	return d
+}
+
+func RenderForecast(w io.Writer, f Forecast) error {
+	write := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(w, format, args...)
+	}
+	write("DepViz forecast: %s %s\n", f.Target, f.Title)
+	write("Cards: %d - trials: %d - start: %s\n\n", f.Cards, f.Trials, f.Start.Format("2006-01-02"))
+	write("Completion\n")
+	for _, p := range f.Percentiles {
+		write("  P%d %s (%.1f working days)\n", p.Percentile, p.Date.Format("2006-01-02"), p.Days)
+	}
+	write("\n")
+	write("Risk drivers\n")
+	if len(f.Drivers) == 0 {
+		write("  none\n")
+	}
+	for _, d := range f.Drivers {
+		owner := ""
+		if d.Owner != "" {
+			owner = " +" + d.Owner
+		}
+		write("  %s %s%s - on the critical chain in %.0f%% of trials, estimate %.1fd\n", d.ID, d.Title, owner, d.Share*100, d.Estimate)
+	}
+	if len(f.Unestimated) > 0 {
+		write("\nUnestimated (median estimate assumed)\n")
+		for _, id := range f.Unestimated {
+			write("  %s\n", id)
+		}
+	}
+	return nil
+}
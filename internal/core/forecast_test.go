@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseEstimate(t *testing.T) {
+	for in, want := range map[string]float64{"3d": 3, "4h": 0.5, "2w": 10, "1.5": 1.5, "M": 3, "xl": 10, "5 pts": 5} {
+		got, ok := ParseEstimate(in)
+		if !ok || got != want {
+			t.Fatalf("ParseEstimate(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "soon", "-2d", "0"} {
+		if _, ok := ParseEstimate(in); ok {
+			t.Fatalf("ParseEstimate(%q) accepted", in)
+		}
+	}
+}
+
+func TestBuildForecastSerializesOwners(t *testing.T) {
+	snap := Snapshot{
+		Nodes: []Node{
+			{ID: "goal", State: "open", Owner: "ann"},
+			{ID: "a", State: "open", Owner: "bob"},
+			{ID: "b", State: "open", Owner: "bob"},
+			{ID: "c", State: "open", Owner: "cy"},
+			{ID: "done", State: "closed", Owner: "cy"},
+		},
+		Edges: []Edge{
+			{ID: "e1", FromID: "goal", ToID: "a", Kind: "blocked_by", Confidence: 1},
+			{ID: "e2", FromID: "goal", ToID: "b", Kind: "blocked_by", Confidence: 1},
+			{ID: "e3", FromID: "goal", ToID: "c", Kind: "blocked_by", Confidence: 1},
+			{ID: "e4", FromID: "c", ToID: "done", Kind: "blocked_by", Confidence: 1},
+		},
+	}
+	estimates := map[string]float64{"goal": 1, "a": 5, "b": 5, "c": 1}
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) // a Monday
+	f, err := BuildForecast(snap, estimates, ForecastOptions{Target: "goal", Trials: 500, Start: start, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Cards != 4 || len(f.Unestimated) != 0 {
+		t.Fatalf("scope = %d cards, unestimated %v", f.Cards, f.Unestimated)
+	}
+	// bob works a then b: at least 2 * 0.6 * 5 days, plus 0.6 for the goal.
+	p50 := f.Percentiles[0]
+	if p50.Percentile != 50 || p50.Days < 6.6 || p50.Days > 21 {
+		t.Fatalf("P50 = %+v", p50)
+	}
+	if f.Percentiles[2].Days < p50.Days {
+		t.Fatalf("P95 %v < P50 %v", f.Percentiles[2].Days, p50.Days)
+	}
+	if p50.Date.Weekday() == time.Saturday || p50.Date.Weekday() == time.Sunday {
+		t.Fatalf("P50 lands on a weekend: %s", p50.Date)
+	}
+	if len(f.Drivers) < 2 || f.Drivers[0].Share < 0.99 || f.Drivers[0].Owner != "bob" {
+		t.Fatalf("drivers = %+v, want bob's cards on every critical chain", f.Drivers)
+	}
+	for _, d := range f.Drivers {
+		if d.ID == "c" && d.Share > 0.01 {
+			t.Fatalf("short parallel card c drives %.2f of trials", d.Share)
+		}
+	}
+
+	parallel, err := BuildForecast(snap, estimates, ForecastOptions{Target: "goal", Trials: 500, Start: start, Seed: 7, PerOwner: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parallel.Percentiles[0].Days >= p50.Days {
+		t.Fatalf("per-owner 2 P50 = %v, want below %v", parallel.Percentiles[0].Days, p50.Days)
+	}
+}
+
+func TestForecastReadsEstimateFieldOverLabel(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.IngestEvent(ctx, []byte(`{"type":"node","id":"task:x","kind":"task","title":"X","state":"open","labels":["size:XL"]}`), DefaultBoardID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetFieldValue(ctx, FieldValue{BoardID: DefaultBoardID, OwnerType: "node", OwnerID: "task:x", Key: "estimate", ValueJSON: `"1d"`}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.Forecast(ctx, DefaultBoardID, ForecastOptions{Target: "task:x", Trials: 200, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Percentiles[2].Days > 2 {
+		t.Fatalf("P95 = %v days, want the 1d field to win over size:XL", f.Percentiles[2].Days)
+	}
+}
@@ -0,0 +1,302 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitSyncOptions selects the local repository SyncGit reads and where its
+// commits and branches go.
+type GitSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Path is the repository's working tree; it defaults to the current
+	// directory.
+	Path string
+	// Repo is the owner/name short refs such as #12 and branch numbers point
+	// at; it defaults to the GitHub repo of the origin remote.
+	Repo string
+	// Limit caps the commits read; it defaults to 500.
+	Limit int
+	// Hard stores the edges with full confidence so blocked_by trailers
+	// block. By default they are soft.
+	Hard bool
+}
+
+// GitSyncResult counts what a git sync imported.
+type GitSyncResult struct {
+	Source   string `json:"source"`
+	Repo     string `json:"repo"`
+	Commits  int    `json:"commits"`
+	Branches int    `json:"branches"`
+	Links    int    `json:"links"`
+	Removed  int    `json:"removed"`
+}
+
+var (
+	gitTrailerRE = regexp.MustCompile(`(?im)^(refs?|references|see-also|part-of|blocked-by|depends-on|closes|fixes|resolves)(?::\s*|\s+)(.+?)\s*$`)
+	// gitBranchRE finds the issue a branch is for in its last segment:
+	// feat/123-oauth, 123, gh-123 or issue-123-oauth. The slug must start with
+	// a letter so dates such as hotfix/2024-10-01 do not count.
+	gitBranchRE    = regexp.MustCompile(`(?i)(?:^|/)(?:gh-|issue-)?([0-9]+)(?:[-_][a-z][^/]*)?$`)
+	gitHubRemoteRE = regexp.MustCompile(`github\.com[:/]([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+?)(?:\.git)?/?$`)
+)
+
+// gitTrailerKind maps a trailer to the edge it states.
+func gitTrailerKind(trailer string) string {
+	switch strings.ToLower(trailer) {
+	case "blocked-by", "depends-on":
+		return "blocked_by"
+	case "closes", "fixes", "resolves":
+		return "closes"
+	default:
+		return "addresses"
+	}
+}
+
+// gitRepo runs git in a working tree.
+type gitRepo string
+
+func (r gitRepo) run(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", string(r)}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// SyncGit reads a local repository, with no network, for the issues its work
+// points at. Commits whose messages carry Refs:, Blocked-By:, Depends-On:,
+// Closes, Fixes or Resolves trailers become commit: nodes and branches named
+// after an issue, such as feat/123-foo, branch: nodes, with edges to the
+// issues carrying authority git and the trailer or branch as evidence.
+// Edges are soft unless Hard is set. Branches record how many of their
+// commits no remote has, which the brief reports for open issues.
+func SyncGit(ctx context.Context, s *Store, opts GitSyncOptions) (GitSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 500
+	}
+	var res GitSyncResult
+	err := s.runSync(ctx, opts.Board, "git", func() (int, int, error) {
+		var err error
+		res, err = syncGit(ctx, s, opts)
+		return res.Commits + res.Branches, res.Links, err
+	})
+	return res, err
+}
+
+func syncGit(ctx context.Context, s *Store, opts GitSyncOptions) (GitSyncResult, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return GitSyncResult{}, errors.New("git is required to sync a repository")
+	}
+	path, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+	top, err := gitRepo(path).run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+	repo := gitRepo(strings.TrimSpace(top))
+	if opts.Repo == "" {
+		if remote, err := repo.run(ctx, "config", "--get", "remote.origin.url"); err == nil {
+			if m := gitHubRemoteRE.FindStringSubmatch(strings.TrimSpace(remote)); m != nil {
+				opts.Repo = m[1]
+			}
+		}
+	}
+	sourceID := "git:" + filepath.ToSlash(string(repo))
+	res := GitSyncResult{Source: sourceID, Repo: opts.Repo}
+	if err := s.UpsertSource(ctx, Source{
+		ID:           sourceID,
+		Kind:         "git",
+		Name:         filepath.Base(string(repo)),
+		URL:          "file://" + filepath.ToSlash(string(repo)),
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"local"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+	confidence := func(kind string) float64 {
+		if opts.Hard {
+			return 1
+		}
+		return relationConfidence(kind)
+	}
+	refs := func(text string) []string {
+		if opts.Repo == "" {
+			return codeRefs("", text)
+		}
+		return itemRefs("gh:", opts.Repo, text)
+	}
+	seen := map[string]bool{}
+
+	// Commits are read from every local branch, newest first; \x1e ends a
+	// commit and \x1f separates its fields.
+	out, err := repo.run(ctx, "log", "--branches", "-n", strconv.Itoa(opts.Limit), "--format=%H%x1f%an%x1f%aI%x1f%s%x1f%B%x1e")
+	if err != nil {
+		return res, err
+	}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		sha, author, date, subject, message := fields[0], fields[1], fields[2], fields[3], fields[4]
+		var edges []ExtractedEdge
+		for _, m := range gitTrailerRE.FindAllStringSubmatch(message, -1) {
+			kind := gitTrailerKind(m[1])
+			for _, ref := range refs(m[2]) {
+				edges = append(edges, ExtractedEdge{To: ref, Kind: kind, Line: strings.TrimSpace(m[0]), Confidence: confidence(kind)})
+			}
+		}
+		if len(edges) == 0 {
+			continue
+		}
+		id := "commit:" + sha[:min(len(sha), 12)]
+		updated, _ := time.Parse(time.RFC3339, date)
+		url := ""
+		if opts.Repo != "" {
+			url = "https://github.com/" + opts.Repo + "/commit/" + sha
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"source":    "git",
+			"sha":       sha,
+			"author":    author,
+			"message":   strings.TrimSpace(message),
+			"synced_at": formatTime(nowUTC()),
+		})
+		links, err := s.upsertGitNode(ctx, opts.Board, sourceID, Node{ID: id, Kind: "commit", Title: subject, State: "done", Owner: author, DataJSON: string(payload), UpdatedAt: updated.UTC()}, url, edges, map[string]any{"commit": sha})
+		if err != nil {
+			return res, err
+		}
+		seen[id] = true
+		res.Commits++
+		res.Links += links
+	}
+
+	if opts.Repo == "" {
+		// Branch numbers mean nothing without a repo to point them at.
+		res.Removed, err = s.removeUnseenSourceNodes(ctx, opts.Board, sourceID, seen)
+		return res, err
+	}
+	trunk := repo.defaultBranch(ctx)
+	out, err = repo.run(ctx, "for-each-ref", "refs/heads", "--format=%(refname:short)%1f%(objectname)%1f%(committerdate:iso-strict)%1f%(upstream:short)")
+	if err != nil {
+		return res, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) < 4 || fields[0] == trunk {
+			continue
+		}
+		name, head, date, upstream := fields[0], fields[1], fields[2], fields[3]
+		m := gitBranchRE.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		state := "open"
+		if trunk != "" {
+			if _, err := repo.run(ctx, "merge-base", "--is-ancestor", name, trunk); err == nil {
+				state = "merged"
+			}
+		}
+		unpushed := 0
+		if count, err := repo.run(ctx, "rev-list", "--count", name, "--not", "--remotes"); err == nil {
+			unpushed, _ = strconv.Atoi(strings.TrimSpace(count))
+		}
+		id := "branch:" + opts.Repo + ":" + name
+		updated, _ := time.Parse(time.RFC3339, date)
+		payload, _ := json.Marshal(map[string]any{
+			"source":    "git",
+			"branch":    name,
+			"head":      head,
+			"upstream":  upstream,
+			"unpushed":  unpushed,
+			"synced_at": formatTime(nowUTC()),
+		})
+		url := ""
+		if upstream != "" {
+			url = "https://github.com/" + opts.Repo + "/tree/" + name
+		}
+		edge := ExtractedEdge{To: "gh:" + opts.Repo + "#" + m[1], Kind: "addresses", Line: name, Confidence: confidence("addresses")}
+		links, err := s.upsertGitNode(ctx, opts.Board, sourceID, Node{ID: id, Kind: "branch", Title: name, State: state, DataJSON: string(payload), UpdatedAt: updated.UTC()}, url, []ExtractedEdge{edge}, map[string]any{"branch": name, "head": head, "unpushed": unpushed})
+		if err != nil {
+			return res, err
+		}
+		seen[id] = true
+		res.Branches++
+		res.Links += links
+	}
+	res.Removed, err = s.removeUnseenSourceNodes(ctx, opts.Board, sourceID, seen)
+	return res, err
+}
+
+// defaultBranch names the branch work merges into: the one origin/HEAD
+// points at, else main or master, else none.
+func (r gitRepo) defaultBranch(ctx context.Context) string {
+	if out, err := r.run(ctx, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(out), "origin/")
+	}
+	for _, name := range []string{"main", "master"} {
+		if _, err := r.run(ctx, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// upsertGitNode stores a commit or branch and replaces its edges with
+// authority git, each carrying evidence plus the line it comes from.
+func (s *Store) upsertGitNode(ctx context.Context, boardID, sourceID string, n Node, url string, edges []ExtractedEdge, evidence map[string]any) (int, error) {
+	if n.UpdatedAt.IsZero() {
+		n.UpdatedAt = nowUTC()
+	}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return 0, err
+	}
+	if err := s.UpsertSourceRef(ctx, n.ID, sourceID, n.ID, url); err != nil {
+		return 0, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, n.ID, n.Kind, ""); err != nil {
+		return 0, err
+	}
+	keep := map[string]bool{}
+	for _, e := range edges {
+		ev := map[string]any{"text": e.Line}
+		for k, v := range evidence {
+			ev[k] = v
+		}
+		edge, err := s.AddEdgeWithConfidence(ctx, boardID, n.ID, e.To, e.Kind, "git", e.Confidence, ev)
+		if err != nil {
+			return 0, err
+		}
+		keep[edge.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, n.ID, "git", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
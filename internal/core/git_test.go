@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSyncGitReadsTrailersAndBranches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	origin, dir := filepath.Join(root, "origin.git"), filepath.Join(root, "api")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Ada", "-c", "user.email=ada@acme.dev", "-c", "commit.gpgsign=false"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", "--bare", origin).CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	git("remote", "add", "origin", origin)
+	git("commit", "-q", "--allow-empty", "-m", "Initial")
+	git("commit", "-q", "--allow-empty", "-m", "Add login\n\nRefs: #12\nBlocked-By: gh:acme/web#9")
+	git("push", "-q", "origin", "main")
+	git("remote", "set-head", "origin", "main")
+	git("checkout", "-q", "-b", "feat/123-oauth")
+	git("commit", "-q", "--allow-empty", "-m", "Wire OAuth")
+	git("commit", "-q", "--allow-empty", "-m", "Finish OAuth\n\nCloses #123")
+	git("branch", "chore-cleanup", "main")
+	git("branch", "release-2024-10", "main")
+	git("branch", "hotfix/2024-10-01", "main")
+
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := GitSyncOptions{Path: dir, Repo: "acme/api"}
+	res, err := SyncGit(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commits != 2 || res.Branches != 1 || res.Links != 4 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		if !edgeIsSoft(e) || e.Authority != "git" {
+			t.Fatalf("edge %+v should be soft with authority git", e)
+		}
+		edges = append(edges, fmt.Sprintf("%s %s %s", e.FromID[:7], e.Kind, e.ToID))
+	}
+	sort.Strings(edges)
+	want := []string{
+		"branch: addresses gh:acme/api#123",
+		"commit: addresses gh:acme/api#12",
+		"commit: blocked_by gh:acme/web#9",
+		"commit: closes gh:acme/api#123",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %q, want %q", edges, want)
+	}
+
+	if err := s.UpsertNode(ctx, Node{ID: "gh:acme/api#123", Kind: "issue", Title: "OAuth", State: "open", UpdatedAt: nowUTC()}); err != nil {
+		t.Fatal(err)
+	}
+	brief, err := s.BuildBrief(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brief.Branches) != 1 || brief.Branches[0].ID != "gh:acme/api#123" || brief.Branches[0].Reason != "has an active branch feat/123-oauth with 2 unpushed commits" {
+		t.Fatalf("branches = %+v", brief.Branches)
+	}
+	for _, item := range brief.Ready {
+		if item.Kind == "branch" || item.Kind == "commit" {
+			t.Fatalf("ready = %+v", brief.Ready)
+		}
+	}
+
+	// Pushing the branch leaves nothing unpushed; hard edges block.
+	git("push", "-q", "origin", "feat/123-oauth")
+	opts.Hard = true
+	if _, err := SyncGit(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	if brief, err = s.BuildBrief(ctx, DefaultBoardID); err != nil {
+		t.Fatal(err)
+	}
+	if len(brief.Branches) != 1 || brief.Branches[0].Reason != "has an active branch feat/123-oauth" {
+		t.Fatalf("branches = %+v", brief.Branches)
+	}
+	if snap, err = s.Snapshot(ctx, DefaultBoardID); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range snap.Edges {
+		if edgeIsSoft(e) {
+			t.Fatalf("edge %+v should be hard", e)
+		}
+	}
+}
+
+func TestGitBranchRE(t *testing.T) {
+	for name, want := range map[string]string{
+		"feat/123-oauth":    "123",
+		"fix/7":             "7",
+		"gh-42":             "42",
+		"issue-9-login":     "9",
+		"alice/GH-5_cache":  "5",
+		"release-2024-10":   "",
+		"hotfix/2024-10-01": "",
+		"chore-cleanup":     "",
+		"v2/deps":           "",
+	} {
+		got := ""
+		if m := gitBranchRE.FindStringSubmatch(name); m != nil {
+			got = m[1]
+		}
+		if got != want {
+			t.Errorf("%s: issue = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBriefLimitsBranches(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := range 20 {
+		branch := fmt.Sprintf("branch:acme/api:feat/%d-x", i)
+		if err := s.UpsertNode(ctx, Node{ID: branch, Kind: "branch", Title: fmt.Sprintf("feat/%d-x", i), State: "open"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddEdge(ctx, DefaultBoardID, branch, fmt.Sprintf("gh:acme/api#%d", i), "addresses", "git", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	brief, err := s.BuildBrief(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brief.Branches) != 12 {
+		t.Fatalf("branches = %d, want the section capped like the others", len(brief.Branches))
+	}
+}
@@ -0,0 +1,295 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"moul.io/depviz/v4/internal/core/gitea"
+)
+
+// GiteaSyncOptions selects the repo SyncGitea imports and where.
+type GiteaSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Repo is owner/name on the client's instance.
+	Repo string
+	// Limit caps the issues, and separately the pull requests, read; it
+	// defaults to 200.
+	Limit int
+	// Client defaults to gitea.FromEnv.
+	Client *gitea.Client
+}
+
+// GiteaSyncResult counts what a Gitea sync imported.
+type GiteaSyncResult struct {
+	Repo  string `json:"repo"`
+	Items int    `json:"items"`
+	Links int    `json:"links"`
+}
+
+// giteaItem is an issue or pull request from the REST API, which lists both
+// as issues; PullRequest is only set on pull requests.
+type giteaItem struct {
+	Number    int         `json:"number"`
+	Title     string      `json:"title"`
+	Body      string      `json:"body"`
+	State     string      `json:"state"`
+	HTMLURL   string      `json:"html_url"`
+	Labels    []restLabel `json:"labels"`
+	Assignees []giteaUser `json:"assignees"`
+	User      giteaUser   `json:"user"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PullRequest *struct {
+		Merged bool `json:"merged"`
+		Draft  bool `json:"draft"`
+	} `json:"pull_request"`
+	Repository *struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type giteaUser struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+}
+
+func (u giteaUser) person() GitHubPerson {
+	return GitHubPerson{Login: u.Login, AvatarURL: u.AvatarURL, HTMLURL: u.HTMLURL}
+}
+
+func (item giteaItem) marker() string {
+	if item.PullRequest != nil {
+		return "!"
+	}
+	return "#"
+}
+
+// SyncGitea imports a Gitea or Forgejo repo: its issues as
+// gitea:HOST/owner/name#N nodes and its pull requests as
+// gitea:HOST/owner/name!N, so two instances never share IDs. Labels, assignees
+// and relations written in bodies come along as for GitHub, and Gitea's issue
+// dependencies become hard blocked_by edges with authority gitea. Every run is
+// recorded in sync_logs.
+func SyncGitea(ctx context.Context, s *Store, opts GiteaSyncOptions) (GiteaSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Client == nil {
+		opts.Client = gitea.FromEnv()
+	}
+	var res GiteaSyncResult
+	err := s.runSync(ctx, opts.Board, "gitea", func() (int, int, error) {
+		var err error
+		res, err = syncGitea(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncGitea(ctx context.Context, s *Store, opts GiteaSyncOptions) (GiteaSyncResult, error) {
+	ref := strings.Trim(strings.TrimSpace(opts.Repo), "/")
+	if strings.Count(ref, "/") != 1 {
+		return GiteaSyncResult{}, fmt.Errorf("repo is required: use owner/name")
+	}
+	host := opts.Client.Host()
+	if host == "" {
+		return GiteaSyncResult{}, errors.New("gitea instance URL is required: set GITEA_URL or --url")
+	}
+	var repo struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	}
+	if err := opts.Client.Get(ctx, gitea.RepoPath(ref), &repo); err != nil {
+		return GiteaSyncResult{}, err
+	}
+	name := repo.FullName
+	if name == "" {
+		name = ref
+	}
+	if repo.HTMLURL == "" {
+		repo.HTMLURL = opts.Client.WebURL() + "/" + name
+	}
+	path := host + "/" + name
+	res := GiteaSyncResult{Repo: path}
+	if err := s.UpsertSource(ctx, Source{
+		ID:           "gitea:" + path,
+		Kind:         "gitea",
+		Name:         name,
+		URL:          repo.HTMLURL,
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"rest"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	api := gitea.RepoPath(name)
+	issues, err := gitea.List[giteaItem](ctx, opts.Client, api+"/issues?state=all&type=issues", opts.Limit)
+	if err != nil {
+		return res, err
+	}
+	pulls, err := gitea.List[giteaItem](ctx, opts.Client, api+"/issues?state=all&type=pulls", opts.Limit)
+	if err != nil {
+		return res, err
+	}
+	for _, item := range append(issues, pulls...) {
+		if item.Number == 0 {
+			continue
+		}
+		node, err := s.upsertGiteaItem(ctx, opts.Board, path, item)
+		if err != nil {
+			return res, err
+		}
+		res.Items++
+		links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges(path, node.ID, item.Body))
+		if err != nil {
+			return res, err
+		}
+		res.Links += links
+		var deps [2][]giteaItem
+		for i, endpoint := range []string{"dependencies", "blocks"} {
+			deps[i], err = gitea.List[giteaItem](ctx, opts.Client, fmt.Sprintf("%s/issues/%d/%s", api, item.Number, endpoint), 0)
+			var apiErr *gitea.Error
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				// Dependencies are turned off on this repo or instance.
+				deps[i], err = nil, nil
+			}
+			if err != nil {
+				return res, fmt.Errorf("%s of %s: %w", endpoint, node.ID, err)
+			}
+		}
+		if links, err = s.replaceGiteaDependencies(ctx, opts.Board, host, name, node.ID, deps[0], deps[1]); err != nil {
+			return res, err
+		}
+		res.Links += links
+	}
+	return res, nil
+}
+
+// upsertGiteaItem stores an issue or pull request of the repo at path,
+// host/owner/name, creating its gitea:path source from the item's URL when
+// missing, and adds it to a board. People are stored like GitHub's, so cards
+// show them the same way.
+func (s *Store) upsertGiteaItem(ctx context.Context, boardID, path string, item giteaItem) (Node, error) {
+	marker := item.marker()
+	id := fmt.Sprintf("gitea:%s%s%d", path, marker, item.Number)
+	kind, state := "issue", strings.ToLower(item.State)
+	if item.PullRequest != nil {
+		kind = "pr"
+		if item.PullRequest.Merged {
+			state = "merged"
+		}
+	}
+	assignees := forgePeople(item.Assignees...)
+	owner := ""
+	if len(assignees) > 0 {
+		owner = assignees[0].Login
+	}
+	var author *GitHubPerson
+	if a := forgePeople(item.User); len(a) > 0 {
+		author = &a[0]
+	}
+	milestone := ""
+	if item.Milestone != nil {
+		milestone = item.Milestone.Title
+	}
+	labels := []string{}
+	for _, l := range item.Labels {
+		labels = append(labels, l.Name)
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "gitea",
+		"kind":      kind,
+		"repo":      path,
+		"number":    item.Number,
+		"labels":    labels,
+		"assignees": assignees,
+		"author":    author,
+		"milestone": milestone,
+		"body":      item.Body,
+		"synced_at": formatTime(nowUTC()),
+		"html_url":  item.HTMLURL,
+		"draft":     item.PullRequest != nil && item.PullRequest.Draft,
+	})
+	updated := item.UpdatedAt.UTC()
+	if item.UpdatedAt.IsZero() {
+		updated = nowUTC()
+	}
+	n := Node{ID: id, Kind: kind, Title: item.Title, State: state, Owner: owner, DataJSON: string(payload), UpdatedAt: updated, URL: item.HTMLURL}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return Node{}, err
+	}
+	sourceID := "gitea:" + path
+	web := ""
+	for _, sep := range []string{"/issues/", "/pulls/"} {
+		if before, _, ok := strings.Cut(item.HTMLURL, sep); ok {
+			web = before
+		}
+	}
+	_, name, _ := strings.Cut(path, "/")
+	if err := s.ensureItemSource(ctx, Source{ID: sourceID, Kind: "gitea", Name: name, URL: web, Capabilities: `{"read":true}`, Sync: `{"mode":"rest"}`}); err != nil {
+		return Node{}, err
+	}
+	if err := s.UpsertSourceRef(ctx, id, sourceID, fmt.Sprintf("%s%d", marker, item.Number), item.HTMLURL); err != nil {
+		return Node{}, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, id, kind, ""); err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+// replaceGiteaDependencies stores an item's dependencies, the items blocking
+// it, and the items it blocks as hard edges, and drops those it no longer
+// has. Both ends list a dependency, and both read as the same blocked_by
+// edge. Items not yet known, possibly of other repos on the instance, are
+// imported from the dependency, which carries the whole issue.
+func (s *Store) replaceGiteaDependencies(ctx context.Context, boardID, host, repo, nodeID string, dependencies, blocks []giteaItem) (int, error) {
+	keep := map[string]bool{}
+	for i, items := range [][]giteaItem{dependencies, blocks} {
+		for _, item := range items {
+			if item.Number == 0 {
+				continue
+			}
+			path := host + "/" + repo
+			if item.Repository != nil && item.Repository.FullName != "" {
+				path = host + "/" + item.Repository.FullName
+			}
+			other := fmt.Sprintf("gitea:%s%s%d", path, item.marker(), item.Number)
+			exists, err := s.nodeExists(ctx, other)
+			if err != nil {
+				return 0, err
+			}
+			if !exists {
+				if _, err := s.upsertGiteaItem(ctx, boardID, path, item); err != nil {
+					return 0, err
+				}
+			}
+			from, to := nodeID, other
+			if i == 1 {
+				from, to = other, nodeID
+			}
+			e, err := s.AddEdgeWithConfidence(ctx, boardID, from, to, "blocked_by", "gitea", 1, map[string]any{"relation": "dependency"})
+			if err != nil {
+				return 0, err
+			}
+			keep[e.ID] = true
+		}
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, nodeID, "gitea", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
@@ -0,0 +1,178 @@
+// Package gitea is a small client for the Gitea REST API, which Forgejo serves
+// unchanged.
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Client calls the API of one Gitea or Forgejo instance with an access token,
+// or anonymously when Token is empty.
+type Client struct {
+	// BaseURL is the instance, such as https://git.example; the API is
+	// served under /api/v1.
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is a non-2xx Gitea response.
+type Error struct {
+	StatusCode int
+	Status     string
+	Path       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Status, e.Path, e.Message)
+}
+
+// FromEnv returns a client for the CLI, configured by GITEA_URL and
+// GITEA_TOKEN, or FORGEJO_URL and FORGEJO_TOKEN.
+func FromEnv() *Client {
+	c := &Client{}
+	for _, prefix := range []string{"GITEA", "FORGEJO"} {
+		if c.BaseURL == "" {
+			c.BaseURL = strings.TrimSpace(os.Getenv(prefix + "_URL"))
+		}
+		if c.Token == "" {
+			c.Token = strings.TrimSpace(os.Getenv(prefix + "_TOKEN"))
+		}
+	}
+	return c
+}
+
+// WebURL is the instance URL without a trailing slash or /api/v1.
+func (c *Client) WebURL() string {
+	return strings.TrimSuffix(strings.TrimRight(c.BaseURL, "/"), "/api/v1")
+}
+
+// Host is the instance's host, with its port if any, which namespaces the
+// IDs of its repos.
+func (c *Client) Host() string {
+	if u, err := url.Parse(c.WebURL()); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// RepoPath is the API path of a repo given as owner/name.
+func RepoPath(repo string) string {
+	owner, name, _ := strings.Cut(strings.Trim(repo, "/"), "/")
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+}
+
+// Get decodes the JSON response of a GET request into out.
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	_, data, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("expected JSON from %s, got %s: %w", pathOf(path), bodySummary(data), err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string) (http.Header, []byte, error) {
+	if c.WebURL() == "" {
+		return nil, nil, errors.New("gitea instance URL is required: set GITEA_URL or --url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.WebURL()+"/api/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 20<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, nil, &Error{StatusCode: res.StatusCode, Status: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)), Path: pathOf(path), Message: bodySummary(data)}
+	}
+	return res.Header, data, nil
+}
+
+// List GETs a list endpoint and follows its pages until limit items are read,
+// or all of them when limit is not positive. Pages are 50 items, the most
+// instances serve by default, and end at X-Total-Count or a short page.
+func List[T any](ctx context.Context, c *Client, path string, limit int) ([]T, error) {
+	pageSize := 50
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	var out []T
+	for page := 1; ; page++ {
+		target := path + sep + "limit=" + strconv.Itoa(pageSize) + "&page=" + strconv.Itoa(page)
+		header, data, err := c.get(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("expected a JSON list from %s, got %s: %w", pathOf(target), bodySummary(data), err)
+		}
+		out = append(out, items...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+			if len(out) >= total || len(items) == 0 {
+				return out, nil
+			}
+		} else if len(items) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+func pathOf(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return target
+}
+
+func bodySummary(data []byte) string {
+	body := strings.TrimSpace(string(data))
+	if body == "" {
+		return "empty response"
+	}
+	var envelope struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Message != "" {
+		return envelope.Message
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if len(body) > 240 {
+		body = body[:240] + "..."
+	}
+	return body
+}
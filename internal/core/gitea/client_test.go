@@ -0,0 +1,75 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListPagesUntilTotalCount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/api/v1/repos/acme/api/issues" || r.URL.Query().Get("state") != "all" || r.URL.Query().Get("limit") != "50" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("X-Total-Count", "3")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			// A server capped below the page size still pages on.
+			fmt.Fprint(w, `[{"number":1},{"number":2}]`)
+		case "2":
+			fmt.Fprint(w, `[{"number":3}]`)
+		default:
+			t.Errorf("page = %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer srv.Close()
+	// The API path is optional in the instance URL.
+	c := &Client{BaseURL: srv.URL + "/api/v1/", Token: "secret"}
+	items, err := List[struct{ Number int }](context.Background(), c, RepoPath("acme/api")+"/issues?state=all", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(items) != "[{1} {2} {3}]" {
+		t.Fatalf("items = %v", items)
+	}
+}
+
+func TestGetReportsGiteaMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"The target couldn't be found.","url":"https://git.example/api/swagger"}`)
+	}))
+	defer srv.Close()
+	err := (&Client{BaseURL: srv.URL}).Get(context.Background(), RepoPath("acme/missing"), &struct{}{})
+	if got := fmt.Sprint(err); got != "404 Not Found /repos/acme/missing: The target couldn't be found." {
+		t.Fatalf("err = %q", got)
+	}
+	if host := (&Client{BaseURL: "http://git.example:3000/"}).Host(); host != "git.example:3000" {
+		t.Fatalf("host = %q", host)
+	}
+}
+
+func TestListStopsAtLimit(t *testing.T) {
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("limit = %q, want the page sized to the limit", r.URL.Query().Get("limit"))
+		}
+		w.Header().Set("X-Total-Count", "10")
+		fmt.Fprint(w, `[{"number":1},{"number":2}]`)
+	}))
+	defer srv.Close()
+	items, err := List[struct{ Number int }](context.Background(), &Client{BaseURL: srv.URL}, RepoPath("acme/api")+"/issues", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || pages != 1 {
+		t.Fatalf("items = %v after %d pages, want 2 items from one page", items, pages)
+	}
+}
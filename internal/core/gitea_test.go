@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/gitea"
+)
+
+func TestSyncGiteaImportsItemsAndDependencies(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		web := srv.URL
+		issue := func(repo string, number int, title, state, extra string) string {
+			return fmt.Sprintf(`{"number":%d,"title":%q,"state":%q,"html_url":"%s/%s/issues/%d","repository":{"full_name":%q}%s}`, number, title, state, web, repo, number, repo, extra)
+		}
+		pull := fmt.Sprintf(`{"number":4,"title":"Schema","state":"closed","html_url":"%s/acme/api/pulls/4","pull_request":{"merged":true},"repository":{"full_name":"acme/api"}}`, web)
+		one := issue("acme/api", 1, "Ship", "open", fmt.Sprintf(`,"body":"Depends on #3, %s/acme/web/pulls/7 and https://github.com/acme/api/issues/9","labels":[{"name":"launch"}],"assignees":[{"login":"ada"}]`, web))
+		switch r.URL.Path {
+		case "/api/v1/repos/acme/api":
+			fmt.Fprintf(w, `{"full_name":"acme/api","html_url":"%s/acme/api"}`, web)
+		case "/api/v1/repos/acme/api/issues":
+			if r.URL.Query().Get("type") == "pulls" {
+				fmt.Fprintf(w, `[%s]`, pull)
+			} else {
+				fmt.Fprintf(w, `[%s,%s]`, one, issue("acme/api", 2, "Auth", "closed", ""))
+			}
+		case "/api/v1/repos/acme/api/issues/1/dependencies":
+			fmt.Fprintf(w, `[%s,%s,%s]`, issue("acme/api", 2, "Auth", "closed", ""), pull, issue("acme/lib", 5, "Client", "open", ""))
+		case "/api/v1/repos/acme/api/issues/2/blocks":
+			fmt.Fprintf(w, `[%s]`, one)
+		case "/api/v1/repos/acme/api/issues/4/dependencies":
+			http.NotFound(w, r)
+		case "/api/v1/repos/acme/api/issues/4/blocks":
+			fmt.Fprintf(w, `[%s]`, one)
+		default:
+			if !strings.HasSuffix(r.URL.Path, "/dependencies") && !strings.HasSuffix(r.URL.Path, "/blocks") {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	res, err := SyncGitea(ctx, s, GiteaSyncOptions{Repo: "acme/api", Client: &gitea.Client{BaseURL: srv.URL, Token: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	repo := "gitea:" + host + "/acme/api"
+	if res.Repo != host+"/acme/api" || res.Items != 3 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, strings.ReplaceAll(fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority), host, "HOST"))
+	}
+	sort.Strings(edges)
+	want := []string{
+		"gitea:HOST/acme/api#1 blocked_by gh:acme/api#9 gitea-inferred",
+		"gitea:HOST/acme/api#1 blocked_by gitea:HOST/acme/api!4 gitea",
+		"gitea:HOST/acme/api#1 blocked_by gitea:HOST/acme/api#2 gitea",
+		"gitea:HOST/acme/api#1 blocked_by gitea:HOST/acme/api#3 gitea-inferred",
+		"gitea:HOST/acme/api#1 blocked_by gitea:HOST/acme/lib#5 gitea",
+		"gitea:HOST/acme/api#1 blocked_by gitea:HOST/acme/web!7 gitea-inferred",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %q, want %q", edges, want)
+	}
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	if n := nodes[repo+"#1"]; n.State != "open" || n.Owner != "ada" || !reflect.DeepEqual(n.Labels(), []string{"launch"}) {
+		t.Fatalf("#1 = %+v", n)
+	}
+	if n := nodes[repo+"!4"]; n.Kind != "pr" || n.State != "merged" {
+		t.Fatalf("!4 = %+v", n)
+	}
+	if n := nodes["gitea:"+host+"/acme/lib#5"]; n.Title != "Client" || n.URL != srv.URL+"/acme/lib/issues/5" {
+		t.Fatalf("lib#5 = %+v", n)
+	}
+	// Placeholders follow the instance's own scheme.
+	if n := nodes[repo+"#3"]; n.URL != srv.URL+"/acme/api/issues/3" {
+		t.Fatalf("placeholder #3 = %+v", n)
+	}
+
+	doc, err := ParseFlow("repo gitea:git.example/acme/api\n#1 depends on #2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Edges) != 1 || doc.Edges[0].From != "gitea:git.example/acme/api#1" || doc.Edges[0].To != "gitea:git.example/acme/api#2" {
+		t.Fatalf("flow = %+v", doc.Edges)
+	}
+}
+
+func TestSyncGiteaPrunesRemovedDependenciesAndLogsFailures(t *testing.T) {
+	dependencies, fail := `[{"number":2,"title":"Auth","state":"open","repository":{"full_name":"acme/api"}}]`, false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message":"database is locked"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/repos/acme/api":
+			fmt.Fprint(w, `{"full_name":"acme/api"}`)
+		case "/api/v1/repos/acme/api/issues":
+			if r.URL.Query().Get("type") == "pulls" {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			if r.URL.Query().Get("limit") != "1" {
+				t.Errorf("limit = %q, want the sync limit", r.URL.Query().Get("limit"))
+			}
+			w.Header().Set("X-Total-Count", "2")
+			fmt.Fprint(w, `[{"number":1,"title":"Ship","state":"open"}]`)
+		case "/api/v1/repos/acme/api/issues/1/dependencies":
+			fmt.Fprint(w, dependencies)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := GiteaSyncOptions{Repo: "acme/api", Limit: 1, Client: &gitea.Client{BaseURL: srv.URL}}
+	res, err := SyncGitea(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 1 || res.Links != 1 {
+		t.Fatalf("result = %+v, want the one issue under the limit and its dependency", res)
+	}
+
+	dependencies = `[]`
+	if _, err := SyncGitea(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Edges) != 0 {
+		t.Fatalf("edges = %+v, want the removed dependency pruned", snap.Edges)
+	}
+
+	fail = true
+	if _, err := SyncGitea(ctx, s, opts); err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Fatalf("err = %v, want the server's message", err)
+	}
+	logs, err := s.GetSyncLogs(ctx, DefaultBoardID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []SyncLog
+	for _, l := range logs {
+		if l.Status == "failed" {
+			failed = append(failed, l)
+		}
+	}
+	if len(logs) != 3 || len(failed) != 1 || failed[0].Mode != "gitea" || !strings.Contains(failed[0].Error, "database is locked") {
+		t.Fatalf("sync logs = %+v, want the last of three failed", logs)
+	}
+}
@@ -1,215 +1,269 @@
 package core
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"moul.io/depviz/v4/internal/core/github"
 )
 
-type GitHubSyncOptions struct {
-	Repo  string
-	Limit int
+// GitHubItem is an issue or pull request as seen by a GitHubFetcher, whatever
+// API it came from.
+type GitHubItem struct {
+	Repo        string         `json:"repo"`
+	Number      int            `json:"number"`
+	PullRequest bool           `json:"pull_request"`
+	Title       string         `json:"title"`
+	State       string         `json:"state"`
+	Body        string         `json:"body"`
+	HTMLURL     string         `json:"html_url"`
+	APIURL      string         `json:"api_url"`
+	Labels      []string       `json:"labels"`
+	Assignees   []GitHubPerson `json:"assignees"`
+	Author      GitHubPerson   `json:"author"`
+	Milestone   string         `json:"milestone"`
+	Draft       bool           `json:"draft"`
+	Merged      bool           `json:"merged"`
+	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
-func SyncGitHub(ctx context.Context, s *Store, opts GitHubSyncOptions) (int, error) {
-	if opts.Repo == "" {
-		return 0, fmt.Errorf("repo is required")
-	}
-	if opts.Limit <= 0 {
-		opts.Limit = 200
+// GitHubPerson is a GitHub user reference stored in node data.
+type GitHubPerson struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	HTMLURL   string `json:"html_url,omitempty"`
+}
+
+// forgeUser is a user of another forge, stored the way GitHub people are so
+// cards show them the same way.
+type forgeUser interface {
+	person() GitHubPerson
+}
+
+// forgePeople converts users, dropping those without a login such as a
+// missing author.
+func forgePeople[U forgeUser](users ...U) []GitHubPerson {
+	out := make([]GitHubPerson, 0, len(users))
+	for _, u := range users {
+		if p := u.person(); p.Login != "" {
+			out = append(out, p)
+		}
 	}
-	sourceID := "github:" + opts.Repo
-	if err := s.UpsertSource(ctx, Source{
-		ID:           sourceID,
-		Kind:         "github",
-		Name:         opts.Repo,
-		URL:          "https://github.com/" + opts.Repo,
-		Capabilities: `{"read":true,"write":"via-gh-later"}`,
-		Sync:         `{"tool":"gh"}`,
-		UpdatedAt:    nowUTC(),
-	}); err != nil {
-		return 0, err
+	return out
+}
+
+// GitHubFetcher reads issues and pull requests for the sync engine.
+// GitHubRESTFetcher is the implementation; tests substitute fakes.
+type GitHubFetcher interface {
+	// RepoItems returns the most recently updated issues and PRs of a repo,
+	// or with a non-zero since the ones updated at or after it, oldest first.
+	RepoItems(ctx context.Context, repo string, since time.Time, limit int) ([]GitHubItem, error)
+	// OrgRepos returns the most recently updated repos of an owner.
+	OrgRepos(ctx context.Context, owner string, limit int) ([]string, error)
+	// SearchItems runs a GitHub issue search query.
+	SearchItems(ctx context.Context, query string, limit int) ([]GitHubItem, error)
+	// Viewer returns the login of the authenticated user.
+	Viewer(ctx context.Context) (string, error)
+}
+
+// GitHubRESTFetcher fetches from the GitHub REST API. The CLI builds its
+// client with github.FromEnv and the server with the account's token.
+type GitHubRESTFetcher struct {
+	Client *github.Client
+}
+
+func (f GitHubRESTFetcher) RepoItems(ctx context.Context, repo string, since time.Time, limit int) ([]GitHubItem, error) {
+	path := fmt.Sprintf("/repos/%s/issues?state=all&sort=updated&direction=desc", repo)
+	if !since.IsZero() {
+		path = fmt.Sprintf("/repos/%s/issues?state=all&sort=updated&direction=asc&since=%s", repo, url.QueryEscape(since.UTC().Format(time.RFC3339)))
 	}
-	issues, err := ghList[ghIssue](ctx, "issue", opts.Repo, opts.Limit)
+	issues, err := github.List[restIssue](ctx, f.Client, path, limit)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	out := make([]GitHubItem, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, issue.item(repo))
+	}
+	return out, nil
+}
+
+// OrgRepos lists an organization's repos, or a user's when owner is not an
+// organization, skipping archived ones.
+func (f GitHubRESTFetcher) OrgRepos(ctx context.Context, owner string, limit int) ([]string, error) {
+	type repo struct {
+		FullName string `json:"full_name"`
+		Archived bool   `json:"archived"`
+	}
+	repos, err := github.List[repo](ctx, f.Client, fmt.Sprintf("/orgs/%s/repos?sort=updated&direction=desc", owner), limit)
+	var apiErr *github.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		repos, err = github.List[repo](ctx, f.Client, fmt.Sprintf("/users/%s/repos?sort=updated&direction=desc", owner), limit)
 	}
-	prs, err := ghList[ghPR](ctx, "pr", opts.Repo, opts.Limit)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	count := 0
-	for _, issue := range issues {
-		id := fmt.Sprintf("gh:%s#%d", opts.Repo, issue.Number)
-		body := issue.Body
-		payload := githubPayload("issue", opts.Repo, issue.Number, issue.LabelNames(), issue.AssigneeNames(), body)
-		n := Node{
-			ID:        id,
-			Kind:      "issue",
-			Title:     issue.Title,
-			State:     strings.ToLower(issue.State),
-			Owner:     first(issue.AssigneeNames()),
-			DataJSON:  payload,
-			UpdatedAt: parseGitHubTime(issue.UpdatedAt),
-		}
-		if err := s.UpsertNode(ctx, n); err != nil {
-			return count, err
-		}
-		if err := s.UpsertSourceRef(ctx, id, sourceID, fmt.Sprintf("#%d", issue.Number), issue.URL); err != nil {
-			return count, err
-		}
-		if err := s.AddNodeToBoard(ctx, DefaultBoardID, id, "issue", ""); err != nil {
-			return count, err
-		}
-		count++
-		for _, edge := range extractDependencyEdges(opts.Repo, id, body) {
-			if _, err := s.AddEdgeWithConfidence(ctx, DefaultBoardID, edge.From, edge.To, edge.Kind, "github-inferred", edge.Confidence, edge); err != nil {
-				return count, err
-			}
+	out := make([]string, 0, len(repos))
+	for _, r := range repos {
+		if !r.Archived {
+			out = append(out, r.FullName)
 		}
 	}
-	for _, pr := range prs {
-		id := fmt.Sprintf("gh:%s!%d", opts.Repo, pr.Number)
-		payload := githubPayload("pr", opts.Repo, pr.Number, pr.LabelNames(), pr.AssigneeNames(), pr.Body)
-		state := strings.ToLower(pr.State)
-		if pr.MergedAt != "" {
-			state = "merged"
-		}
-		n := Node{
-			ID:        id,
-			Kind:      "pr",
-			Title:     pr.Title,
-			State:     state,
-			Owner:     first(pr.AssigneeNames()),
-			DataJSON:  payload,
-			UpdatedAt: parseGitHubTime(pr.UpdatedAt),
-			URL:       pr.URL,
-		}
-		if err := s.UpsertNode(ctx, n); err != nil {
-			return count, err
-		}
-		if err := s.UpsertSourceRef(ctx, id, sourceID, fmt.Sprintf("!%d", pr.Number), pr.URL); err != nil {
-			return count, err
-		}
-		if err := s.AddNodeToBoard(ctx, DefaultBoardID, id, "pr", ""); err != nil {
-			return count, err
-		}
-		count++
-		for _, edge := range extractDependencyEdges(opts.Repo, id, pr.Body) {
-			if _, err := s.AddEdgeWithConfidence(ctx, DefaultBoardID, edge.From, edge.To, edge.Kind, "github-inferred", edge.Confidence, edge); err != nil {
-				return count, err
-			}
-		}
+	return out, nil
+}
+
+func (f GitHubRESTFetcher) SearchItems(ctx context.Context, query string, limit int) ([]GitHubItem, error) {
+	found, err := github.Search[restIssue](ctx, f.Client, "/search/issues?q="+url.QueryEscape(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]GitHubItem, 0, len(found))
+	for _, issue := range found {
+		out = append(out, issue.item(issue.repoFullName()))
 	}
-	return count, nil
+	return out, nil
 }
 
-func ghList[T any](ctx context.Context, kind, repo string, limit int) ([]T, error) {
-	fields := "number,title,state,url,labels,assignees,updatedAt,createdAt,body"
-	if kind == "pr" {
-		fields = "number,title,state,url,labels,assignees,updatedAt,createdAt,body,mergedAt"
+func (f GitHubRESTFetcher) Viewer(ctx context.Context) (string, error) {
+	var user struct {
+		Login string `json:"login"`
 	}
-	cmd := exec.CommandContext(ctx, "gh", kind, "list", "--repo", repo, "--state", "all", "--limit", fmt.Sprint(limit), "--json", fields)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("gh %s list failed: %w: %s", kind, err, strings.TrimSpace(stderr.String()))
+	if err := f.Client.Get(ctx, "/user", &user); err != nil {
+		return "", err
 	}
-	var items []T
-	if err := json.Unmarshal(out, &items); err != nil {
-		return nil, err
+	if user.Login == "" {
+		return "", errors.New("github user response is missing a login")
 	}
-	return items, nil
+	return user.Login, nil
 }
 
-type ghIssue struct {
-	Number    int       `json:"number"`
-	Title     string    `json:"title"`
-	State     string    `json:"state"`
-	URL       string    `json:"url"`
-	Body      string    `json:"body"`
-	Labels    []ghLabel `json:"labels"`
-	Assignees []ghUser  `json:"assignees"`
-	UpdatedAt string    `json:"updatedAt"`
+// RateLimit reports the core quota from the last response, or asks the
+// rate_limit endpoint, which is free, when nothing was fetched yet.
+func (f GitHubRESTFetcher) RateLimit(ctx context.Context) (GitHubRateLimit, error) {
+	if rate := f.Client.RateLimit(); rate.Limit > 0 {
+		return GitHubRateLimit{Limit: rate.Limit, Remaining: rate.Remaining, Reset: rate.Reset}, nil
+	}
+	var payload struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := f.Client.Get(ctx, "/rate_limit", &payload); err != nil {
+		return GitHubRateLimit{}, err
+	}
+	quota := payload.Resources.Core
+	return GitHubRateLimit{Limit: quota.Limit, Remaining: quota.Remaining, Reset: time.Unix(quota.Reset, 0).UTC()}, nil
 }
 
-type ghPR struct {
-	Number    int       `json:"number"`
-	Title     string    `json:"title"`
-	State     string    `json:"state"`
-	URL       string    `json:"url"`
-	Body      string    `json:"body"`
-	MergedAt  string    `json:"mergedAt"`
-	Labels    []ghLabel `json:"labels"`
-	Assignees []ghUser  `json:"assignees"`
-	UpdatedAt string    `json:"updatedAt"`
+// GitHubResponseCache is a github.Cache kept in github_cache for an account.
+type GitHubResponseCache struct {
+	Store     *Store
+	AccountID string
 }
 
-type ghLabel struct {
-	Name string `json:"name"`
+const githubResponseCacheRepo = "rest"
+
+func (c GitHubResponseCache) Get(ctx context.Context, key string) ([]byte, string, bool) {
+	payload, etag, ok, err := c.Store.GitHubCache(ctx, c.AccountID, githubResponseCacheRepo, key)
+	if err != nil || !ok {
+		return nil, "", false
+	}
+	return []byte(payload), etag, true
 }
 
-type ghUser struct {
-	Login string `json:"login"`
-	Name  string `json:"name"`
+func (c GitHubResponseCache) Put(ctx context.Context, key string, body []byte, etag string) {
+	_ = c.Store.UpsertGitHubCache(ctx, c.AccountID, githubResponseCacheRepo, key, string(body), etag, time.Hour)
 }
 
-func (g ghIssue) LabelNames() []string {
-	return labelNames(g.Labels)
+// localAccountID is the account the command line keeps its GitHub responses
+// under. It has no OAuth connection, so no one can sign in as it.
+const localAccountID = "account:local"
+
+// LocalGitHubResponseCache returns the response cache used outside of a
+// signed-in session, creating the local account that owns it.
+func (s *Store) LocalGitHubResponseCache(ctx context.Context) (GitHubResponseCache, error) {
+	now := formatTime(nowUTC())
+	if _, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO accounts(id, primary_provider, login, created_at, updated_at)
+		VALUES(?, 'local', 'local', ?, ?)`, localAccountID, now, now); err != nil {
+		return GitHubResponseCache{}, err
+	}
+	return GitHubResponseCache{Store: s, AccountID: localAccountID}, nil
 }
 
-func (g ghIssue) AssigneeNames() []string {
-	return assigneeNames(g.Assignees)
+// restIssue is an issue, or a pull request, from the REST issues and search
+// APIs or a webhook. Pull request objects carry merged_at at the top level.
+type restIssue struct {
+	Number        int            `json:"number"`
+	Title         string         `json:"title"`
+	State         string         `json:"state"`
+	URL           string         `json:"url"`
+	HTMLURL       string         `json:"html_url"`
+	RepositoryURL string         `json:"repository_url"`
+	Body          string         `json:"body"`
+	UpdatedAt     string         `json:"updated_at"`
+	Draft         bool           `json:"draft"`
+	MergedAt      string         `json:"merged_at"`
+	Labels        []restLabel    `json:"labels"`
+	Assignees     []GitHubPerson `json:"assignees"`
+	User          GitHubPerson   `json:"user"`
+	Milestone     struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	PullRequest struct {
+		URL      string `json:"url"`
+		MergedAt string `json:"merged_at"`
+	} `json:"pull_request"`
 }
 
-func (g ghPR) LabelNames() []string {
-	return labelNames(g.Labels)
+type restLabel struct {
+	Name string `json:"name"`
 }
 
-func (g ghPR) AssigneeNames() []string {
-	return assigneeNames(g.Assignees)
+func (g restIssue) repoFullName() string {
+	_, repo, _ := strings.Cut(g.RepositoryURL, "/repos/")
+	return repo
 }
 
-func labelNames(labels []ghLabel) []string {
-	out := make([]string, 0, len(labels))
-	for _, l := range labels {
+func (g restIssue) item(repo string) GitHubItem {
+	item := GitHubItem{
+		Repo:        repo,
+		Number:      g.Number,
+		PullRequest: g.PullRequest.URL != "",
+		Title:       g.Title,
+		State:       g.State,
+		Body:        g.Body,
+		HTMLURL:     g.HTMLURL,
+		APIURL:      g.URL,
+		Milestone:   g.Milestone.Title,
+		Draft:       g.Draft,
+		Merged:      g.PullRequest.MergedAt != "" || g.MergedAt != "",
+		UpdatedAt:   parseGitHubTime(g.UpdatedAt),
+	}
+	for _, l := range g.Labels {
 		if l.Name != "" {
-			out = append(out, l.Name)
+			item.Labels = append(item.Labels, l.Name)
 		}
 	}
-	return out
-}
-
-func assigneeNames(users []ghUser) []string {
-	out := make([]string, 0, len(users))
-	for _, u := range users {
+	for _, u := range g.Assignees {
 		if u.Login != "" {
-			out = append(out, u.Login)
-		} else if u.Name != "" {
-			out = append(out, u.Name)
+			item.Assignees = append(item.Assignees, u)
 		}
 	}
-	return out
-}
-
-func githubPayload(kind, repo string, number int, labels, assignees []string, body string) string {
-	payload, _ := json.Marshal(map[string]any{
-		"source":    "github",
-		"kind":      kind,
-		"repo":      repo,
-		"number":    number,
-		"labels":    labels,
-		"assignees": assignees,
-		"body":      body,
-	})
-	return string(payload)
+	if g.User.Login != "" {
+		item.Author = g.User
+	}
+	return item
 }
 
 type ExtractedEdge struct {
@@ -222,16 +276,31 @@ type ExtractedEdge struct {
 
 var (
 	githubRefRE          = regexp.MustCompile(`(?i)(?:gh:)?([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?([#!])([0-9]+)`)
+	githubQualifiedRefRE = regexp.MustCompile(`(?i)gh:([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)`)
+	gitlabRefRE          = regexp.MustCompile(`(?i)(?:gl:)?((?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+)?([#!])([0-9]+)`)
+	gitlabQualifiedRefRE = regexp.MustCompile(`(?i)gl:((?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+)([#!])([0-9]+)`)
+	giteaQualifiedRefRE  = regexp.MustCompile(`(?i)gitea:([A-Za-z0-9.:-]+/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)`)
+	giteaURLRefRE        = regexp.MustCompile(`(?i)https?://([A-Za-z0-9.:-]+)/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(issues|pulls)/([0-9]+)`)
+	trackerRefRE         = regexp.MustCompile(`(?:(jira|linear):)?([A-Z][A-Z0-9_]*-[0-9]+)`)
+	gitlabURLRefRE       = regexp.MustCompile(`(?i)https?://[A-Za-z0-9.:-]+/((?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+)/-/(issues|merge_requests)/([0-9]+)`)
 	githubURLRefRE       = regexp.MustCompile(`(?i)https://(?:www\.)?(?:github|redirect\.github)\.com/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(issues|pull)/([0-9]+)`)
 	htmlAnchorRefStartRE = regexp.MustCompile(`(?i)^\s*<a\s+href=["']https://(?:www\.)?(?:github|redirect\.github)\.com/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/(?:issues|pull)/[0-9]+["']>`)
-	relationRefStartRE   = regexp.MustCompile(`(?i)^\s*[:\-]?\s*(?:https://(?:www\.)?(?:github|redirect\.github)\.com/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/(?:issues|pull)/[0-9]+|(?:gh:)?(?:[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?[#!][0-9]+)`)
+	relationRefStartRE   = regexp.MustCompile(`(?i)^\s*[:\-]?\s*(?:https://(?:www\.)?(?:github|redirect\.github)\.com/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/(?:issues|pull)/[0-9]+|https?://[A-Za-z0-9.:-]+/(?:[A-Za-z0-9_.-]+/)+-/(?:issues|merge_requests)/[0-9]+|https?://[A-Za-z0-9.:-]+/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/(?:issues|pulls)/[0-9]+|gitea:[A-Za-z0-9.:-]+/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+[#!][0-9]+|(?:g[hl]:)?(?:(?:[A-Za-z0-9_.-]+/)+[A-Za-z0-9_.-]+)?[#!][0-9]+|(?:(?:jira|linear):)?[A-Z][A-Z0-9_]*-[0-9]+)`)
 	relationVerbRE       = regexp.MustCompile(`(?i)\b(blocked by|depends on|depend on|depends|after|blocks|unblocks|addresses|mentions|relates to|relates|closes|closed|close|fixes|fixed|fix|resolves|resolved|resolve)\b`)
 )
 
+// extractDependencyEdges reads the relations a body states about currentID.
+// Short refs (#N, !N, path#N) resolve in the forge of currentID: gh: nodes
+// read GitHub refs and gl: nodes GitLab refs, whose project paths may nest
+// groups, gitea: nodes GitHub-style refs and issue URLs on their own
+// instance, and jira: and linear: nodes bare issue keys (OPS-42) of their
+// tracker. Qualified gh:, gl:, gitea:, jira: and linear: refs and GitHub and
+// GitLab issue URLs always work.
 func extractDependencyEdges(repo, currentID, body string) []ExtractedEdge {
+	prefix := refPrefix(currentID)
 	var edges []ExtractedEdge
 	seen := map[string]bool{}
-	for _, line := range strings.Split(body, "\n") {
+	for _, line := range strings.Split(stripGitHubDependencySection(body), "\n") {
 		lower := strings.ToLower(line)
 		if !strings.Contains(lower, "block") && !strings.Contains(lower, "depend") && !strings.Contains(lower, "after") && !strings.Contains(lower, "address") && !strings.Contains(lower, "mention") && !strings.Contains(lower, "relate") && !strings.Contains(lower, "close") && !strings.Contains(lower, "fix") && !strings.Contains(lower, "resolve") {
 			continue
@@ -241,11 +310,11 @@ func extractDependencyEdges(repo, currentID, body string) []ExtractedEdge {
 			if kind == "" {
 				continue
 			}
-			if !relationChunkTargetsCurrent(repo, chunk) {
+			if !relationChunkTargetsCurrent(prefix, repo, chunk) {
 				continue
 			}
 			confidence := relationConfidence(kind)
-			for _, target := range githubRefs(repo, chunk.text) {
+			for _, target := range itemRefs(prefix, repo, chunk.text) {
 				if target == currentID {
 					continue
 				}
@@ -265,11 +334,21 @@ func ExtractDependencyEdges(repo, currentID, body string) []ExtractedEdge {
 	return extractDependencyEdges(repo, currentID, body)
 }
 
-func relationChunkTargetsCurrent(repo string, chunk relationChunk) bool {
+func relationChunkTargetsCurrent(prefix, repo string, chunk relationChunk) bool {
 	if !relationRefStartRE.MatchString(chunk.text) && !htmlAnchorRefStartRE.MatchString(chunk.text) {
 		return false
 	}
-	return len(githubRefs(repo, chunk.prev)) == 0
+	return len(itemRefs(prefix, repo, chunk.prev)) == 0
+}
+
+// refPrefix is the forge prefix short refs resolve to for a node.
+func refPrefix(nodeID string) string {
+	for _, prefix := range []string{"gl:", "gitea:", "jira:", "linear:"} {
+		if strings.HasPrefix(nodeID, prefix) {
+			return prefix
+		}
+	}
+	return "gh:"
 }
 
 type relationChunk struct {
@@ -338,7 +417,9 @@ func relationConfidence(kind string) float64 {
 	}
 }
 
-func githubRefs(defaultRepo, text string) []string {
+// itemRefs lists the issue and PR refs of text as node IDs. Short refs take
+// the forge prefix and defaultRepo; where refs overlap the longest wins.
+func itemRefs(prefix, defaultRepo, text string) []string {
 	type refMatch struct {
 		start int
 		end   int
@@ -356,20 +437,82 @@ func githubRefs(defaultRepo, text string) []string {
 			id:    "gh:" + text[match[2]:match[3]] + marker + text[match[6]:match[7]],
 		})
 	}
-	for _, match := range githubRefRE.FindAllStringSubmatchIndex(text, -1) {
+	for _, match := range gitlabURLRefRE.FindAllStringSubmatchIndex(text, -1) {
+		marker := "#"
+		if strings.EqualFold(text[match[4]:match[5]], "merge_requests") {
+			marker = "!"
+		}
+		matches = append(matches, refMatch{
+			start: match[0],
+			end:   match[1],
+			id:    "gl:" + text[match[2]:match[3]] + marker + text[match[6]:match[7]],
+		})
+	}
+	shortRE, repoPrefix := githubRefRE, ""
+	switch prefix {
+	case "gl:":
+		shortRE = gitlabRefRE
+	case "gitea:":
+		// owner/repo#N points at the same instance as the current repo.
+		host, _, _ := strings.Cut(defaultRepo, "/")
+		repoPrefix = host + "/"
+		for _, match := range giteaURLRefRE.FindAllStringSubmatchIndex(text, -1) {
+			if !strings.EqualFold(text[match[2]:match[3]], host) {
+				continue
+			}
+			marker := "#"
+			if strings.EqualFold(text[match[6]:match[7]], "pulls") {
+				marker = "!"
+			}
+			matches = append(matches, refMatch{
+				start: match[0],
+				end:   match[1],
+				id:    "gitea:" + host + "/" + text[match[4]:match[5]] + marker + text[match[8]:match[9]],
+			})
+		}
+	case "jira:", "linear:":
+		shortRE = nil
+	}
+	for _, match := range trackerRefRE.FindAllStringSubmatchIndex(text, -1) {
 		if !validRefBoundary(text, match[0], match[1]) {
 			continue
 		}
-		refRepo := defaultRepo
+		tracker := prefix
 		if match[2] >= 0 {
-			refRepo = strings.TrimPrefix(text[match[2]:match[3]], "gh:")
+			tracker = text[match[2]:match[3]] + ":"
+		} else if shortRE != nil {
+			// Bare keys only resolve inside a tracker.
+			continue
 		}
 		matches = append(matches, refMatch{
 			start: match[0],
 			end:   match[1],
-			id:    "gh:" + refRepo + text[match[4]:match[5]] + text[match[6]:match[7]],
+			id:    tracker + text[match[4]:match[5]],
 		})
 	}
+	for _, re := range []struct {
+		re         *regexp.Regexp
+		prefix     string
+		repoPrefix string
+	}{{githubQualifiedRefRE, "gh:", ""}, {gitlabQualifiedRefRE, "gl:", ""}, {giteaQualifiedRefRE, "gitea:", ""}, {shortRE, prefix, repoPrefix}} {
+		if re.re == nil {
+			continue
+		}
+		for _, match := range re.re.FindAllStringSubmatchIndex(text, -1) {
+			if !validRefBoundary(text, match[0], match[1]) {
+				continue
+			}
+			refRepo := defaultRepo
+			if match[2] >= 0 {
+				refRepo = re.repoPrefix + text[match[2]:match[3]]
+			}
+			matches = append(matches, refMatch{
+				start: match[0],
+				end:   match[1],
+				id:    re.prefix + refRepo + text[match[4]:match[5]] + text[match[6]:match[7]],
+			})
+		}
+	}
 	if len(matches) == 0 {
 		return nil
 	}
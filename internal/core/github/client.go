@@ -0,0 +1,455 @@
+// Package github is a small client for the GitHub REST and GraphQL APIs,
+// shared by the CLI and the server so neither needs the gh tool installed.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the public GitHub API. GitHub Enterprise Server lives at
+// https://HOST/api/v3.
+const DefaultBaseURL = "https://api.github.com"
+
+// Client calls the GitHub API with a token, or anonymously when Token is
+// empty. Rate-limited and 5xx responses are retried with backoff.
+type Client struct {
+	// BaseURL defaults to DefaultBaseURL; tests point it at an httptest server.
+	BaseURL string
+	// Token is a personal, OAuth or installation token, or a GitHub App JWT.
+	Token      string
+	HTTPClient *http.Client
+	// Cache, when set, makes GET requests conditional on a cached ETag.
+	Cache Cache
+	// MaxRetries bounds retries of one request.
+	MaxRetries int
+	// Backoff is the first retry delay when GitHub sends no Retry-After. It
+	// doubles on every retry.
+	Backoff time.Duration
+	// MaxRetryAfter is the longest Retry-After worth waiting for; a request
+	// asked to wait longer fails with GitHub's error instead. It defaults to
+	// DefaultMaxRetryAfter.
+	MaxRetryAfter time.Duration
+
+	mu   sync.Mutex
+	rate RateLimit
+}
+
+// Cache keeps GET responses by URL so they can be revalidated with
+// If-None-Match; a 304 costs no rate limit.
+type Cache interface {
+	Get(ctx context.Context, key string) (body []byte, etag string, ok bool)
+	Put(ctx context.Context, key string, body []byte, etag string)
+}
+
+// RateLimit is the quota reported by the last response.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Request is one API call. Path is relative to BaseURL, or absolute as in a
+// Link header. Body, when set, is sent as JSON.
+type Request struct {
+	Method string
+	Path   string
+	Accept string
+	Body   any
+}
+
+// Response is a successful or not-modified API response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Error is a non-2xx GitHub response.
+type Error struct {
+	StatusCode int
+	Status     string
+	Path       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Status, e.Path, e.Message)
+}
+
+// NewClient returns a client for the public API.
+func NewClient(token string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Token: token, MaxRetries: 3, Backoff: time.Second}
+}
+
+// FromEnv returns a client for the CLI: the base URL comes from
+// GITHUB_API_URL when set, and the token from Token.
+func FromEnv(ctx context.Context) *Client {
+	c := NewClient(Token(ctx))
+	if base := strings.TrimSpace(os.Getenv("GITHUB_API_URL")); base != "" {
+		c.BaseURL = base
+	}
+	return c
+}
+
+// Token finds a token for the CLI: GITHUB_TOKEN, then GH_TOKEN, then
+// `gh auth token` when gh is installed. It returns "" when there is none,
+// which still allows anonymous reads of public repos.
+func Token(ctx context.Context) string {
+	for _, key := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := strings.TrimSpace(os.Getenv(key)); token != "" {
+			return token
+		}
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	out, err := exec.CommandContext(ctx, "gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// WithToken returns a copy of c that authenticates with token.
+func (c *Client) WithToken(token string) *Client {
+	return &Client{BaseURL: c.BaseURL, Token: token, HTTPClient: c.HTTPClient, Cache: c.Cache, MaxRetries: c.MaxRetries, Backoff: c.Backoff, MaxRetryAfter: c.MaxRetryAfter}
+}
+
+// RateLimit returns the quota from the last response, zero before any.
+func (c *Client) RateLimit() RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rate
+}
+
+// Get decodes the JSON response of a GET request into out.
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	_, err := c.Do(ctx, Request{Path: path}, out)
+	return err
+}
+
+// Do sends req, retrying secondary rate limits and server errors, and decodes
+// a JSON response into out when out is not nil.
+func (c *Client) Do(ctx context.Context, req Request, out any) (Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = json.Marshal(req.Body); err != nil {
+			return Response{}, err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, req, body)
+		if err != nil {
+			return Response{}, err
+		}
+		if delay, retry := c.retryDelay(res, attempt); retry {
+			select {
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if res.StatusCode != http.StatusNotModified && (res.StatusCode < 200 || res.StatusCode >= 300) {
+			return res, &Error{StatusCode: res.StatusCode, Status: statusText(res.StatusCode), Path: pathOf(req.Path), Message: bodySummary(res.Body)}
+		}
+		if out != nil && len(bytes.TrimSpace(res.Body)) > 0 {
+			if err := json.Unmarshal(res.Body, out); err != nil {
+				return res, fmt.Errorf("expected JSON from %s, got %s: %w", pathOf(req.Path), bodySummary(res.Body), err)
+			}
+		}
+		return res, nil
+	}
+}
+
+func (c *Client) send(ctx context.Context, req Request, body []byte) (Response, error) {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	target := c.url(req.Path)
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return Response{}, err
+	}
+	accept := req.Accept
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	httpReq.Header.Set("Accept", accept)
+	httpReq.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	var cached []byte
+	if c.Cache != nil && method == http.MethodGet {
+		if data, etag, ok := c.Cache.Get(ctx, target); ok && etag != "" {
+			cached = data
+			httpReq.Header.Set("If-None-Match", etag)
+		}
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpRes.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpRes.Body, 20<<20))
+	if err != nil {
+		return Response{}, err
+	}
+	c.recordRate(httpRes.Header)
+	res := Response{StatusCode: httpRes.StatusCode, Header: httpRes.Header, Body: data}
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		res.Body = cached
+	case res.StatusCode == http.StatusOK && c.Cache != nil && method == http.MethodGet:
+		if etag := httpRes.Header.Get("ETag"); etag != "" {
+			c.Cache.Put(ctx, target, data, etag)
+		}
+	}
+	return res, nil
+}
+
+// DefaultMaxRetryAfter caps the Retry-After a request waits for when the
+// client sets no MaxRetryAfter.
+const DefaultMaxRetryAfter = time.Minute
+
+// retryDelay decides whether a response is worth retrying: secondary rate
+// limits (403 or 429 with Retry-After or the secondary limit message) and
+// 502/503/504. An exhausted primary quota is not retried, since it may not
+// reset for an hour, and neither is a Retry-After past MaxRetryAfter.
+func (c *Client) retryDelay(res Response, attempt int) (time.Duration, bool) {
+	if attempt >= c.MaxRetries {
+		return 0, false
+	}
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		secondary := res.Header.Get("Retry-After") != "" || strings.Contains(strings.ToLower(string(res.Body)), "secondary rate limit")
+		if !secondary && (res.StatusCode == http.StatusForbidden || res.Header.Get("X-RateLimit-Remaining") == "0") {
+			return 0, false
+		}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		limit := c.MaxRetryAfter
+		if limit <= 0 {
+			limit = DefaultMaxRetryAfter
+		}
+		delay := time.Duration(seconds) * time.Second
+		return delay, delay <= limit
+	}
+	return c.Backoff << attempt, true
+}
+
+func (c *Client) recordRate(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	rate := RateLimit{Limit: limit}
+	rate.Remaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rate.Reset = time.Unix(reset, 0).UTC()
+	}
+	c.mu.Lock()
+	c.rate = rate
+	c.mu.Unlock()
+}
+
+func (c *Client) url(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// List GETs a list endpoint and follows its rel="next" links until limit
+// items are read, or all of them when limit is not positive.
+func List[T any](ctx context.Context, c *Client, path string, limit int) ([]T, error) {
+	return paginate(ctx, c, path, limit, func(data []byte) ([]T, error) {
+		var page []T
+		err := json.Unmarshal(data, &page)
+		return page, err
+	})
+}
+
+// Search is List for the search API, whose pages wrap items in an object.
+func Search[T any](ctx context.Context, c *Client, path string, limit int) ([]T, error) {
+	return paginate(ctx, c, path, limit, func(data []byte) ([]T, error) {
+		var page struct {
+			Items []T `json:"items"`
+		}
+		err := json.Unmarshal(data, &page)
+		return page.Items, err
+	})
+}
+
+func paginate[T any](ctx context.Context, c *Client, path string, limit int, decode func([]byte) ([]T, error)) ([]T, error) {
+	if !strings.Contains(path, "per_page=") {
+		perPage := 100
+		if limit > 0 && limit < perPage {
+			perPage = limit
+		}
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + "per_page=" + strconv.Itoa(perPage)
+	}
+	var out []T
+	for path != "" {
+		res, err := c.Do(ctx, Request{Path: path}, nil)
+		if err != nil {
+			return nil, err
+		}
+		page, err := decode(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("expected a JSON list from %s, got %s: %w", pathOf(path), bodySummary(res.Body), err)
+		}
+		out = append(out, page...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if len(page) == 0 {
+			break
+		}
+		path = nextLink(res.Header.Get("Link"))
+	}
+	return out, nil
+}
+
+// nextLink returns the rel="next" URL of a Link header.
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+	return ""
+}
+
+// GraphQL runs a query and decodes its data into out. GraphQL errors are
+// returned even though GitHub answers them with 200.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	body := map[string]any{"query": query}
+	if len(variables) > 0 {
+		body["variables"] = variables
+	}
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.Do(ctx, Request{Method: http.MethodPost, Path: c.graphQLURL(), Body: body}, &envelope); err != nil {
+		return err
+	}
+	if len(envelope.Errors) > 0 {
+		return errors.New(envelope.Errors[0].Message)
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// graphQLURL is BaseURL/graphql, except on GitHub Enterprise Server where
+// REST is served under /api/v3 and GraphQL at /api/graphql.
+func (c *Client) graphQLURL() string {
+	base := strings.TrimRight(c.url(""), "/")
+	if strings.HasSuffix(base, "/api/v3") {
+		base = strings.TrimSuffix(base, "/v3")
+	}
+	return base + "/graphql"
+}
+
+// InstallationToken is a GitHub App installation access token.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateInstallationToken exchanges the GitHub App JWT the client holds as
+// Token for an installation access token.
+func (c *Client) CreateInstallationToken(ctx context.Context, installationID int64) (InstallationToken, error) {
+	var token InstallationToken
+	if _, err := c.Do(ctx, Request{Method: http.MethodPost, Path: fmt.Sprintf("/app/installations/%d/access_tokens", installationID), Body: map[string]any{}}, &token); err != nil {
+		return InstallationToken{}, err
+	}
+	if token.Token == "" {
+		return InstallationToken{}, errors.New("github did not return an installation token")
+	}
+	return token, nil
+}
+
+func pathOf(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return target
+}
+
+func statusText(code int) string {
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
+
+func bodySummary(data []byte) string {
+	body := strings.TrimSpace(string(data))
+	if body == "" {
+		return "empty response"
+	}
+	var envelope struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(data, &envelope) == nil {
+		if envelope.Message != "" {
+			return envelope.Message
+		}
+		if envelope.Error != "" {
+			return envelope.Error
+		}
+	}
+	lower := strings.ToLower(body[:min(len(body), 120)])
+	if strings.Contains(lower, "<html") || strings.HasPrefix(lower, "<!doctype") {
+		return "non-JSON HTML response"
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if len(body) > 240 {
+		body = body[:240] + "..."
+	}
+	return body
+}
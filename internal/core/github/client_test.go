@@ -0,0 +1,210 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient("secret")
+	c.BaseURL = srv.URL
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestListFollowsLinkPages(t *testing.T) {
+	var c *Client
+	c = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.URL.Query().Get("per_page") != "3" {
+			t.Errorf("per_page = %q, want 3", r.URL.Query().Get("per_page"))
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?per_page=3&page=2>; rel="next", <%s/items?per_page=3&page=9>; rel="last"`, c.BaseURL, c.BaseURL))
+			fmt.Fprint(w, `[1, 2]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?per_page=3&page=3>; rel="next"`, c.BaseURL))
+			fmt.Fprint(w, `[3, 4]`)
+		default:
+			t.Errorf("fetched page %s past the limit", r.URL.Query().Get("page"))
+		}
+	})
+	got, err := List[int](context.Background(), c, "/items", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Fatalf("List = %v, want [1 2 3]", got)
+	}
+	if rate := c.RateLimit(); rate.Limit != 5000 || rate.Remaining != 4999 || rate.Reset.Unix() != 1700000000 {
+		t.Fatalf("RateLimit = %+v", rate)
+	}
+}
+
+func TestDoRetriesSecondaryRateLimit(t *testing.T) {
+	calls := 0
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+			return
+		}
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"login":"octo"}`)
+	})
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.Get(context.Background(), "/user", &user); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || user.Login != "octo" {
+		t.Fatalf("calls = %d, login = %q", calls, user.Login)
+	}
+}
+
+func TestDoDoesNotRetryPrimaryRateLimit(t *testing.T) {
+	calls := 0
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+	})
+	err := c.Get(context.Background(), "/repos/a/b", nil)
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusForbidden || calls != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+	if got := err.Error(); got != "403 Forbidden /repos/a/b: API rate limit exceeded" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
+
+func TestDoGivesUpOnLongRetryAfter(t *testing.T) {
+	calls := 0
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+	})
+	start := time.Now()
+	err := c.Get(context.Background(), "/user", nil)
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusTooManyRequests || calls != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("waited %s for an hour-long Retry-After", elapsed)
+	}
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][2]string
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) ([]byte, string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	return []byte(e[0]), e[1], ok
+}
+
+func (m *memoryCache) Put(_ context.Context, key string, body []byte, etag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = [2]string{string(body), etag}
+}
+
+func TestDoRevalidatesCachedETag(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"login":"octo"}`)
+	})
+	c.Cache = &memoryCache{entries: map[string][2]string{}}
+	for i := 0; i < 2; i++ {
+		var user struct {
+			Login string `json:"login"`
+		}
+		res, err := c.Do(context.Background(), Request{Path: "/user"}, &user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Login != "octo" {
+			t.Fatalf("call %d: login = %q (status %d)", i, user.Login, res.StatusCode)
+		}
+		if want := []int{http.StatusOK, http.StatusNotModified}[i]; res.StatusCode != want {
+			t.Fatalf("call %d: status = %d, want %d", i, res.StatusCode, want)
+		}
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/graphql" {
+			t.Errorf("%s %s, want POST /api/graphql", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "broken") {
+			fmt.Fprint(w, `{"errors":[{"message":"Field 'broken' doesn't exist"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"viewer":{"login":"octo"}}}`)
+	})
+	c.BaseURL += "/api/v3"
+	var out struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	if err := c.GraphQL(context.Background(), "{viewer{login}}", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Viewer.Login != "octo" {
+		t.Fatalf("login = %q", out.Viewer.Login)
+	}
+	if err := c.GraphQL(context.Background(), "{broken}", nil, &out); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("err = %v, want the GraphQL error", err)
+	}
+}
+
+func TestTokenPrefersEnvironment(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", " from-gh-token ")
+	if got := Token(context.Background()); got != "from-gh-token" {
+		t.Fatalf("Token = %q, want from-gh-token", got)
+	}
+	t.Setenv("GITHUB_TOKEN", "from-github-token")
+	if got := Token(context.Background()); got != "from-github-token" {
+		t.Fatalf("Token = %q, want from-github-token", got)
+	}
+}
@@ -0,0 +1,457 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
+
+// GitHubProject is a Projects v2 board with its custom fields and items.
+type GitHubProject struct {
+	ID     string               `json:"id"`
+	Title  string               `json:"title"`
+	URL    string               `json:"url"`
+	Number int                  `json:"number"`
+	Fields []GitHubProjectField `json:"fields"`
+	Items  []GitHubProjectItem  `json:"items"`
+}
+
+// GitHubProjectField is a project field DepViz imports, with Type one of the
+// FieldType constants: single selects become enums and iterations text.
+type GitHubProjectField struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"`
+}
+
+// GitHubProjectItem is an issue, PR or draft issue on a project. Drafts only
+// fill Item's title, body, assignees and updated_at. Values are keyed by field
+// name: strings for selects, iterations, dates and text, float64 for numbers.
+type GitHubProjectItem struct {
+	ID      string         `json:"id"`
+	DraftID string         `json:"draft_id,omitempty"`
+	Item    GitHubItem     `json:"item"`
+	Values  map[string]any `json:"values,omitempty"`
+}
+
+// GitHubProjectFetcher reads a project by node ID or owner/number.
+type GitHubProjectFetcher interface {
+	Project(ctx context.Context, ref string, limit int) (GitHubProject, error)
+}
+
+// GitHubProjectSyncOptions selects the project SyncGitHubProject imports and
+// the board receiving it.
+type GitHubProjectSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Project is a project node ID, owner/number, or project URL.
+	Project string
+	// Limit caps the items read; it defaults to 500.
+	Limit int
+	// Fetcher defaults to the GitHub API with a token from github.Token.
+	Fetcher GitHubProjectFetcher
+}
+
+// GitHubProjectSyncResult counts what a project sync imported.
+type GitHubProjectSyncResult struct {
+	Project string `json:"project"`
+	Title   string `json:"title"`
+	Items   int    `json:"items"`
+	Drafts  int    `json:"drafts"`
+	Fields  int    `json:"fields"`
+	Links   int    `json:"links"`
+}
+
+// SyncGitHubProject imports a Projects v2 board: its issues and PRs like a
+// repo sync, its draft issues as gh-draft: nodes, and its select, iteration,
+// number, date and text fields as board fields whose values the project owns.
+// A "Status" field becomes the status field the status brief reads, so
+// planning done in the project drives what is ready to pull.
+func SyncGitHubProject(ctx context.Context, s *Store, opts GitHubProjectSyncOptions) (GitHubProjectSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 500
+	}
+	if opts.Fetcher == nil {
+		opts.Fetcher = GitHubRESTFetcher{Client: github.FromEnv(ctx)}
+	}
+	var res GitHubProjectSyncResult
+	err := s.runSync(ctx, opts.Board, "project", func() (int, int, error) {
+		var err error
+		res, err = syncGitHubProject(ctx, s, opts)
+		return res.Items + res.Drafts, res.Links, err
+	})
+	return res, err
+}
+
+func syncGitHubProject(ctx context.Context, s *Store, opts GitHubProjectSyncOptions) (GitHubProjectSyncResult, error) {
+	if strings.TrimSpace(opts.Project) == "" {
+		return GitHubProjectSyncResult{}, fmt.Errorf("project is required: use owner/number, a project URL or a node ID")
+	}
+	project, err := opts.Fetcher.Project(ctx, strings.TrimSpace(opts.Project), opts.Limit)
+	if err != nil {
+		return GitHubProjectSyncResult{}, err
+	}
+	res := GitHubProjectSyncResult{Project: project.ID, Title: project.Title}
+	sourceID := "github-project:" + project.ID
+	if err := s.UpsertSource(ctx, Source{
+		ID:           sourceID,
+		Kind:         "github-project",
+		Name:         project.Title,
+		URL:          project.URL,
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"graphql"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	keys := map[string]string{}
+	owned := map[string]map[string]FieldValue{}
+	for _, f := range project.Fields {
+		key := githubProjectFieldKey(f.Name)
+		if f.Type == FieldTypeEnum && len(f.Options) == 0 {
+			f.Type = FieldTypeText
+		}
+		if _, err := s.DefineField(ctx, FieldDefinition{BoardID: opts.Board, Key: key, Type: f.Type, Label: f.Name, Options: f.Options}); err != nil {
+			return res, fmt.Errorf("project field %q: %w", f.Name, err)
+		}
+		values, err := s.FieldValuesByKey(ctx, opts.Board, "node", FieldNamespace, key)
+		if err != nil {
+			return res, err
+		}
+		keys[f.Name], owned[key] = key, values
+		res.Fields++
+	}
+
+	for _, pi := range project.Items {
+		var nodeID string
+		if pi.DraftID != "" {
+			nodeID = "gh-draft:" + pi.DraftID
+			if err := s.upsertGitHubDraft(ctx, opts.Board, sourceID, project, pi); err != nil {
+				return res, err
+			}
+			res.Drafts++
+		} else {
+			item := pi.Item
+			if item.Repo == "" || item.Number == 0 {
+				continue
+			}
+			if err := s.ensureGitHubSource(ctx, item.Repo, "project"); err != nil {
+				return res, err
+			}
+			node, err := s.UpsertGitHubItem(ctx, opts.Board, item)
+			if err != nil {
+				return res, err
+			}
+			links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges(item.Repo, node.ID, item.Body))
+			if err != nil {
+				return res, err
+			}
+			nodeID = node.ID
+			res.Items++
+			res.Links += links
+		}
+		for name, key := range keys {
+			value, ok := pi.Values[name]
+			if !ok {
+				// Clear values the project set before; leave local ones.
+				if fv, had := owned[key][nodeID]; had && fv.SourceID == sourceID {
+					if err := s.clearNodeField(ctx, opts.Board, nodeID, key); err != nil {
+						return res, err
+					}
+				}
+				continue
+			}
+			data, _ := json.Marshal(value)
+			if err := s.SetFieldValue(ctx, FieldValue{BoardID: opts.Board, OwnerType: "node", OwnerID: nodeID, Namespace: FieldNamespace, Key: key, ValueJSON: string(data), Authority: "github-project", SourceID: sourceID}); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *Store) upsertGitHubDraft(ctx context.Context, boardID, sourceID string, project GitHubProject, pi GitHubProjectItem) error {
+	id := "gh-draft:" + pi.DraftID
+	assignees := make([]string, 0, len(pi.Item.Assignees))
+	for _, a := range pi.Item.Assignees {
+		assignees = append(assignees, a.Login)
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "github-project",
+		"kind":      "draft_issue",
+		"project":   project.Title,
+		"body":      pi.Item.Body,
+		"assignees": pi.Item.Assignees,
+		"synced_at": formatTime(nowUTC()),
+	})
+	updated := pi.Item.UpdatedAt
+	if updated.IsZero() {
+		updated = nowUTC()
+	}
+	if err := s.UpsertNode(ctx, Node{
+		ID:        id,
+		Kind:      "draft_issue",
+		Title:     pi.Item.Title,
+		State:     "open",
+		Owner:     first(assignees),
+		DataJSON:  string(payload),
+		UpdatedAt: updated,
+		URL:       project.URL,
+	}); err != nil {
+		return err
+	}
+	if err := s.UpsertSourceRef(ctx, id, sourceID, pi.DraftID, project.URL); err != nil {
+		return err
+	}
+	return s.AddNodeToBoard(ctx, boardID, id, "draft_issue", "")
+}
+
+var githubProjectFieldKeyRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// githubProjectFieldKey turns a field name such as "Story Points" into a
+// field key such as "story_points".
+func githubProjectFieldKey(name string) string {
+	key := strings.Trim(githubProjectFieldKeyRE.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		key = "field_" + key
+	}
+	return key
+}
+
+var githubProjectURLRE = regexp.MustCompile(`^https://(?:www\.)?github\.com/(?:orgs|users)/([A-Za-z0-9-]+)/projects/([0-9]+)`)
+var githubProjectRefRE = regexp.MustCompile(`^([A-Za-z0-9-]+)/([0-9]+)$`)
+
+const githubProjectFragment = `fragment project on ProjectV2 {
+  id title url number
+  fields(first: 50) {
+    nodes {
+      ... on ProjectV2FieldCommon { name dataType }
+      ... on ProjectV2SingleSelectField { options { name } }
+    }
+  }
+  items(first: $first, after: $after) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      id
+      fieldValues(first: 50) {
+        nodes {
+          ... on ProjectV2ItemFieldSingleSelectValue { name field { ... on ProjectV2FieldCommon { name } } }
+          ... on ProjectV2ItemFieldIterationValue { title field { ... on ProjectV2FieldCommon { name } } }
+          ... on ProjectV2ItemFieldNumberValue { number field { ... on ProjectV2FieldCommon { name } } }
+          ... on ProjectV2ItemFieldDateValue { date field { ... on ProjectV2FieldCommon { name } } }
+          ... on ProjectV2ItemFieldTextValue { text field { ... on ProjectV2FieldCommon { name } } }
+        }
+      }
+      content {
+        __typename
+        ... on DraftIssue { id title body updatedAt assignees(first: 10) { nodes { login } } }
+        ... on Issue { number title body url state updatedAt repository { nameWithOwner } labels(first: 20) { nodes { name } } assignees(first: 10) { nodes { login } } author { login } milestone { title } }
+        ... on PullRequest { number title body url state merged isDraft updatedAt repository { nameWithOwner } labels(first: 20) { nodes { name } } assignees(first: 10) { nodes { login } } author { login } milestone { title } }
+      }
+    }
+  }
+}`
+
+type graphQLProject struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Number int    `json:"number"`
+	Fields struct {
+		Nodes []struct {
+			Name     string `json:"name"`
+			DataType string `json:"dataType"`
+			Options  []struct {
+				Name string `json:"name"`
+			} `json:"options"`
+		} `json:"nodes"`
+	} `json:"fields"`
+	Items struct {
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+		Nodes []graphQLProjectItem `json:"nodes"`
+	} `json:"items"`
+}
+
+type graphQLLogins struct {
+	Nodes []GitHubPerson `json:"nodes"`
+}
+
+type graphQLProjectItem struct {
+	ID          string `json:"id"`
+	FieldValues struct {
+		Nodes []struct {
+			Name   *string  `json:"name"`
+			Title  *string  `json:"title"`
+			Number *float64 `json:"number"`
+			Date   *string  `json:"date"`
+			Text   *string  `json:"text"`
+			Field  struct {
+				Name string `json:"name"`
+			} `json:"field"`
+		} `json:"nodes"`
+	} `json:"fieldValues"`
+	Content struct {
+		Typename   string        `json:"__typename"`
+		ID         string        `json:"id"`
+		Number     int           `json:"number"`
+		Title      string        `json:"title"`
+		Body       string        `json:"body"`
+		URL        string        `json:"url"`
+		State      string        `json:"state"`
+		Merged     bool          `json:"merged"`
+		IsDraft    bool          `json:"isDraft"`
+		UpdatedAt  string        `json:"updatedAt"`
+		Assignees  graphQLLogins `json:"assignees"`
+		Author     *GitHubPerson `json:"author"`
+		Repository struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"repository"`
+		Labels struct {
+			Nodes []restLabel `json:"nodes"`
+		} `json:"labels"`
+		Milestone *struct {
+			Title string `json:"title"`
+		} `json:"milestone"`
+	} `json:"content"`
+}
+
+// githubProjectFieldTypes maps the project field types DepViz imports to
+// field types; built-in fields such as Title, Assignees or Labels are left to
+// the issue itself.
+var githubProjectFieldTypes = map[string]string{
+	"SINGLE_SELECT": FieldTypeEnum,
+	"ITERATION":     FieldTypeText,
+	"NUMBER":        FieldTypeNumber,
+	"DATE":          FieldTypeDate,
+	"TEXT":          FieldTypeText,
+}
+
+// Project reads a Projects v2 board through GraphQL, paging through its
+// items up to limit.
+func (f GitHubRESTFetcher) Project(ctx context.Context, ref string, limit int) (GitHubProject, error) {
+	query := githubProjectFragment + `
+query($id: ID!, $first: Int!, $after: String) { node(id: $id) { ...project } }`
+	vars := map[string]any{"id": ref}
+	if m := githubProjectURLRE.FindStringSubmatch(ref); m != nil {
+		ref = m[1] + "/" + m[2]
+	}
+	if m := githubProjectRefRE.FindStringSubmatch(ref); m != nil {
+		number, _ := strconv.Atoi(m[2])
+		query = githubProjectFragment + `
+query($owner: String!, $number: Int!, $first: Int!, $after: String) {
+  repositoryOwner(login: $owner) { ... on ProjectV2Owner { projectV2(number: $number) { ...project } } }
+}`
+		vars = map[string]any{"owner": m[1], "number": number}
+	}
+	var project GitHubProject
+	var after *string
+	for {
+		vars["first"] = min(100, limit-len(project.Items))
+		vars["after"] = after
+		var out struct {
+			Node            *graphQLProject `json:"node"`
+			RepositoryOwner *struct {
+				ProjectV2 *graphQLProject `json:"projectV2"`
+			} `json:"repositoryOwner"`
+		}
+		if err := f.Client.GraphQL(ctx, query, vars, &out); err != nil {
+			return GitHubProject{}, err
+		}
+		page := out.Node
+		if out.RepositoryOwner != nil {
+			page = out.RepositoryOwner.ProjectV2
+		}
+		if page == nil || page.ID == "" {
+			return GitHubProject{}, fmt.Errorf("github project %s not found", ref)
+		}
+		if project.ID == "" {
+			project = GitHubProject{ID: page.ID, Title: page.Title, URL: page.URL, Number: page.Number}
+			for _, field := range page.Fields.Nodes {
+				typ, ok := githubProjectFieldTypes[field.DataType]
+				if !ok || field.Name == "" {
+					continue
+				}
+				pf := GitHubProjectField{Name: field.Name, Type: typ}
+				for _, opt := range field.Options {
+					pf.Options = append(pf.Options, opt.Name)
+				}
+				project.Fields = append(project.Fields, pf)
+			}
+		}
+		for _, node := range page.Items.Nodes {
+			if pi, ok := node.item(); ok {
+				project.Items = append(project.Items, pi)
+			}
+		}
+		if !page.Items.PageInfo.HasNextPage || len(project.Items) >= limit {
+			return project, nil
+		}
+		after = &page.Items.PageInfo.EndCursor
+	}
+}
+
+func (g graphQLProjectItem) item() (GitHubProjectItem, bool) {
+	c := g.Content
+	pi := GitHubProjectItem{ID: g.ID, Values: map[string]any{}}
+	for _, v := range g.FieldValues.Nodes {
+		if v.Field.Name == "" {
+			continue
+		}
+		switch {
+		case v.Name != nil:
+			pi.Values[v.Field.Name] = *v.Name
+		case v.Title != nil:
+			pi.Values[v.Field.Name] = *v.Title
+		case v.Number != nil:
+			pi.Values[v.Field.Name] = *v.Number
+		case v.Date != nil:
+			pi.Values[v.Field.Name] = *v.Date
+		case v.Text != nil:
+			pi.Values[v.Field.Name] = *v.Text
+		}
+	}
+	pi.Item = GitHubItem{
+		Title:     c.Title,
+		Body:      c.Body,
+		Assignees: c.Assignees.Nodes,
+		UpdatedAt: parseGitHubTime(c.UpdatedAt),
+	}
+	switch c.Typename {
+	case "DraftIssue":
+		pi.DraftID = c.ID
+		return pi, c.ID != ""
+	case "Issue", "PullRequest":
+		pi.Item.Repo = c.Repository.NameWithOwner
+		pi.Item.Number = c.Number
+		pi.Item.PullRequest = c.Typename == "PullRequest"
+		pi.Item.State = c.State
+		pi.Item.HTMLURL = c.URL
+		pi.Item.Draft = c.IsDraft
+		pi.Item.Merged = c.Merged
+		if c.Author != nil {
+			pi.Item.Author = *c.Author
+		}
+		if c.Milestone != nil {
+			pi.Item.Milestone = c.Milestone.Title
+		}
+		for _, l := range c.Labels.Nodes {
+			pi.Item.Labels = append(pi.Item.Labels, l.Name)
+		}
+		return pi, true
+	default:
+		// Redacted items the token cannot see.
+		return pi, false
+	}
+}
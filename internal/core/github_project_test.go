@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
+
+func TestSyncGitHubProjectImportsFieldsAndStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/graphql" || !strings.Contains(string(body), `"owner":"acme"`) {
+			t.Errorf("unexpected request %s %s", r.URL.Path, body)
+		}
+		fmt.Fprint(w, `{"data":{"repositoryOwner":{"projectV2":{
+			"id":"PVT_1","title":"Roadmap","url":"https://github.com/orgs/acme/projects/5","number":5,
+			"fields":{"nodes":[
+				{"name":"Title","dataType":"TITLE"},
+				{"name":"Status","dataType":"SINGLE_SELECT","options":[{"name":"Todo"},{"name":"In Progress"},{"name":"Done"}]},
+				{"name":"Story Points","dataType":"NUMBER"},
+				{"name":"Sprint","dataType":"ITERATION"},
+				{"name":"Due","dataType":"DATE"}
+			]},
+			"items":{"pageInfo":{"hasNextPage":false},"nodes":[
+				{"id":"PVTI_1","fieldValues":{"nodes":[
+					{"text":"Ship","field":{"name":"Title"}},
+					{"name":"Todo","field":{"name":"Status"}},
+					{"number":3,"field":{"name":"Story Points"}},
+					{"title":"Sprint 4","field":{"name":"Sprint"}},
+					{"date":"2026-11-02","field":{"name":"Due"}}
+				]},"content":{"__typename":"Issue","number":1,"title":"Ship","state":"OPEN","body":"blocked by !2","url":"https://github.com/acme/api/issues/1","repository":{"nameWithOwner":"acme/api"},"labels":{"nodes":[{"name":"status:parked"}]},"assignees":{"nodes":[{"login":"ada"}]}}},
+				{"id":"PVTI_2","fieldValues":{"nodes":[{"name":"In Progress","field":{"name":"Status"}}]},
+					"content":{"__typename":"PullRequest","number":2,"title":"Schema","state":"MERGED","merged":true,"url":"https://github.com/acme/api/pull/2","repository":{"nameWithOwner":"acme/api"}}},
+				{"id":"PVTI_3","fieldValues":{"nodes":[{"name":"Todo","field":{"name":"Status"}}]},
+					"content":{"__typename":"DraftIssue","id":"DI_3","title":"Write the launch post","assignees":{"nodes":[{"login":"bob"}]}}},
+				{"id":"PVTI_4","fieldValues":{"nodes":[]},"content":{"__typename":"REDACTED"}}
+			]}
+		}}}}`)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := github.NewClient("")
+	client.BaseURL = srv.URL
+	res, err := SyncGitHubProject(ctx, s, GitHubProjectSyncOptions{Project: "https://github.com/orgs/acme/projects/5", Fetcher: GitHubRESTFetcher{Client: client}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Project != "PVT_1" || res.Items != 2 || res.Drafts != 1 || res.Fields != 4 || res.Links != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]map[string]any{}
+	for _, n := range snap.Nodes {
+		fields[n.ID] = n.Fields
+	}
+	want := map[string]map[string]any{
+		"gh:acme/api#1": {"status": "Todo", "story_points": 3.0, "sprint": "Sprint 4", "due": "2026-11-02"},
+		"gh:acme/api!2": {"status": "In Progress"},
+		"gh-draft:DI_3": {"status": "Todo"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+	var status FieldDefinition
+	for _, def := range snap.Fields {
+		if def.Key == "status" {
+			status = def
+		}
+	}
+	if status.Type != FieldTypeEnum || !reflect.DeepEqual(status.Options, []string{"Todo", "In Progress", "Done"}) {
+		t.Fatalf("status field = %+v", status)
+	}
+
+	// The Status column wins over status:* labels.
+	brief := BuildBoardStatusBriefFromSnapshot(snap, nil)
+	var pullable []string
+	for _, item := range brief.Pullable {
+		pullable = append(pullable, item.ID)
+	}
+	if !reflect.DeepEqual(pullable, []string{"gh-draft:DI_3", "gh:acme/api#1"}) || len(brief.Untriaged) != 0 {
+		t.Fatalf("pullable = %v, untriaged = %+v", pullable, brief.Untriaged)
+	}
+}
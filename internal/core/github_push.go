@@ -0,0 +1,485 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
+
+// The managed section PushGitHub writes into issue bodies. Everything between
+// the markers belongs to DepViz and is rewritten on every push; sync ignores it
+// so published edges are not read back as inferred ones.
+const (
+	githubDependenciesStart = "<!-- depviz:dependencies:start -->"
+	githubDependenciesEnd   = "<!-- depviz:dependencies:end -->"
+)
+
+// ErrGitHubNativeUnsupported is returned by GitHubIssueWriter.AddBlockedBy when
+// the repo has no native issue dependencies.
+var ErrGitHubNativeUnsupported = errors.New("github issue dependencies are not available")
+
+// GitHubIssueWriter reads and edits issues for PushGitHub. GitHubRESTFetcher
+// implements it.
+type GitHubIssueWriter interface {
+	IssueBody(ctx context.Context, repo string, number int) (string, error)
+	SetIssueBody(ctx context.Context, repo string, number int, body string) error
+	// BlockedBy returns the node IDs of an issue's native blockers.
+	BlockedBy(ctx context.Context, repo string, number int) ([]string, error)
+	// AddBlockedBy records a native "blocked by" relation.
+	AddBlockedBy(ctx context.Context, repo string, number int, blockerRepo string, blockerNumber int) error
+	// CanPush reports whether the token may edit a repo's issues.
+	CanPush(ctx context.Context, repo string) (bool, error)
+}
+
+type GitHubPushOptions struct {
+	Board string
+	// DryRun computes the changes without writing anything.
+	DryRun bool
+	// Native records GitHub-to-GitHub blockers as native blocked-by relations,
+	// falling back to the managed section where the API is unavailable.
+	Native bool
+	// Writer defaults to the REST API with a token from github.Token.
+	Writer GitHubIssueWriter
+}
+
+// GitHubPushChange is what a push does, or would do, to one issue: lines
+// removed from and added to its managed section, and native relations added.
+type GitHubPushChange struct {
+	NodeID  string   `json:"node_id"`
+	Repo    string   `json:"repo"`
+	Number  int      `json:"number"`
+	Removed []string `json:"removed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Native  []string `json:"native,omitempty"`
+}
+
+type GitHubPushResult struct {
+	Board     string             `json:"board"`
+	DryRun    bool               `json:"dry_run"`
+	Changes   []GitHubPushChange `json:"changes"`
+	Unchanged int                `json:"unchanged"`
+	// Skipped are the issues outside the board's repos that the token cannot
+	// edit, such as upstream issues a local card is blocked by.
+	Skipped []string `json:"skipped,omitempty"`
+	// Failed are the issues GitHub refused; the others are pushed anyway.
+	Failed []GitHubPushFailure `json:"failed,omitempty"`
+}
+
+type GitHubPushFailure struct {
+	NodeID string `json:"node_id"`
+	Error  string `json:"error"`
+}
+
+var githubNodeIDRE = regexp.MustCompile(`^gh:([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)([#!])([0-9]+)$`)
+
+func parseGitHubNodeID(id string) (repo string, number int, ok bool) {
+	m := githubNodeIDRE.FindStringSubmatch(id)
+	if m == nil {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(m[3])
+	return m[1], number, err == nil
+}
+
+// githubPushEdge reports whether an edge is curated in DepViz and should be
+// published: a hard blocking edge whose authority is not a GitHub import.
+func githubPushEdge(e Edge) bool {
+	if strings.HasPrefix(strings.ToLower(e.Authority), "github") {
+		return false
+	}
+	blocked, _ := edgeBlockedAndBlocker(e)
+	return blocked != ""
+}
+
+// PushGitHub publishes a board's local blocking edges to the GitHub issues and
+// PRs on it. Each one gets a managed Dependencies section listing what blocks
+// it and what it blocks, or loses the section when it has none left. Native
+// relations are only ever added; DepViz cannot tell which ones people made.
+// Issues are only written in the repos the board syncs or the token can push
+// to. A failing issue is reported in the result and does not stop the others;
+// the error is for failures of the store itself.
+func PushGitHub(ctx context.Context, s *Store, opts GitHubPushOptions) (GitHubPushResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Writer == nil {
+		opts.Writer = GitHubRESTFetcher{Client: github.FromEnv(ctx)}
+	}
+	res := GitHubPushResult{Board: opts.Board, DryRun: opts.DryRun, Changes: []GitHubPushChange{}}
+	snap, err := s.Snapshot(ctx, opts.Board)
+	if err != nil {
+		return res, err
+	}
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	blockers := map[string][]string{}
+	blocks := map[string][]string{}
+	for _, e := range snap.Edges {
+		if !githubPushEdge(e) {
+			continue
+		}
+		blocked, blocker := edgeBlockedAndBlocker(e)
+		if _, ok := nodes[blocked]; !ok {
+			continue
+		}
+		if _, ok := nodes[blocker]; !ok {
+			continue
+		}
+		blockers[blocked] = appendUnique(blockers[blocked], blocker)
+		blocks[blocker] = appendUnique(blocks[blocker], blocked)
+	}
+	// Issues pushed to before may still carry a section after their last edge
+	// is gone, whether or not a sync has stored their new body since.
+	pushed, err := s.githubPushedNodes(ctx)
+	if err != nil {
+		return res, err
+	}
+	scope, err := s.githubPushScope(ctx, snap.Board)
+	if err != nil {
+		return res, err
+	}
+	canPush := map[string]error{}
+	for _, n := range snap.Nodes {
+		repo, number, ok := parseGitHubNodeID(n.ID)
+		if !ok {
+			continue
+		}
+		var stored struct {
+			Body string `json:"body"`
+		}
+		_ = json.Unmarshal([]byte(n.DataJSON), &stored)
+		if len(blockers[n.ID]) == 0 && len(blocks[n.ID]) == 0 && !pushed[n.ID] && !strings.Contains(stored.Body, githubDependenciesStart) {
+			continue
+		}
+		fail := func(err error) {
+			res.Failed = append(res.Failed, GitHubPushFailure{NodeID: n.ID, Error: err.Error()})
+		}
+		if !scope.HasRepo(repo) {
+			err, checked := canPush[repo]
+			if !checked {
+				var ok bool
+				if ok, err = opts.Writer.CanPush(ctx, repo); err == nil && !ok {
+					err = errGitHubPushSkipped
+				}
+				canPush[repo] = err
+			}
+			if errors.Is(err, errGitHubPushSkipped) {
+				res.Skipped = append(res.Skipped, n.ID)
+				continue
+			}
+			if err != nil {
+				fail(err)
+				continue
+			}
+		}
+		change := GitHubPushChange{NodeID: n.ID, Repo: repo, Number: number}
+		var lines []string
+		var native []string
+		for _, id := range blockers[n.ID] {
+			blockerRepo, blockerNumber, isGitHub := parseGitHubNodeID(id)
+			if opts.Native && isGitHub {
+				native = append(native, id)
+				continue
+			}
+			lines = append(lines, githubDependencyLine("Blocked by", repo, nodes[id], blockerRepo, blockerNumber, isGitHub))
+		}
+		if len(native) > 0 {
+			added, fallback, err := pushGitHubNative(ctx, opts, repo, number, native)
+			change.Native = added
+			if err != nil {
+				if len(added) > 0 {
+					res.Changes = append(res.Changes, change)
+				}
+				fail(err)
+				continue
+			}
+			for _, id := range fallback {
+				blockerRepo, blockerNumber, _ := parseGitHubNodeID(id)
+				lines = append(lines, githubDependencyLine("Blocked by", repo, nodes[id], blockerRepo, blockerNumber, true))
+			}
+		}
+		for _, id := range blocks[n.ID] {
+			blockedRepo, blockedNumber, isGitHub := parseGitHubNodeID(id)
+			if opts.Native && isGitHub {
+				continue
+			}
+			lines = append(lines, githubDependencyLine("Blocks", repo, nodes[id], blockedRepo, blockedNumber, isGitHub))
+		}
+		sort.Strings(lines)
+		body, err := opts.Writer.IssueBody(ctx, repo, number)
+		if err != nil {
+			if len(change.Native) > 0 {
+				res.Changes = append(res.Changes, change)
+			}
+			fail(err)
+			continue
+		}
+		current := githubDependencySectionLines(body)
+		change.Removed, change.Added = diffLines(current, lines)
+		if len(change.Removed) == 0 && len(change.Added) == 0 && len(change.Native) == 0 {
+			res.Unchanged++
+			continue
+		}
+		if opts.DryRun || (len(change.Removed) == 0 && len(change.Added) == 0) {
+			res.Changes = append(res.Changes, change)
+			continue
+		}
+		if err := opts.Writer.SetIssueBody(ctx, repo, number, replaceGitHubDependencySection(body, lines)); err != nil {
+			// Only the native relations, if any, made it.
+			if len(change.Native) > 0 {
+				change.Removed, change.Added = nil, nil
+				res.Changes = append(res.Changes, change)
+			}
+			fail(err)
+			continue
+		}
+		res.Changes = append(res.Changes, change)
+		payload, _ := json.Marshal(change)
+		if err := s.RecordEvent(ctx, "depviz.github_push.v1", n.ID, payload); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// errGitHubPushSkipped marks the repos PushGitHub leaves alone.
+var errGitHubPushSkipped = errors.New("github push: repo not writable")
+
+// githubPushScope is the scope a board last synced, or its configured one
+// when it never synced.
+func (s *Store) githubPushScope(ctx context.Context, board Board) (GitHubScope, error) {
+	state, err := s.githubSyncState(ctx, board.ID)
+	if err != nil {
+		return GitHubScope{}, err
+	}
+	if state.Scope != "" {
+		if scope, err := ParseGitHubScope(state.Scope); err == nil {
+			return scope, nil
+		}
+	}
+	scope, _ := BoardGitHubScope(board)
+	return scope, nil
+}
+
+// githubPushedNodes returns the nodes PushGitHub has written a section to.
+func (s *Store) githubPushedNodes(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT object_id FROM events WHERE type = ?`, "depviz.github_push.v1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pushed := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		pushed[id] = true
+	}
+	return pushed, rows.Err()
+}
+
+// pushGitHubNative adds the missing native blocked-by relations of an issue.
+// When the repo does not support them, every blocker falls back to the
+// managed section.
+func pushGitHubNative(ctx context.Context, opts GitHubPushOptions, repo string, number int, blockers []string) (added, fallback []string, err error) {
+	existing, err := opts.Writer.BlockedBy(ctx, repo, number)
+	if errors.Is(err, ErrGitHubNativeUnsupported) {
+		return nil, blockers, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	have := map[string]bool{}
+	for _, id := range existing {
+		have[id] = true
+	}
+	for _, id := range blockers {
+		if have[id] {
+			continue
+		}
+		if !opts.DryRun {
+			blockerRepo, blockerNumber, _ := parseGitHubNodeID(id)
+			err := opts.Writer.AddBlockedBy(ctx, repo, number, blockerRepo, blockerNumber)
+			if errors.Is(err, ErrGitHubNativeUnsupported) {
+				fallback = append(fallback, id)
+				continue
+			}
+			if err != nil {
+				return added, nil, err
+			}
+		}
+		added = append(added, id)
+	}
+	return added, fallback, nil
+}
+
+// githubDependencyLine renders one task-list line, checked once the other side
+// is closed. GitHub refs are short within the same repo; local cards are
+// listed by title since GitHub cannot link to them.
+func githubDependencyLine(verb, repo string, other Node, otherRepo string, otherNumber int, isGitHub bool) string {
+	box := "[ ]"
+	if other.IsClosed() {
+		box = "[x]"
+	}
+	ref := fmt.Sprintf("%s (DepViz `%s`)", strings.TrimSpace(other.Title), other.ID)
+	if isGitHub {
+		ref = fmt.Sprintf("%s#%d", otherRepo, otherNumber)
+		if otherRepo == repo {
+			ref = fmt.Sprintf("#%d", otherNumber)
+		}
+	}
+	return fmt.Sprintf("- %s %s %s", box, verb, ref)
+}
+
+// githubDependencySection splits a body around its managed section. ok is
+// false when there is none.
+func githubDependencySection(body string) (before, section, after string, ok bool) {
+	start := strings.Index(body, githubDependenciesStart)
+	if start < 0 {
+		return body, "", "", false
+	}
+	end := strings.Index(body[start:], githubDependenciesEnd)
+	if end < 0 {
+		return body, "", "", false
+	}
+	end += start
+	return body[:start], body[start+len(githubDependenciesStart) : end], body[end+len(githubDependenciesEnd):], true
+}
+
+func githubDependencySectionLines(body string) []string {
+	_, section, _, ok := githubDependencySection(body)
+	if !ok {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(section, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "- ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// stripGitHubDependencySection drops the managed section from a body.
+func stripGitHubDependencySection(body string) string {
+	before, _, after, ok := githubDependencySection(body)
+	if !ok {
+		return body
+	}
+	return before + after
+}
+
+// replaceGitHubDependencySection writes lines into the managed section,
+// appending one when the body has none and removing it when lines is empty.
+func replaceGitHubDependencySection(body string, lines []string) string {
+	before, _, after, ok := githubDependencySection(body)
+	if !ok {
+		before, after = body, ""
+	}
+	rest := strings.TrimRight(before, "\n") + after
+	if len(lines) == 0 {
+		return strings.TrimRight(rest, "\n")
+	}
+	section := githubDependenciesStart + "\n### Dependencies\n\n" + strings.Join(lines, "\n") +
+		"\n\n<sub>Managed by DepViz; edits between these markers are overwritten.</sub>\n" + githubDependenciesEnd
+	if !ok {
+		if strings.TrimSpace(rest) == "" {
+			return section
+		}
+		return strings.TrimRight(rest, "\n") + "\n\n" + section
+	}
+	return strings.TrimRight(before, "\n") + "\n\n" + section + after
+}
+
+func diffLines(old, new []string) (removed, added []string) {
+	have := map[string]bool{}
+	for _, line := range old {
+		have[line] = true
+	}
+	want := map[string]bool{}
+	for _, line := range new {
+		want[line] = true
+		if !have[line] {
+			added = append(added, line)
+		}
+	}
+	for _, line := range old {
+		if !want[line] {
+			removed = append(removed, line)
+		}
+	}
+	return removed, added
+}
+
+func (f GitHubRESTFetcher) IssueBody(ctx context.Context, repo string, number int) (string, error) {
+	var issue struct {
+		Body string `json:"body"`
+	}
+	if err := f.Client.Get(ctx, fmt.Sprintf("/repos/%s/issues/%d", repo, number), &issue); err != nil {
+		return "", err
+	}
+	return issue.Body, nil
+}
+
+func (f GitHubRESTFetcher) SetIssueBody(ctx context.Context, repo string, number int, body string) error {
+	_, err := f.Client.Do(ctx, github.Request{Method: http.MethodPatch, Path: fmt.Sprintf("/repos/%s/issues/%d", repo, number), Body: map[string]string{"body": body}}, nil)
+	return err
+}
+
+func (f GitHubRESTFetcher) CanPush(ctx context.Context, repo string) (bool, error) {
+	var r struct {
+		Permissions struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	if err := f.Client.Get(ctx, "/repos/"+repo, &r); err != nil {
+		return false, err
+	}
+	return r.Permissions.Push, nil
+}
+
+func (f GitHubRESTFetcher) BlockedBy(ctx context.Context, repo string, number int) ([]string, error) {
+	issues, err := github.List[restIssue](ctx, f.Client, fmt.Sprintf("/repos/%s/issues/%d/dependencies/blocked_by", repo, number), 0)
+	if err != nil {
+		return nil, githubNativeError(err)
+	}
+	out := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		item := issue.item(issue.repoFullName())
+		marker, _ := githubItemMarker(item)
+		out = append(out, fmt.Sprintf("gh:%s%s%d", item.Repo, marker, item.Number))
+	}
+	return out, nil
+}
+
+func (f GitHubRESTFetcher) AddBlockedBy(ctx context.Context, repo string, number int, blockerRepo string, blockerNumber int) error {
+	var blocker struct {
+		ID int64 `json:"id"`
+	}
+	if err := f.Client.Get(ctx, fmt.Sprintf("/repos/%s/issues/%d", blockerRepo, blockerNumber), &blocker); err != nil {
+		return err
+	}
+	_, err := f.Client.Do(ctx, github.Request{Method: http.MethodPost, Path: fmt.Sprintf("/repos/%s/issues/%d/dependencies/blocked_by", repo, number), Body: map[string]int64{"issue_id": blocker.ID}}, nil)
+	return githubNativeError(err)
+}
+
+// githubNativeError maps the 404 of a repo without issue dependencies to
+// ErrGitHubNativeUnsupported.
+func githubNativeError(err error) error {
+	var apiErr *github.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrGitHubNativeUnsupported, apiErr.Message)
+	}
+	return err
+}
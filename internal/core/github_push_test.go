@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type fakeGitHubWriter struct {
+	bodies      map[string]string
+	native      map[string][]string
+	unsupported map[string]bool
+	writable    map[string]bool
+	refused     map[string]error
+	writes      int
+}
+
+func (f *fakeGitHubWriter) IssueBody(_ context.Context, repo string, number int) (string, error) {
+	return f.bodies[fmt.Sprintf("%s#%d", repo, number)], nil
+}
+
+func (f *fakeGitHubWriter) SetIssueBody(_ context.Context, repo string, number int, body string) error {
+	if err := f.refused[fmt.Sprintf("%s#%d", repo, number)]; err != nil {
+		return err
+	}
+	f.writes++
+	f.bodies[fmt.Sprintf("%s#%d", repo, number)] = body
+	return nil
+}
+
+func (f *fakeGitHubWriter) BlockedBy(_ context.Context, repo string, number int) ([]string, error) {
+	if f.unsupported[repo] {
+		return nil, ErrGitHubNativeUnsupported
+	}
+	return f.native[fmt.Sprintf("%s#%d", repo, number)], nil
+}
+
+func (f *fakeGitHubWriter) AddBlockedBy(_ context.Context, repo string, number int, blockerRepo string, blockerNumber int) error {
+	key := fmt.Sprintf("%s#%d", repo, number)
+	f.native[key] = append(f.native[key], fmt.Sprintf("gh:%s#%d", blockerRepo, blockerNumber))
+	return nil
+}
+
+func (f *fakeGitHubWriter) CanPush(_ context.Context, repo string) (bool, error) {
+	return f.writable[repo], nil
+}
+
+func TestPushGitHubManagesDependencySection(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fetcher := &fakeGitHubFetcher{repos: map[string][]GitHubItem{
+		"moul/depviz": {
+			{Repo: "moul/depviz", Number: 1, Title: "Ship", State: "OPEN"},
+			{Repo: "moul/depviz", Number: 2, Title: "Schema", State: "CLOSED"},
+		},
+		"moul/other": {{Repo: "moul/other", Number: 3, Title: "API", State: "OPEN", Body: "blocked by moul/depviz#1"}},
+	}}
+	if _, err := SyncGitHub(ctx, s, GitHubSyncOptions{Scope: "repo:moul/depviz repo:moul/other", Fetcher: fetcher}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertNode(ctx, Node{ID: "note:design", Kind: "note", Title: "Design review", State: "open"}); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := s.AddEdge(ctx, DefaultBoardID, "gh:moul/depviz#1", "gh:moul/depviz#2", "blocked_by", "user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	design, err := s.AddEdge(ctx, DefaultBoardID, "note:design", "gh:moul/depviz#1", "blocks", "local", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := &fakeGitHubWriter{bodies: map[string]string{"moul/depviz#1": "Ship it.", "moul/depviz#2": ""}}
+
+	dry, err := PushGitHub(ctx, s, GitHubPushOptions{DryRun: true, Writer: writer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.writes != 0 {
+		t.Fatalf("dry run wrote %d bodies", writer.writes)
+	}
+	got := map[string][]string{}
+	for _, c := range dry.Changes {
+		got[c.NodeID] = c.Added
+	}
+	want := map[string][]string{
+		"gh:moul/depviz#1": {"- [ ] Blocked by Design review (DepViz `note:design`)", "- [x] Blocked by #2"},
+		"gh:moul/depviz#2": {"- [ ] Blocks #1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dry run changes = %v, want %v (the inferred moul/other edge stays out)", got, want)
+	}
+
+	if _, err := PushGitHub(ctx, s, GitHubPushOptions{Writer: writer}); err != nil {
+		t.Fatal(err)
+	}
+	body := writer.bodies["moul/depviz#1"]
+	if !strings.HasPrefix(body, "Ship it.\n\n"+githubDependenciesStart) || !strings.HasSuffix(body, githubDependenciesEnd) {
+		t.Fatalf("body = %q", body)
+	}
+	if edges := extractDependencyEdges("moul/depviz", "gh:moul/depviz#1", body); len(edges) != 0 {
+		t.Fatalf("sync would read the managed section back as %+v", edges)
+	}
+	again, err := PushGitHub(ctx, s, GitHubPushOptions{Writer: writer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Changes) != 0 || again.Unchanged != 2 {
+		t.Fatalf("second push = %+v, want no changes", again)
+	}
+
+	// The stored body is what tells a push to revisit an issue whose last
+	// edge is gone.
+	if err := s.DeleteEdge(ctx, design.ID); err != nil {
+		t.Fatal(err)
+	}
+	fetcher.repos["moul/depviz"][0].Body = body
+	if _, err := SyncGitHub(ctx, s, GitHubSyncOptions{Scope: "repo:moul/depviz repo:moul/other", Fetcher: fetcher, Full: true}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := PushGitHub(ctx, s, GitHubPushOptions{Writer: writer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Changes) != 1 || !reflect.DeepEqual(res.Changes[0].Removed, []string{"- [ ] Blocked by Design review (DepViz `note:design`)"}) {
+		t.Fatalf("push after delete = %+v", res)
+	}
+
+	// Issues pushed to before are revisited once their last edge is gone,
+	// even when no sync has stored the section since.
+	if err := s.DeleteEdge(ctx, schema.ID); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = PushGitHub(ctx, s, GitHubPushOptions{Writer: writer}); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Changes) != 2 {
+		t.Fatalf("push after deleting every edge = %+v, want both sections removed", res)
+	}
+	for key, body := range writer.bodies {
+		if strings.Contains(body, githubDependenciesStart) {
+			t.Fatalf("%s body = %q, want no section", key, body)
+		}
+	}
+}
+
+func TestPushGitHubNativeFallsBackToSection(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fetcher := &fakeGitHubFetcher{repos: map[string][]GitHubItem{
+		"moul/depviz": {{Repo: "moul/depviz", Number: 1, State: "OPEN"}, {Repo: "moul/depviz", Number: 2, State: "OPEN"}},
+		"moul/legacy": {{Repo: "moul/legacy", Number: 3, State: "OPEN"}},
+	}}
+	if _, err := SyncGitHub(ctx, s, GitHubSyncOptions{Scope: "repo:moul/depviz repo:moul/legacy", Fetcher: fetcher}); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range [][2]string{{"gh:moul/depviz#1", "gh:moul/depviz#2"}, {"gh:moul/legacy#3", "gh:moul/depviz#1"}} {
+		if _, err := s.AddEdge(ctx, DefaultBoardID, e[0], e[1], "blocked_by", "user", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer := &fakeGitHubWriter{bodies: map[string]string{}, native: map[string][]string{}, unsupported: map[string]bool{"moul/legacy": true}}
+	if _, err := PushGitHub(ctx, s, GitHubPushOptions{Native: true, Writer: writer}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(writer.native, map[string][]string{"moul/depviz#1": {"gh:moul/depviz#2"}}) {
+		t.Fatalf("native relations = %v", writer.native)
+	}
+	if lines := githubDependencySectionLines(writer.bodies["moul/legacy#3"]); !reflect.DeepEqual(lines, []string{"- [ ] Blocked by moul/depviz#1"}) {
+		t.Fatalf("legacy section = %v", lines)
+	}
+	if _, ok := writer.bodies["moul/depviz#1"]; ok {
+		t.Fatalf("native-only issue got a section: %q", writer.bodies["moul/depviz#1"])
+	}
+}
+
+func TestPushGitHubSkipsUpstreamAndKeepsGoingOnFailures(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fetcher := &fakeGitHubFetcher{repos: map[string][]GitHubItem{
+		"moul/depviz": {{Repo: "moul/depviz", Number: 1, State: "OPEN"}, {Repo: "moul/depviz", Number: 2, State: "OPEN"}, {Repo: "moul/depviz", Number: 3, State: "OPEN"}},
+	}}
+	if _, err := SyncGitHub(ctx, s, GitHubSyncOptions{Scope: "repo:moul/depviz", Fetcher: fetcher}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"gh:golang/go#1", "gh:moul/fork#4"} {
+		if err := s.UpsertNode(ctx, Node{ID: id, Kind: "issue", State: "open"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddNodeToBoard(ctx, DefaultBoardID, id, "issue", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range [][2]string{
+		{"gh:moul/depviz#1", "gh:golang/go#1"},
+		{"gh:moul/depviz#2", "gh:moul/fork#4"},
+		{"gh:moul/depviz#3", "gh:moul/depviz#2"},
+	} {
+		if _, err := s.AddEdge(ctx, DefaultBoardID, e[0], e[1], "blocked_by", "user", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer := &fakeGitHubWriter{
+		bodies:   map[string]string{},
+		writable: map[string]bool{"moul/fork": true},
+		refused:  map[string]error{"moul/depviz#2": fmt.Errorf("github: forbidden")},
+	}
+	res, err := PushGitHub(ctx, s, GitHubPushOptions{Writer: writer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Skipped, []string{"gh:golang/go#1"}) {
+		t.Fatalf("skipped = %v, want the upstream issue", res.Skipped)
+	}
+	if len(res.Failed) != 1 || res.Failed[0].NodeID != "gh:moul/depviz#2" || res.Failed[0].Error != "github: forbidden" {
+		t.Fatalf("failed = %+v", res.Failed)
+	}
+	var changed []string
+	for _, c := range res.Changes {
+		changed = append(changed, c.NodeID)
+	}
+	sort.Strings(changed)
+	if want := []string{"gh:moul/depviz#1", "gh:moul/depviz#3", "gh:moul/fork#4"}; !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changes = %v, want %v", changed, want)
+	}
+	if _, ok := writer.bodies["golang/go#1"]; ok {
+		t.Fatal("pushed to an upstream issue")
+	}
+}
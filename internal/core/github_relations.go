@@ -0,0 +1,388 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
+
+// GitHubRelationFetcher is implemented by fetchers that can read what GitHub
+// says about an item besides its body. Sync uses it when available.
+type GitHubRelationFetcher interface {
+	ItemRelations(ctx context.Context, item GitHubItem) (GitHubItemRelations, error)
+}
+
+// GitHubItemRelations is the conversation around an issue or PR.
+type GitHubItemRelations struct {
+	// Comments holds issue comments and, for PRs, reviews and review
+	// comments.
+	Comments []GitHubComment `json:"comments,omitempty"`
+	// Closes lists the issues a PR closes when merged, as GitHub links them
+	// from closing keywords or the development sidebar.
+	Closes []GitHubReference `json:"closes,omitempty"`
+	// CrossRefs lists the issues and PRs whose text mentions the item, from
+	// its timeline.
+	CrossRefs []GitHubReference `json:"cross_refs,omitempty"`
+}
+
+// GitHubComment is a comment or review; URL identifies it.
+type GitHubComment struct {
+	URL    string `json:"url"`
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	Review bool   `json:"review,omitempty"`
+}
+
+// GitHubReference points at another issue or PR.
+type GitHubReference struct {
+	Repo        string `json:"repo"`
+	Number      int    `json:"number"`
+	PullRequest bool   `json:"pull_request,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Author      string `json:"author,omitempty"`
+}
+
+func (r GitHubReference) nodeID() string {
+	marker, _ := githubItemMarker(GitHubItem{PullRequest: r.PullRequest})
+	return fmt.Sprintf("gh:%s%s%d", r.Repo, marker, r.Number)
+}
+
+// Sources of inferred GitHub edges, from the most to the least deliberate.
+const (
+	githubSourceClosing  = "closing"
+	githubSourceBody     = "body"
+	githubSourceComment  = "comment"
+	githubSourceReview   = "review"
+	githubSourceCrossRef = "cross_reference"
+)
+
+// githubSourceConfidence grades an inferred edge by where it was found. A
+// closing reference is linked by GitHub itself; a body is written to describe
+// the item, while comments are conversation and may be speculative or later
+// contradicted; a cross-reference only says another item mentioned this one.
+func githubSourceConfidence(source, kind string) float64 {
+	switch source {
+	case githubSourceClosing:
+		return 0.95
+	case githubSourceComment, githubSourceReview:
+		return relationConfidence(kind) - 0.15
+	case githubSourceCrossRef:
+		return 0.35
+	default:
+		return relationConfidence(kind)
+	}
+}
+
+// githubEdgeEvidence is the evidence of an inferred GitHub edge: the
+// extracted relation, its source, and where it was found: "body",
+// "closing", or the URL of a comment, review or referencing item.
+type githubEdgeEvidence struct {
+	ExtractedEdge
+	Source string `json:"source,omitempty"`
+	Origin string `json:"origin,omitempty"`
+	Author string `json:"author,omitempty"`
+	// Origins lists every place the edge was found when there are several.
+	// The evidence itself is the most confident of them, whose confidence
+	// the edge takes.
+	Origins []githubEdgeEvidence `json:"origins,omitempty"`
+}
+
+// origins returns each place stored evidence was found. Edges from before
+// origins were recorded count as "body".
+func (ev githubEdgeEvidence) origins() []githubEdgeEvidence {
+	if len(ev.Origins) > 0 {
+		return ev.Origins
+	}
+	ev.Origins = nil
+	if ev.Origin == "" {
+		ev.Source, ev.Origin = githubSourceBody, githubSourceBody
+	}
+	if ev.Confidence <= 0 {
+		ev.Confidence = githubSourceConfidence(ev.Source, ev.Kind)
+	}
+	return []githubEdgeEvidence{ev}
+}
+
+// mergeGitHubEvidence folds origins into the evidence of one edge.
+func mergeGitHubEvidence(origins []githubEdgeEvidence) githubEdgeEvidence {
+	best := origins[0]
+	for _, o := range origins[1:] {
+		if o.Confidence > best.Confidence {
+			best = o
+		}
+	}
+	best.Origins = nil
+	if len(origins) > 1 {
+		best.Origins = origins
+	}
+	return best
+}
+
+// applyGitHubRelations stores the edges found in an item's conversation.
+// Comment edges are replaced comment by comment and dropped with their
+// comment; closing references are replaced as a set; cross-references only
+// accumulate, since GitHub keeps them in the timeline for good.
+func (s *Store) applyGitHubRelations(ctx context.Context, boardID, nodeID string, item GitHubItem, rel GitHubItemRelations) (int, error) {
+	links := 0
+	comments := map[string]bool{}
+	for _, c := range rel.Comments {
+		if c.URL == "" {
+			continue
+		}
+		comments[c.URL] = true
+		source := githubSourceComment
+		if c.Review {
+			source = githubSourceReview
+		}
+		n, err := s.replaceGitHubInferredEdges(ctx, boardID, nodeID, githubEdgeEvidence{Source: source, Origin: c.URL, Author: c.Author}, extractDependencyEdges(item.Repo, nodeID, c.Body))
+		if err != nil {
+			return links, err
+		}
+		links += n
+	}
+	err := s.pruneGitHubInferredEdges(ctx, boardID, nodeID, func(ev githubEdgeEvidence) bool {
+		return (ev.Source == githubSourceComment || ev.Source == githubSourceReview) && !comments[ev.Origin]
+	})
+	if err != nil {
+		return links, err
+	}
+	if item.PullRequest {
+		var closes []ExtractedEdge
+		for _, ref := range rel.Closes {
+			closes = append(closes, ExtractedEdge{From: nodeID, To: ref.nodeID(), Kind: "closes", Line: ref.URL})
+		}
+		n, err := s.replaceGitHubInferredEdges(ctx, boardID, nodeID, githubEdgeEvidence{Source: githubSourceClosing, Origin: githubSourceClosing}, closes)
+		if err != nil {
+			return links, err
+		}
+		links += n
+	}
+	for _, ref := range rel.CrossRefs {
+		from := ref.nodeID()
+		if from == nodeID {
+			continue
+		}
+		ev := githubEdgeEvidence{
+			ExtractedEdge: ExtractedEdge{From: from, To: nodeID, Kind: "mentions", Line: ref.URL, Confidence: githubSourceConfidence(githubSourceCrossRef, "mentions")},
+			Source:        githubSourceCrossRef,
+			Origin:        ref.URL,
+			Author:        ref.Author,
+		}
+		if err := s.addGitHubInferredEdge(ctx, boardID, "github-inferred", ev); err != nil {
+			return links, err
+		}
+		links++
+	}
+	return links, nil
+}
+
+// replaceGitHubInferredEdges swaps the inferred edges a node got from one
+// origin for freshly extracted ones, so edited text drops relations it no
+// longer states. An edge also stated elsewhere, say in the body and in a
+// comment, keeps the other origins and only goes with the last of them.
+// Confidences are graded by the evidence's source.
+func (s *Store) replaceGitHubInferredEdges(ctx context.Context, boardID, nodeID string, origin githubEdgeEvidence, edges []ExtractedEdge) (int, error) {
+	if origin.Source == "" {
+		origin.Source = githubSourceBody
+	}
+	keep := map[string]bool{}
+	for _, e := range edges {
+		keep[stableID("edge", boardID, e.From, e.To, e.Kind)] = true
+	}
+	err := s.pruneGitHubInferredEdges(ctx, boardID, nodeID, func(ev githubEdgeEvidence) bool {
+		return ev.Origin == origin.Origin && !keep[stableID("edge", boardID, ev.From, ev.To, ev.Kind)]
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range edges {
+		e.Confidence = githubSourceConfidence(origin.Source, e.Kind)
+		ev := origin
+		ev.ExtractedEdge = e
+		if err := s.addGitHubInferredEdge(ctx, boardID, inferredAuthority(nodeID), ev); err != nil {
+			return 0, err
+		}
+	}
+	return len(edges), nil
+}
+
+// addGitHubInferredEdge stores an inferred edge found at one origin, next to
+// the other origins the edge already has.
+func (s *Store) addGitHubInferredEdge(ctx context.Context, boardID, authority string, ev githubEdgeEvidence) error {
+	origins := []githubEdgeEvidence{ev}
+	var stored, evidenceJSON string
+	err := s.db.QueryRowContext(ctx, `SELECT authority, evidence_json FROM edges WHERE id = ?`, stableID("edge", boardID, ev.From, ev.To, ev.Kind)).Scan(&stored, &evidenceJSON)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && stored == authority {
+		var old githubEdgeEvidence
+		_ = json.Unmarshal([]byte(evidenceJSON), &old)
+		for _, o := range old.origins() {
+			if o.Origin != ev.Origin {
+				origins = append(origins, o)
+			}
+		}
+	}
+	merged := mergeGitHubEvidence(origins)
+	_, err = s.AddEdgeWithConfidence(ctx, boardID, ev.From, ev.To, ev.Kind, authority, merged.Confidence, merged)
+	return err
+}
+
+// inferredAuthority is the authority of edges read from a node's text. The
+// same extraction serves GitLab, Gitea, Jira and Linear, whose edges are kept
+// apart so a GitHub push never treats them as its own.
+func inferredAuthority(nodeID string) string {
+	switch refPrefix(nodeID) {
+	case "gl:":
+		return "gitlab-inferred"
+	case "gitea:":
+		return "gitea-inferred"
+	case "jira:":
+		return "jira-inferred"
+	case "linear:":
+		return "linear-inferred"
+	default:
+		return "github-inferred"
+	}
+}
+
+// pruneGitHubInferredEdges drops the stale origins of the inferred edges
+// from a node, and deletes the edges left with none.
+func (s *Store) pruneGitHubInferredEdges(ctx context.Context, boardID, nodeID string, stale func(githubEdgeEvidence) bool) error {
+	authority := inferredAuthority(nodeID)
+	rows, err := s.db.QueryContext(ctx, `SELECT id, from_id, to_id, kind, evidence_json FROM edges WHERE scope_board_id = ? AND from_id = ? AND authority = ?`, boardID, nodeID, authority)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	var kept []githubEdgeEvidence
+	for rows.Next() {
+		var id, from, to, kind, evidenceJSON string
+		if err := rows.Scan(&id, &from, &to, &kind, &evidenceJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		var stored githubEdgeEvidence
+		_ = json.Unmarshal([]byte(evidenceJSON), &stored)
+		origins := stored.origins()
+		var left []githubEdgeEvidence
+		for _, o := range origins {
+			o.From, o.To, o.Kind = from, to, kind
+			if !stale(o) {
+				left = append(left, o)
+			}
+		}
+		switch {
+		case len(left) == 0:
+			ids = append(ids, id)
+		case len(left) < len(origins):
+			kept = append(kept, mergeGitHubEvidence(left))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.DeleteEdge(ctx, id); err != nil {
+			return err
+		}
+	}
+	for _, ev := range kept {
+		if _, err := s.AddEdgeWithConfidence(ctx, boardID, ev.From, ev.To, ev.Kind, authority, ev.Confidence, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restComment is an issue comment, review or review comment.
+type restComment struct {
+	HTMLURL string       `json:"html_url"`
+	Body    string       `json:"body"`
+	User    GitHubPerson `json:"user"`
+}
+
+// ItemRelations reads an item's comments and timeline cross-references and,
+// for a PR, its reviews, review comments and closing issue references.
+func (f GitHubRESTFetcher) ItemRelations(ctx context.Context, item GitHubItem) (GitHubItemRelations, error) {
+	var rel GitHubItemRelations
+	paths := []string{fmt.Sprintf("/repos/%s/issues/%d/comments", item.Repo, item.Number)}
+	if item.PullRequest {
+		paths = append(paths, fmt.Sprintf("/repos/%s/pulls/%d/reviews", item.Repo, item.Number), fmt.Sprintf("/repos/%s/pulls/%d/comments", item.Repo, item.Number))
+	}
+	for i, path := range paths {
+		comments, err := github.List[restComment](ctx, f.Client, path, 100)
+		if err != nil {
+			return rel, err
+		}
+		for _, c := range comments {
+			if strings.TrimSpace(c.Body) != "" {
+				rel.Comments = append(rel.Comments, GitHubComment{URL: c.HTMLURL, Author: c.User.Login, Body: c.Body, Review: i > 0})
+			}
+		}
+	}
+
+	type timelineEvent struct {
+		Event  string       `json:"event"`
+		Actor  GitHubPerson `json:"actor"`
+		Source struct {
+			Issue restIssue `json:"issue"`
+		} `json:"source"`
+	}
+	events, err := github.List[timelineEvent](ctx, f.Client, fmt.Sprintf("/repos/%s/issues/%d/timeline", item.Repo, item.Number), 100)
+	if err != nil {
+		return rel, err
+	}
+	for _, e := range events {
+		src := e.Source.Issue
+		if e.Event != "cross-referenced" || src.Number == 0 {
+			continue
+		}
+		repo := src.repoFullName()
+		if repo == "" {
+			repo = item.Repo
+		}
+		rel.CrossRefs = append(rel.CrossRefs, GitHubReference{Repo: repo, Number: src.Number, PullRequest: src.PullRequest.URL != "", URL: src.HTMLURL, Author: e.Actor.Login})
+	}
+
+	if !item.PullRequest {
+		return rel, nil
+	}
+	owner, name, _ := strings.Cut(item.Repo, "/")
+	var out struct {
+		Repository struct {
+			PullRequest struct {
+				ClosingIssuesReferences struct {
+					Nodes []struct {
+						Number     int    `json:"number"`
+						URL        string `json:"url"`
+						Repository struct {
+							NameWithOwner string `json:"nameWithOwner"`
+						} `json:"repository"`
+					} `json:"nodes"`
+				} `json:"closingIssuesReferences"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	const query = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      closingIssuesReferences(first: 50) { nodes { number url repository { nameWithOwner } } }
+    }
+  }
+}`
+	if err := f.Client.GraphQL(ctx, query, map[string]any{"owner": owner, "name": name, "number": item.Number}, &out); err != nil {
+		return rel, err
+	}
+	for _, n := range out.Repository.PullRequest.ClosingIssuesReferences.Nodes {
+		rel.Closes = append(rel.Closes, GitHubReference{Repo: n.Repository.NameWithOwner, Number: n.Number, URL: n.URL})
+	}
+	return rel, nil
+}
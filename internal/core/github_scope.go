@@ -0,0 +1,283 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GitHubScope is what a board syncs from GitHub, parsed from Board.ScopeQuery.
+// The query is a space-separated list of GitHub search terms where repo:,
+// org: (or owner:/user:), label: and milestone: are understood by DepViz,
+// "my-work" means issues involving the signed-in user, and any other term is
+// passed to the GitHub search API as is:
+//
+//	repo:moul/depviz repo:moul/depviz-web label:"needs review" is:open
+type GitHubScope struct {
+	Repos     []string `json:"repos,omitempty"`
+	Orgs      []string `json:"orgs,omitempty"`
+	MyWork    bool     `json:"my_work,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Milestone string   `json:"milestone,omitempty"`
+	Terms     []string `json:"terms,omitempty"`
+}
+
+var (
+	githubScopeRepoRE  = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	githubScopeOwnerRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// ParseGitHubScope reads a scope query. Values may be quoted to hold spaces,
+// and repo:, org: and label: accept comma-separated lists.
+func ParseGitHubScope(query string) (GitHubScope, error) {
+	var scope GitHubScope
+	tokens, err := splitGitHubScope(query)
+	if err != nil {
+		return GitHubScope{}, err
+	}
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token, ":")
+		key = strings.ToLower(key)
+		value = strings.Trim(value, `"`)
+		switch {
+		case strings.EqualFold(token, "my-work"):
+			scope.MyWork = true
+		case ok && key == "repo":
+			for _, repo := range strings.Split(value, ",") {
+				if repo = strings.TrimSpace(repo); !githubScopeRepoRE.MatchString(repo) {
+					return GitHubScope{}, fmt.Errorf("invalid repo %q in scope: use owner/name", repo)
+				}
+				scope.Repos = appendUnique(scope.Repos, repo)
+			}
+		case ok && (key == "org" || key == "owner" || key == "user"):
+			for _, owner := range strings.Split(value, ",") {
+				if owner = strings.TrimSpace(owner); !githubScopeOwnerRE.MatchString(owner) {
+					return GitHubScope{}, fmt.Errorf("invalid owner %q in scope", owner)
+				}
+				scope.Orgs = appendUnique(scope.Orgs, owner)
+			}
+		case ok && key == "label" && value != "":
+			scope.Labels = appendUnique(scope.Labels, value)
+		case ok && key == "milestone" && value != "":
+			scope.Milestone = value
+		default:
+			scope.Terms = append(scope.Terms, token)
+		}
+	}
+	return scope, nil
+}
+
+// BoardGitHubScope returns the scope of a board: its ScopeQuery, or for boards
+// created before scope queries the repo, owner and preset of its config.
+func BoardGitHubScope(board Board) (GitHubScope, error) {
+	scope, err := ParseGitHubScope(board.ScopeQuery)
+	if err != nil {
+		return GitHubScope{}, err
+	}
+	if scope.IsZero() {
+		var cfg struct {
+			Repo   string `json:"repo"`
+			Owner  string `json:"owner"`
+			Preset string `json:"preset"`
+		}
+		if board.ConfigJSON != "" && json.Unmarshal([]byte(board.ConfigJSON), &cfg) == nil {
+			switch {
+			case githubScopeRepoRE.MatchString(strings.TrimSpace(cfg.Repo)):
+				scope.Repos = []string{strings.TrimSpace(cfg.Repo)}
+			case githubScopeOwnerRE.MatchString(strings.TrimSpace(cfg.Owner)):
+				scope.Orgs = []string{strings.TrimSpace(cfg.Owner)}
+			case strings.TrimSpace(cfg.Preset) == "my-work":
+				scope.MyWork = true
+			}
+		}
+	}
+	if scope.IsZero() {
+		return GitHubScope{}, fmt.Errorf("board %s has no GitHub scope; set one such as repo:owner/name, org:owner or my-work", board.ID)
+	}
+	return scope, nil
+}
+
+// IsZero reports an empty scope.
+func (sc GitHubScope) IsZero() bool {
+	return len(sc.Repos) == 0 && len(sc.Orgs) == 0 && !sc.MyWork && len(sc.Labels) == 0 && sc.Milestone == "" && len(sc.Terms) == 0
+}
+
+// NeedsSearch reports scopes that cannot be listed repo by repo and go
+// through the search API instead.
+func (sc GitHubScope) NeedsSearch() bool {
+	return sc.MyWork || len(sc.Labels) > 0 || sc.Milestone != "" || len(sc.Terms) > 0
+}
+
+// Matches reports whether an item falls in the scope, for routing webhooks
+// without a search. Repos and orgs must match, every label must be present,
+// and is:open, is:closed, is:merged, is:issue and is:pr terms are checked;
+// other terms are ignored. my-work depends on who is asking, so it never
+// matches: such boards only receive items they already hold.
+func (sc GitHubScope) Matches(item GitHubItem) bool {
+	if sc.MyWork || (len(sc.Repos) == 0 && len(sc.Orgs) == 0) {
+		return false
+	}
+	if !sc.HasRepo(item.Repo) {
+		return false
+	}
+	for _, label := range sc.Labels {
+		found := false
+		for _, l := range item.Labels {
+			found = found || strings.EqualFold(l, label)
+		}
+		if !found {
+			return false
+		}
+	}
+	if sc.Milestone != "" && !strings.EqualFold(sc.Milestone, item.Milestone) {
+		return false
+	}
+	for _, term := range sc.Terms {
+		switch strings.ToLower(term) {
+		case "is:open":
+			if !strings.EqualFold(item.State, "open") {
+				return false
+			}
+		case "is:closed":
+			if strings.EqualFold(item.State, "open") {
+				return false
+			}
+		case "is:merged":
+			if !item.Merged {
+				return false
+			}
+		case "is:issue":
+			if item.PullRequest {
+				return false
+			}
+		case "is:pr":
+			if !item.PullRequest {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HasRepo reports whether a repo is one of the scope's repos or belongs to
+// one of its orgs, whatever the other qualifiers.
+func (sc GitHubScope) HasRepo(repo string) bool {
+	owner, _, _ := strings.Cut(repo, "/")
+	for _, r := range sc.Repos {
+		if strings.EqualFold(r, repo) {
+			return true
+		}
+	}
+	for _, org := range sc.Orgs {
+		if strings.EqualFold(org, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// Owners returns the repo owners and orgs of the scope, in order, so callers
+// can pick credentials such as a GitHub App installation.
+func (sc GitHubScope) Owners() []string {
+	var out []string
+	for _, repo := range sc.Repos {
+		owner, _, _ := strings.Cut(repo, "/")
+		out = appendUnique(out, owner)
+	}
+	for _, org := range sc.Orgs {
+		out = appendUnique(out, org)
+	}
+	return out
+}
+
+// SearchQuery renders the scope as a GitHub issue search query. login is
+// required for my-work scopes.
+func (sc GitHubScope) SearchQuery(login string) (string, error) {
+	var parts []string
+	for _, repo := range sc.Repos {
+		parts = append(parts, "repo:"+repo)
+	}
+	for _, org := range sc.Orgs {
+		parts = append(parts, "org:"+org)
+	}
+	if sc.MyWork {
+		if login == "" {
+			return "", errors.New("github login is required for my-work sync")
+		}
+		parts = append(parts, "involves:"+login)
+	}
+	for _, label := range sc.Labels {
+		parts = append(parts, "label:"+githubScopeQuote(label))
+	}
+	if sc.Milestone != "" {
+		parts = append(parts, "milestone:"+githubScopeQuote(sc.Milestone))
+	}
+	parts = append(parts, sc.Terms...)
+	return strings.Join(append(parts, "archived:false", "sort:updated-desc"), " "), nil
+}
+
+// String renders the scope back as a scope query.
+func (sc GitHubScope) String() string {
+	var parts []string
+	for _, repo := range sc.Repos {
+		parts = append(parts, "repo:"+repo)
+	}
+	for _, org := range sc.Orgs {
+		parts = append(parts, "org:"+org)
+	}
+	if sc.MyWork {
+		parts = append(parts, "my-work")
+	}
+	for _, label := range sc.Labels {
+		parts = append(parts, "label:"+githubScopeQuote(label))
+	}
+	if sc.Milestone != "" {
+		parts = append(parts, "milestone:"+githubScopeQuote(sc.Milestone))
+	}
+	return strings.Join(append(parts, sc.Terms...), " ")
+}
+
+func splitGitHubScope(query string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote in scope")
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func githubScopeQuote(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
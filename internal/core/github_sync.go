@@ -0,0 +1,404 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
+
+// GitHubSyncOptions selects what SyncGitHub imports and where.
+type GitHubSyncOptions struct {
+	// Board receives the cards; it defaults to the default board.
+	Board string
+	// Scope overrides the board's ScopeQuery, and Repo is shorthand for a
+	// single repo:owner/name scope.
+	Scope string
+	Repo  string
+	// Login is used for my-work scopes; when empty it is asked from the
+	// fetcher.
+	Login string
+	Limit int
+	// Full ignores the board's cursors and re-lists everything up to Limit.
+	Full bool
+	// BodiesOnly skips comments, reviews, closing references and timeline
+	// cross-references, which cost a few requests per changed item.
+	BodiesOnly bool
+	// Fetcher defaults to the REST API with a token from github.Token,
+	// revalidating the responses cached in the store by their ETags.
+	Fetcher GitHubFetcher
+	// Capabilities and Sync are recorded on each repo's source, and Mode on the
+	// sync log.
+	Capabilities string
+	Sync         string
+	Mode         string
+	// Progress, when set, is told about each fetch and imported card.
+	Progress func(done, total int, message string)
+}
+
+// GitHubSyncResult counts what a sync imported. Unchanged items were fetched
+// but skipped because their updated_at matched the last sync.
+type GitHubSyncResult struct {
+	Scope       string          `json:"scope"`
+	Incremental bool            `json:"incremental"`
+	Items       int             `json:"items"`
+	Unchanged   int             `json:"unchanged"`
+	Links       int             `json:"links"`
+	RateLimit   GitHubRateLimit `json:"rate_limit"`
+}
+
+// GitHubRateLimit is the core API quota reported by GitHub.
+type GitHubRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// GitHubRateLimiter is implemented by fetchers that can report the quota left
+// after a sync.
+type GitHubRateLimiter interface {
+	RateLimit(ctx context.Context) (GitHubRateLimit, error)
+}
+
+// githubSyncState is what a board remembers between syncs: the scope it last
+// synced and, per repo (or "search"), the newest updated_at seen. A different
+// scope invalidates the cursors, since they only cover what the old scope
+// matched.
+type githubSyncState struct {
+	Scope   string               `json:"scope"`
+	Cursors map[string]time.Time `json:"cursors"`
+}
+
+const githubSearchCursor = "search"
+
+// SyncGitHub imports a board's GitHub scope. Plain repo and org scopes are
+// listed repo by repo; scopes with my-work, labels, a milestone or free terms
+// go through issue search. A first sync imports the most recently updated
+// items, up to Limit, and leaves older ones out. Later syncs fetch only items
+// updated since the board's cursors, oldest first, so one cut short by Limit
+// resumes where it stopped. Relations are read from bodies and, when the
+// fetcher supports it, from the conversation around each changed item. Every
+// run is recorded in sync_logs.
+func SyncGitHub(ctx context.Context, s *Store, opts GitHubSyncOptions) (GitHubSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Mode == "" {
+		opts.Mode = "rest"
+	}
+	if opts.Fetcher == nil {
+		cache, err := s.LocalGitHubResponseCache(ctx)
+		if err != nil {
+			return GitHubSyncResult{}, err
+		}
+		client := github.FromEnv(ctx)
+		client.Cache = cache
+		opts.Fetcher = GitHubRESTFetcher{Client: client}
+	}
+	log := SyncLog{ID: fmt.Sprintf("sync-%d", time.Now().UnixNano()), BoardID: opts.Board, StartedAt: formatTime(nowUTC()), Status: "running", Mode: opts.Mode}
+	_ = s.AddSyncLog(ctx, log)
+	res, err := syncGitHub(ctx, s, opts)
+	log.CompletedAt = formatTime(nowUTC())
+	log.ItemsSynced, log.EdgesSynced = res.Items, res.Links
+	if res.Incremental {
+		log.Mode += " incremental"
+	}
+	log.Status = "ok"
+	if err != nil {
+		log.Status, log.Error = "failed", err.Error()
+	}
+	if limiter, ok := opts.Fetcher.(GitHubRateLimiter); ok {
+		if rate, rateErr := limiter.RateLimit(ctx); rateErr == nil {
+			res.RateLimit = rate
+			log.RateLimitRemaining = rate.Remaining
+			if !rate.Reset.IsZero() {
+				log.RateLimitReset = formatTime(rate.Reset)
+			}
+		}
+	}
+	if logErr := s.AddSyncLog(ctx, log); err == nil && logErr != nil {
+		return res, logErr
+	}
+	return res, err
+}
+
+func syncGitHub(ctx context.Context, s *Store, opts GitHubSyncOptions) (GitHubSyncResult, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Capabilities == "" {
+		opts.Capabilities = `{"read":true,"write":"token"}`
+	}
+	if opts.Sync == "" {
+		opts.Sync = `{"mode":"rest"}`
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(int, int, string) {}
+	}
+	var scope GitHubScope
+	var err error
+	switch {
+	case opts.Repo != "":
+		scope, err = ParseGitHubScope("repo:" + opts.Repo)
+	case opts.Scope != "":
+		scope, err = ParseGitHubScope(opts.Scope)
+	default:
+		var board Board
+		board, err = s.board(ctx, opts.Board)
+		if err == nil {
+			scope, err = BoardGitHubScope(board)
+		}
+	}
+	if err != nil {
+		return GitHubSyncResult{}, err
+	}
+	result := GitHubSyncResult{Scope: scope.String()}
+
+	state, err := s.githubSyncState(ctx, opts.Board)
+	if err != nil {
+		return result, err
+	}
+	if opts.Full || state.Scope != result.Scope {
+		state = githubSyncState{Scope: result.Scope, Cursors: map[string]time.Time{}}
+	} else {
+		result.Incremental = true
+	}
+
+	type batch struct {
+		cursor string
+		items  []GitHubItem
+	}
+	var batches []batch
+	if scope.NeedsSearch() {
+		login := opts.Login
+		if scope.MyWork && login == "" {
+			if login, err = opts.Fetcher.Viewer(ctx); err != nil {
+				return result, err
+			}
+		}
+		query, err := scope.SearchQuery(login)
+		if err != nil {
+			return result, err
+		}
+		if since := state.Cursors[githubSearchCursor]; !since.IsZero() {
+			query = strings.Replace(query, "sort:updated-desc", "updated:>="+since.UTC().Format(time.RFC3339)+" sort:updated-asc", 1)
+		}
+		progress(0, 0, "searching "+query)
+		items, err := opts.Fetcher.SearchItems(ctx, query, opts.Limit)
+		if err != nil {
+			return result, err
+		}
+		batches = append(batches, batch{cursor: githubSearchCursor, items: items})
+	} else {
+		repos := append([]string(nil), scope.Repos...)
+		perRepo := opts.Limit
+		for _, owner := range scope.Orgs {
+			progress(0, 0, "fetching repos for "+owner)
+			orgRepos, err := opts.Fetcher.OrgRepos(ctx, owner, 20)
+			if err != nil {
+				return result, err
+			}
+			for _, repo := range orgRepos {
+				repos = appendUnique(repos, repo)
+			}
+			// Orgs spread the limit over their repos so one sync stays cheap.
+			perRepo = min(max(opts.Limit/max(1, len(orgRepos)), 5), 30)
+		}
+		fetched := 0
+		for _, repo := range repos {
+			progress(fetched, 0, "fetching "+repo)
+			items, err := opts.Fetcher.RepoItems(ctx, repo, state.Cursors[repo], perRepo)
+			if err != nil {
+				return result, err
+			}
+			fetched += len(items)
+			batches = append(batches, batch{cursor: repo, items: items})
+		}
+	}
+
+	total := 0
+	for _, b := range batches {
+		total += len(b.items)
+	}
+	sources := map[string]bool{}
+	for _, b := range batches {
+		for _, item := range b.items {
+			if item.Repo == "" || item.Number == 0 {
+				continue
+			}
+			if item.UpdatedAt.After(state.Cursors[b.cursor]) {
+				state.Cursors[b.cursor] = item.UpdatedAt
+			}
+			if !sources[item.Repo] {
+				sources[item.Repo] = true
+				if err := s.UpsertSource(ctx, Source{
+					ID:           "github:" + item.Repo,
+					Kind:         "github",
+					Name:         item.Repo,
+					URL:          "https://github.com/" + item.Repo,
+					Capabilities: opts.Capabilities,
+					Sync:         opts.Sync,
+					UpdatedAt:    nowUTC(),
+				}); err != nil {
+					return result, err
+				}
+			}
+			unchanged, err := s.githubItemUnchanged(ctx, opts.Board, item)
+			if err != nil {
+				return result, err
+			}
+			if unchanged {
+				result.Unchanged++
+				continue
+			}
+			node, err := s.UpsertGitHubItem(ctx, opts.Board, item)
+			if err != nil {
+				return result, err
+			}
+			result.Items++
+			progress(result.Items+result.Unchanged, total, "")
+			links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges(item.Repo, node.ID, item.Body))
+			if err != nil {
+				return result, err
+			}
+			result.Links += links
+			if relFetcher, ok := opts.Fetcher.(GitHubRelationFetcher); ok && !opts.BodiesOnly {
+				rel, err := relFetcher.ItemRelations(ctx, item)
+				if err != nil {
+					return result, fmt.Errorf("relations of %s: %w", node.ID, err)
+				}
+				links, err := s.applyGitHubRelations(ctx, opts.Board, node.ID, item, rel)
+				if err != nil {
+					return result, err
+				}
+				result.Links += links
+			}
+		}
+	}
+	return result, s.saveGitHubSyncState(ctx, opts.Board, state)
+}
+
+// UpsertGitHubItem stores an issue or PR as a node, links it to its
+// github:owner/repo source with the item's updated_at as sync cursor, and adds
+// it to a board. The source must exist.
+func (s *Store) UpsertGitHubItem(ctx context.Context, boardID string, item GitHubItem) (Node, error) {
+	marker, kind := githubItemMarker(item)
+	id := fmt.Sprintf("gh:%s%s%d", item.Repo, marker, item.Number)
+	state := strings.ToLower(item.State)
+	if item.Merged {
+		state = "merged"
+	}
+	assignees := make([]string, 0, len(item.Assignees))
+	for _, a := range item.Assignees {
+		assignees = append(assignees, a.Login)
+	}
+	var author *GitHubPerson
+	if item.Author.Login != "" {
+		author = &item.Author
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":     "github",
+		"kind":       kind,
+		"repo":       item.Repo,
+		"number":     item.Number,
+		"labels":     item.Labels,
+		"assignees":  item.Assignees,
+		"author":     author,
+		"milestone":  item.Milestone,
+		"body":       item.Body,
+		"synced_at":  formatTime(nowUTC()),
+		"html_url":   item.HTMLURL,
+		"api_url":    item.APIURL,
+		"repository": item.Repo,
+		"draft":      item.Draft,
+	})
+	updated := item.UpdatedAt
+	if updated.IsZero() {
+		updated = nowUTC()
+	}
+	n := Node{
+		ID:        id,
+		Kind:      kind,
+		Title:     item.Title,
+		State:     state,
+		Owner:     first(assignees),
+		DataJSON:  string(payload),
+		UpdatedAt: updated,
+		URL:       item.HTMLURL,
+	}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return Node{}, err
+	}
+	sourceID, externalID := "github:"+item.Repo, fmt.Sprintf("%s%d", marker, item.Number)
+	if err := s.UpsertSourceRef(ctx, id, sourceID, externalID, item.HTMLURL); err != nil {
+		return Node{}, err
+	}
+	if !item.UpdatedAt.IsZero() {
+		if _, err := s.db.ExecContext(ctx, `UPDATE source_refs SET sync_cursor = ? WHERE source_id = ? AND external_id = ?`,
+			formatTime(item.UpdatedAt), sourceID, externalID); err != nil {
+			return Node{}, err
+		}
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, id, kind, ""); err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+func githubItemMarker(item GitHubItem) (marker, kind string) {
+	if item.PullRequest {
+		return "!", "pr"
+	}
+	return "#", "issue"
+}
+
+// githubItemUnchanged reports an item already on the board whose source ref
+// cursor matches its updated_at, so re-importing it would change nothing.
+func (s *Store) githubItemUnchanged(ctx context.Context, boardID string, item GitHubItem) (bool, error) {
+	if item.UpdatedAt.IsZero() {
+		return false, nil
+	}
+	marker, _ := githubItemMarker(item)
+	var cursor string
+	err := s.db.QueryRowContext(ctx, `SELECT sr.sync_cursor
+		FROM source_refs sr
+		JOIN board_items bi ON bi.node_id = sr.node_id AND bi.board_id = ?
+		WHERE sr.source_id = ? AND sr.external_id = ?`,
+		boardID, "github:"+item.Repo, fmt.Sprintf("%s%d", marker, item.Number)).Scan(&cursor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return cursor == formatTime(item.UpdatedAt), nil
+}
+
+func (s *Store) githubSyncState(ctx context.Context, boardID string) (githubSyncState, error) {
+	values, err := s.FieldValuesByKey(ctx, "", "board", githubSyncNamespace, "github")
+	if err != nil {
+		return githubSyncState{}, err
+	}
+	var state githubSyncState
+	if fv, ok := values[boardID]; ok {
+		_ = json.Unmarshal([]byte(fv.ValueJSON), &state)
+	}
+	if state.Cursors == nil {
+		state.Cursors = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+func (s *Store) saveGitHubSyncState(ctx context.Context, boardID string, state githubSyncState) error {
+	value, _ := json.Marshal(state)
+	return s.SetFieldValue(ctx, FieldValue{OwnerType: "board", OwnerID: boardID, Namespace: githubSyncNamespace, Key: "github", ValueJSON: string(value), Authority: "sync"})
+}
+
+// githubSyncNamespace keeps sync cursors apart from user-visible fields.
+const githubSyncNamespace = "depviz.sync"
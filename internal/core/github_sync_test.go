@@ -0,0 +1,339 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fakeGitHubFetcher struct {
+	repos    map[string][]GitHubItem
+	orgs     map[string][]string
+	searches []string
+	found    []GitHubItem
+	since    map[string]time.Time
+}
+
+func (f *fakeGitHubFetcher) RepoItems(_ context.Context, repo string, since time.Time, limit int) ([]GitHubItem, error) {
+	items, ok := f.repos[repo]
+	if !ok {
+		return nil, fmt.Errorf("404 Not Found: %s", repo)
+	}
+	if f.since == nil {
+		f.since = map[string]time.Time{}
+	}
+	f.since[repo] = since
+	var out []GitHubItem
+	for _, item := range items {
+		if !item.UpdatedAt.Before(since) {
+			out = append(out, item)
+		}
+	}
+	if since.IsZero() {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	}
+	return out[:min(limit, len(out))], nil
+}
+
+func (f *fakeGitHubFetcher) RateLimit(context.Context) (GitHubRateLimit, error) {
+	return GitHubRateLimit{Limit: 5000, Remaining: 4321}, nil
+}
+
+func (f *fakeGitHubFetcher) OrgRepos(_ context.Context, owner string, _ int) ([]string, error) {
+	return f.orgs[owner], nil
+}
+
+func (f *fakeGitHubFetcher) SearchItems(_ context.Context, query string, _ int) ([]GitHubItem, error) {
+	f.searches = append(f.searches, query)
+	return f.found, nil
+}
+
+func (f *fakeGitHubFetcher) Viewer(context.Context) (string, error) {
+	return "moul", nil
+}
+
+func TestParseGitHubScope(t *testing.T) {
+	scope, err := ParseGitHubScope(`repo:moul/depviz,moul/depviz-web org:berty label:"needs review" milestone:v4 is:open`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GitHubScope{
+		Repos:     []string{"moul/depviz", "moul/depviz-web"},
+		Orgs:      []string{"berty"},
+		Labels:    []string{"needs review"},
+		Milestone: "v4",
+		Terms:     []string{"is:open"},
+	}
+	if !reflect.DeepEqual(scope, want) {
+		t.Fatalf("scope = %+v, want %+v", scope, want)
+	}
+	if got := scope.Owners(); !reflect.DeepEqual(got, []string{"moul", "berty"}) {
+		t.Fatalf("owners = %v", got)
+	}
+	query, err := scope.SearchQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != `repo:moul/depviz repo:moul/depviz-web org:berty label:"needs review" milestone:v4 is:open archived:false sort:updated-desc` {
+		t.Fatalf("search query = %s", query)
+	}
+	if _, err := ParseGitHubScope("repo:depviz"); err == nil {
+		t.Fatal("repo without owner was accepted")
+	}
+	if _, err := BoardGitHubScope(Board{ID: "empty"}); err == nil {
+		t.Fatal("board without scope was accepted")
+	}
+	legacy, err := BoardGitHubScope(Board{ConfigJSON: `{"preset":"repo","repo":"moul/depviz"}`})
+	if err != nil || !reflect.DeepEqual(legacy.Repos, []string{"moul/depviz"}) {
+		t.Fatalf("legacy scope = %+v, %v", legacy, err)
+	}
+}
+
+func TestSyncGitHubUsesBoardScope(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	board, err := s.CreateBoardWithConfig(ctx, "Roadmap", "", "repo:moul/depviz org:berty", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := &fakeGitHubFetcher{
+		repos: map[string][]GitHubItem{
+			"moul/depviz":    {{Repo: "moul/depviz", Number: 1, Title: "Sync", State: "OPEN", Body: "depends on berty/berty#2"}},
+			"berty/berty":    {{Repo: "berty/berty", Number: 2, Title: "Protocol", State: "OPEN"}},
+			"berty/weshnet":  {{Repo: "berty/weshnet", Number: 3, Title: "Merge", State: "CLOSED", PullRequest: true, Merged: true}},
+			"moul/untouched": {{Repo: "moul/untouched", Number: 9}},
+		},
+		orgs: map[string][]string{"berty": {"berty/berty", "berty/weshnet"}},
+	}
+	res, err := SyncGitHub(ctx, s, GitHubSyncOptions{Board: board.ID, Fetcher: fetcher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 3 || res.Links != 1 || res.Scope != "repo:moul/depviz org:berty" {
+		t.Fatalf("result = %+v, want 3 items and 1 link", res)
+	}
+	snap, err := s.Snapshot(ctx, board.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]string{}
+	for _, n := range snap.Nodes {
+		states[n.ID] = n.State
+	}
+	want := map[string]string{"gh:moul/depviz#1": "open", "gh:berty/berty#2": "open", "gh:berty/weshnet!3": "merged"}
+	if !reflect.DeepEqual(states, want) {
+		t.Fatalf("board nodes = %v, want %v", states, want)
+	}
+	def, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(def.Nodes) != 0 {
+		t.Fatalf("default board got %d nodes, want none", len(def.Nodes))
+	}
+
+	if _, err := s.SetBoardScope(ctx, board.ID, "my-work label:bug"); err != nil {
+		t.Fatal(err)
+	}
+	fetcher.found = []GitHubItem{{Repo: "moul/other", Number: 5, Title: "Bug", State: "OPEN"}}
+	if res, err = SyncGitHub(ctx, s, GitHubSyncOptions{Board: board.ID, Fetcher: fetcher}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 1 || len(fetcher.searches) != 1 || fetcher.searches[0] != "involves:moul label:bug archived:false sort:updated-desc" {
+		t.Fatalf("search sync = %+v, queries %q", res, fetcher.searches)
+	}
+}
+
+func TestSyncGitHubIsIncremental(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetcher := &fakeGitHubFetcher{repos: map[string][]GitHubItem{
+		"moul/depviz": {
+			{Repo: "moul/depviz", Number: 1, Title: "Old", State: "open", UpdatedAt: t0},
+			{Repo: "moul/depviz", Number: 2, Title: "Newer", State: "open", UpdatedAt: t0.Add(time.Hour)},
+		},
+	}}
+	opts := GitHubSyncOptions{Repo: "moul/depviz", Fetcher: fetcher}
+	res, err := SyncGitHub(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Incremental || res.Items != 2 || res.RateLimit.Remaining != 4321 {
+		t.Fatalf("first sync = %+v, want full sync of 2 items", res)
+	}
+
+	// Nothing changed: only the item at the cursor comes back, and it is
+	// skipped.
+	res, err = SyncGitHub(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Incremental || res.Items != 0 || res.Unchanged != 1 || !fetcher.since["moul/depviz"].Equal(t0.Add(time.Hour)) {
+		t.Fatalf("second sync = %+v since %v, want one unchanged item since the cursor", res, fetcher.since["moul/depviz"])
+	}
+
+	fetcher.repos["moul/depviz"][0] = GitHubItem{Repo: "moul/depviz", Number: 1, Title: "Old, edited", State: "closed", UpdatedAt: t0.Add(2 * time.Hour)}
+	res, err = SyncGitHub(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 1 || res.Unchanged != 1 {
+		t.Fatalf("third sync = %+v, want the edited item only", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range snap.Nodes {
+		if n.ID == "gh:moul/depviz#1" && (n.Title != "Old, edited" || n.State != "closed") {
+			t.Fatalf("edited node = %+v", n)
+		}
+	}
+
+	if res, err = SyncGitHub(ctx, s, GitHubSyncOptions{Repo: "moul/depviz", Fetcher: fetcher, Full: true}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Incremental || !fetcher.since["moul/depviz"].IsZero() {
+		t.Fatalf("full sync = %+v since %v, want no cursor", res, fetcher.since["moul/depviz"])
+	}
+	logs, err := s.GetSyncLogs(ctx, DefaultBoardID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 4 {
+		t.Fatalf("sync logs = %+v, want 4 runs", logs)
+	}
+	for _, l := range logs {
+		if l.Status != "ok" || l.RateLimitRemaining != 4321 || l.CompletedAt == "" {
+			t.Fatalf("sync log = %+v, want a completed ok run with the rate limit", l)
+		}
+	}
+}
+
+func TestSyncGitHubFirstSyncTakesTheNewestItems(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := func(n int) GitHubItem {
+		return GitHubItem{Repo: "moul/depviz", Number: n, Title: fmt.Sprintf("Issue %d", n), State: "open", UpdatedAt: t0.Add(time.Duration(n) * time.Hour)}
+	}
+	fetcher := &fakeGitHubFetcher{repos: map[string][]GitHubItem{"moul/depviz": {issue(1), issue(2), issue(3)}}}
+	opts := GitHubSyncOptions{Repo: "moul/depviz", Fetcher: fetcher, Limit: 2}
+	ids := func() []string {
+		snap, err := s.Snapshot(ctx, DefaultBoardID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, n := range snap.Nodes {
+			out = append(out, n.ID)
+		}
+		sort.Strings(out)
+		return out
+	}
+	if _, err := SyncGitHub(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(); !reflect.DeepEqual(got, []string{"gh:moul/depviz#2", "gh:moul/depviz#3"}) {
+		t.Fatalf("first sync imported %v, want the two newest issues", got)
+	}
+
+	// Incremental syncs cut short by Limit resume from the cursor.
+	fetcher.repos["moul/depviz"] = append(fetcher.repos["moul/depviz"], issue(4), issue(5))
+	for _, want := range []int{1, 1} {
+		res, err := SyncGitHub(ctx, s, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Items != want {
+			t.Fatalf("incremental sync = %+v, want %d new item", res, want)
+		}
+	}
+	if got := ids(); len(got) != 4 || got[3] != "gh:moul/depviz#5" {
+		t.Fatalf("after incremental syncs = %v, want #2 to #5", got)
+	}
+}
+
+func TestGitHubScopeMatches(t *testing.T) {
+	scope, err := ParseGitHubScope(`repo:moul/depviz org:berty label:bug is:open`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		item GitHubItem
+		want bool
+	}{
+		{GitHubItem{Repo: "moul/depviz", State: "open", Labels: []string{"Bug"}}, true},
+		{GitHubItem{Repo: "berty/weshnet", State: "open", Labels: []string{"bug", "p1"}}, true},
+		{GitHubItem{Repo: "moul/other", State: "open", Labels: []string{"bug"}}, false},
+		{GitHubItem{Repo: "moul/depviz", State: "open"}, false},
+		{GitHubItem{Repo: "moul/depviz", State: "closed", Labels: []string{"bug"}}, false},
+	} {
+		if got := scope.Matches(tc.item); got != tc.want {
+			t.Errorf("Matches(%+v) = %v, want %v", tc.item, got, tc.want)
+		}
+	}
+	if (GitHubScope{MyWork: true}).Matches(GitHubItem{Repo: "moul/depviz"}) {
+		t.Fatal("my-work scope matched without a viewer")
+	}
+}
+
+type fakeGitHubRelationFetcher struct {
+	*fakeGitHubFetcher
+	relations map[string]GitHubItemRelations
+}
+
+func (f *fakeGitHubRelationFetcher) ItemRelations(_ context.Context, item GitHubItem) (GitHubItemRelations, error) {
+	return f.relations[fmt.Sprintf("%s#%d", item.Repo, item.Number)], nil
+}
+
+func TestSyncGitHubDropsRelationsOfDeletedComments(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fetcher := &fakeGitHubRelationFetcher{
+		fakeGitHubFetcher: &fakeGitHubFetcher{repos: map[string][]GitHubItem{
+			"moul/depviz": {{Repo: "moul/depviz", Number: 1, State: "OPEN", Body: "blocked by #2"}},
+		}},
+		relations: map[string]GitHubItemRelations{"moul/depviz#1": {Comments: []GitHubComment{
+			{URL: "https://github.com/moul/depviz/issues/1#issuecomment-1", Author: "octo", Body: "also depends on #3"},
+		}}},
+	}
+	opts := GitHubSyncOptions{Repo: "moul/depviz", Fetcher: fetcher, Full: true}
+	if res, err := SyncGitHub(ctx, s, opts); err != nil || res.Links != 2 {
+		t.Fatalf("sync = %+v, %v; want the body and comment links", res, err)
+	}
+	if res, err := SyncGitHub(ctx, s, GitHubSyncOptions{Repo: "moul/depviz", Fetcher: fetcher, Full: true, BodiesOnly: true}); err != nil || res.Links != 1 {
+		t.Fatalf("bodies-only sync = %+v, %v", res, err)
+	}
+	fetcher.relations = nil
+	if _, err := SyncGitHub(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Edges) != 1 || snap.Edges[0].ToID != "gh:moul/depviz#2" {
+		t.Fatalf("edges = %+v, want only the body relation", snap.Edges)
+	}
+}
@@ -1,6 +1,16 @@
 package core
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/github"
+)
 
 func TestExtractDependencyEdgesParsesRelationChunks(t *testing.T) {
 	body := `
@@ -72,3 +82,125 @@ func TestExtractDependencyEdgesResolvesRedirectGitHubHTMLLinks(t *testing.T) {
 		t.Fatalf("edge target = %s, want gh:alecthomas/chroma#1266", edges[0].To)
 	}
 }
+
+func TestGitHubRESTFetcherSyncsOrgScope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/octo/repos":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		case "/users/octo/repos":
+			fmt.Fprint(w, `[{"full_name":"octo/app"},{"full_name":"octo/old","archived":true}]`)
+		case "/repos/octo/app/issues":
+			if r.URL.Query().Get("state") != "all" {
+				t.Errorf("issues query = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[
+				{"number":1,"title":"Ship","state":"open","html_url":"https://github.com/octo/app/issues/1","body":"depends on !2","updated_at":"2026-01-02T00:00:00Z","labels":[{"name":"bug"}],"assignees":[{"login":"ada"}],"user":{"login":"bob"}},
+				{"number":2,"title":"Fix","state":"closed","updated_at":"2026-01-01T00:00:00Z","pull_request":{"url":"https://api.github.com/repos/octo/app/pulls/2","merged_at":"2026-01-01T00:00:00Z"}}
+			]`)
+		case "/repos/octo/app/issues/1/comments":
+			fmt.Fprint(w, `[{"html_url":"https://github.com/octo/app/issues/1#issuecomment-5","body":"also blocked by #3","user":{"login":"cy"}}]`)
+		case "/repos/octo/app/issues/1/timeline":
+			fmt.Fprint(w, `[{"event":"labeled"},{"event":"cross-referenced","actor":{"login":"dan"},"source":{"issue":{"number":7,"html_url":"https://github.com/octo/lib/issues/7","repository_url":"https://api.github.com/repos/octo/lib"}}}]`)
+		case "/repos/octo/app/pulls/2/reviews":
+			fmt.Fprint(w, `[{"html_url":"https://github.com/octo/app/pull/2#pullrequestreview-6","body":"","user":{"login":"ada"}}]`)
+		case "/repos/octo/app/issues/2/comments", "/repos/octo/app/pulls/2/comments", "/repos/octo/app/issues/2/timeline":
+			fmt.Fprint(w, `[]`)
+		case "/graphql":
+			fmt.Fprint(w, `{"data":{"repository":{"pullRequest":{"closingIssuesReferences":{"nodes":[{"number":1,"url":"https://github.com/octo/app/issues/1","repository":{"nameWithOwner":"octo/app"}}]}}}}}`)
+		case "/rate_limit":
+			fmt.Fprint(w, `{"resources":{"core":{"limit":60,"remaining":57,"reset":1700000000}}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := github.NewClient("")
+	client.BaseURL = srv.URL
+	res, err := SyncGitHub(ctx, s, GitHubSyncOptions{Scope: "org:octo", Fetcher: GitHubRESTFetcher{Client: client}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 2 || res.Links != 4 || res.RateLimit.Remaining != 57 {
+		t.Fatalf("result = %+v, want 2 items, 4 links and the rate limit", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]string{}
+	for _, n := range snap.Nodes {
+		states[n.ID] = n.State + "/" + n.Owner
+	}
+	want := map[string]string{"gh:octo/app#1": "open/ada", "gh:octo/app!2": "merged/", "gh:octo/app#3": "open/", "gh:octo/lib#7": "open/"}
+	if !reflect.DeepEqual(states, want) {
+		t.Fatalf("nodes = %v, want %v", states, want)
+	}
+	evidence := map[string]string{}
+	for _, e := range snap.Edges {
+		var ev githubEdgeEvidence
+		if err := json.Unmarshal([]byte(e.EvidenceJSON), &ev); err != nil {
+			t.Fatal(err)
+		}
+		evidence[e.FromID+" "+e.Kind+" "+e.ToID] = fmt.Sprintf("%s %s %s %.2f", ev.Source, ev.Origin, ev.Author, e.Confidence)
+	}
+	wantEvidence := map[string]string{
+		"gh:octo/app#1 blocked_by gh:octo/app!2": "body body  0.75",
+		"gh:octo/app#1 blocked_by gh:octo/app#3": "comment https://github.com/octo/app/issues/1#issuecomment-5 cy 0.60",
+		"gh:octo/app!2 closes gh:octo/app#1":     "closing closing  0.95",
+		"gh:octo/lib#7 mentions gh:octo/app#1":   "cross_reference https://github.com/octo/lib/issues/7 dan 0.35",
+	}
+	if !reflect.DeepEqual(evidence, wantEvidence) {
+		t.Fatalf("edges = %v, want %v", evidence, wantEvidence)
+	}
+}
+
+func TestSyncGitHubRevalidatesCachedResponsesByDefault(t *testing.T) {
+	revalidated := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/app/issues":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `[{"number":1,"title":"Ship","state":"open","updated_at":"2026-01-02T00:00:00Z"}]`)
+		case "/rate_limit":
+			fmt.Fprint(w, `{"resources":{"core":{"limit":60,"remaining":57,"reset":1700000000}}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_API_URL", srv.URL)
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for range 2 {
+		res, err := SyncGitHub(ctx, s, GitHubSyncOptions{Repo: "octo/app", Full: true, BodiesOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Items+res.Unchanged != 1 {
+			t.Fatalf("result = %+v, want the one issue", res)
+		}
+	}
+	if revalidated != 1 {
+		t.Fatalf("revalidated %d times, want the second sync to send the cached ETag", revalidated)
+	}
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// githubFieldNamespace holds GitHub state that sync does not fetch and only
+// webhooks report: a PR's head SHA, last review and CI status.
+const githubFieldNamespace = "github"
+
+// GitHubWebhookResult is what one webhook delivery changed.
+type GitHubWebhookResult struct {
+	Event  string   `json:"event"`
+	Action string   `json:"action,omitempty"`
+	Nodes  []string `json:"nodes,omitempty"`
+	Boards []string `json:"boards,omitempty"`
+	Links  int      `json:"links,omitempty"`
+}
+
+type githubWebhookRepo struct {
+	FullName string `json:"full_name"`
+}
+
+type githubWebhookComment struct {
+	HTMLURL string       `json:"html_url"`
+	Body    string       `json:"body"`
+	State   string       `json:"state"`
+	User    GitHubPerson `json:"user"`
+}
+
+type githubWebhookPayload struct {
+	Action      string                `json:"action"`
+	Issue       *restIssue            `json:"issue"`
+	PullRequest *githubWebhookPR      `json:"pull_request"`
+	Comment     *githubWebhookComment `json:"comment"`
+	Review      *githubWebhookComment `json:"review"`
+	CheckSuite  *struct {
+		HeadSHA      string `json:"head_sha"`
+		Status       string `json:"status"`
+		Conclusion   string `json:"conclusion"`
+		PullRequests []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_suite"`
+	SHA             string             `json:"sha"`
+	State           string             `json:"state"`
+	Context         string             `json:"context"`
+	ParentIssue     *restIssue         `json:"parent_issue"`
+	ParentIssueRepo *githubWebhookRepo `json:"parent_issue_repo"`
+	SubIssue        *restIssue         `json:"sub_issue"`
+	Repository      githubWebhookRepo  `json:"repository"`
+}
+
+type githubWebhookPR struct {
+	restIssue
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// IngestGitHubWebhook applies a webhook delivery. Issues and PRs are stored
+// like sync stores them and routed to every board whose scope matches or that
+// already holds them; body and comment relations are re-extracted, reviews
+// and CI status are kept as github fields, and sub-issues become blocking
+// edges. Events DepViz has no use for are accepted and ignored.
+func IngestGitHubWebhook(ctx context.Context, s *Store, event string, body []byte) (GitHubWebhookResult, error) {
+	var p githubWebhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return GitHubWebhookResult{}, err
+	}
+	res := GitHubWebhookResult{Event: event, Action: p.Action}
+	repo := p.Repository.FullName
+	var err error
+	switch {
+	case event == "issues" && p.Issue != nil:
+		item := p.Issue.item(repo)
+		if p.Action == "deleted" {
+			item.State = "closed"
+		}
+		err = s.ingestGitHubWebhookItem(ctx, &res, item)
+	case event == "pull_request" && p.PullRequest != nil:
+		err = s.ingestGitHubWebhookPR(ctx, &res, repo, p.PullRequest)
+	case event == "issue_comment" && p.Issue != nil && p.Comment != nil:
+		if err = s.ingestGitHubWebhookItem(ctx, &res, p.Issue.item(repo)); err == nil {
+			comment := *p.Comment
+			if p.Action == "deleted" {
+				comment.Body = ""
+			}
+			err = s.ingestGitHubWebhookComment(ctx, &res, repo, githubSourceComment, comment)
+		}
+	case event == "pull_request_review" && p.PullRequest != nil && p.Review != nil:
+		if err = s.ingestGitHubWebhookPR(ctx, &res, repo, p.PullRequest); err == nil {
+			err = s.setGitHubNodeField(ctx, res.Nodes[0], "review", map[string]string{"state": strings.ToLower(p.Review.State), "login": p.Review.User.Login, "url": p.Review.HTMLURL})
+		}
+		if err == nil {
+			err = s.ingestGitHubWebhookComment(ctx, &res, repo, githubSourceReview, *p.Review)
+		}
+	case event == "check_suite" && p.CheckSuite != nil:
+		state := p.CheckSuite.Conclusion
+		if state == "" {
+			state = p.CheckSuite.Status
+		}
+		for _, pr := range p.CheckSuite.PullRequests {
+			if err == nil {
+				err = s.setGitHubChecks(ctx, &res, fmt.Sprintf("gh:%s!%d", repo, pr.Number), map[string]string{"state": state, "sha": p.CheckSuite.HeadSHA})
+			}
+		}
+	case event == "status" && p.SHA != "":
+		var heads map[string]FieldValue
+		if heads, err = s.FieldValuesByKey(ctx, "", "node", githubFieldNamespace, "head_sha"); err == nil {
+			want, _ := json.Marshal(p.SHA)
+			for nodeID, fv := range heads {
+				if fv.ValueJSON == string(want) && err == nil {
+					err = s.setGitHubChecks(ctx, &res, nodeID, map[string]string{"state": p.State, "context": p.Context, "sha": p.SHA})
+				}
+			}
+		}
+	case event == "sub_issues" && p.ParentIssue != nil && p.SubIssue != nil:
+		err = s.ingestGitHubSubIssue(ctx, &res, p)
+	default:
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	sort.Strings(res.Boards)
+	payload, _ := json.Marshal(res)
+	return res, s.RecordEvent(ctx, "depviz.github_webhook.v1", strings.Join(res.Nodes, ","), payload)
+}
+
+func (s *Store) ingestGitHubWebhookPR(ctx context.Context, res *GitHubWebhookResult, repo string, pr *githubWebhookPR) error {
+	item := pr.item(repo)
+	item.PullRequest = true
+	if err := s.ingestGitHubWebhookItem(ctx, res, item); err != nil {
+		return err
+	}
+	if pr.Head.SHA == "" {
+		return nil
+	}
+	return s.setGitHubNodeField(ctx, res.Nodes[len(res.Nodes)-1], "head_sha", pr.Head.SHA)
+}
+
+// ingestGitHubWebhookItem stores an item on its boards and re-extracts the
+// relations in its body.
+func (s *Store) ingestGitHubWebhookItem(ctx context.Context, res *GitHubWebhookResult, item GitHubItem) error {
+	if item.Repo == "" || item.Number == 0 {
+		return fmt.Errorf("webhook item is missing its repo or number")
+	}
+	marker, _ := githubItemMarker(item)
+	nodeID := fmt.Sprintf("gh:%s%s%d", item.Repo, marker, item.Number)
+	res.Nodes = append(res.Nodes, nodeID)
+	boards, err := s.githubWebhookBoards(ctx, item, nodeID)
+	if err != nil || len(boards) == 0 {
+		return err
+	}
+	if err := s.ensureGitHubSource(ctx, item.Repo, "webhook"); err != nil {
+		return err
+	}
+	for _, boardID := range boards {
+		if _, err := s.UpsertGitHubItem(ctx, boardID, item); err != nil {
+			return err
+		}
+		links, err := s.replaceGitHubInferredEdges(ctx, boardID, nodeID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges(item.Repo, nodeID, item.Body))
+		if err != nil {
+			return err
+		}
+		res.Links += links
+		res.Boards = appendUnique(res.Boards, boardID)
+	}
+	return nil
+}
+
+// ingestGitHubWebhookComment replaces the relations extracted from one
+// comment or review, on every board holding the commented node. An empty
+// body, as for a deleted comment, removes them.
+func (s *Store) ingestGitHubWebhookComment(ctx context.Context, res *GitHubWebhookResult, repo, source string, comment githubWebhookComment) error {
+	if comment.HTMLURL == "" || len(res.Nodes) == 0 {
+		return nil
+	}
+	nodeID := res.Nodes[len(res.Nodes)-1]
+	boards, err := s.nodeBoards(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	edges := extractDependencyEdges(repo, nodeID, comment.Body)
+	for _, boardID := range boards {
+		links, err := s.replaceGitHubInferredEdges(ctx, boardID, nodeID, githubEdgeEvidence{Source: source, Origin: comment.HTMLURL, Author: comment.User.Login}, edges)
+		if err != nil {
+			return err
+		}
+		res.Links += links
+		res.Boards = appendUnique(res.Boards, boardID)
+	}
+	return nil
+}
+
+func (s *Store) setGitHubChecks(ctx context.Context, res *GitHubWebhookResult, nodeID string, checks map[string]string) error {
+	exists, err := s.nodeExists(ctx, nodeID)
+	if err != nil || !exists {
+		return err
+	}
+	if err := s.setGitHubNodeField(ctx, nodeID, "checks", checks); err != nil {
+		return err
+	}
+	res.Nodes = append(res.Nodes, nodeID)
+	boards, err := s.nodeBoards(ctx, nodeID)
+	for _, boardID := range boards {
+		res.Boards = appendUnique(res.Boards, boardID)
+	}
+	return err
+}
+
+func (s *Store) setGitHubNodeField(ctx context.Context, nodeID, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.SetFieldValue(ctx, FieldValue{OwnerType: "node", OwnerID: nodeID, Namespace: githubFieldNamespace, Key: key, ValueJSON: string(data), Authority: "github"})
+}
+
+// ingestGitHubSubIssue mirrors a sub-issue link as "parent blocked_by sub" on
+// every board holding both, since a parent is not done until its sub-issues
+// are.
+func (s *Store) ingestGitHubSubIssue(ctx context.Context, res *GitHubWebhookResult, p githubWebhookPayload) error {
+	parentRepo := p.Repository.FullName
+	if p.ParentIssueRepo != nil && p.ParentIssueRepo.FullName != "" {
+		parentRepo = p.ParentIssueRepo.FullName
+	}
+	subRepo := p.SubIssue.repoFullName()
+	if subRepo == "" {
+		subRepo = p.Repository.FullName
+	}
+	if err := s.ingestGitHubWebhookItem(ctx, res, p.ParentIssue.item(parentRepo)); err != nil {
+		return err
+	}
+	if err := s.ingestGitHubWebhookItem(ctx, res, p.SubIssue.item(subRepo)); err != nil {
+		return err
+	}
+	parentID, subID := res.Nodes[0], res.Nodes[1]
+	parentBoards, err := s.nodeBoards(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	subBoards, err := s.nodeBoards(ctx, subID)
+	if err != nil {
+		return err
+	}
+	removed := strings.HasSuffix(p.Action, "_removed")
+	for _, boardID := range parentBoards {
+		if !containsString(subBoards, boardID) {
+			continue
+		}
+		if removed {
+			if _, err := s.db.ExecContext(ctx, `DELETE FROM edges WHERE id = ? AND authority = 'github'`, stableID("edge", boardID, parentID, subID, "blocked_by")); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := s.AddEdge(ctx, boardID, parentID, subID, "blocked_by", "github", map[string]string{"relation": "sub_issue"}); err != nil {
+			return err
+		}
+		res.Links++
+	}
+	return nil
+}
+
+// githubWebhookBoards returns the boards an item belongs on: those whose
+// scope matches it and those already holding it.
+func (s *Store) githubWebhookBoards(ctx context.Context, item GitHubItem, nodeID string) ([]string, error) {
+	boards, err := s.nodeBoards(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, scope_query, config_json FROM boards ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b Board
+		if err := rows.Scan(&b.ID, &b.ScopeQuery, &b.ConfigJSON); err != nil {
+			return nil, err
+		}
+		if scope, err := BoardGitHubScope(b); err == nil && scope.Matches(item) {
+			boards = appendUnique(boards, b.ID)
+		}
+	}
+	return boards, rows.Err()
+}
+
+func (s *Store) nodeBoards(ctx context.Context, nodeID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT board_id FROM board_items WHERE node_id = ? ORDER BY board_id`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var boards []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		boards = append(boards, id)
+	}
+	return boards, rows.Err()
+}
+
+// ensureGitHubSource creates the github:owner/repo source of a repo first
+// seen outside a repo sync, such as through a webhook or a project, leaving an
+// existing one as sync configured it.
+func (s *Store) ensureGitHubSource(ctx context.Context, repo, mode string) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sources WHERE id = ?`, "github:"+repo).Scan(&count); err != nil || count > 0 {
+		return err
+	}
+	return s.UpsertSource(ctx, Source{
+		ID:           "github:" + repo,
+		Kind:         "github",
+		Name:         repo,
+		URL:          "https://github.com/" + repo,
+		Capabilities: `{"read":true}`,
+		Sync:         fmt.Sprintf(`{"mode":%q}`, mode),
+		UpdatedAt:    nowUTC(),
+	})
+}
+
+func containsString(values []string, needle string) bool {
+	for _, v := range values {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIngestGitHubWebhookRoutesAndReextracts(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	bugs, err := s.CreateBoardWithConfig(ctx, "Bugs", "", "repo:moul/depviz label:bug", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.CreateBoardWithConfig(ctx, "All", "", "org:moul", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliver := func(event, body string) GitHubWebhookResult {
+		t.Helper()
+		res, err := IngestGitHubWebhook(ctx, s, event, []byte(body))
+		if err != nil {
+			t.Fatalf("%s: %v", event, err)
+		}
+		return res
+	}
+	edges := func(boardID string) map[string]Edge {
+		t.Helper()
+		snap, err := s.Snapshot(ctx, boardID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]Edge{}
+		for _, e := range snap.Edges {
+			out[e.FromID+" "+e.Kind+" "+e.ToID] = e
+		}
+		return out
+	}
+	repo := `"repository":{"full_name":"moul/depviz"}`
+
+	res := deliver("issues", `{"action":"labeled",`+repo+`,"issue":{"number":1,"title":"Ship","state":"open","body":"blocked by #2","labels":[{"name":"bug"}],"assignees":[{"login":"moul"}]}}`)
+	if !reflect.DeepEqual(res.Boards, []string{all.ID, bugs.ID}) || res.Links != 2 {
+		t.Fatalf("labeled issue = %+v, want both boards", res)
+	}
+	res = deliver("issues", `{"action":"opened",`+repo+`,"issue":{"number":5,"title":"Docs","state":"open"}}`)
+	if !reflect.DeepEqual(res.Boards, []string{all.ID}) {
+		t.Fatalf("unlabeled issue went to %v", res.Boards)
+	}
+	node, err := s.nodeByID(ctx, "gh:moul/depviz#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		Labels    []string       `json:"labels"`
+		Assignees []GitHubPerson `json:"assignees"`
+	}
+	if err := json.Unmarshal([]byte(node.DataJSON), &data); err != nil || len(data.Labels) != 1 || len(data.Assignees) != 1 {
+		t.Fatalf("webhook dropped labels or assignees: %s", node.DataJSON)
+	}
+
+	comment := `"comment":{"html_url":"https://github.com/moul/depviz/issues/2#issuecomment-9","body":"this depends on #1","user":{"login":"octo"}}`
+	deliver("issue_comment", `{"action":"created",`+repo+`,"issue":{"number":2,"title":"Schema","state":"open"},`+comment+`}`)
+	e, ok := edges(all.ID)["gh:moul/depviz#2 blocked_by gh:moul/depviz#1"]
+	if !ok {
+		t.Fatalf("comment relation missing: %v", edges(all.ID))
+	}
+	var ev githubEdgeEvidence
+	if err := json.Unmarshal([]byte(e.EvidenceJSON), &ev); err != nil || ev.Author != "octo" || ev.Origin != "https://github.com/moul/depviz/issues/2#issuecomment-9" {
+		t.Fatalf("comment evidence = %s", e.EvidenceJSON)
+	}
+	deliver("issue_comment", `{"action":"deleted",`+repo+`,"issue":{"number":2,"title":"Schema","state":"open"},`+comment+`}`)
+	if _, ok := edges(all.ID)["gh:moul/depviz#2 blocked_by gh:moul/depviz#1"]; ok {
+		t.Fatal("deleted comment kept its relation")
+	}
+
+	deliver("issues", `{"action":"edited",`+repo+`,"issue":{"number":1,"title":"Ship","state":"open","body":"nothing left","labels":[{"name":"bug"}]}}`)
+	if got := edges(bugs.ID); len(got) != 0 {
+		t.Fatalf("edited body kept relations %v", got)
+	}
+	deliver("sub_issues", `{"action":"sub_issue_added",`+repo+`,"parent_issue":{"number":1,"title":"Ship","state":"open","labels":[{"name":"bug"}]},"sub_issue":{"number":5,"title":"Docs","state":"open","repository_url":"https://api.github.com/repos/moul/depviz"}}`)
+	if _, ok := edges(all.ID)["gh:moul/depviz#1 blocked_by gh:moul/depviz#5"]; !ok {
+		t.Fatalf("sub-issue edge missing: %v", edges(all.ID))
+	}
+	if got := edges(bugs.ID); len(got) != 0 {
+		t.Fatalf("sub-issue edge landed on a board without the sub-issue: %v", got)
+	}
+	deliver("sub_issues", `{"action":"sub_issue_removed",`+repo+`,"parent_issue":{"number":1,"title":"Ship","state":"open","labels":[{"name":"bug"}]},"sub_issue":{"number":5,"title":"Docs","state":"open"}}`)
+	if got := edges(all.ID); len(got) != 0 {
+		t.Fatalf("removed sub-issue kept %v", got)
+	}
+
+	deliver("pull_request", `{"action":"opened",`+repo+`,"pull_request":{"number":3,"title":"Fix","state":"open","head":{"sha":"abc123"}}}`)
+	res = deliver("status", `{"sha":"abc123","state":"failure","context":"ci/test",`+repo+`}`)
+	if !reflect.DeepEqual(res.Nodes, []string{"gh:moul/depviz!3"}) {
+		t.Fatalf("status touched %v", res.Nodes)
+	}
+	checks, err := s.FieldValuesByKey(ctx, "", "node", githubFieldNamespace, "checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := checks["gh:moul/depviz!3"].ValueJSON; got != `{"context":"ci/test","sha":"abc123","state":"failure"}` {
+		t.Fatalf("checks = %s", got)
+	}
+	if res := deliver("watch", `{"action":"started",`+repo+`}`); len(res.Nodes) != 0 {
+		t.Fatalf("ignored event changed %v", res.Nodes)
+	}
+}
+
+func TestGitHubInferredEdgeKeepsEveryOrigin(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	all, err := s.CreateBoardWithConfig(ctx, "All", "", "org:moul", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliver := func(event, body string) {
+		t.Helper()
+		if _, err := IngestGitHubWebhook(ctx, s, event, []byte(body)); err != nil {
+			t.Fatalf("%s: %v", event, err)
+		}
+	}
+	edge := func() (Edge, bool) {
+		t.Helper()
+		snap, err := s.Snapshot(ctx, all.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range snap.Edges {
+			if e.FromID == "gh:moul/depviz#1" && e.ToID == "gh:moul/depviz#2" {
+				return e, true
+			}
+		}
+		return Edge{}, false
+	}
+	repo := `"repository":{"full_name":"moul/depviz"}`
+	issue := `"issue":{"number":1,"title":"Ship","state":"open","body":"blocked by #2"}`
+	comment := `"comment":{"html_url":"https://github.com/moul/depviz/issues/1#issuecomment-9","body":"still blocked by #2","user":{"login":"octo"}}`
+
+	deliver("issues", `{"action":"opened",`+repo+`,`+issue+`}`)
+	body, ok := edge()
+	if !ok {
+		t.Fatal("body relation missing")
+	}
+	deliver("issue_comment", `{"action":"created",`+repo+`,`+issue+`,`+comment+`}`)
+	e, _ := edge()
+	var ev githubEdgeEvidence
+	if err := json.Unmarshal([]byte(e.EvidenceJSON), &ev); err != nil || len(ev.Origins) != 2 {
+		t.Fatalf("evidence = %s, want the body and the comment", e.EvidenceJSON)
+	}
+	if e.Confidence != body.Confidence || ev.Source != githubSourceBody {
+		t.Fatalf("confidence = %v from %s, want the body's %v", e.Confidence, ev.Source, body.Confidence)
+	}
+
+	deliver("issue_comment", `{"action":"deleted",`+repo+`,`+issue+`,`+comment+`}`)
+	e, ok = edge()
+	if !ok {
+		t.Fatal("deleting the comment removed a relation the body still states")
+	}
+	ev = githubEdgeEvidence{}
+	if err := json.Unmarshal([]byte(e.EvidenceJSON), &ev); err != nil || len(ev.Origins) != 0 || ev.Origin != githubSourceBody {
+		t.Fatalf("evidence = %s, want the body alone", e.EvidenceJSON)
+	}
+
+	deliver("issues", `{"action":"edited",`+repo+`,"issue":{"number":1,"title":"Ship","state":"open","body":"nothing left"}}`)
+	if _, ok := edge(); ok {
+		t.Fatal("edited body kept its relation")
+	}
+}
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"moul.io/depviz/v4/internal/core/gitlab"
+)
+
+// GitLabSyncOptions selects the project SyncGitLab imports and where.
+type GitLabSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Project is the project's full path, group/subgroup/project.
+	Project string
+	// Limit caps the issues, and separately the merge requests, read; it
+	// defaults to 200.
+	Limit int
+	// Client defaults to gitlab.FromEnv, which honours GITLAB_URL for
+	// self-hosted instances.
+	Client *gitlab.Client
+}
+
+// GitLabSyncResult counts what a GitLab sync imported.
+type GitLabSyncResult struct {
+	Project string `json:"project"`
+	Items   int    `json:"items"`
+	Links   int    `json:"links"`
+}
+
+// gitlabItem is an issue or merge request from the REST API. LinkType and
+// IssueLinkID are only set on the issues of an issue's links.
+type gitlabItem struct {
+	IID         int          `json:"iid"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	WebURL      string       `json:"web_url"`
+	Labels      []string     `json:"labels"`
+	Assignees   []gitlabUser `json:"assignees"`
+	Author      gitlabUser   `json:"author"`
+	Milestone   *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Draft      bool      `json:"draft"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	References struct {
+		Full string `json:"full"`
+	} `json:"references"`
+	LinkType    string `json:"link_type"`
+	IssueLinkID int    `json:"issue_link_id"`
+}
+
+type gitlabUser struct {
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	WebURL    string `json:"web_url"`
+}
+
+func (u gitlabUser) person() GitHubPerson {
+	return GitHubPerson{Login: u.Username, AvatarURL: u.AvatarURL, HTMLURL: u.WebURL}
+}
+
+// SyncGitLab imports a GitLab project: its issues as gl:path#N nodes, its merge
+// requests as gl:path!N, with labels and assignees, relations written in their
+// descriptions, and issue links. GitLab's "blocks" and "is blocked by" links
+// are hard edges; "relates to" links become relates_to edges. Every run is
+// recorded in sync_logs.
+func SyncGitLab(ctx context.Context, s *Store, opts GitLabSyncOptions) (GitLabSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Client == nil {
+		opts.Client = gitlab.FromEnv()
+	}
+	var res GitLabSyncResult
+	err := s.runSync(ctx, opts.Board, "gitlab", func() (int, int, error) {
+		var err error
+		res, err = syncGitLab(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncGitLab(ctx context.Context, s *Store, opts GitLabSyncOptions) (GitLabSyncResult, error) {
+	ref := strings.Trim(strings.TrimSpace(opts.Project), "/")
+	if ref == "" {
+		return GitLabSyncResult{}, fmt.Errorf("project is required: use group/project")
+	}
+	var project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	}
+	if err := opts.Client.Get(ctx, gitlab.ProjectPath(ref), &project); err != nil {
+		return GitLabSyncResult{}, err
+	}
+	path := project.PathWithNamespace
+	if path == "" {
+		path = ref
+	}
+	if project.WebURL == "" {
+		project.WebURL = opts.Client.WebURL() + "/" + path
+	}
+	res := GitLabSyncResult{Project: path}
+	if err := s.UpsertSource(ctx, Source{
+		ID:           "gitlab:" + path,
+		Kind:         "gitlab",
+		Name:         path,
+		URL:          project.WebURL,
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"rest"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	api := gitlab.ProjectPath(path)
+	issues, err := gitlab.List[gitlabItem](ctx, opts.Client, api+"/issues?scope=all&order_by=updated_at&sort=desc", opts.Limit)
+	if err != nil {
+		return res, err
+	}
+	mrs, err := gitlab.List[gitlabItem](ctx, opts.Client, api+"/merge_requests?scope=all&state=all&order_by=updated_at&sort=desc", opts.Limit)
+	if err != nil {
+		return res, err
+	}
+	for _, batch := range []struct {
+		marker string
+		items  []gitlabItem
+	}{{"#", issues}, {"!", mrs}} {
+		for _, item := range batch.items {
+			if item.IID == 0 {
+				continue
+			}
+			node, err := s.upsertGitLabItem(ctx, opts.Board, path, batch.marker, item)
+			if err != nil {
+				return res, err
+			}
+			res.Items++
+			links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges(path, node.ID, item.Description))
+			if err != nil {
+				return res, err
+			}
+			res.Links += links
+			if batch.marker != "#" {
+				continue
+			}
+			linked, err := gitlab.List[gitlabItem](ctx, opts.Client, fmt.Sprintf("%s/issues/%d/links", api, item.IID), 0)
+			if err != nil {
+				return res, fmt.Errorf("links of %s: %w", node.ID, err)
+			}
+			if links, err = s.replaceGitLabLinks(ctx, opts.Board, node.ID, path, linked); err != nil {
+				return res, err
+			}
+			res.Links += links
+		}
+	}
+	return res, nil
+}
+
+// upsertGitLabItem stores an issue (marker #) or merge request (marker !) of
+// the project at path, creating its gitlab:path source from the item's URL
+// when missing, and adds it to a board. People are stored like GitHub's, so
+// cards show them the same way.
+func (s *Store) upsertGitLabItem(ctx context.Context, boardID, path, marker string, item gitlabItem) (Node, error) {
+	id := fmt.Sprintf("gl:%s%s%d", path, marker, item.IID)
+	kind := "issue"
+	if marker == "!" {
+		kind = "pr"
+	}
+	state := strings.ToLower(item.State)
+	if state == "opened" {
+		state = "open"
+	}
+	assignees := forgePeople(item.Assignees...)
+	owner := ""
+	if len(assignees) > 0 {
+		owner = assignees[0].Login
+	}
+	var author *GitHubPerson
+	if a := forgePeople(item.Author); len(a) > 0 {
+		author = &a[0]
+	}
+	milestone := ""
+	if item.Milestone != nil {
+		milestone = item.Milestone.Title
+	}
+	labels := item.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "gitlab",
+		"kind":      kind,
+		"project":   path,
+		"number":    item.IID,
+		"labels":    labels,
+		"assignees": assignees,
+		"author":    author,
+		"milestone": milestone,
+		"body":      item.Description,
+		"synced_at": formatTime(nowUTC()),
+		"html_url":  item.WebURL,
+		"draft":     item.Draft,
+	})
+	updated := item.UpdatedAt.UTC()
+	if item.UpdatedAt.IsZero() {
+		updated = nowUTC()
+	}
+	n := Node{ID: id, Kind: kind, Title: item.Title, State: state, Owner: owner, DataJSON: string(payload), UpdatedAt: updated, URL: item.WebURL}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return Node{}, err
+	}
+	sourceID := "gitlab:" + path
+	web := ""
+	if before, _, ok := strings.Cut(item.WebURL, "/-/"); ok {
+		web = before
+	}
+	if err := s.ensureItemSource(ctx, Source{ID: sourceID, Kind: "gitlab", Name: path, URL: web, Capabilities: `{"read":true}`, Sync: `{"mode":"rest"}`}); err != nil {
+		return Node{}, err
+	}
+	if err := s.UpsertSourceRef(ctx, id, sourceID, fmt.Sprintf("%s%d", marker, item.IID), item.WebURL); err != nil {
+		return Node{}, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, id, kind, ""); err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+// replaceGitLabLinks stores an issue's links as hard edges and drops the links
+// it no longer has. Both ends of a link list it, so each link is stored in
+// one direction only: "A blocks B" and "B is blocked by A" are both B
+// blocked_by A, and relates_to runs from the smaller ID. Linked issues not yet
+// known are imported from the link, which carries the whole issue.
+func (s *Store) replaceGitLabLinks(ctx context.Context, boardID, nodeID, path string, linked []gitlabItem) (int, error) {
+	keep := map[string]bool{}
+	for _, item := range linked {
+		otherPath := gitlabItemPath(item, "#", path)
+		other := fmt.Sprintf("gl:%s#%d", otherPath, item.IID)
+		exists, err := s.nodeExists(ctx, other)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			if _, err := s.upsertGitLabItem(ctx, boardID, otherPath, "#", item); err != nil {
+				return 0, err
+			}
+		}
+		from, to, kind := nodeID, other, "relates_to"
+		switch item.LinkType {
+		case "blocks":
+			from, to, kind = other, nodeID, "blocked_by"
+		case "is_blocked_by":
+			kind = "blocked_by"
+		default:
+			if to < from {
+				from, to = to, from
+			}
+		}
+		e, err := s.AddEdgeWithConfidence(ctx, boardID, from, to, kind, "gitlab", 1, map[string]any{"link_type": item.LinkType, "issue_link_id": item.IssueLinkID})
+		if err != nil {
+			return 0, err
+		}
+		keep[e.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, nodeID, "gitlab", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
+
+// gitlabItemPath is the project path of an item from its full reference
+// (group/project#N), or its URL, falling back to the project being synced.
+func gitlabItemPath(item gitlabItem, marker, fallback string) string {
+	if i := strings.LastIndex(item.References.Full, marker); i > 0 {
+		return item.References.Full[:i]
+	}
+	if m := gitlabURLRefRE.FindStringSubmatch(item.WebURL); m != nil {
+		return m[1]
+	}
+	return fallback
+}
@@ -0,0 +1,204 @@
+// Package gitlab is a small client for the GitLab REST API, for gitlab.com and
+// self-hosted instances alike.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultBaseURL is the gitlab.com API. A self-hosted instance serves it at
+// https://HOST/api/v4.
+const DefaultBaseURL = "https://gitlab.com/api/v4"
+
+// Client calls the GitLab API with a personal, project or group access token,
+// or anonymously when Token is empty.
+type Client struct {
+	// BaseURL defaults to DefaultBaseURL; tests point it at an httptest server.
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is a non-2xx GitLab response.
+type Error struct {
+	StatusCode int
+	Status     string
+	Path       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Status, e.Path, e.Message)
+}
+
+// NewClient returns a client for gitlab.com.
+func NewClient(token string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Token: token}
+}
+
+// FromEnv returns a client for the CLI: the token comes from GITLAB_TOKEN and
+// the instance from GITLAB_URL, which may be given with or without /api/v4.
+func FromEnv() *Client {
+	c := NewClient(strings.TrimSpace(os.Getenv("GITLAB_TOKEN")))
+	if base := strings.TrimSpace(os.Getenv("GITLAB_URL")); base != "" {
+		c.BaseURL = APIURL(base)
+	}
+	return c
+}
+
+// APIURL turns an instance URL such as https://gitlab.example.com into its
+// API base URL.
+func APIURL(instance string) string {
+	instance = strings.TrimRight(instance, "/")
+	if strings.HasSuffix(instance, "/api/v4") {
+		return instance
+	}
+	return instance + "/api/v4"
+}
+
+// WebURL is the instance URL the API is served under, used to build links to
+// projects and issues.
+func (c *Client) WebURL() string {
+	return strings.TrimSuffix(strings.TrimRight(c.base(), "/"), "/api/v4")
+}
+
+// ProjectPath is the API path of a project, which GitLab addresses by its
+// URL-encoded full path.
+func ProjectPath(project string) string {
+	return "/projects/" + url.PathEscape(strings.Trim(project, "/"))
+}
+
+// Get decodes the JSON response of a GET request into out.
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	_, data, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("expected JSON from %s, got %s: %w", pathOf(path), bodySummary(data), err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string) (http.Header, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 20<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, nil, &Error{StatusCode: res.StatusCode, Status: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)), Path: pathOf(path), Message: bodySummary(data)}
+	}
+	return res.Header, data, nil
+}
+
+func (c *Client) base() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return c.BaseURL
+}
+
+func (c *Client) url(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	return strings.TrimRight(c.base(), "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// List GETs a list endpoint and follows its pages until limit items are read,
+// or all of them when limit is not positive. GitLab announces the next page in
+// X-Next-Page, and in a Link header on most endpoints.
+func List[T any](ctx context.Context, c *Client, path string, limit int) ([]T, error) {
+	perPage := 100
+	if limit > 0 && limit < perPage {
+		perPage = limit
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	page := "1"
+	var out []T
+	for page != "" {
+		target := path + sep + "per_page=" + strconv.Itoa(perPage) + "&page=" + page
+		header, data, err := c.get(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("expected a JSON list from %s, got %s: %w", pathOf(target), bodySummary(data), err)
+		}
+		out = append(out, items...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if len(items) == 0 {
+			break
+		}
+		page = strings.TrimSpace(header.Get("X-Next-Page"))
+	}
+	return out, nil
+}
+
+func pathOf(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return target
+}
+
+func bodySummary(data []byte) string {
+	body := strings.TrimSpace(string(data))
+	if body == "" {
+		return "empty response"
+	}
+	var envelope struct {
+		Message any    `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(data, &envelope) == nil {
+		switch m := envelope.Message.(type) {
+		case string:
+			return m
+		case nil:
+		default:
+			// Validation errors come as an object of field messages.
+			out, _ := json.Marshal(m)
+			return string(out)
+		}
+		if envelope.Error != "" {
+			return envelope.Error
+		}
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if len(body) > 240 {
+		body = body[:240] + "..."
+	}
+	return body
+}
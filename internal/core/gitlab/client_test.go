@@ -0,0 +1,64 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListFollowsNextPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", got)
+		}
+		if got := r.URL.EscapedPath(); got != "/api/v4/projects/group%2Fsub%2Fproject/issues" {
+			t.Errorf("path = %s", got)
+		}
+		if r.URL.Query().Get("per_page") != "3" {
+			t.Errorf("per_page = %q, want 3", r.URL.Query().Get("per_page"))
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[1, 2]`)
+		case "2":
+			w.Header().Set("X-Next-Page", "3")
+			fmt.Fprint(w, `[3, 4]`)
+		default:
+			t.Errorf("fetched page %s past the limit", r.URL.Query().Get("page"))
+		}
+	}))
+	defer srv.Close()
+	c := NewClient("secret")
+	c.BaseURL = APIURL(srv.URL)
+	got, err := List[int](context.Background(), c, ProjectPath("group/sub/project")+"/issues", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Fatalf("List = %v, want [1 2 3]", got)
+	}
+	if c.WebURL() != srv.URL {
+		t.Fatalf("WebURL = %q, want %q", c.WebURL(), srv.URL)
+	}
+}
+
+func TestGetReportsGitLabMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"404 Project Not Found"}`)
+	}))
+	defer srv.Close()
+	c := NewClient("")
+	c.BaseURL = srv.URL + "/api/v4"
+	err := c.Get(context.Background(), ProjectPath("group/missing"), &struct{}{})
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("err = %v", err)
+	}
+	if got := err.Error(); got != "404 Not Found /projects/group/missing: 404 Project Not Found" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
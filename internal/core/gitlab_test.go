@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/gitlab"
+)
+
+func TestSyncGitLabImportsItemsAndIssueLinks(t *testing.T) {
+	var base string
+	links := map[string]string{
+		"1": `[{"iid":2,"title":"Schema","state":"opened","issue_link_id":10,"link_type":"is_blocked_by","references":{"full":"acme/platform/api#2"},"web_url":"%[1]s/acme/platform/api/-/issues/2"},
+			{"iid":7,"title":"Web login","state":"opened","issue_link_id":11,"link_type":"blocks","references":{"full":"acme/web#7"},"web_url":"%[1]s/acme/web/-/issues/7"}]`,
+		"2": `[{"iid":1,"title":"Ship","state":"opened","issue_link_id":10,"link_type":"blocks","references":{"full":"acme/platform/api#1"},"web_url":"%[1]s/acme/platform/api/-/issues/1"}]`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			t.Errorf("PRIVATE-TOKEN = %q", r.Header.Get("PRIVATE-TOKEN"))
+		}
+		const api = "/gitlab/api/v4/projects/acme%2Fplatform%2Fapi"
+		if iid, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.EscapedPath(), api+"/issues/"), "/links"); ok {
+			fmt.Fprintf(w, links[iid], base)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case api:
+			fmt.Fprintf(w, `{"path_with_namespace":"acme/platform/api","web_url":"%s/acme/platform/api"}`, base)
+		case api + "/issues":
+			fmt.Fprintf(w, `[
+				{"iid":1,"title":"Ship","state":"opened","description":"Depends on !3","labels":["backend"],"assignees":[{"username":"ada"}],"author":{"username":"bob"},"updated_at":"2026-10-01T10:00:00.000Z","web_url":"%[1]s/acme/platform/api/-/issues/1"},
+				{"iid":2,"title":"Schema","state":"closed","web_url":"%[1]s/acme/platform/api/-/issues/2"}
+			]`, base)
+		case api + "/merge_requests":
+			fmt.Fprintf(w, `[{"iid":3,"title":"Add schema","state":"merged","description":"Closes #2","web_url":"%s/acme/platform/api/-/merge_requests/3"}]`, base)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	base = srv.URL + "/gitlab"
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := gitlab.NewClient("secret")
+	client.BaseURL = gitlab.APIURL(base)
+	sync := func() GitLabSyncResult {
+		t.Helper()
+		res, err := SyncGitLab(ctx, s, GitLabSyncOptions{Project: "acme/platform/api", Client: client})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	edges := func() []string {
+		t.Helper()
+		snap, err := s.Snapshot(ctx, DefaultBoardID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, e := range snap.Edges {
+			out = append(out, fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	if res := sync(); res.Project != "acme/platform/api" || res.Items != 3 {
+		t.Fatalf("result = %+v", res)
+	}
+	want := []string{
+		"gl:acme/platform/api!3 closes gl:acme/platform/api#2 gitlab-inferred",
+		"gl:acme/platform/api#1 blocked_by gl:acme/platform/api!3 gitlab-inferred",
+		"gl:acme/platform/api#1 blocked_by gl:acme/platform/api#2 gitlab",
+		"gl:acme/web#7 blocked_by gl:acme/platform/api#1 gitlab",
+	}
+	if got := edges(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("edges = %q, want %q", got, want)
+	}
+	ship, err := s.nodeByID(ctx, "gl:acme/platform/api#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ship.Kind != "issue" || ship.State != "open" || ship.Owner != "ada" || !reflect.DeepEqual(ship.Labels(), []string{"backend"}) {
+		t.Fatalf("issue = %+v", ship)
+	}
+	web, err := s.nodeByID(ctx, "gl:acme/web#7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.Title != "Web login" || web.IsPlaceholder() {
+		t.Fatalf("linked issue = %+v", web)
+	}
+	// Refs to projects seen through a link resolve on the same instance.
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "gl:acme/web#8", "gl:acme/web#7", "blocked_by", "user", nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := map[string]string{}
+	for _, n := range snap.Nodes {
+		urls[n.ID] = n.URL
+	}
+	if got := urls["gl:acme/web#8"]; got != base+"/acme/web/-/issues/8" {
+		t.Fatalf("placeholder URL = %q", got)
+	}
+
+	links["1"] = `[{"iid":2,"title":"Schema","state":"closed","issue_link_id":10,"link_type":"is_blocked_by","references":{"full":"acme/platform/api#2"},"web_url":"%[1]s/acme/platform/api/-/issues/2"}]`
+	sync()
+	want = append(want[:3:3], "gl:acme/web#8 blocked_by gl:acme/web#7 user")
+	if got := edges(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("edges after unlinking = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GraphExport is the work graph of one or more boards, for graph analysis
+// tools such as Gephi, NetworkX and Cytoscape.
+type GraphExport struct {
+	Boards []Board           `json:"boards"`
+	Nodes  []GraphExportNode `json:"nodes"`
+	Edges  []Edge            `json:"edges"`
+}
+
+// GraphExportNode is a node with the boards it is on, and its role and local
+// state on each of them by board ID. The embedded per-board fields are unset.
+type GraphExportNode struct {
+	Node
+	Boards      []string          `json:"boards"`
+	BoardRoles  map[string]string `json:"board_roles"`
+	LocalStates map[string]string `json:"local_states"`
+}
+
+// BuildGraphExport gathers the nodes and edges of the given boards, or of
+// every board when none is given. A node on several boards is exported once;
+// edges are kept when both of their ends are exported.
+func (s *Store) BuildGraphExport(ctx context.Context, boardIDs []string) (GraphExport, error) {
+	if len(boardIDs) == 0 {
+		boards, err := s.BoardList(ctx)
+		if err != nil {
+			return GraphExport{}, err
+		}
+		for _, b := range boards {
+			boardIDs = append(boardIDs, b.ID)
+		}
+	}
+	var g GraphExport
+	nodes := map[string]*GraphExportNode{}
+	edges := map[string]Edge{}
+	for _, id := range boardIDs {
+		snap, err := s.Snapshot(ctx, id)
+		if err != nil {
+			return GraphExport{}, err
+		}
+		snap.Board.Metrics = nil
+		g.Boards = append(g.Boards, snap.Board)
+		for _, n := range snap.Nodes {
+			gn, ok := nodes[n.ID]
+			if !ok {
+				gn = &GraphExportNode{Node: n, BoardRoles: map[string]string{}, LocalStates: map[string]string{}}
+				gn.BoardRole, gn.LocalState, gn.Fields = "", "", nil
+				nodes[n.ID] = gn
+			}
+			gn.Boards = append(gn.Boards, snap.Board.ID)
+			gn.BoardRoles[snap.Board.ID] = n.BoardRole
+			if n.LocalState != "" {
+				gn.LocalStates[snap.Board.ID] = n.LocalState
+			}
+		}
+		for _, e := range snap.Edges {
+			edges[e.ID] = e
+		}
+	}
+	for _, n := range nodes {
+		sort.Strings(n.Boards)
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	for _, e := range edges {
+		if nodes[e.FromID] != nil && nodes[e.ToID] != nil {
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.FromID != b.FromID {
+			return a.FromID < b.FromID
+		}
+		if a.ToID != b.ToID {
+			return a.ToID < b.ToID
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+	return g, nil
+}
+
+// graphExportPerBoard writes a per-board value as the value itself when the
+// node is on one board or has the same value on all of them, else as
+// board=value pairs separated by commas.
+func graphExportPerBoard(boards []string, values map[string]string) string {
+	if len(boards) == 0 {
+		return ""
+	}
+	same := true
+	var pairs []string
+	for _, b := range boards {
+		same = same && values[b] == values[boards[0]]
+		if values[b] != "" {
+			pairs = append(pairs, b+"="+values[b])
+		}
+	}
+	if same {
+		return values[boards[0]]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// graphExportAttr is an attribute the exports carry, read off a node or edge.
+type graphExportAttr struct {
+	name, typ string
+	node      func(GraphExportNode) string
+	edge      func(Edge) string
+}
+
+var graphExportNodeAttrs = []graphExportAttr{
+	{name: "label", typ: "string", node: func(n GraphExportNode) string { return n.Title }},
+	{name: "kind", typ: "string", node: func(n GraphExportNode) string { return n.Kind }},
+	{name: "state", typ: "string", node: func(n GraphExportNode) string { return n.State }},
+	{name: "closed", typ: "boolean", node: func(n GraphExportNode) string { return fmt.Sprint(n.IsClosed()) }},
+	{name: "owner", typ: "string", node: func(n GraphExportNode) string { return n.Owner }},
+	{name: "labels", typ: "string", node: func(n GraphExportNode) string { return strings.Join(n.Labels(), ",") }},
+	{name: "source", typ: "string", node: func(n GraphExportNode) string { return n.SourceID }},
+	{name: "external_id", typ: "string", node: func(n GraphExportNode) string { return n.ExternalID }},
+	{name: "url", typ: "string", node: func(n GraphExportNode) string { return n.URL }},
+	{name: "boards", typ: "string", node: func(n GraphExportNode) string { return strings.Join(n.Boards, ",") }},
+	{name: "board_role", typ: "string", node: func(n GraphExportNode) string { return graphExportPerBoard(n.Boards, n.BoardRoles) }},
+	{name: "local_state", typ: "string", node: func(n GraphExportNode) string { return graphExportPerBoard(n.Boards, n.LocalStates) }},
+	{name: "updated_at", typ: "string", node: func(n GraphExportNode) string { return graphExportTime(n.UpdatedAt) }},
+}
+
+var graphExportEdgeAttrs = []graphExportAttr{
+	{name: "kind", typ: "string", edge: func(e Edge) string { return e.Kind }},
+	{name: "confidence", typ: "double", edge: func(e Edge) string { return fmt.Sprint(e.Confidence) }},
+	{name: "authority", typ: "string", edge: func(e Edge) string { return e.Authority }},
+	{name: "evidence", typ: "string", edge: func(e Edge) string { return strings.TrimSpace(e.EvidenceJSON) }},
+	{name: "observed_at", typ: "string", edge: func(e Edge) string { return graphExportTime(e.ObservedAt) }},
+	{name: "scope_board", typ: "string", edge: func(e Edge) string { return e.ScopeBoardID }},
+}
+
+func graphExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RenderGraphML writes a graph export as GraphML, the XML graph format Gephi,
+// NetworkX and Cytoscape read. Multi-valued attributes are joined with
+// commas; empty ones are left out.
+func RenderGraphML(w io.Writer, g GraphExport) error {
+	var sb strings.Builder
+	text := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">` + "\n")
+	sb.WriteString(`  <key id="g_boards" for="graph" attr.name="boards" attr.type="string"/>` + "\n")
+	for _, a := range graphExportNodeAttrs {
+		fmt.Fprintf(&sb, "  <key id=\"n_%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", a.name, a.name, a.typ)
+	}
+	for _, a := range graphExportEdgeAttrs {
+		fmt.Fprintf(&sb, "  <key id=\"e_%s\" for=\"edge\" attr.name=\"%s\" attr.type=\"%s\"/>\n", a.name, a.name, a.typ)
+	}
+	sb.WriteString(`  <graph id="depviz" edgedefault="directed">` + "\n")
+	var boards []string
+	for _, b := range g.Boards {
+		boards = append(boards, b.ID)
+	}
+	fmt.Fprintf(&sb, "    <data key=\"g_boards\">%s</data>\n", text(strings.Join(boards, ",")))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "    <node id=\"%s\">\n", text(n.ID))
+		for _, a := range graphExportNodeAttrs {
+			if v := a.node(n); v != "" {
+				fmt.Fprintf(&sb, "      <data key=\"n_%s\">%s</data>\n", a.name, text(v))
+			}
+		}
+		sb.WriteString("    </node>\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "    <edge id=\"%s\" source=\"%s\" target=\"%s\">\n", text(e.ID), text(e.FromID), text(e.ToID))
+		for _, a := range graphExportEdgeAttrs {
+			if v := a.edge(e); v != "" {
+				fmt.Fprintf(&sb, "      <data key=\"e_%s\">%s</data>\n", a.name, text(v))
+			}
+		}
+		sb.WriteString("    </edge>\n")
+	}
+	sb.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// RenderCytoscape writes a graph export as Cytoscape JSON, the elements
+// format read by Cytoscape and cytoscape.js. Labels and boards are arrays,
+// per-board roles and local states objects and evidence the recorded JSON.
+func RenderCytoscape(w io.Writer, g GraphExport) error {
+	type element struct {
+		Data map[string]any `json:"data"`
+	}
+	out := struct {
+		Data     map[string]any `json:"data"`
+		Elements struct {
+			Nodes []element `json:"nodes"`
+			Edges []element `json:"edges"`
+		} `json:"elements"`
+	}{Data: map[string]any{"name": "depviz"}}
+	var boards []string
+	for _, b := range g.Boards {
+		boards = append(boards, b.ID)
+	}
+	if len(g.Boards) == 1 {
+		out.Data["name"] = g.Boards[0].Name
+	}
+	out.Data["boards"] = boards
+	out.Elements.Nodes, out.Elements.Edges = []element{}, []element{}
+	for _, n := range g.Nodes {
+		data := map[string]any{"id": n.ID, "name": n.Title}
+		for _, a := range graphExportNodeAttrs {
+			if v := a.node(n); v != "" && a.name != "label" {
+				data[a.name] = v
+			}
+		}
+		data["closed"] = n.IsClosed()
+		data["labels"] = append([]string{}, n.Labels()...)
+		data["boards"] = n.Boards
+		data["board_roles"] = n.BoardRoles
+		data["local_states"] = n.LocalStates
+		out.Elements.Nodes = append(out.Elements.Nodes, element{Data: data})
+	}
+	for _, e := range g.Edges {
+		data := map[string]any{"id": e.ID, "source": e.FromID, "target": e.ToID}
+		for _, a := range graphExportEdgeAttrs {
+			if v := a.edge(e); v != "" {
+				data[a.name] = v
+			}
+		}
+		data["confidence"] = e.Confidence
+		if evidence := strings.TrimSpace(e.EvidenceJSON); evidence != "" && json.Valid([]byte(evidence)) {
+			data["evidence"] = json.RawMessage(evidence)
+		}
+		out.Elements.Edges = append(out.Elements.Edges, element{Data: data})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestGraphExportCarriesAttributesAcrossBoards(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	platform, err := s.CreateBoard(ctx, "Platform", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []Node{
+		{ID: "gh:acme/api#1", Kind: "issue", Title: "Auth & <SSO>", State: "open", Owner: "ada", DataJSON: `{"labels":["backend","auth"]}`, UpdatedAt: nowUTC()},
+		{ID: "gh:acme/api#2", Kind: "issue", Title: "Schema", State: "closed", UpdatedAt: nowUTC()},
+	} {
+		if err := s.UpsertNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.UpsertSource(ctx, Source{ID: "github:acme/api", Kind: "github", Name: "acme/api", UpdatedAt: nowUTC()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertSourceRef(ctx, "gh:acme/api#1", "github:acme/api", "1", "https://github.com/acme/api/issues/1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct{ board, node, role, local string }{
+		{DefaultBoardID, "gh:acme/api#1", "card", "now"},
+		{DefaultBoardID, "gh:acme/api#2", "card", ""},
+		{platform.ID, "gh:acme/api#1", "context", ""},
+	} {
+		if err := s.AddNodeToBoard(ctx, m.board, m.node, m.role, m.local); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.AddEdgeWithConfidence(ctx, DefaultBoardID, "gh:acme/api#1", "gh:acme/api#2", "blocked_by", "github-inferred", 0.75, map[string]any{"text": "needs #2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := s.BuildGraphExport(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Boards) != 2 || len(g.Nodes) != 2 || len(g.Edges) != 1 {
+		t.Fatalf("export = %+v", g)
+	}
+	var out bytes.Buffer
+	if err := RenderGraphML(&out, g); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Keys  []struct{ ID, For string } `xml:"key"`
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Data   []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	node := map[string]string{}
+	for _, d := range doc.Nodes[0].Data {
+		node[d.Key] = d.Value
+	}
+	for key, want := range map[string]string{
+		"n_label":       "Auth & <SSO>",
+		"n_owner":       "ada",
+		"n_labels":      "backend,auth",
+		"n_source":      "github:acme/api",
+		"n_url":         "https://github.com/acme/api/issues/1",
+		"n_boards":      "default," + platform.ID,
+		"n_board_role":  "default=card," + platform.ID + "=context",
+		"n_local_state": "default=now",
+	} {
+		if node[key] != want {
+			t.Fatalf("%s = %q, want %q in\n%s", key, node[key], want, out.String())
+		}
+	}
+	edge := map[string]string{}
+	for _, d := range doc.Edges[0].Data {
+		edge[d.Key] = d.Value
+	}
+	if edge["e_kind"] != "blocked_by" || edge["e_confidence"] != "0.75" || edge["e_authority"] != "github-inferred" || edge["e_evidence"] != `{"text":"needs #2"}` || edge["e_observed_at"] == "" || edge["e_scope_board"] != DefaultBoardID {
+		t.Fatalf("edge = %+v", edge)
+	}
+
+	// A single board exports its own role and edges.
+	if g, err = s.BuildGraphExport(ctx, []string{platform.ID}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := RenderCytoscape(&out, g); err != nil {
+		t.Fatal(err)
+	}
+	var cy struct {
+		Data     map[string]any `json:"data"`
+		Elements struct {
+			Nodes []struct{ Data map[string]any } `json:"nodes"`
+			Edges []struct{ Data map[string]any } `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &cy); err != nil {
+		t.Fatal(err)
+	}
+	if cy.Data["name"] != "Platform" || len(cy.Elements.Nodes) != 1 || len(cy.Elements.Edges) != 0 {
+		t.Fatalf("cytoscape = %s", out.String())
+	}
+	n := cy.Elements.Nodes[0].Data
+	if n["id"] != "gh:acme/api#1" || n["name"] != "Auth & <SSO>" || n["board_role"] != "context" || n["closed"] != false || strings.Join(toStrings(n["labels"]), ",") != "backend,auth" {
+		t.Fatalf("node = %+v", n)
+	}
+	if _, err := s.BuildGraphExport(ctx, []string{"missing"}); err == nil {
+		t.Fatal("unknown board accepted")
+	}
+}
+
+func toStrings(v any) []string {
+	var out []string
+	for _, s := range v.([]any) {
+		out = append(out, s.(string))
+	}
+	return out
+}
@@ -0,0 +1,340 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphRenderOptions tunes RenderGraph.
+type GraphRenderOptions struct {
+	// Format is dot, mermaid or plantuml.
+	Format string
+	// Cluster groups nodes by repo or owner; empty leaves them ungrouped.
+	Cluster string
+	// HideClosed drops closed nodes, except the focus node.
+	HideClosed bool
+	// Focus restricts the graph to the nodes within Depth edges of this one.
+	Focus string
+	// Depth defaults to 1.
+	Depth int
+}
+
+// GraphFormats are the formats RenderGraph writes.
+var GraphFormats = []string{"dot", "mermaid", "plantuml"}
+
+// graphView is the part of a snapshot a graph shows, with short stable names
+// for the diagram languages that cannot use node IDs as identifiers.
+type graphView struct {
+	nodes    []Node
+	edges    []Edge
+	names    map[string]string
+	clusters []string
+	members  map[string][]Node
+}
+
+// RenderGraph writes a snapshot as a Graphviz DOT, Mermaid flowchart or
+// PlantUML diagram. Closed nodes are greyed out, local-only notes shaded and
+// placeholders outlined with dashes; hard blockers are bold, soft and
+// inferred edges dashed, and each edge is labelled with its relation read
+// from its source, as in "depends on".
+func RenderGraph(snap Snapshot, opts GraphRenderOptions) (string, error) {
+	switch opts.Cluster {
+	case "", "repo", "owner":
+	default:
+		return "", fmt.Errorf("unknown cluster %q: use repo or owner", opts.Cluster)
+	}
+	v, err := newGraphView(snap, opts)
+	if err != nil {
+		return "", err
+	}
+	switch opts.Format {
+	case "dot":
+		return v.dot(snap.Board.Name), nil
+	case "mermaid":
+		return v.mermaid(), nil
+	case "plantuml":
+		return v.plantuml(snap.Board.Name), nil
+	default:
+		return "", fmt.Errorf("unknown graph format %q: use %s", opts.Format, strings.Join(GraphFormats, ", "))
+	}
+}
+
+func newGraphView(snap Snapshot, opts GraphRenderOptions) (graphView, error) {
+	byID := map[string]Node{}
+	for _, n := range snap.Nodes {
+		if !opts.HideClosed || !n.IsClosed() || n.ID == opts.Focus {
+			byID[n.ID] = n
+		}
+	}
+	if opts.Focus != "" {
+		if _, ok := byID[opts.Focus]; !ok {
+			return graphView{}, fmt.Errorf("node %s is not on board %s", opts.Focus, snap.Board.ID)
+		}
+		depth := opts.Depth
+		if depth <= 0 {
+			depth = 1
+		}
+		// Edges are followed both ways, through shown nodes only.
+		near := map[string]bool{opts.Focus: true}
+		frontier := map[string]bool{opts.Focus: true}
+		for i := 0; i < depth && len(frontier) > 0; i++ {
+			next := map[string]bool{}
+			for _, e := range snap.Edges {
+				for _, pair := range [][2]string{{e.FromID, e.ToID}, {e.ToID, e.FromID}} {
+					if _, ok := byID[pair[1]]; ok && !near[pair[1]] && frontier[pair[0]] {
+						near[pair[1]] = true
+						next[pair[1]] = true
+					}
+				}
+			}
+			frontier = next
+		}
+		for id := range byID {
+			if !near[id] {
+				delete(byID, id)
+			}
+		}
+	}
+	v := graphView{names: map[string]string{}, members: map[string][]Node{}}
+	for _, n := range byID {
+		v.nodes = append(v.nodes, n)
+	}
+	sort.Slice(v.nodes, func(i, j int) bool { return v.nodes[i].ID < v.nodes[j].ID })
+	for i, n := range v.nodes {
+		v.names[n.ID] = fmt.Sprintf("n%d", i)
+		group := graphCluster(n, opts.Cluster)
+		if _, ok := v.members[group]; !ok && group != "" {
+			v.clusters = append(v.clusters, group)
+		}
+		v.members[group] = append(v.members[group], n)
+	}
+	sort.Strings(v.clusters)
+	for _, e := range snap.Edges {
+		if _, ok := byID[e.FromID]; !ok {
+			continue
+		}
+		if _, ok := byID[e.ToID]; !ok {
+			continue
+		}
+		v.edges = append(v.edges, e)
+	}
+	sort.SliceStable(v.edges, func(i, j int) bool {
+		a, b := v.edges[i], v.edges[j]
+		if a.FromID != b.FromID {
+			return a.FromID < b.FromID
+		}
+		if a.ToID != b.ToID {
+			return a.ToID < b.ToID
+		}
+		return a.Kind < b.Kind
+	})
+	return v, nil
+}
+
+// graphCluster names the group a node is drawn in: the repo, project or team
+// of tracker items, or the owner. Nodes outside any are drawn ungrouped.
+func graphCluster(n Node, by string) string {
+	switch by {
+	case "owner":
+		return n.Owner
+	case "repo":
+		if m := flowExternalRef(n.ID); m != nil {
+			return m[1]
+		}
+		if m := trackerNodeRE.FindStringSubmatch(n.ID); m != nil {
+			return m[1] + ":" + m[2]
+		}
+	}
+	return ""
+}
+
+// graphEdgeStyle reads how an edge is drawn: hard blockers bold, soft or
+// inferred edges dashed and other relations plain.
+func graphEdgeStyle(e Edge) string {
+	if edgeIsSoft(e) {
+		return "dashed"
+	}
+	if blocked, _ := edgeBlockedAndBlocker(e); blocked != "" {
+		return "bold"
+	}
+	return "plain"
+}
+
+func graphEdgeLabel(e Edge) string {
+	if verb, ok := flowEdgeVerbs[strings.ToLower(e.Kind)]; ok {
+		return verb
+	}
+	return strings.ReplaceAll(e.Kind, "_", " ")
+}
+
+func graphNodeLabel(n Node) string {
+	title := n.Title
+	if r := []rune(title); len(r) > 60 {
+		title = string(r[:59]) + "…"
+	}
+	if title == "" || title == n.ID {
+		return n.ID
+	}
+	return n.ID + "\n" + title
+}
+
+func (v graphView) dot(name string) string {
+	var sb strings.Builder
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	sb.WriteString("digraph depviz {\n")
+	if name != "" {
+		fmt.Fprintf(&sb, "  label=%s;\n", quote(name))
+	}
+	sb.WriteString("  rankdir=LR;\n  node [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=Helvetica];\n  edge [fontname=Helvetica, fontsize=10];\n")
+	node := func(indent string, n Node) {
+		attrs := []string{"label=" + quote(graphNodeLabel(n))}
+		style := []string{"rounded", "filled"}
+		switch {
+		case n.IsClosed():
+			attrs = append(attrs, `fillcolor="#e5e7eb"`, `fontcolor="#6b7280"`)
+		case n.IsLocalOnly():
+			attrs = append(attrs, "shape=note", `fillcolor="#fef3c7"`)
+		}
+		if n.IsPlaceholder() {
+			style = append(style, "dashed")
+		}
+		attrs = append(attrs, "style="+quote(strings.Join(style, ",")))
+		if n.URL != "" {
+			attrs = append(attrs, "URL="+quote(n.URL))
+		}
+		fmt.Fprintf(&sb, "%s%s [%s];\n", indent, v.names[n.ID], strings.Join(attrs, ", "))
+	}
+	for _, n := range v.members[""] {
+		node("  ", n)
+	}
+	for i, c := range v.clusters {
+		fmt.Fprintf(&sb, "  subgraph cluster_%d {\n    label=%s;\n", i, quote(c))
+		for _, n := range v.members[c] {
+			node("    ", n)
+		}
+		sb.WriteString("  }\n")
+	}
+	for _, e := range v.edges {
+		attrs := []string{"label=" + quote(graphEdgeLabel(e))}
+		switch graphEdgeStyle(e) {
+		case "bold":
+			attrs = append(attrs, "style=bold", "penwidth=2")
+		case "dashed":
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [%s];\n", v.names[e.FromID], v.names[e.ToID], strings.Join(attrs, ", "))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (v graphView) mermaid() string {
+	var sb strings.Builder
+	text := func(s string) string {
+		return `"` + strings.NewReplacer("#", "#35;", `"`, "#quot;", "\n", "<br/>", "<", "#lt;", ">", "#gt;").Replace(s) + `"`
+	}
+	sb.WriteString("flowchart LR\n")
+	node := func(indent string, n Node) {
+		fmt.Fprintf(&sb, "%s%s[%s]\n", indent, v.names[n.ID], text(graphNodeLabel(n)))
+	}
+	for _, n := range v.members[""] {
+		node("  ", n)
+	}
+	for i, c := range v.clusters {
+		fmt.Fprintf(&sb, "  subgraph c%d[%s]\n", i, text(c))
+		for _, n := range v.members[c] {
+			node("    ", n)
+		}
+		sb.WriteString("  end\n")
+	}
+	for _, e := range v.edges {
+		arrow := "-->"
+		switch graphEdgeStyle(e) {
+		case "bold":
+			arrow = "==>"
+		case "dashed":
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&sb, "  %s %s|%s| %s\n", v.names[e.FromID], arrow, text(graphEdgeLabel(e)), v.names[e.ToID])
+	}
+	classes := map[string][]string{}
+	for _, n := range v.nodes {
+		switch {
+		case n.IsClosed():
+			classes["closed"] = append(classes["closed"], v.names[n.ID])
+		case n.IsLocalOnly():
+			classes["local"] = append(classes["local"], v.names[n.ID])
+		}
+		if n.IsPlaceholder() {
+			classes["placeholder"] = append(classes["placeholder"], v.names[n.ID])
+		}
+	}
+	for _, c := range []struct{ name, style string }{
+		{"closed", "fill:#e5e7eb,color:#6b7280"},
+		{"local", "fill:#fef3c7"},
+		{"placeholder", "stroke-dasharray:4 3"},
+	} {
+		if len(classes[c.name]) > 0 {
+			fmt.Fprintf(&sb, "  classDef %s %s\n  class %s %s\n", c.name, c.style, strings.Join(classes[c.name], ","), c.name)
+		}
+	}
+	return sb.String()
+}
+
+func (v graphView) plantuml(name string) string {
+	var sb strings.Builder
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`"`, `'`, "\n", `\n`).Replace(s) + `"`
+	}
+	sb.WriteString("@startuml\n")
+	if name != "" {
+		fmt.Fprintf(&sb, "title %s\n", strings.ReplaceAll(name, "\n", " "))
+	}
+	sb.WriteString("left to right direction\n")
+	node := func(indent string, n Node) {
+		shape, style := "rectangle", ""
+		switch {
+		case n.IsClosed():
+			style = "#e5e7eb;text:6b7280"
+		case n.IsLocalOnly():
+			shape, style = "card", "#fef3c7"
+		}
+		if n.IsPlaceholder() {
+			if style == "" {
+				style = "#"
+			} else {
+				style += ";"
+			}
+			style += "line.dashed"
+		}
+		if style != "" {
+			style = " " + style
+		}
+		fmt.Fprintf(&sb, "%s%s %s as %s%s\n", indent, shape, quote(graphNodeLabel(n)), v.names[n.ID], style)
+	}
+	for _, n := range v.members[""] {
+		node("", n)
+	}
+	for _, c := range v.clusters {
+		fmt.Fprintf(&sb, "package %s {\n", quote(c))
+		for _, n := range v.members[c] {
+			node("  ", n)
+		}
+		sb.WriteString("}\n")
+	}
+	for _, e := range v.edges {
+		arrow := "-->"
+		switch graphEdgeStyle(e) {
+		case "bold":
+			arrow = "-[bold]->"
+		case "dashed":
+			arrow = "-[dashed]->"
+		}
+		fmt.Fprintf(&sb, "%s %s %s : %s\n", v.names[e.FromID], arrow, v.names[e.ToID], graphEdgeLabel(e))
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderGraphStylesAndFilters(t *testing.T) {
+	snap := Snapshot{
+		Board: Board{ID: "default", Name: "Launch"},
+		Nodes: []Node{
+			{ID: "gh:acme/api#1", Kind: "issue", Title: `Ship "v2"`, State: "open", Owner: "ada"},
+			{ID: "gh:acme/api#2", Kind: "issue", Title: "Auth", State: "closed", Owner: "ada"},
+			{ID: "gh:acme/web#3", Kind: "issue", Title: "gh:acme/web#3", State: "open", DataJSON: `{"placeholder":true}`},
+			{ID: "note:plan", Kind: "note", Title: "Plan", State: "local"},
+			{ID: "jira:OPS-4", Kind: "issue", Title: "Ops", State: "open"},
+		},
+		Edges: []Edge{
+			{FromID: "gh:acme/api#1", ToID: "gh:acme/api#2", Kind: "blocked_by", Authority: "github", Confidence: 1},
+			{FromID: "gh:acme/api#1", ToID: "gh:acme/web#3", Kind: "blocked_by", Authority: "github-inferred", Confidence: 0.75},
+			{FromID: "note:plan", ToID: "gh:acme/api#1", Kind: "mentions", Authority: "local", Confidence: 1},
+			{FromID: "jira:OPS-4", ToID: "note:plan", Kind: "relates_to", Authority: "jira", Confidence: 1},
+		},
+	}
+
+	got, err := RenderGraph(snap, GraphRenderOptions{Format: "mermaid", Cluster: "repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `flowchart LR
+  n4["note:plan<br/>Plan"]
+  subgraph c0["acme/api"]
+    n0["gh:acme/api#35;1<br/>Ship #quot;v2#quot;"]
+    n1["gh:acme/api#35;2<br/>Auth"]
+  end
+  subgraph c1["acme/web"]
+    n2["gh:acme/web#35;3"]
+  end
+  subgraph c2["jira:OPS"]
+    n3["jira:OPS-4<br/>Ops"]
+  end
+  n0 ==>|"depends on"| n1
+  n0 -.->|"depends on"| n2
+  n3 -->|"relates to"| n4
+  n4 -->|"mentions"| n0
+  classDef closed fill:#e5e7eb,color:#6b7280
+  class n1 closed
+  classDef local fill:#fef3c7
+  class n4 local
+  classDef placeholder stroke-dasharray:4 3
+  class n2 placeholder
+`
+	if got != want {
+		t.Fatalf("mermaid:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = RenderGraph(snap, GraphRenderOptions{Format: "dot", Cluster: "owner", HideClosed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`  label="Launch";`,
+		`  subgraph cluster_0 {` + "\n" + `    label="ada";` + "\n" + `    n0 [label="gh:acme/api#1\nShip \"v2\"", style="rounded,filled"];`,
+		`  n1 [label="gh:acme/web#3", style="rounded,filled,dashed"];`,
+		`  n3 [label="note:plan\nPlan", shape=note, fillcolor="#fef3c7", style="rounded,filled"];`,
+		`  n0 -> n1 [label="depends on", style=dashed];`,
+	} {
+		if !strings.Contains(got, line) {
+			t.Fatalf("dot is missing %q:\n%s", line, got)
+		}
+	}
+	if strings.Contains(got, "Auth") {
+		t.Fatalf("closed node rendered:\n%s", got)
+	}
+
+	got, err = RenderGraph(snap, GraphRenderOptions{Format: "plantuml", Focus: "jira:OPS-4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `@startuml
+title Launch
+left to right direction
+rectangle "jira:OPS-4\nOps" as n0
+card "note:plan\nPlan" as n1 #fef3c7
+n0 --> n1 : relates to
+@enduml
+`
+	if got != want {
+		t.Fatalf("plantuml:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := RenderGraph(snap, GraphRenderOptions{Format: "svg"}); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+	if _, err := RenderGraph(snap, GraphRenderOptions{Format: "dot", Focus: "gh:acme/api#9"}); err == nil {
+		t.Fatal("unknown focus accepted")
+	}
+}
@@ -12,7 +12,12 @@ func (s *Store) RenderHTML(ctx context.Context, boardID string, w io.Writer) err
 	if err != nil {
 		return err
 	}
-	data, err := json.Marshal(payload)
+	// The graph is laid out here so big boards open in place instead of
+	// reflowing in the browser.
+	data, err := json.Marshal(struct {
+		Export
+		Layout Layout `json:"layout"`
+	}{payload, LayoutGraph(payload.Snapshot)})
 	if err != nil {
 		return err
 	}
@@ -56,7 +61,7 @@ var htmlTemplate = template.Must(template.New("html").Parse(`<!doctype html>
     .card.note { border-color:#0f766e; background:#eefdfa; }
     .card.closed { color:var(--closed); background:#f3f4f6; }
     .card .id { font-size:12px; color:var(--muted); margin-bottom:4px; white-space:nowrap; overflow:hidden; text-overflow:ellipsis; }
-    .card .title { font-weight:650; }
+    .card .title { font-weight:650; white-space:nowrap; overflow:hidden; text-overflow:ellipsis; }
     .card .state { margin-top:6px; font-size:12px; color:var(--muted); }
     svg.edges { position:absolute; inset:0; pointer-events:none; overflow:visible; }
     table { width:100%; border-collapse:collapse; background:var(--panel); border:1px solid var(--line); border-radius:8px; overflow:hidden; }
@@ -140,26 +145,32 @@ function renderGraph() {
   const list = visibleNodes();
   const visible = new Set(list.map(n => n.id));
   const graph = document.getElementById('graph');
-  graph.innerHTML = '<svg class="edges" width="1800" height="1200"><defs><marker id="arrow" markerWidth="8" markerHeight="8" refX="6" refY="3" orient="auto"><path d="M0,0 L0,6 L7,3 z" fill="#9aa4b2"></path></marker></defs></svg>';
-  const cols = 4, xGap = 250, yGap = 130;
+  const layout = data.layout || { width: 900, height: 620, nodes: [], edges: [] };
+  graph.style.width = layout.width + 'px';
+  graph.style.height = layout.height + 'px';
+  graph.innerHTML = '<svg class="edges" width="' + layout.width + '" height="' + layout.height + '"><defs><marker id="arrow" markerWidth="8" markerHeight="8" refX="6" refY="3" orient="auto"><path d="M0,0 L0,6 L7,3 z" fill="#9aa4b2"></path></marker></defs></svg>';
   const pos = {};
-  list.forEach((n, i) => { const col = i % cols, row = Math.floor(i / cols); pos[n.id] = { x: 30 + col * xGap, y: 30 + row * yGap }; });
+  for (const p of layout.nodes) pos[p.id] = p;
   const svg = graph.querySelector('svg');
-  for (const e of edges) {
-    if (!visible.has(e.from_id || e.FromID) || !visible.has(e.to_id || e.ToID)) continue;
-    const from = pos[e.from_id || e.FromID], to = pos[e.to_id || e.ToID];
-    if (!from || !to) continue;
-    const line = document.createElementNS('http://www.w3.org/2000/svg','line');
-    line.setAttribute('x1', from.x + 210); line.setAttribute('y1', from.y + 38);
-    line.setAttribute('x2', to.x); line.setAttribute('y2', to.y + 38);
-    line.setAttribute('stroke', '#9aa4b2'); line.setAttribute('stroke-width', '1.4'); line.setAttribute('marker-end', 'url(#arrow)');
-    svg.appendChild(line);
+  for (const e of layout.edges) {
+    if (!visible.has(e.from) || !visible.has(e.to) || !e.points.length) continue;
+    let d = 'M' + e.points[0].x + ',' + e.points[0].y;
+    for (let i = 1; i < e.points.length; i++) {
+      const a = e.points[i-1], b = e.points[i], mx = (a.x + b.x) / 2;
+      d += a.y === b.y ? ' L' + b.x + ',' + b.y : ' C' + mx + ',' + a.y + ' ' + mx + ',' + b.y + ' ' + b.x + ',' + b.y;
+    }
+    const path = document.createElementNS('http://www.w3.org/2000/svg','path');
+    path.setAttribute('d', d); path.setAttribute('fill', 'none');
+    path.setAttribute('stroke', '#9aa4b2'); path.setAttribute('stroke-width', '1.4'); path.setAttribute('marker-end', 'url(#arrow)');
+    svg.appendChild(path);
   }
   for (const n of list) {
     const p = pos[n.id];
+    if (!p) continue;
     const div = document.createElement('div');
     div.className = 'card ' + (isLocal(n) ? 'note ' : '') + (isClosed(n) ? 'closed' : '');
     div.style.transform = 'translate(' + p.x + 'px, ' + p.y + 'px)';
+    div.style.width = p.w + 'px'; div.style.height = p.h + 'px'; div.style.overflow = 'hidden';
     div.innerHTML = '<div class="id">' + link(n) + '</div><div class="title">' + esc(n.title) + '</div><div class="state">' + esc(n.kind) + ' - ' + esc(n.state || '') + '</div>';
     graph.appendChild(div);
   }
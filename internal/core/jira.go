@@ -0,0 +1,326 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"moul.io/depviz/v4/internal/core/jira"
+)
+
+// JiraSyncOptions selects the Jira issues SyncJira imports and where.
+type JiraSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Project is a project key such as OPS; JQL narrows it, or selects issues
+	// on its own when Project is empty.
+	Project string
+	JQL     string
+	// Limit caps the issues read; it defaults to 200.
+	Limit int
+	// Client defaults to jira.FromEnv.
+	Client *jira.Client
+}
+
+// JiraSyncResult counts what a Jira sync imported.
+type JiraSyncResult struct {
+	Query string `json:"query"`
+	Items int    `json:"items"`
+	Links int    `json:"links"`
+}
+
+// jiraFields are the issue fields a sync asks for.
+var jiraFields = []string{"summary", "description", "status", "assignee", "reporter", "labels", "issuetype", "priority", "updated", "parent", "subtasks", "issuelinks"}
+
+// jiraIssue is an issue from the REST API. Linked issues, parents and
+// sub-tasks come in the same shape with fewer fields.
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      *struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Assignee  *jiraUser `json:"assignee"`
+		Reporter  *jiraUser `json:"reporter"`
+		Labels    []string  `json:"labels"`
+		IssueType *struct {
+			Name    string `json:"name"`
+			Subtask bool   `json:"subtask"`
+		} `json:"issuetype"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Updated    string       `json:"updated"`
+		Parent     *jiraIssue   `json:"parent"`
+		Subtasks   []*jiraIssue `json:"subtasks"`
+		IssueLinks []struct {
+			ID   string `json:"id"`
+			Type struct {
+				Name    string `json:"name"`
+				Inward  string `json:"inward"`
+				Outward string `json:"outward"`
+			} `json:"type"`
+			InwardIssue  *jiraIssue `json:"inwardIssue"`
+			OutwardIssue *jiraIssue `json:"outwardIssue"`
+		} `json:"issuelinks"`
+	} `json:"fields"`
+}
+
+// jiraUser is a Jira user; Cloud only exposes the account ID and display
+// name, Server and Data Center a user name too.
+type jiraUser struct {
+	Name        string `json:"name"`
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+}
+
+func (u *jiraUser) login() string {
+	switch {
+	case u == nil:
+		return ""
+	case u.Name != "":
+		return u.Name
+	default:
+		return u.DisplayName
+	}
+}
+
+// SyncJira imports the Jira issues a project key and JQL select as jira:KEY-N
+// nodes. An issue whose status is in the Done category is done, the In
+// Progress category is in_progress and anything else open, so Node.IsClosed
+// follows Jira's own notion of finished. "blocks" and "is blocked by" links,
+// and sub-tasks, which block their parent, become hard edges with authority
+// jira; "relates to" and other links become relates_to edges. Every run is
+// recorded in sync_logs.
+func SyncJira(ctx context.Context, s *Store, opts JiraSyncOptions) (JiraSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Client == nil {
+		opts.Client = jira.FromEnv()
+	}
+	var res JiraSyncResult
+	err := s.runSync(ctx, opts.Board, "jira", func() (int, int, error) {
+		var err error
+		res, err = syncJira(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncJira(ctx context.Context, s *Store, opts JiraSyncOptions) (JiraSyncResult, error) {
+	query := jiraQuery(opts.Project, opts.JQL)
+	if query == "" {
+		return JiraSyncResult{}, fmt.Errorf("a project key or JQL is required")
+	}
+	res := JiraSyncResult{Query: query}
+	issues, err := jira.Search[jiraIssue](ctx, opts.Client, query, jiraFields, opts.Limit)
+	if err != nil {
+		return res, err
+	}
+	site := strings.TrimRight(opts.Client.BaseURL, "/")
+	for _, issue := range issues {
+		if issue.Key == "" {
+			continue
+		}
+		node, err := s.upsertJiraIssue(ctx, opts.Board, site, issue)
+		if err != nil {
+			return res, err
+		}
+		res.Items++
+		links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges("", node.ID, issue.Fields.Description))
+		if err != nil {
+			return res, err
+		}
+		res.Links += links
+		if links, err = s.replaceJiraLinks(ctx, opts.Board, site, node.ID, issue); err != nil {
+			return res, err
+		}
+		res.Links += links
+	}
+	return res, nil
+}
+
+// jiraQuery combines a project key and JQL, newest first unless the JQL
+// orders the issues itself.
+func jiraQuery(project, jql string) string {
+	jql = strings.TrimSpace(jql)
+	order := " ORDER BY updated DESC"
+	if i := strings.Index(strings.ToLower(jql), "order by"); i >= 0 {
+		jql, order = strings.TrimSpace(jql[:i]), " "+jql[i:]
+	}
+	var parts []string
+	if project = strings.TrimSpace(project); project != "" {
+		parts = append(parts, fmt.Sprintf("project = %q", project))
+	}
+	if jql != "" {
+		parts = append(parts, "("+jql+")")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " AND ") + order
+}
+
+// upsertJiraIssue stores an issue as a node linked to the jira:KEY source of
+// its project, creating the source when missing, and adds it to a board.
+func (s *Store) upsertJiraIssue(ctx context.Context, boardID, site string, issue jiraIssue) (Node, error) {
+	id := "jira:" + issue.Key
+	f := issue.Fields
+	state, status := "open", ""
+	if f.Status != nil {
+		status = f.Status.Name
+		switch f.Status.StatusCategory.Key {
+		case "done":
+			state = "done"
+		case "indeterminate":
+			state = "in_progress"
+		}
+	}
+	issueType, priority := "", ""
+	if f.IssueType != nil {
+		issueType = f.IssueType.Name
+	}
+	if f.Priority != nil {
+		priority = f.Priority.Name
+	}
+	labels := f.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	url := ""
+	if site != "" {
+		url = site + "/browse/" + issue.Key
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":     "jira",
+		"kind":       "issue",
+		"key":        issue.Key,
+		"issue_type": issueType,
+		"status":     status,
+		"priority":   priority,
+		"labels":     labels,
+		"assignee":   f.Assignee.login(),
+		"reporter":   f.Reporter.login(),
+		"body":       f.Description,
+		"synced_at":  formatTime(nowUTC()),
+		"html_url":   url,
+	})
+	updated := parseJiraTime(f.Updated)
+	n := Node{ID: id, Kind: "issue", Title: f.Summary, State: state, Owner: f.Assignee.login(), DataJSON: string(payload), UpdatedAt: updated, URL: url}
+	if n.Title == "" {
+		n.Title = issue.Key
+	}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return Node{}, err
+	}
+	project, _, _ := strings.Cut(issue.Key, "-")
+	sourceID := "jira:" + project
+	if site == "" {
+		if _, err := s.ensureSource(ctx, sourceID); err != nil {
+			return Node{}, err
+		}
+	} else if err := s.UpsertSource(ctx, Source{ID: sourceID, Kind: "jira", Name: project, URL: site + "/browse/" + project, Capabilities: `{"read":true}`, Sync: `{"mode":"rest"}`, UpdatedAt: nowUTC()}); err != nil {
+		return Node{}, err
+	}
+	if err := s.UpsertSourceRef(ctx, id, sourceID, issue.Key, url); err != nil {
+		return Node{}, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, id, "issue", ""); err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+// replaceJiraLinks stores an issue's links, parent and sub-tasks as edges
+// and drops those it no longer has. Links are listed on both issues, so each
+// is stored one way: "A blocks B" and "B is blocked by A" are both B
+// blocked_by A, a parent is blocked_by its sub-tasks, and relates_to runs
+// from the smaller ID. Linked issues not yet known are imported from what the
+// link carries: their summary and status.
+func (s *Store) replaceJiraLinks(ctx context.Context, boardID, site, nodeID string, issue jiraIssue) (int, error) {
+	type link struct {
+		other    *jiraIssue
+		relation string
+		inward   bool
+		id       string
+	}
+	var links []link
+	for _, l := range issue.Fields.IssueLinks {
+		if l.OutwardIssue != nil {
+			links = append(links, link{other: l.OutwardIssue, relation: l.Type.Outward, id: l.ID})
+		}
+		if l.InwardIssue != nil {
+			links = append(links, link{other: l.InwardIssue, relation: l.Type.Inward, inward: true, id: l.ID})
+		}
+	}
+	// Team-managed projects set parent on every child of an epic too; only a
+	// sub-task blocks its parent.
+	if p := issue.Fields.Parent; p != nil && issue.Fields.IssueType != nil && issue.Fields.IssueType.Subtask {
+		links = append(links, link{other: p, relation: "subtask"})
+	}
+	for _, sub := range issue.Fields.Subtasks {
+		links = append(links, link{other: sub, relation: "parent"})
+	}
+	keep := map[string]bool{}
+	for _, l := range links {
+		if l.other == nil || l.other.Key == "" {
+			continue
+		}
+		other := "jira:" + l.other.Key
+		exists, err := s.nodeExists(ctx, other)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			if _, err := s.upsertJiraIssue(ctx, boardID, site, *l.other); err != nil {
+				return 0, err
+			}
+		}
+		from, to, kind := nodeID, other, "relates_to"
+		switch strings.ToLower(strings.TrimSpace(l.relation)) {
+		case "blocks", "is depended on by", "subtask":
+			from, to, kind = other, nodeID, "blocked_by"
+		case "is blocked by", "depends on", "parent":
+			kind = "blocked_by"
+		default:
+			if to < from {
+				from, to = to, from
+			}
+		}
+		evidence := map[string]any{"relation": l.relation}
+		if l.id != "" {
+			evidence["link_id"] = l.id
+		}
+		e, err := s.AddEdgeWithConfidence(ctx, boardID, from, to, kind, "jira", 1, evidence)
+		if err != nil {
+			return 0, err
+		}
+		keep[e.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, nodeID, "jira", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
+
+// parseJiraTime reads Jira's timestamps, which carry milliseconds and a
+// numeric zone without a colon.
+func parseJiraTime(value string) time.Time {
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return nowUTC()
+}
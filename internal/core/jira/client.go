@@ -0,0 +1,186 @@
+// Package jira is a small client for the Jira REST API, for Jira Cloud and
+// Jira Server or Data Center alike.
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Client calls the Jira REST API of one site. With Email set it authenticates
+// like Jira Cloud expects, with the email and an API token; otherwise Token is
+// sent as a Server or Data Center personal access token.
+type Client struct {
+	// BaseURL is the site, such as https://acme.atlassian.net.
+	BaseURL    string
+	Email      string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is a non-2xx Jira response.
+type Error struct {
+	StatusCode int
+	Status     string
+	Path       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Status, e.Path, e.Message)
+}
+
+// FromEnv returns a client for the CLI, configured by JIRA_URL, JIRA_EMAIL and
+// JIRA_API_TOKEN (or JIRA_TOKEN).
+func FromEnv() *Client {
+	c := &Client{
+		BaseURL: strings.TrimSpace(os.Getenv("JIRA_URL")),
+		Email:   strings.TrimSpace(os.Getenv("JIRA_EMAIL")),
+	}
+	for _, key := range []string{"JIRA_API_TOKEN", "JIRA_TOKEN"} {
+		if token := strings.TrimSpace(os.Getenv(key)); token != "" {
+			c.Token = token
+			break
+		}
+	}
+	return c
+}
+
+// Get decodes the JSON response of a GET request into out.
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	if strings.TrimSpace(c.BaseURL) == "" {
+		return errors.New("jira site URL is required: set JIRA_URL or --url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.BaseURL, "/")+"/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.Email != "":
+		req.SetBasicAuth(c.Email, c.Token)
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 20<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &Error{StatusCode: res.StatusCode, Status: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)), Path: pathOf(path), Message: bodySummary(data)}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("expected JSON from %s, got %s: %w", pathOf(path), bodySummary(data), err)
+	}
+	return nil
+}
+
+// Search runs a JQL query and decodes its issues, up to limit of them or all
+// when limit is not positive. It pages with the enhanced search endpoint Jira
+// Cloud serves, and falls back to the classic one on Server and Data Center.
+func Search[T any](ctx context.Context, c *Client, jql string, fields []string, limit int) ([]T, error) {
+	out, err := search[T](ctx, c, "/rest/api/2/search/jql", jql, fields, limit)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return search[T](ctx, c, "/rest/api/2/search", jql, fields, limit)
+	}
+	return out, err
+}
+
+func search[T any](ctx context.Context, c *Client, endpoint, jql string, fields []string, limit int) ([]T, error) {
+	pageSize := 100
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	var out []T
+	token := ""
+	for {
+		q := url.Values{"jql": {jql}, "maxResults": {strconv.Itoa(pageSize)}}
+		if len(fields) > 0 {
+			q.Set("fields", strings.Join(fields, ","))
+		}
+		switch {
+		case strings.HasSuffix(endpoint, "/jql") && token != "":
+			q.Set("nextPageToken", token)
+		case !strings.HasSuffix(endpoint, "/jql"):
+			q.Set("startAt", strconv.Itoa(len(out)))
+		}
+		var page struct {
+			Issues        []T    `json:"issues"`
+			NextPageToken string `json:"nextPageToken"`
+			IsLast        bool   `json:"isLast"`
+			Total         int    `json:"total"`
+		}
+		if err := c.Get(ctx, endpoint+"?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Issues...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if len(page.Issues) == 0 {
+			return out, nil
+		}
+		if strings.HasSuffix(endpoint, "/jql") {
+			if page.IsLast || page.NextPageToken == "" {
+				return out, nil
+			}
+			token = page.NextPageToken
+		} else if len(out) >= page.Total {
+			return out, nil
+		}
+	}
+}
+
+func pathOf(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return target
+}
+
+func bodySummary(data []byte) string {
+	body := strings.TrimSpace(string(data))
+	if body == "" {
+		return "empty response"
+	}
+	var envelope struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+		Message       string            `json:"message"`
+	}
+	if json.Unmarshal(data, &envelope) == nil {
+		msgs := envelope.ErrorMessages
+		for field, msg := range envelope.Errors {
+			msgs = append(msgs, field+": "+msg)
+		}
+		if len(msgs) > 0 {
+			return strings.Join(msgs, "; ")
+		}
+		if envelope.Message != "" {
+			return envelope.Message
+		}
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if len(body) > 240 {
+		body = body[:240] + "..."
+	}
+	return body
+}
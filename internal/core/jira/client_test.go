@@ -0,0 +1,104 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchPagesWithNextPageToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ada@acme.dev" || pass != "secret" {
+			t.Errorf("basic auth = %q %q %v", user, pass, ok)
+		}
+		if r.URL.Path != "/rest/api/2/search/jql" || r.URL.Query().Get("jql") != "project = OPS" || r.URL.Query().Get("fields") != "summary,status" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		switch r.URL.Query().Get("nextPageToken") {
+		case "":
+			fmt.Fprint(w, `{"issues":[{"key":"OPS-1"},{"key":"OPS-2"}],"nextPageToken":"p2"}`)
+		case "p2":
+			fmt.Fprint(w, `{"issues":[{"key":"OPS-3"}],"isLast":true}`)
+		default:
+			t.Errorf("nextPageToken = %q", r.URL.Query().Get("nextPageToken"))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, Email: "ada@acme.dev", Token: "secret"}
+	issues, err := Search[struct{ Key string }](context.Background(), c, "project = OPS", []string{"summary", "status"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(issues) != "[{OPS-1} {OPS-2} {OPS-3}]" {
+		t.Fatalf("issues = %v", issues)
+	}
+}
+
+func TestSearchFallsBackToClassicSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/rest/api/2/search/jql":
+			http.NotFound(w, r)
+		case "/rest/api/2/search":
+			if r.URL.Query().Get("startAt") == "0" {
+				fmt.Fprint(w, `{"issues":[{"key":"OPS-1"}],"total":2}`)
+			} else {
+				fmt.Fprint(w, `{"issues":[{"key":"OPS-2"}],"total":2}`)
+			}
+		}
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, Token: "pat"}
+	issues, err := Search[struct{ Key string }](context.Background(), c, "project = OPS", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(issues) != "[{OPS-1} {OPS-2}]" {
+		t.Fatalf("issues = %v", issues)
+	}
+	err = c.Get(context.Background(), "/rest/api/2/search/jql", &struct{}{})
+	if got := fmt.Sprint(err); got != "404 Not Found /rest/api/2/search/jql: 404 page not found" {
+		t.Fatalf("err = %q", got)
+	}
+}
+
+func TestSearchStopsAtLimit(t *testing.T) {
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if r.URL.Query().Get("maxResults") != "2" {
+			t.Errorf("maxResults = %q", r.URL.Query().Get("maxResults"))
+		}
+		fmt.Fprint(w, `{"issues":[{"key":"OPS-1"},{"key":"OPS-2"}],"nextPageToken":"p2"}`)
+	}))
+	defer srv.Close()
+	issues, err := Search[struct{ Key string }](context.Background(), &Client{BaseURL: srv.URL}, "project = OPS", nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(issues) != "[{OPS-1} {OPS-2}]" || pages != 1 {
+		t.Fatalf("issues = %v after %d pages", issues, pages)
+	}
+}
+
+func TestSearchReportsJiraErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errorMessages":["The value 'NOPE' does not exist for the field 'project'."],"errors":{}}`)
+	}))
+	defer srv.Close()
+	_, err := Search[struct{ Key string }](context.Background(), &Client{BaseURL: srv.URL}, "project = NOPE", nil, 0)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v", err)
+	}
+	if got := err.Error(); got != "400 Bad Request /rest/api/2/search/jql: The value 'NOPE' does not exist for the field 'project'." {
+		t.Fatalf("Error() = %q", got)
+	}
+}
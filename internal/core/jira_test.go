@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/jira"
+)
+
+func TestSyncJiraMapsStatusLinksAndSubtasks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/search/jql" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("jql"); got != `project = "OPS" AND (labels = launch) ORDER BY rank` {
+			t.Errorf("jql = %q", got)
+		}
+		fmt.Fprint(w, `{"isLast":true,"issues":[
+			{"key":"OPS-1","fields":{"summary":"Ship","description":"Depends on OPS-4 and https://github.com/acme/api/issues/3",
+				"status":{"name":"In Review","statusCategory":{"key":"indeterminate"}},"assignee":{"accountId":"a1","displayName":"Ada"},
+				"labels":["launch"],"updated":"2026-10-01T10:00:00.000+0200",
+				"subtasks":[{"key":"OPS-5","fields":{"summary":"Docs","status":{"name":"To Do","statusCategory":{"key":"new"}}}}],
+				"issuelinks":[
+					{"id":"100","type":{"name":"Blocks","inward":"is blocked by","outward":"blocks"},"inwardIssue":{"key":"OPS-2"}},
+					{"id":"101","type":{"name":"Relates","inward":"relates to","outward":"relates to"},"outwardIssue":{"key":"OPS-9","fields":{"summary":"Audit","status":{"name":"Closed","statusCategory":{"key":"done"}}}}}
+				]}},
+			{"key":"OPS-2","fields":{"summary":"Schema","status":{"name":"Done","statusCategory":{"key":"done"}},
+				"issuelinks":[{"id":"100","type":{"name":"Blocks","inward":"is blocked by","outward":"blocks"},"outwardIssue":{"key":"OPS-1"}}]}},
+			{"key":"OPS-5","fields":{"summary":"Docs","status":{"name":"To Do","statusCategory":{"key":"new"}},"issuetype":{"name":"Sub-task","subtask":true},"parent":{"key":"OPS-1"}}},
+			{"key":"OPS-6","fields":{"summary":"Launch page","status":{"name":"To Do","statusCategory":{"key":"new"}},"issuetype":{"name":"Story","subtask":false},"parent":{"key":"OPS-7"}}}
+		]}`)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	res, err := SyncJira(ctx, s, JiraSyncOptions{Project: "OPS", JQL: "labels = launch ORDER BY rank", Client: &jira.Client{BaseURL: srv.URL, Token: "pat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 4 {
+		t.Fatalf("result = %+v", res)
+	}
+	// Cross-tracker edges go through the same placeholders as edge add.
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "gh:acme/api#10", "jira:OPS-42", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority))
+	}
+	sort.Strings(edges)
+	want := []string{
+		"gh:acme/api#10 blocked_by jira:OPS-42 local",
+		"jira:OPS-1 blocked_by gh:acme/api#3 jira-inferred",
+		"jira:OPS-1 blocked_by jira:OPS-2 jira",
+		"jira:OPS-1 blocked_by jira:OPS-4 jira-inferred",
+		"jira:OPS-1 blocked_by jira:OPS-5 jira",
+		"jira:OPS-1 relates_to jira:OPS-9 jira",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %q, want %q", edges, want)
+	}
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	if n := nodes["jira:OPS-1"]; n.State != "in_progress" || n.IsClosed() || n.Owner != "Ada" || n.URL != srv.URL+"/browse/OPS-1" {
+		t.Fatalf("OPS-1 = %+v", n)
+	}
+	if n := nodes["jira:OPS-9"]; n.Title != "Audit" || !n.IsClosed() {
+		t.Fatalf("linked OPS-9 = %+v", n)
+	}
+	if n := nodes["jira:OPS-42"]; n.URL != srv.URL+"/browse/OPS-42" {
+		t.Fatalf("placeholder OPS-42 = %+v", n)
+	}
+
+	doc, err := ParseFlow("gh:acme/api#10 depends on jira:OPS-42\njira:OPS-42 \"Rotate keys\" [done]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Edges) != 1 || doc.Edges[0].To != "jira:OPS-42" || doc.Nodes[1].Title != "Rotate keys" {
+		t.Fatalf("flow = %+v", doc)
+	}
+	if got := extractDependencyEdges("acme/api", "gh:acme/api#10", "Blocked by jira:OPS-42"); len(got) != 1 || got[0].To != "jira:OPS-42" {
+		t.Fatalf("extracted = %+v", got)
+	}
+}
+
+func TestSyncJiraPrunesRemovedLinks(t *testing.T) {
+	links := `[{"id":"100","type":{"name":"Blocks","inward":"is blocked by","outward":"blocks"},"inwardIssue":{"key":"OPS-2"}}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"isLast":true,"issues":[{"key":"OPS-1","fields":{"summary":"Ship","issuelinks":%s}}]}`, links)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := JiraSyncOptions{Project: "OPS", Client: &jira.Client{BaseURL: srv.URL}}
+	if res, err := SyncJira(ctx, s, opts); err != nil || res.Links != 1 {
+		t.Fatalf("first sync = %+v, %v", res, err)
+	}
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "jira:OPS-1", "jira:OPS-3", "blocked_by", "user", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	links = `[]`
+	if _, err := SyncJira(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority))
+	}
+	if want := []string{"jira:OPS-1 blocked_by jira:OPS-3 user"}; !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %q, want %q: the unlinked edge pruned, the local one kept", edges, want)
+	}
+}
@@ -0,0 +1,413 @@
+package core
+
+import (
+	"sort"
+)
+
+// Layout is where LayoutGraph puts a snapshot's nodes and edges, in pixels
+// from the top left corner. Layers run left to right, as in gen dot.
+type Layout struct {
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	Nodes  []LayoutNode `json:"nodes"`
+	Edges  []LayoutEdge `json:"edges"`
+}
+
+type LayoutNode struct {
+	ID    string `json:"id"`
+	Layer int    `json:"layer"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	W     int    `json:"w"`
+	H     int    `json:"h"`
+}
+
+// LayoutEdge is drawn through Points, from the right side of From to the
+// left side of To, bending where it crosses the layers in between. Edges laid
+// out against the layers to break a cycle run from the left side of From.
+type LayoutEdge struct {
+	From   string        `json:"from"`
+	To     string        `json:"to"`
+	Kind   string        `json:"kind"`
+	Points []LayoutPoint `json:"points"`
+}
+
+type LayoutPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+const (
+	layoutNodeW    = 210
+	layoutNodeH    = 84
+	layoutMargin   = 30
+	layoutLayerGap = 90
+	layoutRowGap   = 36
+	layoutSweeps   = 8
+)
+
+// layoutSlot is a node or, for an edge spanning several layers, a dummy the
+// edge bends through.
+type layoutSlot struct {
+	dummy bool
+	layer int
+	y     float64
+	h     float64
+	in    []int
+	out   []int
+}
+
+// LayoutGraph places a snapshot with a layered (Sugiyama-style) layout:
+// cycles are broken by reversing back edges, nodes are put on the layer after
+// their furthest predecessor, crossings are reduced with barycenter sweeps
+// and each node is then pulled level with its neighbours. The result only
+// depends on the snapshot, so the same board is always drawn the same way.
+func LayoutGraph(snap Snapshot) Layout {
+	var ids []string
+	index := map[string]int{}
+	for _, n := range snap.Nodes {
+		if _, ok := index[n.ID]; !ok {
+			index[n.ID] = 0
+			ids = append(ids, n.ID)
+		}
+	}
+	sort.Strings(ids)
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	// One layout edge per linked pair, whatever the relations between them.
+	type pair struct{ from, to int }
+	var pairs []pair
+	seen := map[pair]bool{}
+	var edges []Edge
+	for _, e := range snap.Edges {
+		from, ok := index[e.FromID]
+		if !ok {
+			continue
+		}
+		to, ok := index[e.ToID]
+		if !ok {
+			continue
+		}
+		edges = append(edges, e)
+		p := pair{from, to}
+		if from == to || seen[p] || seen[pair{to, from}] {
+			continue
+		}
+		seen[p] = true
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].from != pairs[j].from {
+			return pairs[i].from < pairs[j].from
+		}
+		return pairs[i].to < pairs[j].to
+	})
+
+	// Break cycles: an edge back into the current depth-first path is laid out
+	// reversed.
+	out := make([][]int, len(ids))
+	for _, p := range pairs {
+		out[p.from] = append(out[p.from], p.to)
+	}
+	reversed := map[pair]bool{}
+	mark := make([]int, len(ids)) // 0 unseen, 1 on the path, 2 done
+	var visit func(int)
+	visit = func(v int) {
+		mark[v] = 1
+		for _, w := range out[v] {
+			switch mark[w] {
+			case 0:
+				visit(w)
+			case 1:
+				reversed[pair{v, w}] = true
+			}
+		}
+		mark[v] = 2
+	}
+	for v := range ids {
+		if mark[v] == 0 {
+			visit(v)
+		}
+	}
+	var dag []pair
+	for _, p := range pairs {
+		if reversed[p] {
+			p = pair{p.to, p.from}
+		}
+		dag = append(dag, p)
+	}
+
+	// Longest-path layering.
+	layer := make([]int, len(ids))
+	indeg := make([]int, len(ids))
+	succ := make([][]int, len(ids))
+	for _, p := range dag {
+		succ[p.from] = append(succ[p.from], p.to)
+		indeg[p.to]++
+	}
+	var queue []int
+	for v := range ids {
+		if indeg[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range succ[v] {
+			if layer[v]+1 > layer[w] {
+				layer[w] = layer[v] + 1
+			}
+			if indeg[w]--; indeg[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	// Slots: the nodes first, then a dummy per layer a long edge crosses.
+	slots := make([]layoutSlot, len(ids))
+	for v := range ids {
+		slots[v] = layoutSlot{layer: layer[v], h: layoutNodeH}
+	}
+	chains := map[pair][]int{}
+	for _, orig := range pairs {
+		p := orig
+		if reversed[p] {
+			p = pair{p.to, p.from}
+		}
+		chain := []int{p.from}
+		for l := layer[p.from] + 1; l < layer[p.to]; l++ {
+			slots = append(slots, layoutSlot{dummy: true, layer: l})
+			chain = append(chain, len(slots)-1)
+		}
+		chain = append(chain, p.to)
+		for i := 0; i+1 < len(chain); i++ {
+			slots[chain[i]].out = append(slots[chain[i]].out, chain[i+1])
+			slots[chain[i+1]].in = append(slots[chain[i+1]].in, chain[i])
+		}
+		chains[orig] = chain
+	}
+
+	layers := [][]int{}
+	for i, s := range slots {
+		for len(layers) <= s.layer {
+			layers = append(layers, nil)
+		}
+		layers[s.layer] = append(layers[s.layer], i)
+	}
+	pos := make([]float64, len(slots))
+	order := func(l []int) {
+		for i, v := range l {
+			pos[v] = float64(i)
+		}
+	}
+	for _, l := range layers {
+		order(l)
+	}
+
+	// Reduce crossings by sorting each layer on the mean position of its
+	// neighbours in the layer just swept, alternating directions, and keep
+	// the ordering with the fewest crossings.
+	best := layoutCopy(layers)
+	bestCrossings := layoutCrossings(slots, layers, pos)
+	for sweep := 0; sweep < layoutSweeps && bestCrossings > 0; sweep++ {
+		down := sweep%2 == 0
+		for k := 1; k < len(layers); k++ {
+			li := k
+			if !down {
+				li = len(layers) - 1 - k
+			}
+			l := layers[li]
+			bary := map[int]float64{}
+			for _, v := range l {
+				nbrs := slots[v].in
+				if !down {
+					nbrs = slots[v].out
+				}
+				if len(nbrs) == 0 {
+					bary[v] = pos[v]
+					continue
+				}
+				var sum float64
+				for _, u := range nbrs {
+					sum += pos[u]
+				}
+				bary[v] = sum / float64(len(nbrs))
+			}
+			sort.SliceStable(l, func(i, j int) bool { return bary[l[i]] < bary[l[j]] })
+			order(l)
+		}
+		if c := layoutCrossings(slots, layers, pos); c < bestCrossings {
+			best, bestCrossings = layoutCopy(layers), c
+		}
+	}
+	layers = best
+	for _, l := range layers {
+		order(l)
+	}
+
+	// Vertical placement: stack each layer, then pull nodes level with their
+	// neighbours without letting them overlap or change order.
+	for _, l := range layers {
+		y := 0.0
+		for _, v := range l {
+			slots[v].y = y
+			y += slots[v].h + layoutRowGap
+		}
+	}
+	for pass := 0; pass < 4; pass++ {
+		for k := range layers {
+			li := k
+			if pass%2 == 1 {
+				li = len(layers) - 1 - k
+			}
+			layoutAlign(slots, layers[li], pass%2 == 0)
+		}
+	}
+	minY, maxY := 0.0, 0.0
+	for i, s := range slots {
+		if i == 0 || s.y < minY {
+			minY = s.y
+		}
+		if i == 0 || s.y+s.h > maxY {
+			maxY = s.y + s.h
+		}
+	}
+
+	x := func(l int) int { return layoutMargin + l*(layoutNodeW+layoutLayerGap) }
+	y := func(v int) int { return layoutMargin + int(slots[v].y-minY+0.5) }
+	lay := Layout{Width: 2 * layoutMargin, Height: 2*layoutMargin + int(maxY-minY+0.5), Nodes: []LayoutNode{}, Edges: []LayoutEdge{}}
+	for v, id := range ids {
+		n := LayoutNode{ID: id, Layer: layer[v], X: x(layer[v]), Y: y(v), W: layoutNodeW, H: layoutNodeH}
+		lay.Nodes = append(lay.Nodes, n)
+		if w := n.X + n.W + layoutMargin; w > lay.Width {
+			lay.Width = w
+		}
+	}
+	for _, e := range edges {
+		from, to := index[e.FromID], index[e.ToID]
+		le := LayoutEdge{From: e.FromID, To: e.ToID, Kind: e.Kind}
+		if from == to {
+			// A self loop bends around the node's top right corner.
+			n := lay.Nodes[from]
+			le.Points = []LayoutPoint{{n.X + n.W, n.Y + 20}, {n.X + n.W + 24, n.Y - 12}, {n.X + n.W - 24, n.Y - 12}, {n.X + n.W - 30, n.Y}}
+			lay.Edges = append(lay.Edges, le)
+			continue
+		}
+		// Chains run the laid out way; flip those drawn against it.
+		chain, ok := chains[pair{from, to}]
+		flip := reversed[pair{from, to}]
+		if !ok {
+			chain, ok = chains[pair{to, from}]
+			flip = !reversed[pair{to, from}]
+		}
+		if !ok {
+			continue
+		}
+		points := make([]LayoutPoint, 0, len(chain)+1)
+		for i, v := range chain {
+			cy := y(v) + int(slots[v].h/2)
+			cx := x(slots[v].layer)
+			switch {
+			case slots[v].dummy:
+				points = append(points, LayoutPoint{cx, cy}, LayoutPoint{cx + layoutNodeW, cy})
+			case i == 0:
+				points = append(points, LayoutPoint{cx + layoutNodeW, cy})
+			default:
+				points = append(points, LayoutPoint{cx, cy})
+			}
+		}
+		if flip {
+			for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+				points[i], points[j] = points[j], points[i]
+			}
+		}
+		le.Points = points
+		lay.Edges = append(lay.Edges, le)
+	}
+	return lay
+}
+
+func layoutCopy(layers [][]int) [][]int {
+	out := make([][]int, len(layers))
+	for i, l := range layers {
+		out[i] = append([]int(nil), l...)
+	}
+	return out
+}
+
+// layoutCrossings counts the edge segments that cross between neighbouring
+// layers.
+func layoutCrossings(slots []layoutSlot, layers [][]int, pos []float64) int {
+	count := 0
+	for _, l := range layers {
+		var segs [][2]float64
+		for _, u := range l {
+			for _, v := range slots[u].out {
+				segs = append(segs, [2]float64{pos[u], pos[v]})
+			}
+		}
+		for i := range segs {
+			for j := i + 1; j < len(segs); j++ {
+				if (segs[i][0]-segs[j][0])*(segs[i][1]-segs[j][1]) < 0 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// layoutAlign moves the slots of a layer as close as it can to the mean
+// centre of their neighbours on the left (or right) while keeping their order
+// and spacing, with least squares over runs of slots pressed together.
+func layoutAlign(slots []layoutSlot, l []int, fromLeft bool) {
+	type block struct {
+		sum   float64
+		count int
+	}
+	var blocks []block
+	var sizes []int
+	offset := 0.0
+	for _, v := range l {
+		nbrs := slots[v].in
+		if !fromLeft {
+			nbrs = slots[v].out
+		}
+		want := slots[v].y + slots[v].h/2
+		if len(nbrs) > 0 {
+			want = 0
+			for _, u := range nbrs {
+				want += slots[u].y + slots[u].h/2
+			}
+			want /= float64(len(nbrs))
+		}
+		// Each slot wants its top at want-h/2; offset turns that into
+		// where the top of the layer's first slot would go.
+		b := block{sum: want - slots[v].h/2 - offset, count: 1}
+		size := 1
+		for len(blocks) > 0 {
+			prev := blocks[len(blocks)-1]
+			if prev.sum/float64(prev.count) < b.sum/float64(b.count) {
+				break
+			}
+			b = block{sum: prev.sum + b.sum, count: prev.count + b.count}
+			size += sizes[len(sizes)-1]
+			blocks, sizes = blocks[:len(blocks)-1], sizes[:len(sizes)-1]
+		}
+		blocks, sizes = append(blocks, b), append(sizes, size)
+		offset += slots[v].h + layoutRowGap
+	}
+	i, offset := 0, 0.0
+	for k, b := range blocks {
+		top := b.sum / float64(b.count)
+		for n := 0; n < sizes[k]; n++ {
+			v := l[i]
+			slots[v].y = top + offset
+			offset += slots[v].h + layoutRowGap
+			i++
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutGraphLayersAndRoutesEdges(t *testing.T) {
+	snap := Snapshot{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}},
+		Edges: []Edge{
+			{FromID: "a", ToID: "b", Kind: "blocked_by"},
+			{FromID: "b", ToID: "c", Kind: "blocked_by"},
+			{FromID: "a", ToID: "c", Kind: "blocked_by"},
+			{FromID: "c", ToID: "d", Kind: "blocked_by"},
+			{FromID: "d", ToID: "b", Kind: "blocked_by"},
+		},
+	}
+	lay := LayoutGraph(snap)
+	nodes := map[string]LayoutNode{}
+	for _, n := range lay.Nodes {
+		nodes[n.ID] = n
+	}
+	// d -> b closes a cycle, so it is laid out backwards.
+	for id, layer := range map[string]int{"a": 0, "b": 1, "c": 2, "d": 3, "e": 0} {
+		if nodes[id].Layer != layer || nodes[id].X != 30+layer*300 {
+			t.Fatalf("%s = %+v, want layer %d", id, nodes[id], layer)
+		}
+	}
+	for _, a := range lay.Nodes {
+		for _, b := range lay.Nodes {
+			if a.ID < b.ID && a.Layer == b.Layer && a.Y < b.Y+b.H && b.Y < a.Y+a.H {
+				t.Fatalf("%+v overlaps %+v", a, b)
+			}
+		}
+		if a.X+a.W > lay.Width || a.Y+a.H > lay.Height {
+			t.Fatalf("%+v is outside %dx%d", a, lay.Width, lay.Height)
+		}
+	}
+	edges := map[string][]LayoutPoint{}
+	for _, e := range lay.Edges {
+		edges[e.From+">"+e.To] = e.Points
+	}
+	a, c := nodes["a"], nodes["c"]
+	if p := edges["a>c"]; len(p) != 4 || p[0] != (LayoutPoint{a.X + a.W, a.Y + a.H/2}) || p[1].X != 330 || p[2].X != 540 || p[1].Y != p[2].Y || p[3] != (LayoutPoint{c.X, c.Y + c.H/2}) {
+		t.Fatalf("a>c = %+v", p)
+	}
+	b, d := nodes["b"], nodes["d"]
+	if p := edges["d>b"]; len(p) != 4 || p[0] != (LayoutPoint{d.X, d.Y + d.H/2}) || p[3] != (LayoutPoint{b.X + b.W, b.Y + b.H/2}) {
+		t.Fatalf("d>b = %+v", p)
+	}
+
+	// The layout does not depend on the order the snapshot lists things in.
+	shuffled := Snapshot{}
+	for i := len(snap.Nodes) - 1; i >= 0; i-- {
+		shuffled.Nodes = append(shuffled.Nodes, snap.Nodes[i])
+	}
+	for i := len(snap.Edges) - 1; i >= 0; i-- {
+		shuffled.Edges = append(shuffled.Edges, snap.Edges[i])
+	}
+	again := LayoutGraph(shuffled)
+	if !reflect.DeepEqual(again.Nodes, lay.Nodes) {
+		t.Fatalf("shuffled = %+v, want %+v", again.Nodes, lay.Nodes)
+	}
+	if empty := LayoutGraph(Snapshot{}); empty.Width != 60 || empty.Height != 60 || len(empty.Nodes) != 0 {
+		t.Fatalf("empty = %+v", empty)
+	}
+}
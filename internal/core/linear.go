@@ -0,0 +1,479 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"moul.io/depviz/v4/internal/core/linear"
+)
+
+// LinearSyncOptions selects the Linear team SyncLinear imports and where.
+type LinearSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Team is a team key such as ENG.
+	Team string
+	// Limit caps the issues read; it defaults to 200.
+	Limit int
+	// Client defaults to linear.FromEnv.
+	Client *linear.Client
+}
+
+// LinearSyncResult counts what a Linear sync imported.
+type LinearSyncResult struct {
+	Team     string `json:"team"`
+	Items    int    `json:"items"`
+	Projects int    `json:"projects"`
+	Cycles   int    `json:"cycles"`
+	Links    int    `json:"links"`
+}
+
+type linearTeam struct {
+	ID           string `json:"id"`
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	Organization struct {
+		URLKey string `json:"urlKey"`
+	} `json:"organization"`
+	Projects struct {
+		Nodes []linearProject `json:"nodes"`
+	} `json:"projects"`
+	Cycles struct {
+		Nodes []linearCycle `json:"nodes"`
+	} `json:"cycles"`
+}
+
+type linearProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type linearCycle struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+}
+
+// label is how a cycle reads on a card: its name, or "Cycle N" for the
+// unnamed cycles Linear creates on its own.
+func (c *linearCycle) label() string {
+	switch {
+	case c == nil:
+		return ""
+	case c.Name != "":
+		return c.Name
+	default:
+		return fmt.Sprintf("Cycle %d", c.Number)
+	}
+}
+
+// linearIssue is an issue from the GraphQL API. Related issues come in the
+// same shape with fewer fields.
+type linearIssue struct {
+	Identifier    string   `json:"identifier"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	URL           string   `json:"url"`
+	Priority      float64  `json:"priority"`
+	PriorityLabel string   `json:"priorityLabel"`
+	Estimate      *float64 `json:"estimate"`
+	UpdatedAt     string   `json:"updatedAt"`
+	State         *struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"state"`
+	Assignee *linearUser `json:"assignee"`
+	Creator  *linearUser `json:"creator"`
+	Labels   struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Project          *linearProject  `json:"project"`
+	Cycle            *linearCycle    `json:"cycle"`
+	Relations        linearRelations `json:"relations"`
+	InverseRelations linearRelations `json:"inverseRelations"`
+}
+
+type linearRelations struct {
+	Nodes []struct {
+		ID           string       `json:"id"`
+		Type         string       `json:"type"`
+		Issue        *linearIssue `json:"issue"`
+		RelatedIssue *linearIssue `json:"relatedIssue"`
+	} `json:"nodes"`
+}
+
+type linearUser struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+func (u *linearUser) login() string {
+	switch {
+	case u == nil:
+		return ""
+	case u.DisplayName != "":
+		return u.DisplayName
+	default:
+		return u.Name
+	}
+}
+
+const linearTeamQuery = `query($key: String!) {
+  teams(filter: { key: { eq: $key } }) {
+    nodes {
+      id key name
+      organization { urlKey }
+      projects(first: 100) { nodes { id name url } }
+      cycles(first: 50) { nodes { id number name } }
+    }
+  }
+}`
+
+const linearIssuesQuery = `query($key: String!, $first: Int!, $after: String) {
+  issues(first: $first, after: $after, orderBy: updatedAt, filter: { team: { key: { eq: $key } } }) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      identifier title description url priority priorityLabel estimate updatedAt
+      state { name type }
+      assignee { name displayName }
+      creator { name displayName }
+      labels(first: 50) { nodes { name } }
+      project { id name url }
+      cycle { id number name }
+      relations(first: 50) { nodes { id type relatedIssue { identifier title url state { name type } } } }
+      inverseRelations(first: 50) { nodes { id type issue { identifier title url state { name type } } } }
+    }
+  }
+}`
+
+// linearPriorities are Linear's priorities from most to least urgent; 0 is
+// "No priority" and leaves the field unset.
+var linearPriorities = []string{"Urgent", "High", "Medium", "Low"}
+
+// SyncLinear imports the issues of a Linear team as linear:KEY-N nodes.
+// Workflow states of the completed and canceled types close a card, started
+// ones put it in progress. "blocks" relations become hard blocked_by edges and
+// "related" and "duplicate" ones relates_to edges, with authority linear.
+// Priority, estimate, project and cycle become board fields owned by the
+// team's linear:KEY source, whose projects and cycles are the options of the
+// project and cycle fields. Every run is recorded in sync_logs.
+func SyncLinear(ctx context.Context, s *Store, opts LinearSyncOptions) (LinearSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 200
+	}
+	if opts.Client == nil {
+		opts.Client = linear.FromEnv()
+	}
+	var res LinearSyncResult
+	err := s.runSync(ctx, opts.Board, "linear", func() (int, int, error) {
+		var err error
+		res, err = syncLinear(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncLinear(ctx context.Context, s *Store, opts LinearSyncOptions) (LinearSyncResult, error) {
+	key := strings.ToUpper(strings.TrimSpace(opts.Team))
+	if key == "" {
+		return LinearSyncResult{}, fmt.Errorf("team is required: use a team key such as ENG")
+	}
+	var teams struct {
+		Teams struct {
+			Nodes []linearTeam `json:"nodes"`
+		} `json:"teams"`
+	}
+	if err := opts.Client.GraphQL(ctx, linearTeamQuery, map[string]any{"key": key}, &teams); err != nil {
+		return LinearSyncResult{}, err
+	}
+	if len(teams.Teams.Nodes) == 0 {
+		return LinearSyncResult{}, fmt.Errorf("linear team %s not found", key)
+	}
+	team := teams.Teams.Nodes[0]
+	res := LinearSyncResult{Team: team.Key, Projects: len(team.Projects.Nodes), Cycles: len(team.Cycles.Nodes)}
+	sourceID := "linear:" + team.Key
+	url := ""
+	if team.Organization.URLKey != "" {
+		url = "https://linear.app/" + team.Organization.URLKey + "/team/" + team.Key
+	}
+	sync, _ := json.Marshal(map[string]any{"mode": "graphql", "team": team.Key, "team_id": team.ID})
+	if err := s.UpsertSource(ctx, Source{
+		ID:           sourceID,
+		Kind:         "linear",
+		Name:         team.Name,
+		URL:          url,
+		Capabilities: `{"read":true,"fields":["priority","estimate","project","cycle"]}`,
+		Sync:         string(sync),
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	var issues []linearIssue
+	var after *string
+	for len(issues) < opts.Limit {
+		var page struct {
+			Issues struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []linearIssue `json:"nodes"`
+			} `json:"issues"`
+		}
+		vars := map[string]any{"key": team.Key, "first": min(100, opts.Limit-len(issues)), "after": after}
+		if err := opts.Client.GraphQL(ctx, linearIssuesQuery, vars, &page); err != nil {
+			return res, err
+		}
+		issues = append(issues, page.Issues.Nodes...)
+		if !page.Issues.PageInfo.HasNextPage {
+			break
+		}
+		after = &page.Issues.PageInfo.EndCursor
+	}
+	if len(issues) > opts.Limit {
+		issues = issues[:opts.Limit]
+	}
+
+	fields, err := s.defineLinearFields(ctx, opts.Board, team, issues)
+	if err != nil {
+		return res, err
+	}
+	for _, issue := range issues {
+		if issue.Identifier == "" {
+			continue
+		}
+		node, err := s.upsertLinearIssue(ctx, opts.Board, issue)
+		if err != nil {
+			return res, err
+		}
+		res.Items++
+		if err := s.setLinearFields(ctx, opts.Board, sourceID, node.ID, issue, fields); err != nil {
+			return res, err
+		}
+		links, err := s.replaceGitHubInferredEdges(ctx, opts.Board, node.ID, githubEdgeEvidence{Source: githubSourceBody, Origin: githubSourceBody}, extractDependencyEdges("", node.ID, issue.Description))
+		if err != nil {
+			return res, err
+		}
+		res.Links += links
+		if links, err = s.replaceLinearRelations(ctx, opts.Board, node.ID, issue); err != nil {
+			return res, err
+		}
+		res.Links += links
+	}
+	return res, nil
+}
+
+// defineLinearFields defines the priority, project and cycle fields on a
+// board, and returns the values each field already holds. Projects and
+// cycles become enum options, along with those the issues carry that the
+// team listing left out; a team without any gets a text field.
+func (s *Store) defineLinearFields(ctx context.Context, boardID string, team linearTeam, issues []linearIssue) (map[string]map[string]FieldValue, error) {
+	var projects, cycles []string
+	for _, p := range team.Projects.Nodes {
+		projects = append(projects, p.Name)
+	}
+	for i := range team.Cycles.Nodes {
+		cycles = append(cycles, team.Cycles.Nodes[i].label())
+	}
+	for _, issue := range issues {
+		if issue.Project != nil {
+			projects = append(projects, issue.Project.Name)
+		}
+		if issue.Cycle != nil {
+			cycles = append(cycles, issue.Cycle.label())
+		}
+	}
+	defs := []FieldDefinition{
+		{Key: "priority", Type: FieldTypeEnum, Label: "Priority", Options: linearPriorities},
+		{Key: "project", Type: FieldTypeEnum, Label: "Project", Options: projects},
+		{Key: "cycle", Type: FieldTypeEnum, Label: "Cycle", Options: cycles},
+	}
+	for _, def := range defs {
+		def.BoardID = boardID
+		if len(def.Options) == 0 {
+			def.Type = FieldTypeText
+		}
+		if _, err := s.DefineField(ctx, def); err != nil {
+			return nil, fmt.Errorf("linear field %s: %w", def.Key, err)
+		}
+	}
+	owned := map[string]map[string]FieldValue{}
+	for _, key := range []string{"priority", "estimate", "project", "cycle"} {
+		values, err := s.FieldValuesByKey(ctx, boardID, "node", FieldNamespace, key)
+		if err != nil {
+			return nil, err
+		}
+		owned[key] = values
+	}
+	return owned, nil
+}
+
+// setLinearFields stores an issue's priority, estimate, project and cycle,
+// and clears those the team set before but the issue no longer has; values
+// set locally are left alone.
+func (s *Store) setLinearFields(ctx context.Context, boardID, sourceID, nodeID string, issue linearIssue, owned map[string]map[string]FieldValue) error {
+	values := map[string]any{}
+	if issue.Priority > 0 && issue.PriorityLabel != "" {
+		values["priority"] = issue.PriorityLabel
+	}
+	if issue.Estimate != nil && *issue.Estimate > 0 {
+		values["estimate"] = *issue.Estimate
+	}
+	if issue.Project != nil {
+		values["project"] = issue.Project.Name
+	}
+	if issue.Cycle != nil {
+		values["cycle"] = issue.Cycle.label()
+	}
+	for _, key := range []string{"priority", "estimate", "project", "cycle"} {
+		value, ok := values[key]
+		if !ok {
+			if fv, had := owned[key][nodeID]; had && fv.SourceID == sourceID {
+				if err := s.clearNodeField(ctx, boardID, nodeID, key); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		data, _ := json.Marshal(value)
+		if err := s.SetFieldValue(ctx, FieldValue{BoardID: boardID, OwnerType: "node", OwnerID: nodeID, Namespace: FieldNamespace, Key: key, ValueJSON: string(data), Authority: "linear", SourceID: sourceID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertLinearIssue stores an issue as a node linked to the linear:KEY source
+// of its team, creating the source from the issue URL when missing, and adds
+// it to a board.
+func (s *Store) upsertLinearIssue(ctx context.Context, boardID string, issue linearIssue) (Node, error) {
+	id := "linear:" + issue.Identifier
+	state, status := "open", ""
+	if issue.State != nil {
+		status = issue.State.Name
+		switch issue.State.Type {
+		case "completed":
+			state = "done"
+		case "canceled":
+			state = "canceled"
+		case "started":
+			state = "in_progress"
+		}
+	}
+	labels := []string{}
+	for _, l := range issue.Labels.Nodes {
+		labels = append(labels, l.Name)
+	}
+	project := ""
+	if issue.Project != nil {
+		project = issue.Project.Name
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "linear",
+		"kind":      "issue",
+		"key":       issue.Identifier,
+		"status":    status,
+		"priority":  issue.PriorityLabel,
+		"estimate":  issue.Estimate,
+		"project":   project,
+		"cycle":     issue.Cycle.label(),
+		"labels":    labels,
+		"assignee":  issue.Assignee.login(),
+		"creator":   issue.Creator.login(),
+		"body":      issue.Description,
+		"synced_at": formatTime(nowUTC()),
+		"html_url":  issue.URL,
+	})
+	updated, err := time.Parse(time.RFC3339, issue.UpdatedAt)
+	if err != nil {
+		updated = nowUTC()
+	}
+	n := Node{ID: id, Kind: "issue", Title: issue.Title, State: state, Owner: issue.Assignee.login(), DataJSON: string(payload), UpdatedAt: updated.UTC(), URL: issue.URL}
+	if n.Title == "" {
+		n.Title = issue.Identifier
+	}
+	if err := s.UpsertNode(ctx, n); err != nil {
+		return Node{}, err
+	}
+	team, _, _ := strings.Cut(issue.Identifier, "-")
+	sourceID := "linear:" + team
+	web := ""
+	if site, _, ok := strings.Cut(issue.URL, "/issue/"); ok {
+		web = site + "/team/" + team
+	}
+	if err := s.ensureItemSource(ctx, Source{ID: sourceID, Kind: "linear", Name: team, URL: web, Capabilities: `{"read":true}`, Sync: `{"mode":"graphql"}`}); err != nil {
+		return Node{}, err
+	}
+	if err := s.UpsertSourceRef(ctx, id, sourceID, issue.Identifier, issue.URL); err != nil {
+		return Node{}, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, id, "issue", ""); err != nil {
+		return Node{}, err
+	}
+	return n, nil
+}
+
+// replaceLinearRelations stores an issue's relations as edges and drops those
+// it no longer has. Relations are listed on both issues, as relations of one
+// and inverse relations of the other, so each is stored one way: "A blocks B"
+// is B blocked_by A, and relates_to runs from the smaller ID. Linear's
+// suggested "similar" relations are skipped. Related issues not yet known are
+// imported from what the relation carries.
+func (s *Store) replaceLinearRelations(ctx context.Context, boardID, nodeID string, issue linearIssue) (int, error) {
+	type relation struct {
+		id, kind string
+		other    *linearIssue
+		inverse  bool
+	}
+	var relations []relation
+	for _, r := range issue.Relations.Nodes {
+		relations = append(relations, relation{id: r.ID, kind: r.Type, other: r.RelatedIssue})
+	}
+	for _, r := range issue.InverseRelations.Nodes {
+		relations = append(relations, relation{id: r.ID, kind: r.Type, other: r.Issue, inverse: true})
+	}
+	keep := map[string]bool{}
+	for _, r := range relations {
+		if r.other == nil || r.other.Identifier == "" || r.kind == "similar" {
+			continue
+		}
+		other := "linear:" + r.other.Identifier
+		exists, err := s.nodeExists(ctx, other)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			if _, err := s.upsertLinearIssue(ctx, boardID, *r.other); err != nil {
+				return 0, err
+			}
+		}
+		from, to, kind := nodeID, other, "relates_to"
+		switch {
+		case r.kind == "blocks" && !r.inverse:
+			from, to, kind = other, nodeID, "blocked_by"
+		case r.kind == "blocks":
+			kind = "blocked_by"
+		case to < from:
+			from, to = to, from
+		}
+		e, err := s.AddEdgeWithConfidence(ctx, boardID, from, to, kind, "linear", 1, map[string]any{"relation": r.kind, "relation_id": r.id})
+		if err != nil {
+			return 0, err
+		}
+		keep[e.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, nodeID, "linear", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
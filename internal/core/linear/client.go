@@ -0,0 +1,115 @@
+// Package linear is a small client for the Linear GraphQL API.
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultBaseURL is Linear's GraphQL endpoint.
+const DefaultBaseURL = "https://api.linear.app/graphql"
+
+// Client calls the Linear GraphQL API. Token is sent as is, which suits
+// personal API keys; OAuth access tokens need their "Bearer " prefix.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is a non-2xx response or a response carrying GraphQL errors.
+type Error struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("linear: %s: %s", e.Status, e.Message)
+}
+
+// NewClient returns a client for linear.app.
+func NewClient(token string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Token: token}
+}
+
+// FromEnv returns a client for the CLI, authenticated by LINEAR_API_KEY.
+func FromEnv() *Client {
+	return NewClient(strings.TrimSpace(os.Getenv("LINEAR_API_KEY")))
+}
+
+// GraphQL runs a query and decodes its data into out.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	endpoint := c.BaseURL
+	if endpoint == "" {
+		endpoint = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 20<<20))
+	if err != nil {
+		return err
+	}
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	decodeErr := json.Unmarshal(data, &envelope)
+	status := fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode))
+	if decodeErr == nil && len(envelope.Errors) > 0 {
+		msgs := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return &Error{StatusCode: res.StatusCode, Status: status, Message: strings.Join(msgs, "; ")}
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &Error{StatusCode: res.StatusCode, Status: status, Message: bodySummary(data)}
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("expected JSON from linear, got %s: %w", bodySummary(data), decodeErr)
+	}
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return fmt.Errorf("linear returned no data")
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+func bodySummary(data []byte) string {
+	body := strings.Join(strings.Fields(string(data)), " ")
+	if body == "" {
+		return "empty response"
+	}
+	if len(body) > 240 {
+		body = body[:240] + "..."
+	}
+	return body
+}
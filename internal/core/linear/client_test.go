@@ -0,0 +1,63 @@
+package linear
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphQLSendsKeyAndDecodesData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "lin_api_key" {
+			t.Errorf("request = %s %q", r.Method, r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Variables["key"] != "ENG" {
+			t.Errorf("body = %+v, %v", req, err)
+		}
+		fmt.Fprint(w, `{"data":{"team":{"key":"ENG"}}}`)
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, Token: "lin_api_key"}
+	var out struct {
+		Team struct{ Key string }
+	}
+	if err := c.GraphQL(context.Background(), `query($key: String!) { team(id: $key) { key } }`, map[string]any{"key": "ENG"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Team.Key != "ENG" {
+		t.Fatalf("out = %+v", out)
+	}
+}
+
+func TestGraphQLReportsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":[{"message":"Authentication required, not authenticated"}]}`)
+	}))
+	defer srv.Close()
+	err := (&Client{BaseURL: srv.URL}).GraphQL(context.Background(), `{ viewer { id } }`, nil, &struct{}{})
+	if got := fmt.Sprint(err); got != "linear: 400 Bad Request: Authentication required, not authenticated" {
+		t.Fatalf("err = %q", got)
+	}
+}
+
+func TestGraphQLReportsNon2xxWithoutErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html>\n<body>upstream unavailable</body>\n</html>")
+	}))
+	defer srv.Close()
+	err := (&Client{BaseURL: srv.URL}).GraphQL(context.Background(), `{ viewer { id } }`, nil, &struct{}{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"moul.io/depviz/v4/internal/core/linear"
+)
+
+func TestSyncLinearImportsRelationsAndFields(t *testing.T) {
+	issues := `[
+		{"identifier":"ENG-1","title":"Ship","description":"Depends on ENG-4","url":"https://linear.app/acme/issue/ENG-1/ship",
+			"priority":2,"priorityLabel":"High","estimate":3,"updatedAt":"2026-10-01T10:00:00.000Z",
+			"state":{"name":"In Progress","type":"started"},"assignee":{"name":"Ada Lovelace","displayName":"ada"},
+			"labels":{"nodes":[{"name":"launch"}]},"project":{"id":"p1","name":"Launch"},"cycle":{"id":"c1","number":12,"name":""},
+			"relations":{"nodes":[{"id":"r2","type":"related","relatedIssue":{"identifier":"OPS-9","title":"Audit","url":"https://linear.app/acme/issue/OPS-9/audit","state":{"name":"Done","type":"completed"}}}]},
+			"inverseRelations":{"nodes":[{"id":"r1","type":"blocks","issue":{"identifier":"ENG-2","title":"Schema"}}]}},
+		{"identifier":"ENG-2","title":"Schema","url":"https://linear.app/acme/issue/ENG-2/schema","priority":0,"priorityLabel":"No priority",
+			"state":{"name":"Canceled","type":"canceled"},
+			"relations":{"nodes":[{"id":"r1","type":"blocks","relatedIssue":{"identifier":"ENG-1"}},{"id":"r3","type":"similar","relatedIssue":{"identifier":"ENG-7"}}]},
+			"inverseRelations":{"nodes":[]}}
+	]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Variables["key"] != "ENG" {
+			t.Errorf("request = %+v, %v", req, err)
+		}
+		switch {
+		case strings.Contains(req.Query, "teams("):
+			fmt.Fprint(w, `{"data":{"teams":{"nodes":[{"id":"t1","key":"ENG","name":"Engineering","organization":{"urlKey":"acme"},
+				"projects":{"nodes":[{"id":"p1","name":"Launch"},{"id":"p2","name":"Billing"}]},
+				"cycles":{"nodes":[{"id":"c1","number":12,"name":""},{"id":"c2","number":13,"name":"Polish"}]}}]}}}`)
+		case strings.Contains(req.Query, "issues("):
+			fmt.Fprintf(w, `{"data":{"issues":{"pageInfo":{"hasNextPage":false},"nodes":%s}}}`, issues)
+		default:
+			t.Errorf("unexpected query %s", req.Query)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := LinearSyncOptions{Team: "eng", Client: &linear.Client{BaseURL: srv.URL, Token: "lin_api_key"}}
+	res, err := SyncLinear(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Team != "ENG" || res.Items != 2 || res.Projects != 2 || res.Cycles != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	if _, err := s.AddEdge(ctx, DefaultBoardID, "gh:acme/api#10", "linear:ENG-42", "blocked_by", "local", nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority))
+	}
+	sort.Strings(edges)
+	want := []string{
+		"gh:acme/api#10 blocked_by linear:ENG-42 local",
+		"linear:ENG-1 blocked_by linear:ENG-2 linear",
+		"linear:ENG-1 blocked_by linear:ENG-4 linear-inferred",
+		"linear:ENG-1 relates_to linear:OPS-9 linear",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Fatalf("edges = %q, want %q", edges, want)
+	}
+	nodes := map[string]Node{}
+	for _, n := range snap.Nodes {
+		nodes[n.ID] = n
+	}
+	if n := nodes["linear:ENG-1"]; n.State != "in_progress" || n.Owner != "ada" || n.Fields["priority"] != "High" || n.Fields["estimate"] != 3.0 || n.Fields["project"] != "Launch" || n.Fields["cycle"] != "Cycle 12" {
+		t.Fatalf("ENG-1 = %+v", n)
+	}
+	if n := nodes["linear:ENG-2"]; !n.IsClosed() || n.Fields["priority"] != nil {
+		t.Fatalf("ENG-2 = %+v", n)
+	}
+	if n := nodes["linear:OPS-9"]; !n.IsClosed() || n.Title != "Audit" {
+		t.Fatalf("OPS-9 = %+v", n)
+	}
+	if n := nodes["linear:ENG-42"]; n.URL != "https://linear.app/acme/issue/ENG-42" {
+		t.Fatalf("placeholder ENG-42 = %+v", n)
+	}
+	var cycle FieldDefinition
+	for _, def := range snap.Fields {
+		if def.Key == "cycle" {
+			cycle = def
+		}
+	}
+	if cycle.Type != FieldTypeEnum || !reflect.DeepEqual(cycle.Options, []string{"Cycle 12", "Polish"}) {
+		t.Fatalf("cycle field = %+v", cycle)
+	}
+	var src Source
+	var capabilities, syncJSON string
+	if err := s.db.QueryRowContext(ctx, `SELECT name, url, capabilities_json, sync_json FROM sources WHERE id = 'linear:ENG'`).Scan(&src.Name, &src.URL, &capabilities, &syncJSON); err != nil {
+		t.Fatal(err)
+	}
+	if src.Name != "Engineering" || src.URL != "https://linear.app/acme/team/ENG" || !strings.Contains(capabilities, `"estimate"`) || !strings.Contains(syncJSON, `"team":"ENG"`) {
+		t.Fatalf("source = %+v %s %s", src, capabilities, syncJSON)
+	}
+
+	// Dropping the relation, the project and the cycle on Linear drops them
+	// here too.
+	issues = `[{"identifier":"ENG-1","title":"Ship","url":"https://linear.app/acme/issue/ENG-1/ship","priority":2,"priorityLabel":"High",
+		"state":{"name":"In Progress","type":"started"},"relations":{"nodes":[]},"inverseRelations":{"nodes":[]}}]`
+	if _, err := SyncLinear(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := s.NodeFields(ctx, DefaultBoardID, "linear:ENG-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].Key != "priority" {
+		t.Fatalf("fields = %+v", fields)
+	}
+	snap, err = s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range snap.Edges {
+		if e.Authority == "linear" {
+			t.Fatalf("stale edge %+v", e)
+		}
+	}
+}
+
+func TestSyncLinearPagesUntilLimit(t *testing.T) {
+	var firsts []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if strings.Contains(req.Query, "teams(") {
+			fmt.Fprint(w, `{"data":{"teams":{"nodes":[{"id":"t1","key":"ENG","name":"Engineering"}]}}}`)
+			return
+		}
+		firsts = append(firsts, req.Variables["first"])
+		n := len(firsts)
+		fmt.Fprintf(w, `{"data":{"issues":{"pageInfo":{"hasNextPage":true,"endCursor":"c%d"},"nodes":[{"identifier":"ENG-%d","state":{"type":"unstarted"}}]}}}`, n, n)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	res, err := SyncLinear(ctx, s, LinearSyncOptions{Team: "ENG", Limit: 2, Client: &linear.Client{BaseURL: srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 2 || fmt.Sprint(firsts) != "[2 1]" {
+		t.Fatalf("result = %+v after pages of %v, want two issues in two pages", res, firsts)
+	}
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MarkdownSyncOptions selects the Markdown files SyncMarkdown scans and
+// where their tasks go.
+type MarkdownSyncOptions struct {
+	// Board defaults to the default board.
+	Board string
+	// Dir is a directory scanned recursively for .md and .markdown files, or
+	// a single file.
+	Dir string
+	// Repo is the owner/name short refs such as #412 point at; without it
+	// they are ignored.
+	Repo string
+}
+
+// MarkdownSyncResult counts what a Markdown sync imported.
+type MarkdownSyncResult struct {
+	Source  string `json:"source"`
+	Files   int    `json:"files"`
+	Items   int    `json:"items"`
+	Links   int    `json:"links"`
+	Removed int    `json:"removed"`
+}
+
+var (
+	markdownTaskRE    = regexp.MustCompile(`^\s*(?:[-*+]|[0-9]+[.)])\s+\[([ xX])\]\s+(.+?)\s*$`)
+	markdownHeadingRE = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	markdownFenceRE   = regexp.MustCompile("^\\s*(```|~~~)")
+	markdownParenRE   = regexp.MustCompile(`\s*\(([^()]*)\)\s*$`)
+)
+
+// markdownTask is a checklist item of a Markdown file.
+type markdownTask struct {
+	id      string
+	title   string
+	text    string
+	done    bool
+	file    string
+	line    int
+	heading string
+}
+
+// SyncMarkdown turns the checklist items of Markdown files into task: nodes.
+// An item's ID comes from its file path, the heading above it and its text,
+// so re-running the sync updates the same cards; items gone from the files
+// leave the board. Checked items are done. Relations written on an item, such
+// as "(blocked by #412)", become edges with authority markdown and the line as
+// evidence, and each card links back to its file and line.
+func SyncMarkdown(ctx context.Context, s *Store, opts MarkdownSyncOptions) (MarkdownSyncResult, error) {
+	if opts.Board == "" {
+		opts.Board = DefaultBoardID
+	}
+	var res MarkdownSyncResult
+	err := s.runSync(ctx, opts.Board, "markdown", func() (int, int, error) {
+		var err error
+		res, err = syncMarkdown(ctx, s, opts)
+		return res.Items, res.Links, err
+	})
+	return res, err
+}
+
+func syncMarkdown(ctx context.Context, s *Store, opts MarkdownSyncOptions) (MarkdownSyncResult, error) {
+	if strings.TrimSpace(opts.Dir) == "" {
+		return MarkdownSyncResult{}, fmt.Errorf("a directory or Markdown file is required")
+	}
+	root, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return MarkdownSyncResult{}, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return MarkdownSyncResult{}, err
+	}
+	base := root
+	if !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+	sourceID := "markdown:" + filepath.ToSlash(root)
+	res := MarkdownSyncResult{Source: sourceID}
+	if err := s.UpsertSource(ctx, Source{
+		ID:           sourceID,
+		Kind:         "markdown",
+		Name:         opts.Dir,
+		URL:          "file://" + filepath.ToSlash(root),
+		Capabilities: `{"read":true}`,
+		Sync:         `{"mode":"files"}`,
+		UpdatedAt:    nowUTC(),
+	}); err != nil {
+		return res, err
+	}
+
+	files, err := sourceFiles(root, func(path string) bool {
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".md" || ext == ".markdown"
+	})
+	if err != nil {
+		return res, err
+	}
+
+	seen := map[string]bool{}
+	for _, path := range files {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return res, err
+		}
+		tasks, err := readMarkdownTasks(path, filepath.ToSlash(rel))
+		if err != nil {
+			return res, err
+		}
+		res.Files++
+		for _, task := range tasks {
+			// Repeated items under one heading are told apart by position.
+			for n, base := 2, task.id; seen[task.id]; n++ {
+				task.id = fmt.Sprintf("%s-%d", base, n)
+			}
+			seen[task.id] = true
+			links, err := s.upsertMarkdownTask(ctx, opts.Board, sourceID, "file://"+filepath.ToSlash(path), opts.Repo, task)
+			if err != nil {
+				return res, err
+			}
+			res.Items++
+			res.Links += links
+		}
+	}
+
+	res.Removed, err = s.removeUnseenSourceNodes(ctx, opts.Board, sourceID, seen)
+	return res, err
+}
+
+// sourceFiles lists the files under root, itself possibly a file, that keep
+// accepts. Hidden directories, node_modules and vendor are skipped.
+func sourceFiles(root string, keep func(path string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules" || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if keep(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// readMarkdownTasks reads the checklist items of a file outside code fences,
+// with the nearest heading above each.
+func readMarkdownTasks(path, rel string) ([]markdownTask, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fileSlug := slug(strings.TrimSuffix(rel, filepath.Ext(rel)))
+	var tasks []markdownTask
+	heading, fence := "", ""
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if m := markdownFenceRE.FindStringSubmatch(text); m != nil {
+			switch fence {
+			case "":
+				fence = m[1]
+			case m[1]:
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if m := markdownHeadingRE.FindStringSubmatch(text); m != nil {
+			heading = m[2]
+			continue
+		}
+		m := markdownTaskRE.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		title := m[2]
+		// A trailing "(blocked by #412)" is a relation, not part of the title.
+		if p := markdownParenRE.FindStringSubmatchIndex(title); p != nil && relationVerbRE.MatchString(title[p[2]:p[3]]) {
+			title = title[:p[0]]
+		}
+		parts := []string{fileSlug}
+		if h := slug(heading); h != "" {
+			parts = append(parts, h)
+		}
+		parts = append(parts, slug(title))
+		id := "task:" + strings.Join(parts, ":")
+		if strings.HasSuffix(id, ":") {
+			id += "untitled"
+		}
+		tasks = append(tasks, markdownTask{id: id, title: title, text: m[2], done: m[1] != " ", file: rel, line: line, heading: heading})
+	}
+	return tasks, scanner.Err()
+}
+
+// upsertMarkdownTask stores a task and replaces the edges its line states.
+func (s *Store) upsertMarkdownTask(ctx context.Context, boardID, sourceID, fileURL, repo string, task markdownTask) (int, error) {
+	state := "open"
+	if task.done {
+		state = "done"
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"source":    "markdown",
+		"text":      task.text,
+		"file":      task.file,
+		"line":      task.line,
+		"heading":   task.heading,
+		"synced_at": formatTime(nowUTC()),
+	})
+	if err := s.UpsertNode(ctx, Node{ID: task.id, Kind: "task", Title: task.title, State: state, DataJSON: string(payload), UpdatedAt: nowUTC()}); err != nil {
+		return 0, err
+	}
+	if err := s.UpsertSourceRef(ctx, task.id, sourceID, task.id, fmt.Sprintf("%s#L%d", fileURL, task.line)); err != nil {
+		return 0, err
+	}
+	if err := s.AddNodeToBoard(ctx, boardID, task.id, "card", ""); err != nil {
+		return 0, err
+	}
+	keep := map[string]bool{}
+	for _, e := range extractDependencyEdges(repo, task.id, task.text) {
+		if repo == "" && (strings.HasPrefix(e.To, "gh:#") || strings.HasPrefix(e.To, "gh:!")) {
+			continue
+		}
+		edge, err := s.AddEdgeWithConfidence(ctx, boardID, e.From, e.To, e.Kind, "markdown", e.Confidence, map[string]any{
+			"file": task.file,
+			"line": task.line,
+			"text": e.Line,
+		})
+		if err != nil {
+			return 0, err
+		}
+		keep[edge.ID] = true
+	}
+	if err := s.pruneAuthorityEdges(ctx, boardID, task.id, "markdown", keep); err != nil {
+		return 0, err
+	}
+	return len(keep), nil
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSyncMarkdownTurnsChecklistsIntoTasks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("docs/design.md", "# Design\n\n## Milestone 1\n\n- [ ] Migrate auth (blocked by #412)\n- [x] Ship schema\n  - [ ] Write docs, depends on gh:acme/web#3\n\n```md\n- [ ] not a task\n```\n")
+	write("notes/todo.markdown", "* [ ] Rotate keys\n")
+	write(".git/ignored.md", "- [ ] hidden\n")
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	opts := MarkdownSyncOptions{Dir: dir, Repo: "acme/api"}
+	res, err := SyncMarkdown(ctx, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Files != 2 || res.Items != 4 || res.Links != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := map[string]Node{}
+	var ids []string
+	for _, n := range snap.Nodes {
+		if n.Kind == "task" {
+			nodes[n.ID] = n
+			ids = append(ids, n.ID)
+		}
+	}
+	sort.Strings(ids)
+	want := []string{
+		"task:docs-design:milestone-1:migrate-auth",
+		"task:docs-design:milestone-1:ship-schema",
+		"task:docs-design:milestone-1:write-docs-depends-on-gh-acme-web-3",
+		"task:notes-todo:rotate-keys",
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ids = %q, want %q", ids, want)
+	}
+	auth := nodes["task:docs-design:milestone-1:migrate-auth"]
+	if auth.Title != "Migrate auth" || auth.State != "open" || auth.URL != "file://"+filepath.ToSlash(filepath.Join(dir, "docs/design.md"))+"#L5" {
+		t.Fatalf("auth = %+v", auth)
+	}
+	if n := nodes["task:docs-design:milestone-1:ship-schema"]; !n.IsClosed() {
+		t.Fatalf("schema = %+v", n)
+	}
+	var edges []string
+	for _, e := range snap.Edges {
+		edges = append(edges, fmt.Sprintf("%s %s %s %s %s", e.FromID, e.Kind, e.ToID, e.Authority, e.EvidenceJSON))
+	}
+	sort.Strings(edges)
+	wantEdges := []string{
+		`task:docs-design:milestone-1:migrate-auth blocked_by gh:acme/api#412 markdown {"file":"docs/design.md","line":5,"text":"Migrate auth (blocked by #412)"}`,
+		`task:docs-design:milestone-1:write-docs-depends-on-gh-acme-web-3 blocked_by gh:acme/web#3 markdown {"file":"docs/design.md","line":7,"text":"Write docs, depends on gh:acme/web#3"}`,
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Fatalf("edges = %q, want %q", edges, wantEdges)
+	}
+
+	// Checking the box and dropping the relation and another item update
+	// the same cards.
+	write("docs/design.md", "# Design\n\n## Milestone 1\n\n- [x] Migrate auth\n- [x] Ship schema\n")
+	if res, err = SyncMarkdown(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	if res.Items != 3 || res.Links != 0 || res.Removed != 1 {
+		t.Fatalf("resync = %+v", res)
+	}
+	snap, err = s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := 0
+	for _, n := range snap.Nodes {
+		if n.Kind != "task" {
+			continue
+		}
+		tasks++
+		if n.ID == "task:docs-design:milestone-1:migrate-auth" && !n.IsClosed() {
+			t.Fatalf("auth = %+v", n)
+		}
+	}
+	for _, e := range snap.Edges {
+		if e.Authority == "markdown" {
+			t.Fatalf("stale edge %+v", e)
+		}
+	}
+	if tasks != 3 {
+		t.Fatalf("tasks = %d, want 3", tasks)
+	}
+}
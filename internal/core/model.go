@@ -35,6 +35,9 @@ type Node struct {
 	URL        string    `json:"url"`
 	SourceID   string    `json:"source_id"`
 	ExternalID string    `json:"external_id"`
+	// Fields holds the node's custom field values by key, typed per the
+	// board's FieldDefinitions.
+	Fields map[string]any `json:"fields,omitempty"`
 }
 
 type Edge struct {
@@ -74,9 +77,10 @@ type BoardMetrics struct {
 }
 
 type Snapshot struct {
-	Board Board  `json:"board"`
-	Nodes []Node `json:"nodes"`
-	Edges []Edge `json:"edges"`
+	Board  Board             `json:"board"`
+	Nodes  []Node            `json:"nodes"`
+	Edges  []Edge            `json:"edges"`
+	Fields []FieldDefinition `json:"fields,omitempty"`
 }
 
 type Export struct {
@@ -91,7 +95,10 @@ type Brief struct {
 	Blockers  []BriefItem `json:"blockers"`
 	LocalOnly []BriefItem `json:"local_only"`
 	Stale     []BriefItem `json:"stale"`
-	Counts    BriefCounts `json:"counts"`
+	// Branches lists open cards with an unmerged local branch.
+	Branches []BriefItem `json:"branches,omitempty"`
+	Cycles   []Cycle     `json:"cycles,omitempty"`
+	Counts   BriefCounts `json:"counts"`
 }
 
 type BriefCounts struct {
@@ -104,14 +111,17 @@ type BriefCounts struct {
 }
 
 type BriefItem struct {
-	ID           string `json:"id"`
-	Title        string `json:"title"`
-	Kind         string `json:"kind"`
-	State        string `json:"state"`
-	URL          string `json:"url,omitempty"`
-	Reason       string `json:"reason,omitempty"`
-	Impact       int    `json:"impact,omitempty"`
-	BlockerCount int    `json:"blocker_count,omitempty"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Kind   string `json:"kind"`
+	State  string `json:"state"`
+	URL    string `json:"url,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Impact int    `json:"impact,omitempty"`
+	// TransitiveImpact counts every open card held up downstream, not just the
+	// direct dependents counted by Impact.
+	TransitiveImpact int `json:"transitive_impact,omitempty"`
+	BlockerCount     int `json:"blocker_count,omitempty"`
 }
 
 type Account struct {
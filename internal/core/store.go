@@ -41,6 +41,9 @@ type BoardSourcePatch struct {
 	Deletes     []BoardSourceDelete
 	LinkCreates []BoardSourceLinkCreate
 	LinkDeletes []BoardSourceLinkDelete
+	// RefuseCycles rolls the patch back with a *CycleError when its links
+	// would close a dependency cycle.
+	RefuseCycles bool
 }
 
 type BoardSourceCreate struct {
@@ -348,6 +351,18 @@ func (s *Store) Migrate(ctx context.Context) error {
 		created_at TEXT NOT NULL
 	)`)
 	_, _ = s.db.ExecContext(ctx, `ALTER TABLE board_views ADD COLUMN visibility TEXT NOT NULL DEFAULT 'personal'`)
+	_, _ = s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS field_definitions (
+		board_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		type TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		options_json TEXT NOT NULL DEFAULT '[]',
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (board_id, key)
+	)`)
+	if err := s.scopeFieldValues(ctx); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -557,8 +572,8 @@ func (s *Store) ensureNodeInBoard(ctx context.Context, boardID, nodeID string) e
 		return err
 	}
 	if !exists {
-		n, sourceID, externalID, url := placeholderNode(nodeID)
-		if err := s.UpsertSource(ctx, Source{ID: sourceID, Kind: sourceKind(sourceID), Name: sourceID, URL: sourceURL(sourceID), Capabilities: `{}`, Sync: `{}`, UpdatedAt: nowUTC()}); err != nil {
+		n, sourceID, externalID, url, err := s.placeholder(ctx, nodeID)
+		if err != nil {
 			return err
 		}
 		if err := s.UpsertNode(ctx, n); err != nil {
@@ -582,6 +597,78 @@ func (s *Store) ensureNodeInBoard(ctx context.Context, boardID, nodeID string) e
 	return nil
 }
 
+// placeholder is placeholderNode with its source created when missing. An
+// existing source is left alone, and the ref URL follows its base URL, so a
+// self-hosted GitLab, or a Gitea served over plain HTTP, keeps pointing at its
+// own host. Jira and Linear have no
+// default host: their issues only get a URL once their project or team was
+// synced.
+func (s *Store) placeholder(ctx context.Context, nodeID string) (Node, string, string, string, error) {
+	n, sourceID, externalID, url := placeholderNode(nodeID)
+	base, err := s.ensureSource(ctx, sourceID)
+	if err != nil {
+		return Node{}, "", "", "", err
+	}
+	return n, sourceID, externalID, placeholderURL(base, sourceID, externalID, url), nil
+}
+
+// placeholderURL moves a placeholder's default URL onto its source's base URL.
+func placeholderURL(base, sourceID, externalID, url string) string {
+	switch def := sourceURL(sourceID); {
+	case base == "":
+	case trackerPaths[sourceKind(sourceID)].issue != "":
+		paths := trackerPaths[sourceKind(sourceID)]
+		site, _, _ := strings.Cut(base, paths.project)
+		url = site + paths.issue + externalID
+	case def != "" && strings.HasPrefix(url, def):
+		url = base + strings.TrimPrefix(url, def)
+	}
+	return url
+}
+
+// trackerPaths are where the trackers with KEY-N issue keys serve a project,
+// or team, and an issue under their site URL.
+var trackerPaths = map[string]struct{ project, issue string }{
+	"jira":   {"/browse/", "/browse/"},
+	"linear": {"/team/", "/issue/"},
+}
+
+// ensureSource creates a source with default settings unless it exists, and
+// returns its URL.
+func (s *Store) ensureSource(ctx context.Context, sourceID string) (string, error) {
+	var url string
+	err := s.db.QueryRowContext(ctx, `SELECT url FROM sources WHERE id = ?`, sourceID).Scan(&url)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return url, err
+	}
+	url = sourceURL(sourceID)
+	return url, s.UpsertSource(ctx, Source{ID: sourceID, Kind: sourceKind(sourceID), Name: sourceID, URL: url, Capabilities: `{}`, Sync: `{}`, UpdatedAt: nowUTC()})
+}
+
+// ensureItemSource creates the source of an item imported on its own, from
+// what its URL tells: src as given, or ensureSource's defaults when src has no
+// URL. An existing source is left alone; its sync configured it.
+func (s *Store) ensureItemSource(ctx context.Context, src Source) error {
+	if src.URL == "" {
+		_, err := s.ensureSource(ctx, src.ID)
+		return err
+	}
+	exists, err := s.sourceExists(ctx, src.ID)
+	if err != nil || exists {
+		return err
+	}
+	src.UpdatedAt = nowUTC()
+	return s.UpsertSource(ctx, src)
+}
+
+func (s *Store) sourceExists(ctx context.Context, sourceID string) (bool, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sources WHERE id = ?`, sourceID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (s *Store) nodeExists(ctx context.Context, nodeID string) (bool, error) {
 	var count int
 	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM nodes WHERE id = ?`, nodeID).Scan(&count); err != nil {
@@ -623,6 +710,11 @@ func (s *Store) availableNodeID(ctx context.Context, base string) (string, error
 	return "", errors.New("could not allocate node id")
 }
 
+// BoardHasNode reports whether a node is an item of a board.
+func (s *Store) BoardHasNode(ctx context.Context, boardID, nodeID string) (bool, error) {
+	return s.boardItemExists(ctx, boardID, nodeID)
+}
+
 func (s *Store) boardItemExists(ctx context.Context, boardID, nodeID string) (bool, error) {
 	var count int
 	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM board_items WHERE board_id = ? AND node_id = ?`, boardID, nodeID).Scan(&count); err != nil {
@@ -803,6 +895,22 @@ func (s *Store) CreateBoardWithConfig(ctx context.Context, name, description, sc
 	return board, err
 }
 
+// SetBoardScope replaces the scope query a board syncs from.
+func (s *Store) SetBoardScope(ctx context.Context, boardID, scopeQuery string) (Board, error) {
+	scopeQuery = strings.TrimSpace(scopeQuery)
+	if _, err := ParseGitHubScope(scopeQuery); err != nil {
+		return Board{}, err
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE boards SET scope_query = ?, updated_at = ? WHERE id = ?`, scopeQuery, formatTime(nowUTC()), boardID)
+	if err != nil {
+		return Board{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Board{}, fmt.Errorf("board %s not found", boardID)
+	}
+	return s.board(ctx, boardID)
+}
+
 func (s *Store) AddTaskToBoard(ctx context.Context, boardID, title string) (Node, error) {
 	title = strings.TrimSpace(title)
 	if title == "" {
@@ -850,6 +958,8 @@ type boardSourcePatchApplier struct {
 	ctx     context.Context
 	tx      *sql.Tx
 	boardID string
+	// linked are the IDs of the edges the patch created.
+	linked map[string]bool
 }
 
 func (a *boardSourcePatchApplier) apply(patch BoardSourcePatch) error {
@@ -881,6 +991,17 @@ func (a *boardSourcePatchApplier) apply(patch BoardSourcePatch) error {
 			return err
 		}
 	}
+	if patch.RefuseCycles && len(a.linked) > 0 {
+		// The links are written by now, so the check sees exactly what the
+		// transaction would commit.
+		snap, err := a.cycleSnapshot()
+		if err != nil {
+			return err
+		}
+		if cycles := cyclesThrough(FindCycles(snap), a.linked); len(cycles) > 0 {
+			return &CycleError{Cycle: cycles[0]}
+		}
+	}
 	_, err := a.tx.ExecContext(a.ctx, `UPDATE boards SET updated_at = ? WHERE id = ?`, formatTime(nowUTC()), a.boardID)
 	return err
 }
@@ -1016,6 +1137,10 @@ func (a *boardSourcePatchApplier) applyLinkCreate(lc BoardSourceLinkCreate) erro
 	if err := a.upsertEdge(e); err != nil {
 		return err
 	}
+	if a.linked == nil {
+		a.linked = map[string]bool{}
+	}
+	a.linked[e.ID] = true
 	payload, _ := json.Marshal(e)
 	return a.recordEvent("depviz.edge.v1", e.ID, payload)
 }
@@ -1167,6 +1292,100 @@ func (a *boardSourcePatchApplier) nodeByID(nodeID string) (Node, error) {
 	return n, nil
 }
 
+// ensureNodeInBoard is Store.ensureNodeInBoard inside the transaction.
+func (a *boardSourcePatchApplier) ensureNodeInBoard(nodeID string) error {
+	exists, err := a.nodeExists(nodeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		n, sourceID, externalID, url, err := a.placeholder(nodeID)
+		if err != nil {
+			return err
+		}
+		if err := a.upsertNode(n); err != nil {
+			return err
+		}
+		if err := a.upsertSourceRef(n.ID, sourceID, externalID, url); err != nil {
+			return err
+		}
+	}
+	itemExists, err := a.boardItemExists(nodeID)
+	if err != nil {
+		return err
+	}
+	if !itemExists {
+		role := "card"
+		if strings.HasPrefix(nodeID, "note:") {
+			role = "note"
+		}
+		return a.addNodeToBoard(nodeID, role, "")
+	}
+	return nil
+}
+
+// placeholder is Store.placeholder inside the transaction.
+func (a *boardSourcePatchApplier) placeholder(nodeID string) (Node, string, string, string, error) {
+	n, sourceID, externalID, url := placeholderNode(nodeID)
+	var base string
+	err := a.tx.QueryRowContext(a.ctx, `SELECT url FROM sources WHERE id = ?`, sourceID).Scan(&base)
+	if errors.Is(err, sql.ErrNoRows) {
+		base = sourceURL(sourceID)
+		_, err = a.tx.ExecContext(a.ctx, `INSERT INTO sources(id, kind, name, url, capabilities_json, sync_json, updated_at)
+			VALUES(?, ?, ?, ?, ?, ?, ?)`, sourceID, sourceKind(sourceID), sourceID, base, `{}`, `{}`, formatTime(nowUTC()))
+	}
+	if err != nil {
+		return Node{}, "", "", "", err
+	}
+	return n, sourceID, externalID, placeholderURL(base, sourceID, externalID, url), nil
+}
+
+// addEdge is Store.AddEdgeWithConfidence inside the transaction.
+func (a *boardSourcePatchApplier) addEdge(fromID, toID, kind, authority string, confidence float64, evidence any) (Edge, error) {
+	if fromID == "" || toID == "" {
+		return Edge{}, errors.New("edge from and to are required")
+	}
+	if kind == "" {
+		kind = "blocked_by"
+	}
+	if authority == "" {
+		authority = "local"
+	}
+	if confidence <= 0 {
+		confidence = 1
+	}
+	if err := a.ensureNodeInBoard(fromID); err != nil {
+		return Edge{}, err
+	}
+	if err := a.ensureNodeInBoard(toID); err != nil {
+		return Edge{}, err
+	}
+	evidenceJSON := `{}`
+	if evidence != nil {
+		b, err := json.Marshal(evidence)
+		if err != nil {
+			return Edge{}, err
+		}
+		evidenceJSON = string(b)
+	}
+	e := Edge{
+		ID:           stableID("edge", a.boardID, fromID, toID, kind),
+		FromID:       fromID,
+		ToID:         toID,
+		Kind:         kind,
+		ScopeBoardID: a.boardID,
+		Confidence:   confidence,
+		Authority:    authority,
+		EvidenceJSON: evidenceJSON,
+		ObservedAt:   nowUTC(),
+	}
+	if err := a.upsertEdge(e); err != nil {
+		return Edge{}, err
+	}
+	payload, _ := json.Marshal(e)
+	return e, a.recordEvent("depviz.edge.v1", e.ID, payload)
+}
+
 func (a *boardSourcePatchApplier) upsertEdge(e Edge) error {
 	_, err := a.tx.ExecContext(a.ctx, `INSERT INTO edges(id, from_id, to_id, kind, scope_board_id, confidence, authority, evidence_json, observed_at)
 		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -1427,6 +1646,72 @@ func (s *Store) DeleteEdge(ctx context.Context, edgeID string) error {
 	return s.RecordEvent(ctx, "depviz.edge_delete.v1", edgeID, payload)
 }
 
+// pruneAuthorityEdges deletes the edges of one authority that touch a node on
+// a board, except those in keep. Trackers whose links are listed on both ends
+// use it to drop links that are gone.
+func (s *Store) pruneAuthorityEdges(ctx context.Context, boardID, nodeID, authority string, keep map[string]bool) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM edges WHERE scope_board_id = ? AND authority = ? AND (from_id = ? OR to_id = ?)`, boardID, authority, nodeID, nodeID)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range stale {
+		if err := s.DeleteEdge(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUnseenSourceNodes takes the nodes a file source refers to but did
+// not see in its last scan off a board and forgets their source refs. It
+// returns how many went.
+func (s *Store) removeUnseenSourceNodes(ctx context.Context, boardID, sourceID string, seen map[string]bool) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT node_id FROM source_refs WHERE source_id = ?`, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	var stale []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !seen[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	sort.Strings(stale)
+	for _, id := range stale {
+		if err := s.RemoveNodeFromBoard(ctx, boardID, id); err != nil {
+			return 0, err
+		}
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM source_refs WHERE source_id = ? AND node_id = ?`, sourceID, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
+
 // DuplicateNode creates a copy of an existing node with "Copy of " prefix.
 func (s *Store) DuplicateNode(ctx context.Context, boardID, nodeID string) (Node, error) {
 	src, err := s.nodeByID(ctx, nodeID)
@@ -1604,6 +1889,17 @@ func (s *Store) Snapshot(ctx context.Context, boardID string) (Snapshot, error)
 	if err := rows.Err(); err != nil {
 		return Snapshot{}, err
 	}
+	fields, err := s.FieldDefinitions(ctx, boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	values, err := s.boardFieldValues(ctx, boardID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for i := range nodes {
+		nodes[i].Fields = values[nodes[i].ID]
+	}
 	edgeRows, err := s.db.QueryContext(ctx, `SELECT id, from_id, to_id, kind, scope_board_id, confidence, authority, evidence_json, observed_at
 		FROM edges
 		WHERE scope_board_id = ? OR scope_board_id = ''
@@ -1622,20 +1918,21 @@ func (s *Store) Snapshot(ctx context.Context, boardID string) (Snapshot, error)
 		e.ObservedAt = parseTime(observed)
 		edges = append(edges, e)
 	}
-	return Snapshot{Board: board, Nodes: nodes, Edges: edges}, edgeRows.Err()
+	return Snapshot{Board: board, Nodes: nodes, Edges: edges, Fields: fields}, edgeRows.Err()
 }
 
 func (s *Store) IngestEvents(ctx context.Context, r io.Reader, defaultBoard string) (int, error) {
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 1024), 1024*1024)
-	count := 0
+	count, lineNo := 0, 0
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 		if err := s.IngestEvent(ctx, []byte(line), defaultBoard); err != nil {
-			return count, fmt.Errorf("line %d: %w", count+1, err)
+			return count, fmt.Errorf("line %d: %w", lineNo, err)
 		}
 		count++
 	}
@@ -1726,16 +2023,29 @@ func (s *Store) ingestNode(ctx context.Context, ev struct {
 		return errors.New("node id is required")
 	}
 	if ev.Source == "" {
-		if strings.HasPrefix(ev.ID, "note:") {
+		switch {
+		case strings.HasPrefix(ev.ID, "note:"):
 			ev.Source = LocalSourceID
-		} else {
+		case githubNodeRE.MatchString(ev.ID) || gitlabNodeRE.MatchString(ev.ID) || giteaNodeRE.MatchString(ev.ID) || trackerNodeRE.MatchString(ev.ID):
+			_, source, externalID, url, err := s.placeholder(ctx, ev.ID)
+			if err != nil {
+				return err
+			}
+			ev.Source = source
+			if ev.ExternalID == "" {
+				ev.ExternalID = externalID
+			}
+			if ev.URL == "" {
+				ev.URL = url
+			}
+		default:
 			ev.Source = "events"
 		}
 	}
 	if ev.ExternalID == "" {
 		ev.ExternalID = ev.ID
 	}
-	if err := s.UpsertSource(ctx, Source{ID: ev.Source, Kind: sourceKind(ev.Source), Name: ev.Source, Capabilities: `{}`, Sync: `{}`, UpdatedAt: nowUTC()}); err != nil {
+	if _, err := s.ensureSource(ctx, ev.Source); err != nil {
 		return err
 	}
 	n := Node{
@@ -1803,6 +2113,9 @@ func stableID(parts ...string) string {
 
 var slugRE = regexp.MustCompile(`[^a-z0-9]+`)
 var githubNodeRE = regexp.MustCompile(`^gh:([^#!]+)([#!])([0-9]+)$`)
+var gitlabNodeRE = regexp.MustCompile(`^gl:([^#!]+)([#!])([0-9]+)$`)
+var giteaNodeRE = regexp.MustCompile(`^gitea:([^/#!]+/[^/#!]+/[^/#!]+)([#!])([0-9]+)$`)
+var trackerNodeRE = regexp.MustCompile(`^(jira|linear):([A-Z][A-Z0-9_]*)-[0-9]+$`)
 
 func slug(s string) string {
 	s = strings.ToLower(strings.TrimSpace(s))
@@ -1818,6 +2131,18 @@ func sourceKind(id string) string {
 	if strings.HasPrefix(id, "github:") {
 		return "github"
 	}
+	if strings.HasPrefix(id, "gitlab:") {
+		return "gitlab"
+	}
+	if strings.HasPrefix(id, "gitea:") {
+		return "gitea"
+	}
+	if strings.HasPrefix(id, "jira:") {
+		return "jira"
+	}
+	if strings.HasPrefix(id, "linear:") {
+		return "linear"
+	}
 	if id == LocalSourceID {
 		return "local"
 	}
@@ -1844,6 +2169,27 @@ func placeholderNode(id string) (Node, string, string, string) {
 		externalID := marker + number
 		return Node{ID: id, Kind: kind, Title: id, State: "open", DataJSON: `{"placeholder":true}`, UpdatedAt: now}, sourceID, externalID, url
 	}
+	if m := gitlabNodeRE.FindStringSubmatch(id); m != nil {
+		kind, path := "issue", "issues"
+		if m[2] == "!" {
+			kind, path = "pr", "merge_requests"
+		}
+		sourceID := "gitlab:" + m[1]
+		url := fmt.Sprintf("%s/-/%s/%s", sourceURL(sourceID), path, m[3])
+		return Node{ID: id, Kind: kind, Title: id, State: "open", DataJSON: `{"placeholder":true}`, UpdatedAt: now}, sourceID, m[2] + m[3], url
+	}
+	if m := giteaNodeRE.FindStringSubmatch(id); m != nil {
+		kind, path := "issue", "issues"
+		if m[2] == "!" {
+			kind, path = "pr", "pulls"
+		}
+		sourceID := "gitea:" + m[1]
+		url := fmt.Sprintf("%s/%s/%s", sourceURL(sourceID), path, m[3])
+		return Node{ID: id, Kind: kind, Title: id, State: "open", DataJSON: `{"placeholder":true}`, UpdatedAt: now}, sourceID, m[2] + m[3], url
+	}
+	if m := trackerNodeRE.FindStringSubmatch(id); m != nil {
+		return Node{ID: id, Kind: "issue", Title: id, State: "open", DataJSON: `{"placeholder":true}`, UpdatedAt: now}, m[1] + ":" + m[2], strings.TrimPrefix(id, m[1]+":"), ""
+	}
 	return Node{ID: id, Kind: "task", Title: id, State: "open", DataJSON: `{"placeholder":true}`, UpdatedAt: now}, LocalSourceID, id, ""
 }
 
@@ -1851,6 +2197,12 @@ func sourceURL(sourceID string) string {
 	if strings.HasPrefix(sourceID, "github:") {
 		return "https://github.com/" + strings.TrimPrefix(sourceID, "github:")
 	}
+	if strings.HasPrefix(sourceID, "gitlab:") {
+		return "https://gitlab.com/" + strings.TrimPrefix(sourceID, "gitlab:")
+	}
+	if strings.HasPrefix(sourceID, "gitea:") {
+		return "https://" + strings.TrimPrefix(sourceID, "gitea:")
+	}
 	return ""
 }
 
@@ -1927,6 +2279,24 @@ func (s *Store) AddSyncLog(ctx context.Context, log SyncLog) error {
 	return err
 }
 
+// runSync logs a sync of a board: a running entry first, then the counts and
+// status once sync returns. sync's error wins over a failure to log.
+func (s *Store) runSync(ctx context.Context, boardID, mode string, sync func() (items, links int, err error)) error {
+	log := SyncLog{ID: fmt.Sprintf("sync-%d", time.Now().UnixNano()), BoardID: boardID, StartedAt: formatTime(nowUTC()), Status: "running", Mode: mode}
+	_ = s.AddSyncLog(ctx, log)
+	items, links, err := sync()
+	log.CompletedAt = formatTime(nowUTC())
+	log.ItemsSynced, log.EdgesSynced = items, links
+	log.Status = "ok"
+	if err != nil {
+		log.Status, log.Error = "failed", err.Error()
+	}
+	if logErr := s.AddSyncLog(ctx, log); err == nil && logErr != nil {
+		return logErr
+	}
+	return err
+}
+
 func (s *Store) GetSyncLogs(ctx context.Context, boardID string, limit int) ([]SyncLog, error) {
 	if limit <= 0 {
 		limit = 20
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -77,3 +79,62 @@ func TestApplyBoardSourcePatchRollsBackAllWrites(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyBoardSourcePatchRefusesCyclesInsideTheTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenStore(ctx, t.TempDir()+"/state.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	link := func(from, to string) BoardSourcePatch {
+		return BoardSourcePatch{RefuseCycles: true, LinkCreates: []BoardSourceLinkCreate{{FromID: from, ToID: to}}}
+	}
+	for i := range 20 {
+		a, b := fmt.Sprintf("task:a%d", i), fmt.Sprintf("task:b%d", i)
+		for _, id := range []string{a, b} {
+			if err := s.UpsertNode(ctx, Node{ID: id, Kind: "task"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddNodeToBoard(ctx, DefaultBoardID, id, "card", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var wg sync.WaitGroup
+		for _, p := range []BoardSourcePatch{link(a, b), link(b, a)} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Losing the race is either a cycle error or a busy store.
+				_ = s.ApplyBoardSourcePatch(ctx, DefaultBoardID, p)
+			}()
+		}
+		wg.Wait()
+	}
+	snap, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycles := FindCycles(snap); len(cycles) != 0 {
+		t.Fatalf("cycles = %+v, want none", cycles)
+	}
+
+	if err := s.ApplyBoardSourcePatch(ctx, DefaultBoardID, BoardSourcePatch{
+		Creates:     []BoardSourceCreate{{Kind: "task", Title: "c"}},
+		LinkCreates: []BoardSourceLinkCreate{{FromID: "task:a0", ToID: "task:c"}, {FromID: "task:b0", ToID: "task:c"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.ApplyBoardSourcePatch(ctx, DefaultBoardID, link("task:c", "task:a0"))
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("err = %v, want a cycle error", err)
+	}
+	after, err := s.Snapshot(ctx, DefaultBoardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(FindCycles(after)) != 0 {
+		t.Fatalf("refused patch was not rolled back: %+v", after.Edges)
+	}
+}
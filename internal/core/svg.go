@@ -0,0 +1,179 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// RenderSVG draws a snapshot as a self-contained SVG image, placed with
+// LayoutGraph, that needs no script or stylesheet to show. It is styled like
+// RenderGraph and honours its HideClosed, Focus and Depth options; Format is
+// ignored and clusters are not drawn.
+func RenderSVG(snap Snapshot, opts GraphRenderOptions) (string, error) {
+	if opts.Cluster != "" {
+		return "", errors.New("svg output cannot cluster nodes")
+	}
+	v, err := newGraphView(snap, opts)
+	if err != nil {
+		return "", err
+	}
+	lay := LayoutGraph(Snapshot{Board: snap.Board, Nodes: v.nodes, Edges: v.edges})
+	nodes := map[string]Node{}
+	for _, n := range v.nodes {
+		nodes[n.ID] = n
+	}
+	edges := map[[3]string]Edge{}
+	for _, e := range v.edges {
+		edges[[3]string{e.FromID, e.ToID, e.Kind}] = e
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n", lay.Width, lay.Height, lay.Width, lay.Height)
+	if snap.Board.Name != "" {
+		fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(snap.Board.Name))
+	}
+	sb.WriteString(`<defs><marker id="arrow" markerWidth="8" markerHeight="8" refX="6" refY="3" orient="auto"><path d="M0,0 L0,6 L7,3 z" fill="#9aa4b2"/></marker></defs>` + "\n")
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", lay.Width, lay.Height)
+
+	// Relations between the same two nodes share a path, so they are drawn
+	// once with every label.
+	type drawn struct {
+		edge   LayoutEdge
+		style  string
+		labels []string
+	}
+	var paths []*drawn
+	byPair := map[[2]string]*drawn{}
+	for _, le := range lay.Edges {
+		e := edges[[3]string{le.From, le.To, le.Kind}]
+		key := [2]string{le.From, le.To}
+		d, ok := byPair[key]
+		if !ok {
+			d = &drawn{edge: le, style: "plain"}
+			byPair[key] = d
+			paths = append(paths, d)
+		}
+		// The strongest style wins: bold, then plain, then dashed.
+		switch style := graphEdgeStyle(e); {
+		case style == "bold", d.labels == nil:
+			d.style = style
+		case style == "plain" && d.style == "dashed":
+			d.style = style
+		}
+		d.labels = appendUnique(d.labels, graphEdgeLabel(e))
+	}
+	sb.WriteString(`<g fill="none" stroke="#9aa4b2">` + "\n")
+	for _, d := range paths {
+		attrs := `stroke-width="1.4"`
+		switch d.style {
+		case "bold":
+			attrs = `stroke-width="2.4" stroke="#4b5563"`
+		case "dashed":
+			attrs += ` stroke-dasharray="5 4"`
+		}
+		fmt.Fprintf(&sb, `<path d="%s" %s marker-end="url(#arrow)"><title>%s</title></path>`+"\n", svgPath(d.edge.Points), attrs, html.EscapeString(d.edge.From+" "+strings.Join(d.labels, ", ")+" "+d.edge.To))
+	}
+	sb.WriteString("</g>\n")
+	sb.WriteString(`<g font-size="10" fill="#677084" text-anchor="middle">` + "\n")
+	for _, d := range paths {
+		if len(d.edge.Points) < 2 {
+			continue
+		}
+		mid := len(d.edge.Points) / 2
+		a, b := d.edge.Points[mid-1], d.edge.Points[mid]
+		fmt.Fprintf(&sb, `<text x="%d" y="%d">%s</text>`+"\n", (a.X+b.X)/2, (a.Y+b.Y)/2-4, html.EscapeString(strings.Join(d.labels, ", ")))
+	}
+	sb.WriteString("</g>\n")
+
+	for _, ln := range lay.Nodes {
+		n := nodes[ln.ID]
+		fill, ink, stroke := "#ffffff", "#20242c", "#d9deea"
+		switch {
+		case n.IsClosed():
+			fill, ink = "#e5e7eb", "#6b7280"
+		case n.IsLocalOnly():
+			fill, stroke = "#fef3c7", "#d97706"
+		}
+		dash := ""
+		if n.IsPlaceholder() {
+			dash = ` stroke-dasharray="4 3"`
+		}
+		if n.URL != "" {
+			fmt.Fprintf(&sb, `<a href="%s" xlink:href="%s">`, html.EscapeString(n.URL), html.EscapeString(n.URL))
+		}
+		fmt.Fprintf(&sb, `<g><title>%s</title><rect x="%d" y="%d" width="%d" height="%d" rx="8" fill="%s" stroke="%s"%s/>`, html.EscapeString(n.ID), ln.X, ln.Y, ln.W, ln.H, fill, stroke, dash)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="11" fill="#677084">%s</text>`, ln.X+10, ln.Y+18, html.EscapeString(svgClip(n.ID, 32)))
+		if n.Title != n.ID {
+			for i, line := range svgWrap(n.Title, 30, 2) {
+				fmt.Fprintf(&sb, `<text x="%d" y="%d" font-weight="bold" fill="%s">%s</text>`, ln.X+10, ln.Y+36+i*15, ink, html.EscapeString(line))
+			}
+		}
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="11" fill="#677084">%s</text></g>`, ln.X+10, ln.Y+ln.H-10, html.EscapeString(strings.TrimSuffix(n.Kind+" - "+n.State, " - ")))
+		if n.URL != "" {
+			sb.WriteString("</a>")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String(), nil
+}
+
+// svgPath joins layout points with straight runs through the layers an edge
+// crosses and S-curves between them.
+func svgPath(points []LayoutPoint) string {
+	if len(points) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "M%d,%d", points[0].X, points[0].Y)
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if a.Y == b.Y {
+			fmt.Fprintf(&sb, " L%d,%d", b.X, b.Y)
+			continue
+		}
+		mx := (a.X + b.X) / 2
+		fmt.Fprintf(&sb, " C%d,%d %d,%d %d,%d", mx, a.Y, mx, b.Y, b.X, b.Y)
+	}
+	return sb.String()
+}
+
+func svgClip(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+// svgWrap breaks text into at most lines lines of width runes, ending the
+// last with an ellipsis when the text does not fit.
+func svgWrap(text string, width, lines int) []string {
+	var out []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			out = append(out, line)
+			line = word
+		}
+	}
+	if line != "" {
+		out = append(out, line)
+	}
+	for i := range out {
+		out[i] = svgClip(out[i], width)
+	}
+	if len(out) > lines {
+		out = out[:lines]
+		if last := svgClip(out[lines-1], width-1); !strings.HasSuffix(last, "…") {
+			out[lines-1] = last + "…"
+		}
+	}
+	return out
+}
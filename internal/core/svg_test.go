@@ -0,0 +1,55 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSVGIsSelfContained(t *testing.T) {
+	snap := Snapshot{
+		Board: Board{ID: "default", Name: "Launch <Q3>"},
+		Nodes: []Node{
+			{ID: "gh:acme/api#1", Kind: "issue", Title: "Ship the new authentication flow to every customer before launch", State: "open", URL: "https://github.com/acme/api/issues/1?a=1&b=2"},
+			{ID: "gh:acme/api#2", Kind: "issue", Title: "Auth", State: "closed"},
+			{ID: "note:plan", Kind: "note", Title: "Plan", State: "local"},
+		},
+		Edges: []Edge{
+			{FromID: "gh:acme/api#1", ToID: "gh:acme/api#2", Kind: "blocked_by", Authority: "github", Confidence: 1},
+			{FromID: "note:plan", ToID: "gh:acme/api#1", Kind: "mentions", Authority: "local", Confidence: 1},
+			{FromID: "note:plan", ToID: "gh:acme/api#1", Kind: "relates_to", Authority: "github-inferred", Confidence: 0.55},
+		},
+	}
+	got, err := RenderSVG(snap, GraphRenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="870" height="144"`,
+		`<title>Launch &lt;Q3&gt;</title>`,
+		`<path d="M540,72 L630,72" stroke-width="2.4" stroke="#4b5563" marker-end="url(#arrow)">`,
+		`<path d="M240,72 L330,72" stroke-width="1.4" marker-end="url(#arrow)"><title>note:plan mentions, relates to gh:acme/api#1</title>`,
+		`<a href="https://github.com/acme/api/issues/1?a=1&amp;b=2"`,
+		`>Ship the new authentication</text>`,
+		`>flow to every customer before…</text>`,
+		`fill="#e5e7eb" stroke="#d9deea"/>`,
+		`fill="#fef3c7" stroke="#d97706"/>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("svg is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script") || strings.Contains(got, "<style") {
+		t.Fatalf("svg is not self-contained:\n%s", got)
+	}
+
+	got, err = RenderSVG(snap, GraphRenderOptions{HideClosed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "Auth<") {
+		t.Fatalf("closed node rendered:\n%s", got)
+	}
+	if _, err := RenderSVG(snap, GraphRenderOptions{Cluster: "repo"}); err == nil {
+		t.Fatal("cluster accepted")
+	}
+}